@@ -0,0 +1,111 @@
+package crdt
+
+import (
+	"sync"
+	"time"
+)
+
+// Timestamp is a hybrid logical clock reading
+// Timestamps follow wall time closely, yet order every event a node has seen before its own
+// The node name breaks ties, so readings of different nodes are never equal
+type Timestamp struct {
+	// Wall is the wall time in nanoseconds since the Unix epoch
+	Wall int64
+	// Logical orders events sharing the same wall time
+	Logical uint32
+	Node    string
+}
+
+// Less checks if t is ordered before u
+func (t Timestamp) Less(u Timestamp) bool {
+	if t.Wall != u.Wall {
+		return t.Wall < u.Wall
+	}
+
+	if t.Logical != u.Logical {
+		return t.Logical < u.Logical
+	}
+
+	return t.Node < u.Node
+}
+
+// IsZero checks if t is the zero Timestamp
+func (t Timestamp) IsZero() bool {
+	return t.Wall == 0 && t.Logical == 0 && t.Node == ""
+}
+
+// Clock is a hybrid logical clock of a node
+// A clock can be shared by every store of the node
+// Embedded sync.Mutex to provide atomic operation ability
+type Clock struct {
+	sync.Mutex
+	node string
+	last Timestamp
+	now  func() int64
+}
+
+// NewClock constructs and initializes a new Clock of the given node
+// Always use this function when creating a new Clock
+func NewClock(node string) *Clock {
+	return &Clock{
+		node: node,
+		last: Timestamp{Node: node},
+		now:  func() int64 { return time.Now().UnixNano() },
+	}
+}
+
+// Node returns the node name of the clock
+func (c *Clock) Node() string {
+	return c.node
+}
+
+func (c *Clock) tick() Timestamp {
+	if wall := c.now(); wall > c.last.Wall {
+		c.last.Wall = wall
+		c.last.Logical = 0
+	} else {
+		c.last.Logical++
+	}
+
+	return c.last
+}
+
+// Now returns a timestamp ordered after every timestamp the clock has returned or seen
+func (c *Clock) Now() Timestamp {
+	c.Lock()
+	t := c.tick()
+	c.Unlock()
+
+	return t
+}
+
+func (c *Clock) update(remote Timestamp) Timestamp {
+	wall := c.now()
+	switch {
+	case wall > c.last.Wall && wall > remote.Wall:
+		c.last.Wall = wall
+		c.last.Logical = 0
+	case c.last.Wall == remote.Wall:
+		if remote.Logical > c.last.Logical {
+			c.last.Logical = remote.Logical
+		}
+		c.last.Logical++
+	case c.last.Wall > remote.Wall:
+		c.last.Logical++
+	default:
+		c.last.Wall = remote.Wall
+		c.last.Logical = remote.Logical + 1
+	}
+
+	return c.last
+}
+
+// Update advances the clock past a timestamp received from another node
+// returns a timestamp ordered after both the remote timestamp and every local one
+func (c *Clock) Update(remote Timestamp) Timestamp {
+	c.Lock()
+	t := c.update(remote)
+	c.Unlock()
+
+	return t
+}
@@ -0,0 +1,90 @@
+package crdt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockClock returns a clock of node reading wall times from *wall
+func mockClock(node string, wall *int64) *Clock {
+	c := NewClock(node)
+	c.now = func() int64 { return *wall }
+
+	return c
+}
+
+func TestTimestampLess(t *testing.T) {
+	a := Timestamp{Wall: 1, Logical: 5, Node: "b"}
+
+	assert.True(t, a.Less(Timestamp{Wall: 2}))
+	assert.False(t, a.Less(Timestamp{Wall: 0, Logical: 9}))
+	assert.True(t, a.Less(Timestamp{Wall: 1, Logical: 6}))
+	assert.True(t, a.Less(Timestamp{Wall: 1, Logical: 5, Node: "c"}))
+	assert.False(t, a.Less(Timestamp{Wall: 1, Logical: 5, Node: "a"}))
+	assert.False(t, a.Less(a))
+
+	assert.True(t, Timestamp{}.IsZero())
+	assert.False(t, a.IsZero())
+}
+
+func TestClockNow(t *testing.T) {
+	wall := int64(100)
+	c := mockClock("a", &wall)
+	assert.Equal(t, "a", c.Node())
+
+	assert.Equal(t, Timestamp{Wall: 100, Node: "a"}, c.Now())
+	assert.Equal(t, Timestamp{Wall: 100, Logical: 1, Node: "a"}, c.Now())
+
+	// wall time moved forward
+	wall = 200
+	assert.Equal(t, Timestamp{Wall: 200, Node: "a"}, c.Now())
+
+	// wall time moved backwards, the clock does not
+	wall = 150
+	assert.Equal(t, Timestamp{Wall: 200, Logical: 1, Node: "a"}, c.Now())
+}
+
+func TestClockUpdate(t *testing.T) {
+	wall := int64(100)
+	c := mockClock("a", &wall)
+	c.Now()
+
+	// remote ahead of the local clock
+	assert.Equal(t, Timestamp{Wall: 300, Logical: 3, Node: "a"}, c.Update(Timestamp{Wall: 300, Logical: 2, Node: "b"}))
+
+	// same wall time, the higher logical counter wins
+	assert.Equal(t, Timestamp{Wall: 300, Logical: 8, Node: "a"}, c.Update(Timestamp{Wall: 300, Logical: 7, Node: "b"}))
+	assert.Equal(t, Timestamp{Wall: 300, Logical: 9, Node: "a"}, c.Update(Timestamp{Wall: 300, Logical: 1, Node: "b"}))
+
+	// remote behind the local clock
+	assert.Equal(t, Timestamp{Wall: 300, Logical: 10, Node: "a"}, c.Update(Timestamp{Wall: 50, Node: "b"}))
+
+	// wall time passed both
+	wall = 400
+	assert.Equal(t, Timestamp{Wall: 400, Node: "a"}, c.Update(Timestamp{Wall: 350, Node: "b"}))
+
+	// every later reading is ordered after the remote timestamp
+	remote := Timestamp{Wall: 500, Logical: 4, Node: "z"}
+	c.Update(remote)
+	assert.True(t, remote.Less(c.Now()))
+}
+
+func TestClockConcurrentNow(t *testing.T) {
+	c := NewClock("a")
+	out := make(chan Timestamp, 4000)
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 1000; j++ {
+				out <- c.Now()
+			}
+		}()
+	}
+
+	seen := make(map[Timestamp]bool)
+	for i := 0; i < 4000; i++ {
+		ts := <-out
+		assert.False(t, seen[ts])
+		seen[ts] = true
+	}
+}
@@ -0,0 +1,38 @@
+// Package crdt provides conflict-free replicated stores for processes that all accept writes
+//
+// Each replica applies its own writes locally and exchanges state with the other replicas in any order,
+// any number of times, and every replica converges to the same contents without coordination.
+// Replicas are identified by a node name that must be unique among the replicas of a store.
+//
+// Every store has a State, the full state to bring a new replica up to date, and a Delta,
+// the entries changed since the last Delta call. Both merge into another replica with Merge,
+// and both encode to bytes with MarshalBinary for sending between processes
+package crdt
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+)
+
+var (
+	// ErrKeyDoesNotExist is thrown when a search key is not found
+	ErrKeyDoesNotExist = errors.New("key does not exist")
+	// ErrCounterOverflow is thrown when an increment would overflow a counter
+	ErrCounterOverflow = errors.New("increment would overflow counter")
+)
+
+// encode gob encodes a state
+func encode(state interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decode gob decodes data into the state pointed to by state
+func decode(data []byte, state interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(state)
+}
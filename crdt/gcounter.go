@@ -0,0 +1,203 @@
+package crdt
+
+import (
+	"sync"
+)
+
+// GCounterState is the state of a GCounterStore, or a delta of it
+// Maps each key to the count of every node that incremented it
+type GCounterState map[string]map[string]uint64
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (g GCounterState) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding g would call MarshalBinary again
+	return encode(map[string]map[string]uint64(g))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (g *GCounterState) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]map[string]uint64)(g))
+}
+
+func (g GCounterState) value(key string) (uint64, bool) {
+	counts, ok := g[key]
+	if !ok {
+		return 0, false
+	}
+
+	var v uint64
+	for _, c := range counts {
+		v += c
+	}
+
+	return v, true
+}
+
+func (g GCounterState) setCount(key, node string, count uint64) {
+	counts, ok := g[key]
+	if !ok {
+		counts = make(map[string]uint64)
+		g[key] = counts
+	}
+
+	counts[node] = count
+}
+
+// incr adds delta to the count of node, checking the total of the key does not overflow
+func (g GCounterState) incr(key, node string, delta uint64) (uint64, error) {
+	v, _ := g.value(key)
+	if v+delta < v {
+		return v, ErrCounterOverflow
+	}
+
+	g.setCount(key, node, g[key][node]+delta)
+
+	return v + delta, nil
+}
+
+// merge raises every count of g to the counts of other, recording raised counts in delta
+func (g GCounterState) merge(other, delta GCounterState) {
+	for k, counts := range other {
+		for node, c := range counts {
+			if cur, ok := g[k][node]; ok && cur >= c {
+				continue
+			}
+
+			g.setCount(k, node, c)
+			delta.setCount(k, node, c)
+		}
+	}
+}
+
+func (g GCounterState) copy() GCounterState {
+	c := make(GCounterState, len(g))
+	for k, counts := range g {
+		cc := make(map[string]uint64, len(counts))
+		for node, v := range counts {
+			cc[node] = v
+		}
+		c[k] = cc
+	}
+
+	return c
+}
+
+// GCounterStore is a store of grow-only counters
+// Each node counts its own increments, the value of a key is the sum over all nodes,
+// so increments made concurrently on different nodes are never lost
+// Embedded sync.Mutex to provide atomic operation ability
+type GCounterStore struct {
+	sync.Mutex
+	node  string
+	store GCounterState
+	delta GCounterState
+}
+
+// NewGCounterStore constructs and initializes a new GCounterStore for the given node
+// Always use this function when creating a new GCounterStore
+func NewGCounterStore(node string) *GCounterStore {
+	return &GCounterStore{node: node, store: make(GCounterState), delta: make(GCounterState)}
+}
+
+func (s *GCounterStore) incr(key string, delta uint64) (uint64, error) {
+	v, err := s.store.incr(key, s.node, delta)
+	if err != nil {
+		return v, err
+	}
+
+	s.delta.setCount(key, s.node, s.store[key][s.node])
+
+	return v, nil
+}
+
+// Incr adds delta to the counter of the given key, creating the key if it does not exist
+// returns the new value, or ErrCounterOverflow leaving the counter unchanged
+func (s *GCounterStore) Incr(key string, delta uint64) (uint64, error) {
+	s.Lock()
+	v, err := s.incr(key, delta)
+	s.Unlock()
+
+	return v, err
+}
+
+// Get returns the value of the counter of the given key
+func (s *GCounterStore) Get(key string) (uint64, bool) {
+	s.Lock()
+	v, ok := s.store.value(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// State returns a copy of the full state of the store
+func (s *GCounterStore) State() GCounterState {
+	s.Lock()
+	st := s.store.copy()
+	s.Unlock()
+
+	return st
+}
+
+func (s *GCounterStore) takeDelta() GCounterState {
+	d := s.delta
+	s.delta = make(GCounterState)
+
+	return d
+}
+
+// Delta returns the counts changed since the last Delta call, by local increments or merges
+func (s *GCounterStore) Delta() GCounterState {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *GCounterStore) Merge(state GCounterState) {
+	s.Lock()
+	s.store.merge(state, s.delta)
+	s.Unlock()
+}
+
+func (s *GCounterStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the number of keys in the store
+func (s *GCounterStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *GCounterStore) members() []string {
+	mems := make([]string, 0, len(s.store))
+	for k := range s.store {
+		mems = append(mems, k)
+	}
+
+	return mems
+}
+
+// Members returns a list of keys in the store
+func (s *GCounterStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *GCounterStore) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.store[key]
+	s.Unlock()
+
+	return ok
+}
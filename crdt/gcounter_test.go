@@ -0,0 +1,147 @@
+package crdt
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGCounterIncr(t *testing.T) {
+	s := NewGCounterStore("a")
+
+	v, err := s.Incr("hits", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), v)
+	v, _ = s.Incr("hits", 3)
+	assert.Equal(t, uint64(5), v)
+	assert.Equal(t, GCounterState{"hits": {"a": 5}}, s.store)
+
+	v, ok := s.Get("hits")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(5), v)
+	_, ok = s.Get("misses")
+	assert.False(t, ok)
+
+	// overflow leaves the counter unchanged
+	v, err = s.Incr("hits", math.MaxUint64)
+	assert.Equal(t, ErrCounterOverflow, err)
+	assert.Equal(t, uint64(5), v)
+	assert.Equal(t, GCounterState{"hits": {"a": 5}}, s.store)
+}
+
+func TestGCounterMerge(t *testing.T) {
+	a := NewGCounterStore("a")
+	b := NewGCounterStore("b")
+
+	a.Incr("hits", 2)
+	b.Incr("hits", 3)
+	b.Incr("misses", 1)
+
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+
+	v, _ := a.Get("hits")
+	assert.Equal(t, uint64(5), v)
+	v, _ = b.Get("misses")
+	assert.Equal(t, uint64(1), v)
+
+	// idempotent
+	a.Merge(b.State())
+	a.Merge(b.State())
+	v, _ = a.Get("hits")
+	assert.Equal(t, uint64(5), v)
+
+	// stale states never lower a count
+	old := a.State()
+	a.Incr("hits", 1)
+	a.Merge(old)
+	v, _ = a.Get("hits")
+	assert.Equal(t, uint64(6), v)
+}
+
+func TestGCounterDelta(t *testing.T) {
+	a := NewGCounterStore("a")
+	b := NewGCounterStore("b")
+
+	a.Incr("hits", 2)
+	a.Incr("hits", 1)
+	a.Incr("misses", 4)
+	d := a.Delta()
+	assert.Equal(t, GCounterState{"hits": {"a": 3}, "misses": {"a": 4}}, d)
+	assert.Equal(t, GCounterState{}, a.Delta())
+
+	b.Merge(d)
+	v, _ := b.Get("hits")
+	assert.Equal(t, uint64(3), v)
+
+	// merged changes are forwarded, merging them again forwards nothing
+	assert.Equal(t, d, b.Delta())
+	b.Merge(d)
+	assert.Equal(t, GCounterState{}, b.Delta())
+}
+
+func TestGCounterState(t *testing.T) {
+	s := NewGCounterStore("a")
+	s.Incr("hits", 1)
+
+	st := s.State()
+	st["hits"]["a"] = 10
+	v, _ := s.Get("hits")
+	assert.Equal(t, uint64(1), v)
+
+	b, err := s.State().MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded GCounterState
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Equal(t, s.State(), decoded)
+
+	assert.NotNil(t, decoded.UnmarshalBinary([]byte("x")))
+}
+
+func TestGCounterSize(t *testing.T) {
+	s := NewGCounterStore("a")
+	s.Incr("a", 1)
+	s.Incr("b", 1)
+
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []string{"a", "b"}, s.Members())
+	assert.True(t, s.IsMember("a"))
+	assert.False(t, s.IsMember("c"))
+}
+
+func TestGCounterConcurrentIncrAndMerge(t *testing.T) {
+	nodes := []*GCounterStore{NewGCounterStore("a"), NewGCounterStore("b"), NewGCounterStore("c")}
+	for _, s := range nodes {
+		go func(s *GCounterStore) {
+			for i := 0; i < 1000; i++ {
+				s.Incr("hits", 1)
+			}
+		}(s)
+	}
+
+	for i := 0; i < 100; i++ {
+		for _, s := range nodes {
+			for _, o := range nodes {
+				o.Merge(s.Delta())
+			}
+		}
+	}
+
+	time.Sleep(2 * time.Second)
+
+	// exchange full states once writes stop
+	for _, s := range nodes {
+		for _, o := range nodes {
+			o.Merge(s.State())
+		}
+	}
+
+	for _, s := range nodes {
+		v, _ := s.Get("hits")
+		assert.Equal(t, uint64(3000), v)
+	}
+}
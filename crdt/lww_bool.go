@@ -0,0 +1,170 @@
+package crdt
+
+import (
+	"sync"
+)
+
+// LWWBoolEntry is a register of an LWWBoolStore
+type LWWBoolEntry struct {
+	Value bool
+	// Time is when the value was written, the latest write wins
+	Time Timestamp
+	// Deleted marks a deleted key, kept so a delete wins over older writes merged later
+	Deleted bool
+}
+
+// LWWBoolState is the state of an LWWBoolStore, or a delta of it
+type LWWBoolState map[string]LWWBoolEntry
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (l LWWBoolState) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding l would call MarshalBinary again
+	return encode(map[string]LWWBoolEntry(l))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *LWWBoolState) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]LWWBoolEntry)(l))
+}
+
+// LWWBoolStore is a store of last-writer-wins bool registers
+// Every write is stamped by a hybrid logical clock, replicas keep the value with the latest timestamp
+// Embedded sync.Mutex to provide atomic operation ability
+type LWWBoolStore struct {
+	sync.Mutex
+	clock *Clock
+	store LWWBoolState
+	delta LWWBoolState
+}
+
+// NewLWWBoolStore constructs and initializes a new LWWBoolStore stamping writes with the given clock
+// Always use this function when creating a new LWWBoolStore
+func NewLWWBoolStore(clock *Clock) *LWWBoolStore {
+	return &LWWBoolStore{clock: clock, store: make(LWWBoolState), delta: make(LWWBoolState)}
+}
+
+func (s *LWWBoolStore) write(key string, e LWWBoolEntry) {
+	s.store[key] = e
+	s.delta[key] = e
+}
+
+// Set stores the given value mapped to the given key
+func (s *LWWBoolStore) Set(key string, value bool) {
+	s.Lock()
+	s.write(key, LWWBoolEntry{Value: value, Time: s.clock.Now()})
+	s.Unlock()
+}
+
+// Delete removes the given key and its value from the store
+// A tombstone is kept, so the key stays deleted on replicas merging older writes
+func (s *LWWBoolStore) Delete(key string) {
+	s.Lock()
+	s.write(key, LWWBoolEntry{Time: s.clock.Now(), Deleted: true})
+	s.Unlock()
+}
+
+func (s *LWWBoolStore) get(key string) (bool, bool) {
+	e, ok := s.store[key]
+	if !ok || e.Deleted {
+		var zero bool
+		return zero, false
+	}
+
+	return e.Value, true
+}
+
+// Get returns the value for the given key
+func (s *LWWBoolStore) Get(key string) (bool, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// State returns a copy of the full state of the store, including tombstones
+func (s *LWWBoolStore) State() LWWBoolState {
+	s.Lock()
+	st := make(LWWBoolState, len(s.store))
+	for k, e := range s.store {
+		st[k] = e
+	}
+	s.Unlock()
+
+	return st
+}
+
+func (s *LWWBoolStore) takeDelta() LWWBoolState {
+	d := s.delta
+	s.delta = make(LWWBoolState)
+
+	return d
+}
+
+// Delta returns the registers changed since the last Delta call, by local writes or merges
+func (s *LWWBoolStore) Delta() LWWBoolState {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+func (s *LWWBoolStore) merge(state LWWBoolState) {
+	for k, e := range state {
+		// remote timestamps advance the clock, so later local writes win over them
+		s.clock.Update(e.Time)
+
+		if cur, ok := s.store[k]; ok && !cur.Time.Less(e.Time) {
+			continue
+		}
+
+		s.write(k, e)
+	}
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *LWWBoolStore) Merge(state LWWBoolState) {
+	s.Lock()
+	s.merge(state)
+	s.Unlock()
+}
+
+func (s *LWWBoolStore) members() []string {
+	mems := make([]string, 0, len(s.store))
+	for k, e := range s.store {
+		if !e.Deleted {
+			mems = append(mems, k)
+		}
+	}
+
+	return mems
+}
+
+// Size returns the number of keys in the store, not counting deleted keys
+func (s *LWWBoolStore) Size() int {
+	s.Lock()
+	size := len(s.members())
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *LWWBoolStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *LWWBoolStore) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.get(key)
+	s.Unlock()
+
+	return ok
+}
@@ -0,0 +1,148 @@
+package crdt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLWWBoolSetGet(t *testing.T) {
+	wall := int64(100)
+	s := NewLWWBoolStore(mockClock("a", &wall))
+
+	s.Set("a", true)
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, true, v)
+	assert.Equal(t, Timestamp{Wall: 100, Node: "a"}, s.store["a"].Time)
+
+	s.Set("a", false)
+	v, _ = s.Get("a")
+	assert.Equal(t, false, v)
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+	assert.True(t, s.store["a"].Deleted)
+	assert.False(t, s.IsMember("a"))
+}
+
+func TestLWWBoolMerge(t *testing.T) {
+	wallA, wallB := int64(100), int64(200)
+	a := NewLWWBoolStore(mockClock("a", &wallA))
+	b := NewLWWBoolStore(mockClock("b", &wallB))
+
+	a.Set("x", true)
+	b.Set("x", false)
+	a.Set("y", true)
+
+	// the later write wins on both replicas
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+	v, _ := a.Get("x")
+	assert.Equal(t, false, v)
+
+	// a write after a merge wins over the merged value even with a slow wall clock
+	a.Set("x", true)
+	b.Merge(a.Delta())
+	v, _ = b.Get("x")
+	assert.Equal(t, true, v)
+
+	// a delete wins over older writes merged later
+	old := a.State()
+	b.Delete("y")
+	b.Merge(old)
+	assert.False(t, b.IsMember("y"))
+	a.Merge(b.Delta())
+	assert.False(t, a.IsMember("y"))
+	assert.Equal(t, a.State(), b.State())
+}
+
+func TestLWWBoolMergeTie(t *testing.T) {
+	wall := int64(100)
+	a := NewLWWBoolStore(mockClock("a", &wall))
+	b := NewLWWBoolStore(mockClock("b", &wall))
+
+	// same wall time and logical counter, the node name decides
+	a.Set("x", true)
+	b.Set("x", false)
+	a.Merge(b.State())
+	b.Merge(a.State())
+
+	v, _ := a.Get("x")
+	assert.Equal(t, false, v)
+	v, _ = b.Get("x")
+	assert.Equal(t, false, v)
+}
+
+func TestLWWBoolDelta(t *testing.T) {
+	s := NewLWWBoolStore(NewClock("a"))
+	s.Set("x", true)
+	s.Set("y", true)
+	s.Delete("y")
+
+	d := s.Delta()
+	assert.Len(t, d, 2)
+	assert.True(t, d["y"].Deleted)
+	assert.Len(t, s.Delta(), 0)
+
+	// encoded deltas merge like states
+	b, err := d.MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded LWWBoolState
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Len(t, decoded, 2)
+	assert.True(t, decoded["x"].Time == d["x"].Time)
+
+	r := NewLWWBoolStore(NewClock("b"))
+	r.Merge(decoded)
+	v, ok := r.Get("x")
+	assert.True(t, ok)
+	assert.Equal(t, true, v)
+	assert.False(t, r.IsMember("y"))
+
+	// merged changes are forwarded once
+	assert.Len(t, r.Delta(), 2)
+	r.Merge(decoded)
+	assert.Len(t, r.Delta(), 0)
+}
+
+func TestLWWBoolSize(t *testing.T) {
+	s := NewLWWBoolStore(NewClock("a"))
+	s.Set("x", true)
+	s.Set("y", true)
+	s.Set("z", true)
+	s.Delete("z")
+
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []string{"x", "y"}, s.Members())
+	assert.Len(t, s.State(), 3)
+}
+
+func TestLWWBoolConcurrentSetAndMerge(t *testing.T) {
+	a := NewLWWBoolStore(NewClock("a"))
+	b := NewLWWBoolStore(NewClock("b"))
+
+	for _, s := range []*LWWBoolStore{a, b} {
+		go func(s *LWWBoolStore) {
+			for i := 0; i < 1000; i++ {
+				s.Set("x", true)
+				s.Set("x", false)
+			}
+		}(s)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.Merge(b.Delta())
+		b.Merge(a.Delta())
+	}
+
+	time.Sleep(2 * time.Second)
+
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+}
@@ -0,0 +1,172 @@
+package crdt
+
+import (
+	"sync"
+
+	"github.com/blacklabcapital/safestore/decimal"
+)
+
+// LWWDecimalEntry is a register of an LWWDecimalStore
+type LWWDecimalEntry struct {
+	Value decimal.Decimal
+	// Time is when the value was written, the latest write wins
+	Time Timestamp
+	// Deleted marks a deleted key, kept so a delete wins over older writes merged later
+	Deleted bool
+}
+
+// LWWDecimalState is the state of an LWWDecimalStore, or a delta of it
+type LWWDecimalState map[string]LWWDecimalEntry
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (l LWWDecimalState) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding l would call MarshalBinary again
+	return encode(map[string]LWWDecimalEntry(l))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *LWWDecimalState) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]LWWDecimalEntry)(l))
+}
+
+// LWWDecimalStore is a store of last-writer-wins decimal.Decimal registers
+// Every write is stamped by a hybrid logical clock, replicas keep the value with the latest timestamp
+// Embedded sync.Mutex to provide atomic operation ability
+type LWWDecimalStore struct {
+	sync.Mutex
+	clock *Clock
+	store LWWDecimalState
+	delta LWWDecimalState
+}
+
+// NewLWWDecimalStore constructs and initializes a new LWWDecimalStore stamping writes with the given clock
+// Always use this function when creating a new LWWDecimalStore
+func NewLWWDecimalStore(clock *Clock) *LWWDecimalStore {
+	return &LWWDecimalStore{clock: clock, store: make(LWWDecimalState), delta: make(LWWDecimalState)}
+}
+
+func (s *LWWDecimalStore) write(key string, e LWWDecimalEntry) {
+	s.store[key] = e
+	s.delta[key] = e
+}
+
+// Set stores the given value mapped to the given key
+func (s *LWWDecimalStore) Set(key string, value decimal.Decimal) {
+	s.Lock()
+	s.write(key, LWWDecimalEntry{Value: value, Time: s.clock.Now()})
+	s.Unlock()
+}
+
+// Delete removes the given key and its value from the store
+// A tombstone is kept, so the key stays deleted on replicas merging older writes
+func (s *LWWDecimalStore) Delete(key string) {
+	s.Lock()
+	s.write(key, LWWDecimalEntry{Time: s.clock.Now(), Deleted: true})
+	s.Unlock()
+}
+
+func (s *LWWDecimalStore) get(key string) (decimal.Decimal, bool) {
+	e, ok := s.store[key]
+	if !ok || e.Deleted {
+		var zero decimal.Decimal
+		return zero, false
+	}
+
+	return e.Value, true
+}
+
+// Get returns the value for the given key
+func (s *LWWDecimalStore) Get(key string) (decimal.Decimal, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// State returns a copy of the full state of the store, including tombstones
+func (s *LWWDecimalStore) State() LWWDecimalState {
+	s.Lock()
+	st := make(LWWDecimalState, len(s.store))
+	for k, e := range s.store {
+		st[k] = e
+	}
+	s.Unlock()
+
+	return st
+}
+
+func (s *LWWDecimalStore) takeDelta() LWWDecimalState {
+	d := s.delta
+	s.delta = make(LWWDecimalState)
+
+	return d
+}
+
+// Delta returns the registers changed since the last Delta call, by local writes or merges
+func (s *LWWDecimalStore) Delta() LWWDecimalState {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+func (s *LWWDecimalStore) merge(state LWWDecimalState) {
+	for k, e := range state {
+		// remote timestamps advance the clock, so later local writes win over them
+		s.clock.Update(e.Time)
+
+		if cur, ok := s.store[k]; ok && !cur.Time.Less(e.Time) {
+			continue
+		}
+
+		s.write(k, e)
+	}
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *LWWDecimalStore) Merge(state LWWDecimalState) {
+	s.Lock()
+	s.merge(state)
+	s.Unlock()
+}
+
+func (s *LWWDecimalStore) members() []string {
+	mems := make([]string, 0, len(s.store))
+	for k, e := range s.store {
+		if !e.Deleted {
+			mems = append(mems, k)
+		}
+	}
+
+	return mems
+}
+
+// Size returns the number of keys in the store, not counting deleted keys
+func (s *LWWDecimalStore) Size() int {
+	s.Lock()
+	size := len(s.members())
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *LWWDecimalStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *LWWDecimalStore) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.get(key)
+	s.Unlock()
+
+	return ok
+}
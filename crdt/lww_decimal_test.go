@@ -0,0 +1,149 @@
+package crdt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLWWDecimalSetGet(t *testing.T) {
+	wall := int64(100)
+	s := NewLWWDecimalStore(mockClock("a", &wall))
+
+	s.Set("a", decimal.MustParse("101.25"))
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, decimal.MustParse("101.25"), v)
+	assert.Equal(t, Timestamp{Wall: 100, Node: "a"}, s.store["a"].Time)
+
+	s.Set("a", decimal.MustParse("101.50"))
+	v, _ = s.Get("a")
+	assert.Equal(t, decimal.MustParse("101.50"), v)
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+	assert.True(t, s.store["a"].Deleted)
+	assert.False(t, s.IsMember("a"))
+}
+
+func TestLWWDecimalMerge(t *testing.T) {
+	wallA, wallB := int64(100), int64(200)
+	a := NewLWWDecimalStore(mockClock("a", &wallA))
+	b := NewLWWDecimalStore(mockClock("b", &wallB))
+
+	a.Set("x", decimal.MustParse("101.25"))
+	b.Set("x", decimal.MustParse("101.50"))
+	a.Set("y", decimal.MustParse("101.25"))
+
+	// the later write wins on both replicas
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+	v, _ := a.Get("x")
+	assert.Equal(t, decimal.MustParse("101.50"), v)
+
+	// a write after a merge wins over the merged value even with a slow wall clock
+	a.Set("x", decimal.MustParse("101.25"))
+	b.Merge(a.Delta())
+	v, _ = b.Get("x")
+	assert.Equal(t, decimal.MustParse("101.25"), v)
+
+	// a delete wins over older writes merged later
+	old := a.State()
+	b.Delete("y")
+	b.Merge(old)
+	assert.False(t, b.IsMember("y"))
+	a.Merge(b.Delta())
+	assert.False(t, a.IsMember("y"))
+	assert.Equal(t, a.State(), b.State())
+}
+
+func TestLWWDecimalMergeTie(t *testing.T) {
+	wall := int64(100)
+	a := NewLWWDecimalStore(mockClock("a", &wall))
+	b := NewLWWDecimalStore(mockClock("b", &wall))
+
+	// same wall time and logical counter, the node name decides
+	a.Set("x", decimal.MustParse("101.25"))
+	b.Set("x", decimal.MustParse("101.50"))
+	a.Merge(b.State())
+	b.Merge(a.State())
+
+	v, _ := a.Get("x")
+	assert.Equal(t, decimal.MustParse("101.50"), v)
+	v, _ = b.Get("x")
+	assert.Equal(t, decimal.MustParse("101.50"), v)
+}
+
+func TestLWWDecimalDelta(t *testing.T) {
+	s := NewLWWDecimalStore(NewClock("a"))
+	s.Set("x", decimal.MustParse("101.25"))
+	s.Set("y", decimal.MustParse("101.25"))
+	s.Delete("y")
+
+	d := s.Delta()
+	assert.Len(t, d, 2)
+	assert.True(t, d["y"].Deleted)
+	assert.Len(t, s.Delta(), 0)
+
+	// encoded deltas merge like states
+	b, err := d.MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded LWWDecimalState
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Len(t, decoded, 2)
+	assert.True(t, decoded["x"].Time == d["x"].Time)
+
+	r := NewLWWDecimalStore(NewClock("b"))
+	r.Merge(decoded)
+	v, ok := r.Get("x")
+	assert.True(t, ok)
+	assert.Equal(t, decimal.MustParse("101.25"), v)
+	assert.False(t, r.IsMember("y"))
+
+	// merged changes are forwarded once
+	assert.Len(t, r.Delta(), 2)
+	r.Merge(decoded)
+	assert.Len(t, r.Delta(), 0)
+}
+
+func TestLWWDecimalSize(t *testing.T) {
+	s := NewLWWDecimalStore(NewClock("a"))
+	s.Set("x", decimal.MustParse("101.25"))
+	s.Set("y", decimal.MustParse("101.25"))
+	s.Set("z", decimal.MustParse("101.25"))
+	s.Delete("z")
+
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []string{"x", "y"}, s.Members())
+	assert.Len(t, s.State(), 3)
+}
+
+func TestLWWDecimalConcurrentSetAndMerge(t *testing.T) {
+	a := NewLWWDecimalStore(NewClock("a"))
+	b := NewLWWDecimalStore(NewClock("b"))
+
+	for _, s := range []*LWWDecimalStore{a, b} {
+		go func(s *LWWDecimalStore) {
+			for i := 0; i < 1000; i++ {
+				s.Set("x", decimal.MustParse("101.25"))
+				s.Set("x", decimal.MustParse("101.50"))
+			}
+		}(s)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.Merge(b.Delta())
+		b.Merge(a.Delta())
+	}
+
+	time.Sleep(2 * time.Second)
+
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+}
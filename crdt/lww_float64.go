@@ -0,0 +1,170 @@
+package crdt
+
+import (
+	"sync"
+)
+
+// LWWFloat64Entry is a register of an LWWFloat64Store
+type LWWFloat64Entry struct {
+	Value float64
+	// Time is when the value was written, the latest write wins
+	Time Timestamp
+	// Deleted marks a deleted key, kept so a delete wins over older writes merged later
+	Deleted bool
+}
+
+// LWWFloat64State is the state of an LWWFloat64Store, or a delta of it
+type LWWFloat64State map[string]LWWFloat64Entry
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (l LWWFloat64State) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding l would call MarshalBinary again
+	return encode(map[string]LWWFloat64Entry(l))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *LWWFloat64State) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]LWWFloat64Entry)(l))
+}
+
+// LWWFloat64Store is a store of last-writer-wins float64 registers
+// Every write is stamped by a hybrid logical clock, replicas keep the value with the latest timestamp
+// Embedded sync.Mutex to provide atomic operation ability
+type LWWFloat64Store struct {
+	sync.Mutex
+	clock *Clock
+	store LWWFloat64State
+	delta LWWFloat64State
+}
+
+// NewLWWFloat64Store constructs and initializes a new LWWFloat64Store stamping writes with the given clock
+// Always use this function when creating a new LWWFloat64Store
+func NewLWWFloat64Store(clock *Clock) *LWWFloat64Store {
+	return &LWWFloat64Store{clock: clock, store: make(LWWFloat64State), delta: make(LWWFloat64State)}
+}
+
+func (s *LWWFloat64Store) write(key string, e LWWFloat64Entry) {
+	s.store[key] = e
+	s.delta[key] = e
+}
+
+// Set stores the given value mapped to the given key
+func (s *LWWFloat64Store) Set(key string, value float64) {
+	s.Lock()
+	s.write(key, LWWFloat64Entry{Value: value, Time: s.clock.Now()})
+	s.Unlock()
+}
+
+// Delete removes the given key and its value from the store
+// A tombstone is kept, so the key stays deleted on replicas merging older writes
+func (s *LWWFloat64Store) Delete(key string) {
+	s.Lock()
+	s.write(key, LWWFloat64Entry{Time: s.clock.Now(), Deleted: true})
+	s.Unlock()
+}
+
+func (s *LWWFloat64Store) get(key string) (float64, bool) {
+	e, ok := s.store[key]
+	if !ok || e.Deleted {
+		var zero float64
+		return zero, false
+	}
+
+	return e.Value, true
+}
+
+// Get returns the value for the given key
+func (s *LWWFloat64Store) Get(key string) (float64, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// State returns a copy of the full state of the store, including tombstones
+func (s *LWWFloat64Store) State() LWWFloat64State {
+	s.Lock()
+	st := make(LWWFloat64State, len(s.store))
+	for k, e := range s.store {
+		st[k] = e
+	}
+	s.Unlock()
+
+	return st
+}
+
+func (s *LWWFloat64Store) takeDelta() LWWFloat64State {
+	d := s.delta
+	s.delta = make(LWWFloat64State)
+
+	return d
+}
+
+// Delta returns the registers changed since the last Delta call, by local writes or merges
+func (s *LWWFloat64Store) Delta() LWWFloat64State {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+func (s *LWWFloat64Store) merge(state LWWFloat64State) {
+	for k, e := range state {
+		// remote timestamps advance the clock, so later local writes win over them
+		s.clock.Update(e.Time)
+
+		if cur, ok := s.store[k]; ok && !cur.Time.Less(e.Time) {
+			continue
+		}
+
+		s.write(k, e)
+	}
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *LWWFloat64Store) Merge(state LWWFloat64State) {
+	s.Lock()
+	s.merge(state)
+	s.Unlock()
+}
+
+func (s *LWWFloat64Store) members() []string {
+	mems := make([]string, 0, len(s.store))
+	for k, e := range s.store {
+		if !e.Deleted {
+			mems = append(mems, k)
+		}
+	}
+
+	return mems
+}
+
+// Size returns the number of keys in the store, not counting deleted keys
+func (s *LWWFloat64Store) Size() int {
+	s.Lock()
+	size := len(s.members())
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *LWWFloat64Store) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *LWWFloat64Store) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.get(key)
+	s.Unlock()
+
+	return ok
+}
@@ -0,0 +1,170 @@
+package crdt
+
+import (
+	"sync"
+)
+
+// LWWInt64Entry is a register of an LWWInt64Store
+type LWWInt64Entry struct {
+	Value int64
+	// Time is when the value was written, the latest write wins
+	Time Timestamp
+	// Deleted marks a deleted key, kept so a delete wins over older writes merged later
+	Deleted bool
+}
+
+// LWWInt64State is the state of an LWWInt64Store, or a delta of it
+type LWWInt64State map[string]LWWInt64Entry
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (l LWWInt64State) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding l would call MarshalBinary again
+	return encode(map[string]LWWInt64Entry(l))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *LWWInt64State) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]LWWInt64Entry)(l))
+}
+
+// LWWInt64Store is a store of last-writer-wins int64 registers
+// Every write is stamped by a hybrid logical clock, replicas keep the value with the latest timestamp
+// Embedded sync.Mutex to provide atomic operation ability
+type LWWInt64Store struct {
+	sync.Mutex
+	clock *Clock
+	store LWWInt64State
+	delta LWWInt64State
+}
+
+// NewLWWInt64Store constructs and initializes a new LWWInt64Store stamping writes with the given clock
+// Always use this function when creating a new LWWInt64Store
+func NewLWWInt64Store(clock *Clock) *LWWInt64Store {
+	return &LWWInt64Store{clock: clock, store: make(LWWInt64State), delta: make(LWWInt64State)}
+}
+
+func (s *LWWInt64Store) write(key string, e LWWInt64Entry) {
+	s.store[key] = e
+	s.delta[key] = e
+}
+
+// Set stores the given value mapped to the given key
+func (s *LWWInt64Store) Set(key string, value int64) {
+	s.Lock()
+	s.write(key, LWWInt64Entry{Value: value, Time: s.clock.Now()})
+	s.Unlock()
+}
+
+// Delete removes the given key and its value from the store
+// A tombstone is kept, so the key stays deleted on replicas merging older writes
+func (s *LWWInt64Store) Delete(key string) {
+	s.Lock()
+	s.write(key, LWWInt64Entry{Time: s.clock.Now(), Deleted: true})
+	s.Unlock()
+}
+
+func (s *LWWInt64Store) get(key string) (int64, bool) {
+	e, ok := s.store[key]
+	if !ok || e.Deleted {
+		var zero int64
+		return zero, false
+	}
+
+	return e.Value, true
+}
+
+// Get returns the value for the given key
+func (s *LWWInt64Store) Get(key string) (int64, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// State returns a copy of the full state of the store, including tombstones
+func (s *LWWInt64Store) State() LWWInt64State {
+	s.Lock()
+	st := make(LWWInt64State, len(s.store))
+	for k, e := range s.store {
+		st[k] = e
+	}
+	s.Unlock()
+
+	return st
+}
+
+func (s *LWWInt64Store) takeDelta() LWWInt64State {
+	d := s.delta
+	s.delta = make(LWWInt64State)
+
+	return d
+}
+
+// Delta returns the registers changed since the last Delta call, by local writes or merges
+func (s *LWWInt64Store) Delta() LWWInt64State {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+func (s *LWWInt64Store) merge(state LWWInt64State) {
+	for k, e := range state {
+		// remote timestamps advance the clock, so later local writes win over them
+		s.clock.Update(e.Time)
+
+		if cur, ok := s.store[k]; ok && !cur.Time.Less(e.Time) {
+			continue
+		}
+
+		s.write(k, e)
+	}
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *LWWInt64Store) Merge(state LWWInt64State) {
+	s.Lock()
+	s.merge(state)
+	s.Unlock()
+}
+
+func (s *LWWInt64Store) members() []string {
+	mems := make([]string, 0, len(s.store))
+	for k, e := range s.store {
+		if !e.Deleted {
+			mems = append(mems, k)
+		}
+	}
+
+	return mems
+}
+
+// Size returns the number of keys in the store, not counting deleted keys
+func (s *LWWInt64Store) Size() int {
+	s.Lock()
+	size := len(s.members())
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *LWWInt64Store) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *LWWInt64Store) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.get(key)
+	s.Unlock()
+
+	return ok
+}
@@ -0,0 +1,148 @@
+package crdt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLWWInt64SetGet(t *testing.T) {
+	wall := int64(100)
+	s := NewLWWInt64Store(mockClock("a", &wall))
+
+	s.Set("a", int64(1))
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), v)
+	assert.Equal(t, Timestamp{Wall: 100, Node: "a"}, s.store["a"].Time)
+
+	s.Set("a", int64(2))
+	v, _ = s.Get("a")
+	assert.Equal(t, int64(2), v)
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+	assert.True(t, s.store["a"].Deleted)
+	assert.False(t, s.IsMember("a"))
+}
+
+func TestLWWInt64Merge(t *testing.T) {
+	wallA, wallB := int64(100), int64(200)
+	a := NewLWWInt64Store(mockClock("a", &wallA))
+	b := NewLWWInt64Store(mockClock("b", &wallB))
+
+	a.Set("x", int64(1))
+	b.Set("x", int64(2))
+	a.Set("y", int64(1))
+
+	// the later write wins on both replicas
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+	v, _ := a.Get("x")
+	assert.Equal(t, int64(2), v)
+
+	// a write after a merge wins over the merged value even with a slow wall clock
+	a.Set("x", int64(1))
+	b.Merge(a.Delta())
+	v, _ = b.Get("x")
+	assert.Equal(t, int64(1), v)
+
+	// a delete wins over older writes merged later
+	old := a.State()
+	b.Delete("y")
+	b.Merge(old)
+	assert.False(t, b.IsMember("y"))
+	a.Merge(b.Delta())
+	assert.False(t, a.IsMember("y"))
+	assert.Equal(t, a.State(), b.State())
+}
+
+func TestLWWInt64MergeTie(t *testing.T) {
+	wall := int64(100)
+	a := NewLWWInt64Store(mockClock("a", &wall))
+	b := NewLWWInt64Store(mockClock("b", &wall))
+
+	// same wall time and logical counter, the node name decides
+	a.Set("x", int64(1))
+	b.Set("x", int64(2))
+	a.Merge(b.State())
+	b.Merge(a.State())
+
+	v, _ := a.Get("x")
+	assert.Equal(t, int64(2), v)
+	v, _ = b.Get("x")
+	assert.Equal(t, int64(2), v)
+}
+
+func TestLWWInt64Delta(t *testing.T) {
+	s := NewLWWInt64Store(NewClock("a"))
+	s.Set("x", int64(1))
+	s.Set("y", int64(1))
+	s.Delete("y")
+
+	d := s.Delta()
+	assert.Len(t, d, 2)
+	assert.True(t, d["y"].Deleted)
+	assert.Len(t, s.Delta(), 0)
+
+	// encoded deltas merge like states
+	b, err := d.MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded LWWInt64State
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Len(t, decoded, 2)
+	assert.True(t, decoded["x"].Time == d["x"].Time)
+
+	r := NewLWWInt64Store(NewClock("b"))
+	r.Merge(decoded)
+	v, ok := r.Get("x")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), v)
+	assert.False(t, r.IsMember("y"))
+
+	// merged changes are forwarded once
+	assert.Len(t, r.Delta(), 2)
+	r.Merge(decoded)
+	assert.Len(t, r.Delta(), 0)
+}
+
+func TestLWWInt64Size(t *testing.T) {
+	s := NewLWWInt64Store(NewClock("a"))
+	s.Set("x", int64(1))
+	s.Set("y", int64(1))
+	s.Set("z", int64(1))
+	s.Delete("z")
+
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []string{"x", "y"}, s.Members())
+	assert.Len(t, s.State(), 3)
+}
+
+func TestLWWInt64ConcurrentSetAndMerge(t *testing.T) {
+	a := NewLWWInt64Store(NewClock("a"))
+	b := NewLWWInt64Store(NewClock("b"))
+
+	for _, s := range []*LWWInt64Store{a, b} {
+		go func(s *LWWInt64Store) {
+			for i := 0; i < 1000; i++ {
+				s.Set("x", int64(1))
+				s.Set("x", int64(2))
+			}
+		}(s)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.Merge(b.Delta())
+		b.Merge(a.Delta())
+	}
+
+	time.Sleep(2 * time.Second)
+
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+}
@@ -0,0 +1,170 @@
+package crdt
+
+import (
+	"sync"
+)
+
+// LWWStringEntry is a register of an LWWStringStore
+type LWWStringEntry struct {
+	Value string
+	// Time is when the value was written, the latest write wins
+	Time Timestamp
+	// Deleted marks a deleted key, kept so a delete wins over older writes merged later
+	Deleted bool
+}
+
+// LWWStringState is the state of an LWWStringStore, or a delta of it
+type LWWStringState map[string]LWWStringEntry
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (l LWWStringState) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding l would call MarshalBinary again
+	return encode(map[string]LWWStringEntry(l))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (l *LWWStringState) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]LWWStringEntry)(l))
+}
+
+// LWWStringStore is a store of last-writer-wins string registers
+// Every write is stamped by a hybrid logical clock, replicas keep the value with the latest timestamp
+// Embedded sync.Mutex to provide atomic operation ability
+type LWWStringStore struct {
+	sync.Mutex
+	clock *Clock
+	store LWWStringState
+	delta LWWStringState
+}
+
+// NewLWWStringStore constructs and initializes a new LWWStringStore stamping writes with the given clock
+// Always use this function when creating a new LWWStringStore
+func NewLWWStringStore(clock *Clock) *LWWStringStore {
+	return &LWWStringStore{clock: clock, store: make(LWWStringState), delta: make(LWWStringState)}
+}
+
+func (s *LWWStringStore) write(key string, e LWWStringEntry) {
+	s.store[key] = e
+	s.delta[key] = e
+}
+
+// Set stores the given value mapped to the given key
+func (s *LWWStringStore) Set(key string, value string) {
+	s.Lock()
+	s.write(key, LWWStringEntry{Value: value, Time: s.clock.Now()})
+	s.Unlock()
+}
+
+// Delete removes the given key and its value from the store
+// A tombstone is kept, so the key stays deleted on replicas merging older writes
+func (s *LWWStringStore) Delete(key string) {
+	s.Lock()
+	s.write(key, LWWStringEntry{Time: s.clock.Now(), Deleted: true})
+	s.Unlock()
+}
+
+func (s *LWWStringStore) get(key string) (string, bool) {
+	e, ok := s.store[key]
+	if !ok || e.Deleted {
+		var zero string
+		return zero, false
+	}
+
+	return e.Value, true
+}
+
+// Get returns the value for the given key
+func (s *LWWStringStore) Get(key string) (string, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+// State returns a copy of the full state of the store, including tombstones
+func (s *LWWStringStore) State() LWWStringState {
+	s.Lock()
+	st := make(LWWStringState, len(s.store))
+	for k, e := range s.store {
+		st[k] = e
+	}
+	s.Unlock()
+
+	return st
+}
+
+func (s *LWWStringStore) takeDelta() LWWStringState {
+	d := s.delta
+	s.delta = make(LWWStringState)
+
+	return d
+}
+
+// Delta returns the registers changed since the last Delta call, by local writes or merges
+func (s *LWWStringStore) Delta() LWWStringState {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+func (s *LWWStringStore) merge(state LWWStringState) {
+	for k, e := range state {
+		// remote timestamps advance the clock, so later local writes win over them
+		s.clock.Update(e.Time)
+
+		if cur, ok := s.store[k]; ok && !cur.Time.Less(e.Time) {
+			continue
+		}
+
+		s.write(k, e)
+	}
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *LWWStringStore) Merge(state LWWStringState) {
+	s.Lock()
+	s.merge(state)
+	s.Unlock()
+}
+
+func (s *LWWStringStore) members() []string {
+	mems := make([]string, 0, len(s.store))
+	for k, e := range s.store {
+		if !e.Deleted {
+			mems = append(mems, k)
+		}
+	}
+
+	return mems
+}
+
+// Size returns the number of keys in the store, not counting deleted keys
+func (s *LWWStringStore) Size() int {
+	s.Lock()
+	size := len(s.members())
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *LWWStringStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *LWWStringStore) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.get(key)
+	s.Unlock()
+
+	return ok
+}
@@ -0,0 +1,148 @@
+package crdt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLWWStringSetGet(t *testing.T) {
+	wall := int64(100)
+	s := NewLWWStringStore(mockClock("a", &wall))
+
+	s.Set("a", "foo")
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", v)
+	assert.Equal(t, Timestamp{Wall: 100, Node: "a"}, s.store["a"].Time)
+
+	s.Set("a", "bar")
+	v, _ = s.Get("a")
+	assert.Equal(t, "bar", v)
+
+	s.Delete("a")
+	_, ok = s.Get("a")
+	assert.False(t, ok)
+	assert.True(t, s.store["a"].Deleted)
+	assert.False(t, s.IsMember("a"))
+}
+
+func TestLWWStringMerge(t *testing.T) {
+	wallA, wallB := int64(100), int64(200)
+	a := NewLWWStringStore(mockClock("a", &wallA))
+	b := NewLWWStringStore(mockClock("b", &wallB))
+
+	a.Set("x", "foo")
+	b.Set("x", "bar")
+	a.Set("y", "foo")
+
+	// the later write wins on both replicas
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+	v, _ := a.Get("x")
+	assert.Equal(t, "bar", v)
+
+	// a write after a merge wins over the merged value even with a slow wall clock
+	a.Set("x", "foo")
+	b.Merge(a.Delta())
+	v, _ = b.Get("x")
+	assert.Equal(t, "foo", v)
+
+	// a delete wins over older writes merged later
+	old := a.State()
+	b.Delete("y")
+	b.Merge(old)
+	assert.False(t, b.IsMember("y"))
+	a.Merge(b.Delta())
+	assert.False(t, a.IsMember("y"))
+	assert.Equal(t, a.State(), b.State())
+}
+
+func TestLWWStringMergeTie(t *testing.T) {
+	wall := int64(100)
+	a := NewLWWStringStore(mockClock("a", &wall))
+	b := NewLWWStringStore(mockClock("b", &wall))
+
+	// same wall time and logical counter, the node name decides
+	a.Set("x", "foo")
+	b.Set("x", "bar")
+	a.Merge(b.State())
+	b.Merge(a.State())
+
+	v, _ := a.Get("x")
+	assert.Equal(t, "bar", v)
+	v, _ = b.Get("x")
+	assert.Equal(t, "bar", v)
+}
+
+func TestLWWStringDelta(t *testing.T) {
+	s := NewLWWStringStore(NewClock("a"))
+	s.Set("x", "foo")
+	s.Set("y", "foo")
+	s.Delete("y")
+
+	d := s.Delta()
+	assert.Len(t, d, 2)
+	assert.True(t, d["y"].Deleted)
+	assert.Len(t, s.Delta(), 0)
+
+	// encoded deltas merge like states
+	b, err := d.MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded LWWStringState
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Len(t, decoded, 2)
+	assert.True(t, decoded["x"].Time == d["x"].Time)
+
+	r := NewLWWStringStore(NewClock("b"))
+	r.Merge(decoded)
+	v, ok := r.Get("x")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", v)
+	assert.False(t, r.IsMember("y"))
+
+	// merged changes are forwarded once
+	assert.Len(t, r.Delta(), 2)
+	r.Merge(decoded)
+	assert.Len(t, r.Delta(), 0)
+}
+
+func TestLWWStringSize(t *testing.T) {
+	s := NewLWWStringStore(NewClock("a"))
+	s.Set("x", "foo")
+	s.Set("y", "foo")
+	s.Set("z", "foo")
+	s.Delete("z")
+
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []string{"x", "y"}, s.Members())
+	assert.Len(t, s.State(), 3)
+}
+
+func TestLWWStringConcurrentSetAndMerge(t *testing.T) {
+	a := NewLWWStringStore(NewClock("a"))
+	b := NewLWWStringStore(NewClock("b"))
+
+	for _, s := range []*LWWStringStore{a, b} {
+		go func(s *LWWStringStore) {
+			for i := 0; i < 1000; i++ {
+				s.Set("x", "foo")
+				s.Set("x", "bar")
+			}
+		}(s)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.Merge(b.Delta())
+		b.Merge(a.Delta())
+	}
+
+	time.Sleep(2 * time.Second)
+
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+}
@@ -0,0 +1,332 @@
+package crdt
+
+import (
+	"sort"
+	"sync"
+)
+
+// Tag uniquely identifies one add of a member to an ORSetStore
+type Tag struct {
+	Node string
+	Seq  uint64
+}
+
+// ORSetEntry is the set of one key of an ORSetStore
+type ORSetEntry struct {
+	// Adds maps each member to the tags of its adds not yet removed
+	Adds map[string]map[Tag]bool
+	// Removed holds the tags of removed adds
+	Removed map[Tag]bool
+}
+
+func newORSetEntry() ORSetEntry {
+	return ORSetEntry{Adds: make(map[string]map[Tag]bool), Removed: make(map[Tag]bool)}
+}
+
+func (e ORSetEntry) addTag(member string, tag Tag) bool {
+	if e.Removed[tag] || e.Adds[member][tag] {
+		return false
+	}
+
+	tags, ok := e.Adds[member]
+	if !ok {
+		tags = make(map[Tag]bool)
+		e.Adds[member] = tags
+	}
+
+	tags[tag] = true
+
+	return true
+}
+
+func (e ORSetEntry) removeTag(tag Tag) bool {
+	if e.Removed[tag] {
+		return false
+	}
+
+	e.Removed[tag] = true
+	for m, tags := range e.Adds {
+		if tags[tag] {
+			delete(tags, tag)
+			if len(tags) == 0 {
+				delete(e.Adds, m)
+			}
+		}
+	}
+
+	return true
+}
+
+// ORSetState is the state of an ORSetStore, or a delta of it
+type ORSetState map[string]ORSetEntry
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (o ORSetState) MarshalBinary() ([]byte, error) {
+	// encode the plain map, encoding o would call MarshalBinary again
+	return encode(map[string]ORSetEntry(o))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (o *ORSetState) UnmarshalBinary(data []byte) error {
+	return decode(data, (*map[string]ORSetEntry)(o))
+}
+
+func (o ORSetState) entry(key string) ORSetEntry {
+	e, ok := o[key]
+	if !ok {
+		e = newORSetEntry()
+		o[key] = e
+	}
+
+	return e
+}
+
+// ORSetStore is a store of observed-remove string sets
+// Every add is tagged, and a remove only removes the adds it has seen,
+// so a member added on one replica while removed on another stays in the set
+// Removed tags are kept as tombstones, a key is never deleted once created
+// Embedded sync.Mutex to provide atomic operation ability
+type ORSetStore struct {
+	sync.Mutex
+	node  string
+	seq   uint64
+	store ORSetState
+	delta ORSetState
+}
+
+// NewORSetStore constructs and initializes a new ORSetStore for the given node
+// A node restarting with an empty store must merge a state from another replica before adding,
+// or use a new node name, otherwise its new tags may collide with removed ones
+// Always use this function when creating a new ORSetStore
+func NewORSetStore(node string) *ORSetStore {
+	return &ORSetStore{node: node, store: make(ORSetState), delta: make(ORSetState)}
+}
+
+func (s *ORSetStore) add(key string, members ...string) int {
+	// never create an empty set
+	if len(members) == 0 {
+		return 0
+	}
+
+	e := s.store.entry(key)
+	d := s.delta.entry(key)
+
+	n := 0
+	for _, m := range members {
+		if len(e.Adds[m]) == 0 {
+			n++
+		}
+
+		s.seq++
+		tag := Tag{Node: s.node, Seq: s.seq}
+		e.addTag(m, tag)
+		d.addTag(m, tag)
+	}
+
+	return n
+}
+
+// Add adds the given members to the set of the given key, creating the key if it does not exist
+// returns the number of members that were not already in the set
+func (s *ORSetStore) Add(key string, members ...string) int {
+	s.Lock()
+	n := s.add(key, members...)
+	s.Unlock()
+
+	return n
+}
+
+func (s *ORSetStore) remove(key string, members ...string) int {
+	e, ok := s.store[key]
+	if !ok {
+		return 0
+	}
+
+	n := 0
+	for _, m := range members {
+		tags := e.Adds[m]
+		if len(tags) == 0 {
+			continue
+		}
+
+		d := s.delta.entry(key)
+		for tag := range tags {
+			e.removeTag(tag)
+			d.removeTag(tag)
+		}
+		n++
+	}
+
+	return n
+}
+
+// Remove removes the given members from the set of the given key
+// Only the adds seen by the store are removed, concurrent adds on other replicas survive the merge
+// returns the number of members removed
+func (s *ORSetStore) Remove(key string, members ...string) int {
+	s.Lock()
+	n := s.remove(key, members...)
+	s.Unlock()
+
+	return n
+}
+
+// Contains checks if member is in the set of the given key
+func (s *ORSetStore) Contains(key, member string) bool {
+	s.Lock()
+	ok := len(s.store[key].Adds[member]) > 0
+	s.Unlock()
+
+	return ok
+}
+
+func (s *ORSetStore) card(key string) (int, error) {
+	e, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return len(e.Adds), nil
+}
+
+// Card returns the number of members in the set of the given key
+func (s *ORSetStore) Card(key string) (int, error) {
+	s.Lock()
+	c, err := s.card(key)
+	s.Unlock()
+
+	return c, err
+}
+
+func (s *ORSetStore) sMembers(key string) ([]string, error) {
+	e, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	mems := make([]string, 0, len(e.Adds))
+	for m := range e.Adds {
+		mems = append(mems, m)
+	}
+
+	sort.Strings(mems)
+
+	return mems, nil
+}
+
+// SMembers returns all members of the set of the given key in ascending order
+// Note: use Members for the keys of the store
+func (s *ORSetStore) SMembers(key string) ([]string, error) {
+	s.Lock()
+	mems, err := s.sMembers(key)
+	s.Unlock()
+
+	return mems, err
+}
+
+// State returns a copy of the full state of the store, including tombstones
+func (s *ORSetStore) State() ORSetState {
+	s.Lock()
+	st := make(ORSetState, len(s.store))
+	for k, e := range s.store {
+		c := st.entry(k)
+		for tag := range e.Removed {
+			c.Removed[tag] = true
+		}
+
+		for m, tags := range e.Adds {
+			for tag := range tags {
+				c.addTag(m, tag)
+			}
+		}
+	}
+	s.Unlock()
+
+	return st
+}
+
+func (s *ORSetStore) takeDelta() ORSetState {
+	d := s.delta
+	s.delta = make(ORSetState)
+
+	return d
+}
+
+// Delta returns the adds and removes since the last Delta call, by local writes or merges
+func (s *ORSetStore) Delta() ORSetState {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+// seen keeps the tag sequence of the local node ahead of its own tags merged back from other replicas
+func (s *ORSetStore) seen(tag Tag) {
+	if tag.Node == s.node && tag.Seq > s.seq {
+		s.seq = tag.Seq
+	}
+}
+
+func (s *ORSetStore) merge(state ORSetState) {
+	for k, other := range state {
+		e := s.store.entry(k)
+		for tag := range other.Removed {
+			s.seen(tag)
+			if e.removeTag(tag) {
+				s.delta.entry(k).removeTag(tag)
+			}
+		}
+
+		for m, tags := range other.Adds {
+			for tag := range tags {
+				s.seen(tag)
+				if e.addTag(m, tag) {
+					s.delta.entry(k).addTag(m, tag)
+				}
+			}
+		}
+	}
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *ORSetStore) Merge(state ORSetState) {
+	s.Lock()
+	s.merge(state)
+	s.Unlock()
+}
+
+// Size returns the number of keys in the store
+func (s *ORSetStore) Size() int {
+	s.Lock()
+	size := len(s.store)
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *ORSetStore) Members() []string {
+	s.Lock()
+	mems := make([]string, 0, len(s.store))
+	for k := range s.store {
+		mems = append(mems, k)
+	}
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *ORSetStore) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.store[key]
+	s.Unlock()
+
+	return ok
+}
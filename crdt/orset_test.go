@@ -0,0 +1,170 @@
+package crdt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestORSetAddRemove(t *testing.T) {
+	s := NewORSetStore("a")
+
+	assert.Equal(t, 2, s.Add("watch", "AAPL", "MSFT"))
+	assert.Equal(t, 1, s.Add("watch", "AAPL", "GOOG"))
+	assert.Equal(t, 0, s.Add("empty"))
+	assert.False(t, s.IsMember("empty"))
+
+	mems, err := s.SMembers("watch")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"AAPL", "GOOG", "MSFT"}, mems)
+	c, _ := s.Card("watch")
+	assert.Equal(t, 3, c)
+
+	// both adds of AAPL are removed
+	assert.Equal(t, 1, s.Remove("watch", "AAPL", "TSLA"))
+	assert.False(t, s.Contains("watch", "AAPL"))
+	assert.True(t, s.Contains("watch", "MSFT"))
+	assert.Len(t, s.store["watch"].Removed, 2)
+	assert.Equal(t, 0, s.Remove("other", "AAPL"))
+
+	// re-adding uses a new tag
+	s.Add("watch", "AAPL")
+	assert.True(t, s.Contains("watch", "AAPL"))
+
+	_, err = s.Card("other")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = s.SMembers("other")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+}
+
+func TestORSetConcurrentAddWins(t *testing.T) {
+	a := NewORSetStore("a")
+	b := NewORSetStore("b")
+
+	a.Add("watch", "AAPL")
+	b.Merge(a.Delta())
+
+	// a removes the add it saw while b adds again
+	a.Remove("watch", "AAPL")
+	b.Add("watch", "AAPL")
+
+	a.Merge(b.Delta())
+	b.Merge(a.Delta())
+	assert.True(t, a.Contains("watch", "AAPL"))
+	assert.True(t, b.Contains("watch", "AAPL"))
+
+	// a remove that saw every add removes the member everywhere
+	a.Remove("watch", "AAPL")
+	b.Merge(a.Delta())
+	assert.False(t, b.Contains("watch", "AAPL"))
+	assert.Equal(t, a.State(), b.State())
+}
+
+func TestORSetMerge(t *testing.T) {
+	a := NewORSetStore("a")
+	b := NewORSetStore("b")
+
+	a.Add("x", "1", "2")
+	b.Add("x", "3")
+	b.Add("y", "1")
+	b.Remove("x", "3")
+
+	a.Merge(b.State())
+	b.Merge(a.State())
+	assert.Equal(t, a.State(), b.State())
+
+	mems, _ := b.SMembers("x")
+	assert.Equal(t, []string{"1", "2"}, mems)
+
+	// idempotent and order independent, stale adds do not resurrect removed members
+	old := b.State()
+	b.Remove("x", "1")
+	b.Merge(old)
+	b.Merge(old)
+	assert.False(t, b.Contains("x", "1"))
+}
+
+func TestORSetMergeOwnTags(t *testing.T) {
+	a := NewORSetStore("a")
+	a.Add("x", "1", "2")
+	st := a.State()
+
+	// a restarted node continues after its own tags
+	r := NewORSetStore("a")
+	r.Merge(st)
+	assert.Equal(t, uint64(2), r.seq)
+	r.Add("x", "3")
+	assert.True(t, r.store["x"].Adds["3"][Tag{Node: "a", Seq: 3}])
+}
+
+func TestORSetState(t *testing.T) {
+	s := NewORSetStore("a")
+	s.Add("x", "1", "2")
+	s.Remove("x", "2")
+
+	st := s.State()
+	st["x"].Adds["9"] = map[Tag]bool{{Node: "z", Seq: 1}: true}
+	assert.False(t, s.Contains("x", "9"))
+
+	b, err := s.State().MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded ORSetState
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Equal(t, s.State(), decoded)
+
+	r := NewORSetStore("b")
+	r.Merge(decoded)
+	mems, _ := r.SMembers("x")
+	assert.Equal(t, []string{"1"}, mems)
+}
+
+func TestORSetSize(t *testing.T) {
+	s := NewORSetStore("a")
+	s.Add("x", "1")
+	s.Add("y", "1")
+	s.Remove("y", "1")
+
+	// keys are kept once created
+	assert.Equal(t, 2, s.Size())
+	assert.ElementsMatch(t, []string{"x", "y"}, s.Members())
+	assert.True(t, s.IsMember("y"))
+	assert.False(t, s.IsMember("z"))
+}
+
+func TestORSetConcurrentAddAndMerge(t *testing.T) {
+	nodes := []*ORSetStore{NewORSetStore("a"), NewORSetStore("b"), NewORSetStore("c")}
+	for _, s := range nodes {
+		go func(s *ORSetStore) {
+			for i := 0; i < 500; i++ {
+				s.Add("x", s.node)
+				s.Remove("x", s.node)
+				s.Add("x", s.node)
+			}
+		}(s)
+	}
+
+	for i := 0; i < 50; i++ {
+		for _, s := range nodes {
+			d := s.Delta()
+			for _, o := range nodes {
+				o.Merge(d)
+			}
+		}
+	}
+
+	time.Sleep(2 * time.Second)
+
+	for _, s := range nodes {
+		for _, o := range nodes {
+			o.Merge(s.State())
+		}
+	}
+
+	for _, s := range nodes {
+		mems, _ := s.SMembers("x")
+		assert.Equal(t, []string{"a", "b", "c"}, mems)
+		assert.Equal(t, nodes[0].State(), s.State())
+	}
+}
@@ -0,0 +1,191 @@
+package crdt
+
+import (
+	"math"
+	"sync"
+)
+
+// PNCounterState is the state of a PNCounterStore, or a delta of it
+// P counts the increments and N the decrements of every key
+type PNCounterState struct {
+	P GCounterState
+	N GCounterState
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler
+func (p PNCounterState) MarshalBinary() ([]byte, error) {
+	return encode(pnCounterState(p))
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (p *PNCounterState) UnmarshalBinary(data []byte) error {
+	return decode(data, (*pnCounterState)(p))
+}
+
+// pnCounterState has the fields of PNCounterState without its methods, so gob does not call MarshalBinary again
+type pnCounterState PNCounterState
+
+// PNCounterStore is a store of counters that can be incremented and decremented
+// Each key is a pair of grow-only counters, its value is the increments less the decrements
+// Embedded sync.Mutex to provide atomic operation ability
+type PNCounterStore struct {
+	sync.Mutex
+	node  string
+	store PNCounterState
+	delta PNCounterState
+}
+
+// NewPNCounterStore constructs and initializes a new PNCounterStore for the given node
+// Always use this function when creating a new PNCounterStore
+func NewPNCounterStore(node string) *PNCounterStore {
+	return &PNCounterStore{
+		node:  node,
+		store: PNCounterState{P: make(GCounterState), N: make(GCounterState)},
+		delta: PNCounterState{P: make(GCounterState), N: make(GCounterState)},
+	}
+}
+
+// value returns p less n, and false if the difference does not fit an int64
+func value(p, n uint64) (int64, bool) {
+	if p >= n {
+		d := p - n
+		return int64(d), d <= math.MaxInt64
+	}
+
+	d := n - p
+	return -int64(d), d <= 1<<63
+}
+
+func (s *PNCounterStore) get(key string) (int64, bool) {
+	_, pok := s.store.P[key]
+	_, nok := s.store.N[key]
+	if !pok && !nok {
+		return 0, false
+	}
+
+	p, _ := s.store.P.value(key)
+	n, _ := s.store.N.value(key)
+	v, _ := value(p, n)
+
+	return v, true
+}
+
+// Get returns the value of the counter of the given key
+func (s *PNCounterStore) Get(key string) (int64, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *PNCounterStore) incr(key string, delta int64) (int64, error) {
+	p, _ := s.store.P.value(key)
+	n, _ := s.store.N.value(key)
+
+	side, deltaSide, d := s.store.P, s.delta.P, uint64(delta)
+	np, nn := p+d, n
+	if delta < 0 {
+		// negating the unsigned conversion also holds for math.MinInt64
+		side, deltaSide, d = s.store.N, s.delta.N, -uint64(delta)
+		np, nn = p, n+d
+	}
+
+	v, ok := value(np, nn)
+	if !ok || np < p || nn < n {
+		cur, _ := value(p, n)
+		return cur, ErrCounterOverflow
+	}
+
+	side.setCount(key, s.node, side[key][s.node]+d)
+	deltaSide.setCount(key, s.node, side[key][s.node])
+
+	return v, nil
+}
+
+// Incr adds delta to the counter of the given key, a negative delta decrements it
+// The key is created if it does not exist
+// returns the new value, or ErrCounterOverflow leaving the counter unchanged if it would not fit an int64
+func (s *PNCounterStore) Incr(key string, delta int64) (int64, error) {
+	s.Lock()
+	v, err := s.incr(key, delta)
+	s.Unlock()
+
+	return v, err
+}
+
+// State returns a copy of the full state of the store
+func (s *PNCounterStore) State() PNCounterState {
+	s.Lock()
+	st := PNCounterState{P: s.store.P.copy(), N: s.store.N.copy()}
+	s.Unlock()
+
+	return st
+}
+
+func (s *PNCounterStore) takeDelta() PNCounterState {
+	d := s.delta
+	s.delta = PNCounterState{P: make(GCounterState), N: make(GCounterState)}
+
+	return d
+}
+
+// Delta returns the counts changed since the last Delta call, by local increments or merges
+func (s *PNCounterStore) Delta() PNCounterState {
+	s.Lock()
+	d := s.takeDelta()
+	s.Unlock()
+
+	return d
+}
+
+// Merge merges a state or delta of another replica into the store
+// Merging is idempotent, commutative and associative, so states can be merged repeatedly and in any order
+func (s *PNCounterStore) Merge(state PNCounterState) {
+	s.Lock()
+	s.store.P.merge(state.P, s.delta.P)
+	s.store.N.merge(state.N, s.delta.N)
+	s.Unlock()
+}
+
+func (s *PNCounterStore) members() []string {
+	mems := make([]string, 0, len(s.store.P))
+	for k := range s.store.P {
+		mems = append(mems, k)
+	}
+
+	for k := range s.store.N {
+		if _, ok := s.store.P[k]; !ok {
+			mems = append(mems, k)
+		}
+	}
+
+	return mems
+}
+
+// Size returns the number of keys in the store
+func (s *PNCounterStore) Size() int {
+	s.Lock()
+	size := len(s.members())
+	s.Unlock()
+
+	return size
+}
+
+// Members returns a list of keys in the store
+func (s *PNCounterStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+// IsMember checks if the given key is in the store
+func (s *PNCounterStore) IsMember(key string) bool {
+	s.Lock()
+	_, ok := s.get(key)
+	s.Unlock()
+
+	return ok
+}
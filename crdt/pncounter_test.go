@@ -0,0 +1,123 @@
+package crdt
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPNCounterIncr(t *testing.T) {
+	s := NewPNCounterStore("a")
+
+	v, err := s.Incr("pos", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), v)
+	v, _ = s.Incr("pos", -8)
+	assert.Equal(t, int64(-3), v)
+	assert.Equal(t, GCounterState{"pos": {"a": 5}}, s.store.P)
+	assert.Equal(t, GCounterState{"pos": {"a": 8}}, s.store.N)
+
+	v, ok := s.Get("pos")
+	assert.True(t, ok)
+	assert.Equal(t, int64(-3), v)
+	_, ok = s.Get("other")
+	assert.False(t, ok)
+}
+
+func TestPNCounterOverflow(t *testing.T) {
+	s := NewPNCounterStore("a")
+
+	v, err := s.Incr("a", math.MaxInt64)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64), v)
+	v, err = s.Incr("a", 1)
+	assert.Equal(t, ErrCounterOverflow, err)
+	assert.Equal(t, int64(math.MaxInt64), v)
+
+	v, err = s.Incr("b", math.MinInt64)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MinInt64), v)
+	v, err = s.Incr("b", -1)
+	assert.Equal(t, ErrCounterOverflow, err)
+	assert.Equal(t, int64(math.MinInt64), v)
+
+	// back into range
+	v, err = s.Incr("b", math.MaxInt64)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), v)
+}
+
+func TestPNCounterMergeAndDelta(t *testing.T) {
+	a := NewPNCounterStore("a")
+	b := NewPNCounterStore("b")
+
+	a.Incr("pos", 10)
+	b.Incr("pos", -4)
+	b.Incr("pos", 1)
+
+	a.Merge(b.Delta())
+	b.Merge(a.Delta())
+	assert.Equal(t, a.State(), b.State())
+
+	v, _ := a.Get("pos")
+	assert.Equal(t, int64(7), v)
+	v, _ = b.Get("pos")
+	assert.Equal(t, int64(7), v)
+
+	// idempotent
+	a.Merge(b.State())
+	v, _ = a.Get("pos")
+	assert.Equal(t, int64(7), v)
+
+	// a key only ever decremented
+	b.Incr("neg", -2)
+	a.Merge(b.Delta())
+	assert.True(t, a.IsMember("neg"))
+	assert.ElementsMatch(t, []string{"pos", "neg"}, a.Members())
+	assert.Equal(t, 2, a.Size())
+}
+
+func TestPNCounterState(t *testing.T) {
+	s := NewPNCounterStore("a")
+	s.Incr("pos", 3)
+	s.Incr("pos", -1)
+
+	b, err := s.State().MarshalBinary()
+	assert.Nil(t, err)
+
+	var decoded PNCounterState
+	assert.Nil(t, decoded.UnmarshalBinary(b))
+	assert.Equal(t, s.State(), decoded)
+
+	r := NewPNCounterStore("b")
+	r.Merge(decoded)
+	v, _ := r.Get("pos")
+	assert.Equal(t, int64(2), v)
+}
+
+func TestPNCounterConcurrentIncrAndMerge(t *testing.T) {
+	nodes := []*PNCounterStore{NewPNCounterStore("a"), NewPNCounterStore("b")}
+	for i, s := range nodes {
+		go func(s *PNCounterStore, delta int64) {
+			for j := 0; j < 1000; j++ {
+				s.Incr("pos", delta)
+			}
+		}(s, int64(2*i-1))
+	}
+
+	for i := 0; i < 100; i++ {
+		nodes[0].Merge(nodes[1].Delta())
+		nodes[1].Merge(nodes[0].Delta())
+	}
+
+	time.Sleep(2 * time.Second)
+
+	nodes[0].Merge(nodes[1].State())
+	nodes[1].Merge(nodes[0].State())
+	for _, s := range nodes {
+		v, _ := s.Get("pos")
+		assert.Equal(t, int64(0), v)
+	}
+}
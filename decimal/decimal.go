@@ -0,0 +1,550 @@
+// Package decimal provides a fixed point decimal number for exact money arithmetic
+//
+// A Decimal is an int64 mantissa scaled by a power of ten, so values such as prices
+// and PnL never pass through binary floating point.
+// Arithmetic is exact, operations that cannot be represented return ErrOverflow
+// or ErrPrecisionLoss instead of silently losing precision.
+package decimal
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// MaxScale is the maximum number of digits after the decimal point
+const MaxScale = 18
+
+var (
+	// ErrOverflow is thrown when a result does not fit an int64 mantissa
+	ErrOverflow = errors.New("decimal overflow")
+	// ErrPrecisionLoss is thrown when reducing the scale of a decimal would drop non zero digits
+	ErrPrecisionLoss = errors.New("decimal precision loss")
+	// ErrInvalidScale is thrown when a scale is negative or above MaxScale
+	ErrInvalidScale = errors.New("invalid decimal scale")
+	// ErrSyntax is thrown when parsing a malformed decimal string
+	ErrSyntax = errors.New("invalid decimal syntax")
+	// ErrInvalidTick is thrown when rounding to a tick size that is not positive
+	ErrInvalidTick = errors.New("tick size must be positive")
+)
+
+var pow10 = [MaxScale + 1]int64{
+	1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000, 1000000000,
+	10000000000, 100000000000, 1000000000000, 10000000000000, 100000000000000,
+	1000000000000000, 10000000000000000, 100000000000000000, 1000000000000000000,
+}
+
+// Decimal is the fixed point number mantissa * 10^-scale
+// The zero value is 0
+type Decimal struct {
+	mantissa int64
+	scale    uint8
+}
+
+// New constructs the decimal mantissa * 10^-scale, e.g. New(12345, 2) is 123.45
+// New panics if scale is negative or above MaxScale
+func New(mantissa int64, scale int) Decimal {
+	if scale < 0 || scale > MaxScale {
+		panic(ErrInvalidScale)
+	}
+
+	return Decimal{mantissa: mantissa, scale: uint8(scale)}
+}
+
+// FromInt constructs the decimal of an integer
+func FromInt(i int64) Decimal {
+	return Decimal{mantissa: i}
+}
+
+// Mantissa returns the unscaled integer value of d
+func (d Decimal) Mantissa() int64 {
+	return d.mantissa
+}
+
+// Scale returns the number of digits after the decimal point of d
+func (d Decimal) Scale() int {
+	return int(d.scale)
+}
+
+// Sign returns -1, 0 or 1 for negative, zero and positive d
+func (d Decimal) Sign() int {
+	switch {
+	case d.mantissa < 0:
+		return -1
+	case d.mantissa > 0:
+		return 1
+	}
+
+	return 0
+}
+
+// IsZero checks if d is zero at any scale
+func (d Decimal) IsZero() bool {
+	return d.mantissa == 0
+}
+
+// Neg returns -d
+func (d Decimal) Neg() (Decimal, error) {
+	if d.mantissa == math.MinInt64 {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{-d.mantissa, d.scale}, nil
+}
+
+func mul64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	p := a * b
+	if p/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	return p, true
+}
+
+func add64(a, b int64) (int64, bool) {
+	s := a + b
+	if (b > 0 && s < a) || (b < 0 && s > a) {
+		return 0, false
+	}
+
+	return s, true
+}
+
+func sub64(a, b int64) (int64, bool) {
+	s := a - b
+	if (b > 0 && s > a) || (b < 0 && s < a) {
+		return 0, false
+	}
+
+	return s, true
+}
+
+// Rescale returns d with the given scale
+// Increasing the scale is exact and returns ErrOverflow if the mantissa does not fit,
+// decreasing it returns ErrPrecisionLoss if non zero digits would be dropped, use Round or Truncate to reduce precision
+func (d Decimal) Rescale(scale int) (Decimal, error) {
+	if scale < 0 || scale > MaxScale {
+		return Decimal{}, ErrInvalidScale
+	}
+
+	if scale >= int(d.scale) {
+		m, ok := mul64(d.mantissa, pow10[scale-int(d.scale)])
+		if !ok {
+			return Decimal{}, ErrOverflow
+		}
+
+		return Decimal{m, uint8(scale)}, nil
+	}
+
+	p := pow10[int(d.scale)-scale]
+	if d.mantissa%p != 0 {
+		return Decimal{}, ErrPrecisionLoss
+	}
+
+	return Decimal{d.mantissa / p, uint8(scale)}, nil
+}
+
+// align rescales a and b to their larger scale
+func align(a, b Decimal) (Decimal, Decimal, error) {
+	var err error
+	if a.scale < b.scale {
+		a, err = a.Rescale(int(b.scale))
+	} else if b.scale < a.scale {
+		b, err = b.Rescale(int(a.scale))
+	}
+
+	return a, b, err
+}
+
+// Add returns d + e at the larger of their scales
+func (d Decimal) Add(e Decimal) (Decimal, error) {
+	d, e, err := align(d, e)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	m, ok := add64(d.mantissa, e.mantissa)
+	if !ok {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{m, d.scale}, nil
+}
+
+// Sub returns d - e at the larger of their scales
+func (d Decimal) Sub(e Decimal) (Decimal, error) {
+	d, e, err := align(d, e)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	m, ok := sub64(d.mantissa, e.mantissa)
+	if !ok {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{m, d.scale}, nil
+}
+
+// Mul returns the exact product d * e, whose scale is the sum of their scales
+// Use Round to bring the product back to a working precision
+func (d Decimal) Mul(e Decimal) (Decimal, error) {
+	scale := int(d.scale) + int(e.scale)
+	if scale > MaxScale {
+		return Decimal{}, ErrInvalidScale
+	}
+
+	m, ok := mul64(d.mantissa, e.mantissa)
+	if !ok {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{m, uint8(scale)}, nil
+}
+
+// divRound divides m by the positive p rounding half away from zero
+func divRound(m, p int64) int64 {
+	q, r := m/p, m%p
+	if r < 0 {
+		r = -r
+	}
+
+	// compare 2r >= p without overflowing
+	if r >= p-r {
+		if m < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+
+	return q
+}
+
+// Round returns d rounded half away from zero to the given scale
+// Rounding to a larger scale is the same as Rescale
+func (d Decimal) Round(scale int) (Decimal, error) {
+	if scale < 0 || scale > MaxScale {
+		return Decimal{}, ErrInvalidScale
+	}
+
+	if scale >= int(d.scale) {
+		return d.Rescale(scale)
+	}
+
+	return Decimal{divRound(d.mantissa, pow10[int(d.scale)-scale]), uint8(scale)}, nil
+}
+
+// Truncate returns d rounded toward zero to the given scale
+func (d Decimal) Truncate(scale int) (Decimal, error) {
+	if scale < 0 || scale > MaxScale {
+		return Decimal{}, ErrInvalidScale
+	}
+
+	if scale >= int(d.scale) {
+		return d.Rescale(scale)
+	}
+
+	return Decimal{d.mantissa / pow10[int(d.scale)-scale], uint8(scale)}, nil
+}
+
+// RoundToTick returns d rounded half away from zero to the nearest multiple of tick, at the scale of tick
+// e.g. 101.237 rounded to a 0.05 tick is 101.25
+func (d Decimal) RoundToTick(tick Decimal) (Decimal, error) {
+	if tick.mantissa <= 0 {
+		return Decimal{}, ErrInvalidTick
+	}
+
+	scale := int(tick.scale)
+	if int(d.scale) > scale {
+		scale = int(d.scale)
+	}
+
+	d, err := d.Rescale(scale)
+	if err != nil {
+		return Decimal{}, err
+	}
+	t, err := tick.Rescale(scale)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	m, ok := mul64(divRound(d.mantissa, t.mantissa), t.mantissa)
+	if !ok {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{m, uint8(scale)}.Round(int(tick.scale))
+}
+
+// Accumulator sums decimals exactly, the zero value is an empty sum
+// It does not allocate while the running total fits an int64
+type Accumulator struct {
+	total int64    // sum of the mantissas aligned to scale, while big is nil
+	big   *big.Int // the same sum once it no longer fits an int64
+	scale int
+	n     int
+}
+
+// toBig moves the total to a big.Int once it overflows
+func (a *Accumulator) toBig() {
+	if a.big == nil {
+		a.big = big.NewInt(a.total)
+	}
+}
+
+// Add adds d to the sum, raising the scale of the sum to the scale of d if it is larger
+func (a *Accumulator) Add(d Decimal) {
+	a.n++
+	s := int(d.scale)
+
+	if s > a.scale {
+		p := pow10[s-a.scale]
+		if a.big == nil {
+			if t, ok := mul64(a.total, p); ok {
+				a.total = t
+			} else {
+				a.toBig()
+			}
+		}
+		if a.big != nil {
+			a.big.Mul(a.big, big.NewInt(p))
+		}
+		a.scale = s
+	}
+
+	p := pow10[a.scale-s]
+	if a.big == nil {
+		if m, ok := mul64(d.mantissa, p); ok {
+			if t, ok := add64(a.total, m); ok {
+				a.total = t
+				return
+			}
+		}
+		a.toBig()
+	}
+
+	m := big.NewInt(d.mantissa)
+	a.big.Add(a.big, m.Mul(m, big.NewInt(p)))
+}
+
+// Len returns the number of decimals added
+func (a *Accumulator) Len() int {
+	return a.n
+}
+
+// Sum returns the exact sum at the largest scale of the decimals added, 0 if none were
+// The sum does not depend on the order they were added in, ErrOverflow is only returned if the total does not fit
+func (a *Accumulator) Sum() (Decimal, error) {
+	if a.big == nil {
+		return Decimal{a.total, uint8(a.scale)}, nil
+	}
+	if !a.big.IsInt64() {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{a.big.Int64(), uint8(a.scale)}, nil
+}
+
+// Mean returns the arithmetic mean of the decimals added rounded half away from zero to the given scale,
+// 0 if none were
+// The mean is computed from the exact sum, so it is returned even if Sum would overflow
+func (a *Accumulator) Mean(scale int) (Decimal, error) {
+	if scale < 0 || scale > MaxScale {
+		return Decimal{}, ErrInvalidScale
+	}
+	if a.n == 0 {
+		return Decimal{0, uint8(scale)}, nil
+	}
+
+	// mean * 10^scale = total * 10^(scale - a.scale) / n
+	num := big.NewInt(a.total)
+	if a.big != nil {
+		num.Set(a.big)
+	}
+	num.Mul(num, big.NewInt(pow10[scale]))
+	den := big.NewInt(int64(a.n))
+	den.Mul(den, big.NewInt(pow10[a.scale]))
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Lsh(r.Abs(r), 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	if !q.IsInt64() {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{q.Int64(), uint8(scale)}, nil
+}
+
+// Sum returns the exact sum of ds at the largest of their scales, 0 if ds is empty
+// The sum does not depend on the order of ds, ErrOverflow is only returned if the total does not fit
+func Sum(ds ...Decimal) (Decimal, error) {
+	var a Accumulator
+	for _, d := range ds {
+		a.Add(d)
+	}
+
+	return a.Sum()
+}
+
+// Mean returns the arithmetic mean of ds rounded half away from zero to the given scale, 0 if ds is empty
+// The mean is computed from the exact sum, so it is returned even if Sum would overflow
+func Mean(scale int, ds ...Decimal) (Decimal, error) {
+	var a Accumulator
+	for _, d := range ds {
+		a.Add(d)
+	}
+
+	return a.Mean(scale)
+}
+
+// Cmp compares the values of d and e regardless of scale
+// returns -1 if d < e, 0 if d == e and 1 if d > e
+func (d Decimal) Cmp(e Decimal) int {
+	a, b, err := align(d, e)
+	if err != nil {
+		// the operand that overflowed on rescale has the larger magnitude
+		if d.scale < e.scale {
+			return d.Sign()
+		}
+
+		return -e.Sign()
+	}
+
+	switch {
+	case a.mantissa < b.mantissa:
+		return -1
+	case a.mantissa > b.mantissa:
+		return 1
+	}
+
+	return 0
+}
+
+// Equal checks if d and e have the same value regardless of scale
+func (d Decimal) Equal(e Decimal) bool {
+	return d.Cmp(e) == 0
+}
+
+// Float64 returns the nearest float64 to d, for display and interop only
+func (d Decimal) Float64() float64 {
+	return float64(d.mantissa) / float64(pow10[d.scale])
+}
+
+// String formats d with exactly Scale digits after the decimal point, e.g. -0.50
+func (d Decimal) String() string {
+	digits := strconv.FormatUint(absUint(d.mantissa), 10)
+
+	s := digits
+	if d.scale > 0 {
+		if len(digits) <= int(d.scale) {
+			digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+		}
+		p := len(digits) - int(d.scale)
+		s = digits[:p] + "." + digits[p:]
+	}
+
+	if d.mantissa < 0 {
+		return "-" + s
+	}
+
+	return s
+}
+
+func absUint(m int64) uint64 {
+	if m < 0 {
+		return uint64(-(m + 1)) + 1
+	}
+
+	return uint64(m)
+}
+
+// Parse parses a decimal string such as "-123.4500", keeping every digit after the point as scale
+func Parse(s string) (Decimal, error) {
+	neg := false
+	if len(s) > 0 && (s[0] == '-' || s[0] == '+') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	if intPart == "" && fracPart == "" {
+		return Decimal{}, ErrSyntax
+	}
+	if len(fracPart) > MaxScale {
+		return Decimal{}, ErrInvalidScale
+	}
+
+	var m uint64
+	for _, c := range intPart + fracPart {
+		if c < '0' || c > '9' {
+			return Decimal{}, ErrSyntax
+		}
+		if m > (math.MaxUint64-9)/10 {
+			return Decimal{}, ErrOverflow
+		}
+		m = m*10 + uint64(c-'0')
+	}
+
+	if neg {
+		if m > uint64(math.MaxInt64)+1 {
+			return Decimal{}, ErrOverflow
+		}
+
+		return Decimal{int64(-m), uint8(len(fracPart))}, nil
+	}
+
+	if m > math.MaxInt64 {
+		return Decimal{}, ErrOverflow
+	}
+
+	return Decimal{int64(m), uint8(len(fracPart))}, nil
+}
+
+// MustParse is like Parse but panics if s cannot be parsed
+// Use for constants only
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Decimal) UnmarshalText(text []byte) error {
+	v, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = v
+
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, so decimals can be gob encoded
+func (d Decimal) MarshalBinary() ([]byte, error) {
+	return d.MarshalText()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler
+func (d *Decimal) UnmarshalBinary(data []byte) error {
+	return d.UnmarshalText(data)
+}
@@ -0,0 +1,366 @@
+package decimal
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	d := New(12345, 2)
+	assert.Equal(t, int64(12345), d.Mantissa())
+	assert.Equal(t, 2, d.Scale())
+	assert.Equal(t, "123.45", d.String())
+
+	assert.Panics(t, func() { New(1, MaxScale+1) })
+	assert.Panics(t, func() { New(1, -1) })
+
+	assert.Equal(t, "42", FromInt(42).String())
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(t, -1, New(-1, 3).Sign())
+	assert.Equal(t, 0, Decimal{}.Sign())
+	assert.Equal(t, 1, New(1, 3).Sign())
+	assert.True(t, New(0, 5).IsZero())
+}
+
+func TestNeg(t *testing.T) {
+	d, err := New(150, 2).Neg()
+	assert.Nil(t, err)
+	assert.Equal(t, New(-150, 2), d)
+
+	_, err = New(math.MinInt64, 0).Neg()
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestAddIsExact(t *testing.T) {
+	// the classic binary floating point drift
+	a, b := 0.1, 0.2
+	assert.NotEqual(t, 0.3, a+b)
+
+	d, err := MustParse("0.1").Add(MustParse("0.2"))
+	assert.Nil(t, err)
+	assert.True(t, d.Equal(MustParse("0.3")))
+	assert.Equal(t, "0.3", d.String())
+}
+
+func TestAdd(t *testing.T) {
+	// scales are aligned to the larger
+	d, err := MustParse("1.5").Add(MustParse("0.25"))
+	assert.Nil(t, err)
+	assert.Equal(t, New(175, 2), d)
+
+	_, err = New(math.MaxInt64, 0).Add(New(1, 0))
+	assert.Equal(t, ErrOverflow, err)
+
+	// alignment overflow
+	_, err = New(math.MaxInt64, 0).Add(New(1, 1))
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestSub(t *testing.T) {
+	d, err := MustParse("100.00").Sub(MustParse("100.01"))
+	assert.Nil(t, err)
+	assert.Equal(t, "-0.01", d.String())
+
+	_, err = New(math.MinInt64, 0).Sub(New(1, 0))
+	assert.Equal(t, ErrOverflow, err)
+	_, err = New(math.MaxInt64, 0).Sub(New(-1, 0))
+	assert.Equal(t, ErrOverflow, err)
+
+	// the result fits even though the operand has no negation
+	d, err = New(-1, 0).Sub(New(math.MinInt64, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, New(math.MaxInt64, 0), d)
+
+	d, err = New(math.MinInt64, 2).Sub(New(math.MinInt64, 2))
+	assert.Nil(t, err)
+	assert.True(t, d.IsZero())
+
+	// alignment overflow
+	_, err = New(math.MaxInt64, 0).Sub(New(1, 1))
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestMul(t *testing.T) {
+	d, err := MustParse("101.25").Mul(MustParse("300"))
+	assert.Nil(t, err)
+	assert.Equal(t, "30375.00", d.String())
+
+	d, err = MustParse("1.5").Mul(MustParse("-0.5"))
+	assert.Nil(t, err)
+	assert.Equal(t, "-0.75", d.String())
+
+	_, err = New(math.MaxInt64/2+1, 0).Mul(New(2, 0))
+	assert.Equal(t, ErrOverflow, err)
+
+	_, err = New(math.MinInt64, 0).Mul(New(-1, 0))
+	assert.Equal(t, ErrOverflow, err)
+
+	_, err = New(1, 10).Mul(New(1, 10))
+	assert.Equal(t, ErrInvalidScale, err)
+}
+
+func TestRescale(t *testing.T) {
+	d, err := MustParse("1.5").Rescale(4)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.5000", d.String())
+
+	d, err = d.Rescale(1)
+	assert.Nil(t, err)
+	assert.Equal(t, New(15, 1), d)
+
+	// would drop digits
+	_, err = MustParse("1.55").Rescale(1)
+	assert.Equal(t, ErrPrecisionLoss, err)
+
+	// mantissa does not fit
+	_, err = New(math.MaxInt64, 0).Rescale(1)
+	assert.Equal(t, ErrOverflow, err)
+
+	_, err = MustParse("1").Rescale(MaxScale + 1)
+	assert.Equal(t, ErrInvalidScale, err)
+}
+
+func TestRound(t *testing.T) {
+	cases := map[string]string{
+		"1.2345":  "1.23",
+		"1.235":   "1.24",
+		"-1.235":  "-1.24",
+		"-1.2349": "-1.23",
+		"0.005":   "0.01",
+		"2":       "2.00",
+	}
+
+	for in, exp := range cases {
+		d, err := MustParse(in).Round(2)
+		assert.Nil(t, err)
+		assert.Equal(t, exp, d.String(), in)
+	}
+
+	_, err := MustParse("1").Round(-1)
+	assert.Equal(t, ErrInvalidScale, err)
+}
+
+func TestTruncate(t *testing.T) {
+	d, err := MustParse("-1.239").Truncate(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "-1.23", d.String())
+
+	d, err = MustParse("1.2").Truncate(3)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.200", d.String())
+}
+
+func TestRoundToTick(t *testing.T) {
+	d, err := MustParse("101.237").RoundToTick(MustParse("0.05"))
+	assert.Nil(t, err)
+	assert.Equal(t, "101.25", d.String())
+
+	d, err = MustParse("101.22").RoundToTick(MustParse("0.05"))
+	assert.Nil(t, err)
+	assert.Equal(t, "101.20", d.String())
+
+	d, err = MustParse("-7.5").RoundToTick(MustParse("5"))
+	assert.Nil(t, err)
+	assert.Equal(t, "-10", d.String())
+
+	// finer price than tick
+	d, err = MustParse("3").RoundToTick(MustParse("0.25"))
+	assert.Nil(t, err)
+	assert.Equal(t, "3.00", d.String())
+
+	_, err = MustParse("1").RoundToTick(MustParse("0"))
+	assert.Equal(t, ErrInvalidTick, err)
+	_, err = MustParse("1").RoundToTick(MustParse("-0.5"))
+	assert.Equal(t, ErrInvalidTick, err)
+}
+
+func TestSum(t *testing.T) {
+	d, err := Sum()
+	assert.Nil(t, err)
+	assert.True(t, d.IsZero())
+
+	d, err = Sum(MustParse("0.1"), MustParse("0.25"), MustParse("-1"))
+	assert.Nil(t, err)
+	assert.Equal(t, New(-65, 2), d)
+
+	// intermediate totals may overflow in any order
+	d, err = Sum(New(math.MaxInt64, 0), New(1, 0), New(-2, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, New(math.MaxInt64-1, 0), d)
+
+	_, err = Sum(New(math.MaxInt64, 0), New(1, 0))
+	assert.Equal(t, ErrOverflow, err)
+	_, err = Sum(New(math.MaxInt64, 0), New(1, 1))
+	assert.Equal(t, ErrOverflow, err)
+}
+
+func TestMean(t *testing.T) {
+	d, err := Mean(2)
+	assert.Nil(t, err)
+	assert.Equal(t, New(0, 2), d)
+
+	d, err = Mean(2, MustParse("1"), MustParse("2"))
+	assert.Nil(t, err)
+	assert.Equal(t, "1.50", d.String())
+
+	// rounded half away from zero
+	d, err = Mean(0, MustParse("1"), MustParse("2"))
+	assert.Nil(t, err)
+	assert.Equal(t, New(2, 0), d)
+	d, err = Mean(0, MustParse("-1"), MustParse("-2"))
+	assert.Nil(t, err)
+	assert.Equal(t, New(-2, 0), d)
+	d, err = Mean(3, MustParse("1"), MustParse("1"), MustParse("0"))
+	assert.Nil(t, err)
+	assert.Equal(t, "0.667", d.String())
+
+	// the sum does not fit but the mean does
+	d, err = Mean(0, New(math.MaxInt64, 0), New(math.MaxInt64, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, New(math.MaxInt64, 0), d)
+
+	_, err = Mean(1, New(math.MaxInt64, 0))
+	assert.Equal(t, ErrOverflow, err)
+	_, err = Mean(MaxScale+1, New(1, 0))
+	assert.Equal(t, ErrInvalidScale, err)
+}
+
+func TestAccumulator(t *testing.T) {
+	var a Accumulator
+	d, err := a.Sum()
+	assert.Nil(t, err)
+	assert.True(t, d.IsZero())
+	assert.Equal(t, 0, a.Len())
+
+	// the sum is raised to each larger scale
+	a.Add(MustParse("1"))
+	a.Add(MustParse("0.5"))
+	a.Add(MustParse("0.25"))
+	d, err = a.Sum()
+	assert.Nil(t, err)
+	assert.Equal(t, New(175, 2), d)
+	assert.Equal(t, 3, a.Len())
+
+	// overflows and comes back in range
+	a.Add(New(math.MaxInt64, 0))
+	_, err = a.Sum()
+	assert.Equal(t, ErrOverflow, err)
+	a.Add(New(-math.MaxInt64, 0))
+	d, err = a.Sum()
+	assert.Nil(t, err)
+	assert.Equal(t, New(175, 2), d)
+
+	d, err = a.Mean(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.35", d.String())
+
+	// no allocation while the total fits
+	var b Accumulator
+	allocs := testing.AllocsPerRun(10, func() {
+		b.Add(New(125, 2))
+		b.Sum()
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func TestCmp(t *testing.T) {
+	assert.Equal(t, 0, MustParse("1.50").Cmp(MustParse("1.5")))
+	assert.Equal(t, -1, MustParse("1.49").Cmp(MustParse("1.5")))
+	assert.Equal(t, 1, MustParse("-1").Cmp(MustParse("-1.0001")))
+	assert.True(t, MustParse("2.000").Equal(FromInt(2)))
+
+	// alignment overflow
+	big := New(math.MaxInt64, 0)
+	assert.Equal(t, 1, big.Cmp(New(1, 1)))
+	assert.Equal(t, -1, New(1, 1).Cmp(big))
+	assert.Equal(t, -1, New(math.MinInt64, 0).Cmp(New(1, 1)))
+}
+
+func TestFloat64(t *testing.T) {
+	assert.Equal(t, 123.45, New(12345, 2).Float64())
+	assert.Equal(t, -0.5, New(-5, 1).Float64())
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "0", Decimal{}.String())
+	assert.Equal(t, "0.00", New(0, 2).String())
+	assert.Equal(t, "0.05", New(5, 2).String())
+	assert.Equal(t, "-0.005", New(-5, 3).String())
+	assert.Equal(t, "-9223372036854775808", New(math.MinInt64, 0).String())
+	assert.Equal(t, "-9.223372036854775808", New(math.MinInt64, 18).String())
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]Decimal{
+		"0":                    {},
+		"+1.50":                New(150, 2),
+		"-0.001":               New(-1, 3),
+		".5":                   New(5, 1),
+		"12.":                  New(12, 0),
+		"-9223372036854775808": New(math.MinInt64, 0),
+	}
+
+	for in, exp := range cases {
+		d, err := Parse(in)
+		assert.Nil(t, err, in)
+		assert.Equal(t, exp, d, in)
+	}
+
+	for _, in := range []string{"", "-", ".", "1.2.3", "abc", "1e5", " 1"} {
+		_, err := Parse(in)
+		assert.Equal(t, ErrSyntax, err, in)
+	}
+
+	_, err := Parse("9223372036854775808")
+	assert.Equal(t, ErrOverflow, err)
+	_, err = Parse("99999999999999999999999")
+	assert.Equal(t, ErrOverflow, err)
+	_, err = Parse("0.1234567890123456789")
+	assert.Equal(t, ErrInvalidScale, err)
+
+	assert.Panics(t, func() { MustParse("x") })
+}
+
+func TestTextMarshaling(t *testing.T) {
+	type quote struct {
+		Price Decimal
+	}
+
+	b, err := json.Marshal(quote{MustParse("101.25")})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"Price":"101.25"}`, string(b))
+
+	var q quote
+	err = json.Unmarshal(b, &q)
+	assert.Nil(t, err)
+	assert.Equal(t, MustParse("101.25"), q.Price)
+
+	err = json.Unmarshal([]byte(`{"Price":"x"}`), &q)
+	assert.Equal(t, ErrSyntax, err)
+}
+
+func TestBinaryMarshaling(t *testing.T) {
+	type quote struct {
+		Price Decimal
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(quote{MustParse("-0.050")})
+	assert.Nil(t, err)
+
+	var q quote
+	err = gob.NewDecoder(&buf).Decode(&q)
+	assert.Nil(t, err)
+	assert.Equal(t, MustParse("-0.050"), q.Price)
+	assert.Equal(t, 3, q.Price.Scale())
+
+	var d Decimal
+	assert.Equal(t, ErrSyntax, d.UnmarshalBinary([]byte("x")))
+}
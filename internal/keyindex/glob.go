@@ -0,0 +1,116 @@
+package keyindex
+
+import (
+	"strings"
+)
+
+// LiteralPrefix returns the part of the glob pattern before its first special character
+// Every key matching the pattern starts with it
+func LiteralPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+
+	return pattern
+}
+
+// Match checks if key matches the glob pattern, like Redis KEYS patterns
+// A star matches any sequence of characters including none, ? matches any single character,
+// [abc] one of the characters, [^abc] or [!abc] one character not listed, [a-z] one character in the range,
+// and a backslash escapes the following character
+// Unlike path.Match a star also matches /, and a malformed pattern only fails to match
+func Match(pattern, key string) bool {
+	// position of the last * and the key position it was tried at, for backtracking
+	star, retry := -1, 0
+
+	p, k := 0, 0
+	for k < len(key) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				star, retry = p, k
+				p++
+				continue
+			case '?':
+				p++
+				k++
+				continue
+			case '[':
+				if end, ok := matchClass(pattern, p, key[k]); ok {
+					p = end
+					k++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == key[k] {
+					p += 2
+					k++
+					continue
+				}
+			default:
+				if pattern[p] == key[k] {
+					p++
+					k++
+					continue
+				}
+			}
+		}
+
+		// mismatch, let the last * absorb one more character
+		if star < 0 {
+			return false
+		}
+		retry++
+		p, k = star+1, retry
+	}
+
+	// trailing stars match the empty rest of the key
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// matchClass matches c against the bracket class starting at pattern[start]
+// returns the index after the class and true if c is matched
+func matchClass(pattern string, start int, c byte) (int, bool) {
+	i := start + 1
+	negate := i < len(pattern) && (pattern[i] == '^' || pattern[i] == '!')
+	if negate {
+		i++
+	}
+
+	matched := false
+	first := true
+	for ; i < len(pattern) && (first || pattern[i] != ']'); i++ {
+		first = false
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi = pattern[i+2]
+			if hi == '\\' && i+3 < len(pattern) {
+				i++
+				hi = pattern[i+2]
+			}
+			i += 2
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+
+	// unterminated class
+	if i >= len(pattern) {
+		return 0, false
+	}
+
+	return i + 1, matched != negate
+}
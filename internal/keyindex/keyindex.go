@@ -0,0 +1,112 @@
+// Package keyindex provides an ordered index of store keys for sorted iteration, prefix and glob lookups
+//
+// Stores keep an *Index alongside their map and update it on every key creation and deletion.
+// A nil *Index is a disabled index and ignores updates, so stores call it unconditionally.
+// Index is NOT safe for concurrent use, callers provide locking.
+package keyindex
+
+import (
+	"strings"
+
+	"github.com/blacklabcapital/safestore/internal/skiplist"
+)
+
+// Index is a set of keys kept in ascending order
+type Index struct {
+	// every key has score 0, so the list is ordered by key alone
+	keys *skiplist.List
+}
+
+// New constructs an empty Index
+func New() *Index {
+	return &Index{keys: skiplist.New()}
+}
+
+func (ix *Index) has(key string) bool {
+	n := ix.keys.Seek(0, key)
+
+	return n != nil && n.Member() == key
+}
+
+// Insert adds the key to the index, if it is not already indexed
+func (ix *Index) Insert(key string) {
+	if ix == nil || ix.has(key) {
+		return
+	}
+
+	ix.keys.Insert(0, key)
+}
+
+// Delete removes the key from the index
+func (ix *Index) Delete(key string) {
+	if ix == nil {
+		return
+	}
+
+	ix.keys.Delete(0, key)
+}
+
+// Clear removes every key from the index
+func (ix *Index) Clear() {
+	if ix == nil {
+		return
+	}
+
+	ix.keys = skiplist.New()
+}
+
+// Len returns the number of indexed keys
+func (ix *Index) Len() int {
+	if ix == nil {
+		return 0
+	}
+
+	return ix.keys.Len()
+}
+
+// Keys returns every indexed key in ascending order
+func (ix *Index) Keys() []string {
+	keys := make([]string, 0, ix.Len())
+	if ix == nil {
+		return keys
+	}
+
+	for n := ix.keys.First(); n != nil; n = n.Next() {
+		keys = append(keys, n.Member())
+	}
+
+	return keys
+}
+
+// WithPrefix returns the indexed keys starting with prefix in ascending order,
+// visiting only the matching keys
+func (ix *Index) WithPrefix(prefix string) []string {
+	keys := make([]string, 0)
+	if ix == nil {
+		return keys
+	}
+
+	for n := ix.keys.Seek(0, prefix); n != nil && strings.HasPrefix(n.Member(), prefix); n = n.Next() {
+		keys = append(keys, n.Member())
+	}
+
+	return keys
+}
+
+// Matching returns the indexed keys matching the glob pattern in ascending order
+// Only keys starting with the literal prefix of the pattern are visited, e.g. "AAPL:" for "AAPL:*"
+func (ix *Index) Matching(pattern string) []string {
+	keys := make([]string, 0)
+	if ix == nil {
+		return keys
+	}
+
+	prefix := LiteralPrefix(pattern)
+	for n := ix.keys.Seek(0, prefix); n != nil && strings.HasPrefix(n.Member(), prefix); n = n.Next() {
+		if Match(pattern, n.Member()) {
+			keys = append(keys, n.Member())
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,128 @@
+package keyindex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockIndex() *Index {
+	ix := New()
+	for _, k := range []string{"MSFT:bid", "AAPL:bid", "AAPL:ask", "AAPL", "GOOG:bid", "AAPLX:bid"} {
+		ix.Insert(k)
+	}
+
+	return ix
+}
+
+func TestIndexInsert(t *testing.T) {
+	ix := New()
+
+	ix.Insert("b")
+	ix.Insert("a")
+
+	// idempotent
+	ix.Insert("b")
+	assert.Equal(t, 2, ix.Len())
+	assert.Equal(t, []string{"a", "b"}, ix.Keys())
+}
+
+func TestIndexDelete(t *testing.T) {
+	ix := mockIndex()
+
+	ix.Delete("AAPL")
+	ix.Delete("foo")
+	assert.Equal(t, 5, ix.Len())
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "AAPLX:bid", "GOOG:bid", "MSFT:bid"}, ix.Keys())
+}
+
+func TestIndexClear(t *testing.T) {
+	ix := mockIndex()
+
+	ix.Clear()
+	assert.Equal(t, 0, ix.Len())
+	assert.Equal(t, []string{}, ix.Keys())
+}
+
+func TestIndexWithPrefix(t *testing.T) {
+	ix := mockIndex()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ix.WithPrefix("AAPL:"))
+	assert.Equal(t, []string{"AAPL", "AAPL:ask", "AAPL:bid", "AAPLX:bid"}, ix.WithPrefix("AAPL"))
+	assert.Equal(t, []string{}, ix.WithPrefix("IBM"))
+	assert.Equal(t, 6, len(ix.WithPrefix("")))
+}
+
+func TestIndexMatching(t *testing.T) {
+	ix := mockIndex()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ix.Matching("AAPL:*"))
+	assert.Equal(t, []string{"AAPL:bid", "AAPLX:bid", "GOOG:bid", "MSFT:bid"}, ix.Matching("*bid"))
+	assert.Equal(t, []string{"AAPL:bid", "GOOG:bid", "MSFT:bid"}, ix.Matching("????:bid"))
+	assert.Equal(t, []string{"AAPL"}, ix.Matching("AAPL"))
+	assert.Equal(t, []string{}, ix.Matching("IBM*"))
+}
+
+func TestNilIndex(t *testing.T) {
+	var ix *Index
+
+	// disabled index ignores updates
+	ix.Insert("a")
+	ix.Delete("a")
+	ix.Clear()
+	assert.Equal(t, 0, ix.Len())
+	assert.Equal(t, []string{}, ix.Keys())
+	assert.Equal(t, []string{}, ix.WithPrefix("a"))
+	assert.Equal(t, []string{}, ix.Matching("a*"))
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	assert.Equal(t, "AAPL:", LiteralPrefix("AAPL:*"))
+	assert.Equal(t, "AAPL", LiteralPrefix("AAPL"))
+	assert.Equal(t, "A", LiteralPrefix("A?PL"))
+	assert.Equal(t, "", LiteralPrefix("[AB]*"))
+	assert.Equal(t, "A", LiteralPrefix(`A\*`))
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		key     string
+		match   bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"*", "", true},
+		{"*", "a/b:c", true},
+		{"AAPL:*", "AAPL:bid", true},
+		{"AAPL:*", "AAPL", false},
+		{"*:bid", "AAPL:bid", true},
+		{"*:bid", "AAPL:ask", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"a*b", "abab", true},
+		{"**", "abc", true},
+		{"?", "a", true},
+		{"?", "", false},
+		{"a?c", "abc", true},
+		{"[abc]x", "bx", true},
+		{"[abc]x", "dx", false},
+		{"[^abc]x", "dx", true},
+		{"[!abc]x", "ax", false},
+		{"[a-c]", "b", true},
+		{"[a-c]", "d", false},
+		{"[]]", "]", true},
+		{"[a-]", "-", true},
+		{`[\]]`, "]", true},
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{`a\`, "a", false},
+		{"[abc", "a", false},
+		{"AAPL", "AAPL", true},
+		{"AAPL", "AAPLX", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, Match(c.pattern, c.key), "%q %q", c.pattern, c.key)
+	}
+}
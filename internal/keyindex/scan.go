@@ -0,0 +1,77 @@
+package keyindex
+
+import (
+	"strings"
+)
+
+// DefaultScanCount is the batch size of a scan given a count < 1
+const DefaultScanCount = 10
+
+// A scan visits keys in ascending order, one batch of at most count keys per call
+// The cursor is the lowest key of the next batch, "" to start a scan
+// The next cursor is "" once every key has been visited, else the smallest string after the last key of the batch,
+// so every key present for the whole scan is returned exactly once, whatever is created or deleted between batches
+
+// scanStart returns the lowest key a scan batch may visit and the literal prefix every visited key must have
+func scanStart(cursor, pattern string) (string, string) {
+	prefix := LiteralPrefix(pattern)
+	if cursor < prefix {
+		return prefix, prefix
+	}
+
+	return cursor, prefix
+}
+
+// after returns the smallest string ordered after key
+func after(key string) string {
+	return key + "\x00"
+}
+
+func scanCount(count int) int {
+	if count < 1 {
+		return DefaultScanCount
+	}
+
+	return count
+}
+
+// filter keeps the keys matching pattern, every key if pattern is ""
+func filter(keys []string, pattern string) []string {
+	if pattern == "" {
+		return keys
+	}
+
+	out := keys[:0]
+	for _, k := range keys {
+		if Match(pattern, k) {
+			out = append(out, k)
+		}
+	}
+
+	return out
+}
+
+// Scan returns the batch of indexed keys starting at cursor that match the glob pattern, and the next cursor
+// A batch visits at most count keys, so it may hold fewer matches or none before the scan is done
+// Only keys starting with the literal prefix of the pattern are visited
+func (ix *Index) Scan(cursor string, count int, pattern string) ([]string, string) {
+	keys := make([]string, 0)
+	if ix == nil {
+		return keys, ""
+	}
+
+	start, prefix := scanStart(cursor, pattern)
+	count = scanCount(count)
+
+	n := ix.keys.Seek(0, start)
+	for ; n != nil && len(keys) < count && strings.HasPrefix(n.Member(), prefix); n = n.Next() {
+		keys = append(keys, n.Member())
+	}
+
+	next := ""
+	if n != nil && len(keys) == count && strings.HasPrefix(n.Member(), prefix) {
+		next = after(keys[len(keys)-1])
+	}
+
+	return filter(keys, pattern), next
+}
@@ -0,0 +1,120 @@
+package keyindex
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("SYM%02d", i)
+	}
+
+	return keys
+}
+
+// scanAll runs a full scan with the given batch function, returning every batch
+func scanAll(batch func(cursor string) ([]string, string)) [][]string {
+	batches := make([][]string, 0)
+	cursor := ""
+	for {
+		keys, next := batch(cursor)
+		batches = append(batches, keys)
+		if next == "" {
+			return batches
+		}
+		cursor = next
+	}
+}
+
+func flatten(batches [][]string) []string {
+	out := make([]string, 0)
+	for _, b := range batches {
+		out = append(out, b...)
+	}
+
+	return out
+}
+
+func TestIndexScan(t *testing.T) {
+	ix := New()
+	for _, k := range mockKeys(25) {
+		ix.Insert(k)
+	}
+
+	batches := scanAll(func(cursor string) ([]string, string) {
+		return ix.Scan(cursor, 10, "")
+	})
+	assert.Equal(t, 3, len(batches))
+	assert.Equal(t, 10, len(batches[0]))
+	assert.Equal(t, 5, len(batches[2]))
+	assert.Equal(t, mockKeys(25), flatten(batches))
+
+	// exact multiple of count ends without an empty batch
+	batches = scanAll(func(cursor string) ([]string, string) {
+		return ix.Scan(cursor, 5, "")
+	})
+	assert.Equal(t, 5, len(batches))
+
+	// default count
+	keys, next := ix.Scan("", 0, "")
+	assert.Equal(t, DefaultScanCount, len(keys))
+	assert.Equal(t, "SYM09\x00", next)
+
+	// pattern
+	batches = scanAll(func(cursor string) ([]string, string) {
+		return ix.Scan(cursor, 3, "SYM1?")
+	})
+	assert.Equal(t, []string{"SYM10", "SYM11", "SYM12", "SYM13", "SYM14", "SYM15", "SYM16", "SYM17", "SYM18", "SYM19"}, flatten(batches))
+
+	batches = scanAll(func(cursor string) ([]string, string) {
+		return ix.Scan(cursor, 10, "*5")
+	})
+	assert.Equal(t, []string{"SYM05", "SYM15"}, flatten(batches))
+
+	// empty
+	keys, next = New().Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+
+	// nil
+	var nilIx *Index
+	keys, next = nilIx.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestScanConcurrentMutation(t *testing.T) {
+	ix := New()
+	stable := mockKeys(50)
+	for _, k := range stable {
+		ix.Insert(k)
+	}
+
+	seen := make(map[string]int)
+	cursor := ""
+	for i := 0; ; i++ {
+		keys, next := ix.Scan(cursor, 7, "")
+		for _, k := range keys {
+			seen[k]++
+		}
+
+		// mutate between batches, before and after the cursor
+		ix.Insert(fmt.Sprintf("NEW%02d", i))
+		ix.Insert(fmt.Sprintf("ZZZ%02d", i))
+		ix.Delete(fmt.Sprintf("ZZZ%02d", i-1))
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	// every key present for the whole scan is returned exactly once
+	for _, k := range stable {
+		assert.Equal(t, 1, seen[k], k)
+	}
+}
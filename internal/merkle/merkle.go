@@ -0,0 +1,205 @@
+// Package merkle provides an incrementally maintained Merkle digest of store contents,
+// and an anti-entropy sync that transfers only the keys whose hashes differ between two replicas
+//
+// Every key hashes into one of Leaves buckets. A bucket hash is the sum of the hashes of its entries,
+// and every node above is the sum of its Fanout children, so setting or deleting a key updates
+// Depth+1 nodes without rehashing anything else. Sums are taken modulo 2^64 and do not depend on insertion order,
+// so two stores with the same contents have the same Root however they were written.
+//
+// Entry hashes are streamed: a store hashes a key with Init, then its value with the Write functions,
+// so an appended series extends the state of its key instead of hashing the whole series again.
+// Hashes are 64-bit FNV-1a, finalized with a mixing function, and are NOT cryptographic.
+//
+// A nil *Tree is a disabled digest and ignores updates, so stores call it unconditionally.
+// Tree is NOT safe for concurrent use, callers provide locking.
+package merkle
+
+const (
+	// Fanout is the number of children of every interior node
+	Fanout = 16
+	// Depth is the number of levels below the root
+	Depth = 3
+	// Leaves is the number of buckets keys hash into
+	Leaves = 4096 // Fanout^Depth
+
+	fanoutBits = 4
+
+	offset64 = 14695981039346656037
+	prime64  = 1099511628211
+)
+
+// Write adds b to the hash state
+func Write(state uint64, b []byte) uint64 {
+	for _, c := range b {
+		state ^= uint64(c)
+		state *= prime64
+	}
+
+	return state
+}
+
+// WriteString adds s to the hash state
+func WriteString(state uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		state ^= uint64(s[i])
+		state *= prime64
+	}
+
+	return state
+}
+
+// WriteUint64 adds the 8 little endian bytes of v to the hash state
+func WriteUint64(state, v uint64) uint64 {
+	for i := uint(0); i < 64; i += 8 {
+		state ^= (v >> i) & 0xff
+		state *= prime64
+	}
+
+	return state
+}
+
+// Init returns the hash state of a key, before any of its value is written
+func Init(key string) uint64 {
+	return WriteString(WriteUint64(offset64, uint64(len(key))), key)
+}
+
+// Mix finalizes a hash state into an entry hash, spreading every input bit over the output
+func Mix(state uint64) uint64 {
+	state ^= state >> 30
+	state *= 0xbf58476d1ce4e5b9
+	state ^= state >> 27
+	state *= 0x94d049bb133111eb
+	state ^= state >> 31
+
+	return state
+}
+
+// Bucket returns the leaf bucket of a key
+func Bucket(key string) int {
+	return int(Mix(Init(key)) & (Leaves - 1))
+}
+
+// Tree is a Merkle digest of the entries of a store
+type Tree struct {
+	// entries holds the hash state of every key, so a key can be updated without its old value
+	entries map[string]uint64
+	// levels[0] is the root, levels[Depth] the leaf buckets
+	levels [Depth + 1][]uint64
+}
+
+// New constructs an empty Tree
+func New() *Tree {
+	t := &Tree{entries: make(map[string]uint64)}
+	for d, n := 0, 1; d <= Depth; d, n = d+1, n*Fanout {
+		t.levels[d] = make([]uint64, n)
+	}
+
+	return t
+}
+
+// add adds h to the bucket of key and every node above it
+func (t *Tree) add(key string, h uint64) {
+	b := Bucket(key)
+	for d := Depth; d >= 0; d-- {
+		t.levels[d][b] += h
+		b >>= fanoutBits
+	}
+}
+
+// Set sets the hash state of key, replacing any previous state
+func (t *Tree) Set(key string, state uint64) {
+	if t == nil {
+		return
+	}
+
+	if old, ok := t.entries[key]; ok {
+		t.add(key, -Mix(old))
+	}
+
+	t.entries[key] = state
+	t.add(key, Mix(state))
+}
+
+// State returns the hash state of key
+func (t *Tree) State(key string) (uint64, bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	st, ok := t.entries[key]
+
+	return st, ok
+}
+
+// Delete removes key from the digest
+func (t *Tree) Delete(key string) {
+	if t == nil {
+		return
+	}
+
+	if old, ok := t.entries[key]; ok {
+		delete(t.entries, key)
+		t.add(key, -Mix(old))
+	}
+}
+
+// Clear removes every key from the digest
+func (t *Tree) Clear() {
+	if t == nil {
+		return
+	}
+
+	*t = *New()
+}
+
+// Len returns the number of keys in the digest
+func (t *Tree) Len() int {
+	if t == nil {
+		return 0
+	}
+
+	return len(t.entries)
+}
+
+// Root returns the digest of every entry
+func (t *Tree) Root() uint64 {
+	if t == nil {
+		return 0
+	}
+
+	return t.levels[0][0]
+}
+
+// Node returns the hash of node i of level d, where level 0 is the root and level Depth the leaf buckets
+func (t *Tree) Node(d, i int) uint64 {
+	return t.levels[d][i]
+}
+
+// Copy returns a copy of the node hashes, without the hash states of the keys
+// The copy only serves Root and Node, it is used to compare trees while the store keeps changing
+func (t *Tree) Copy() *Tree {
+	c := &Tree{}
+	for d := range t.levels {
+		c.levels[d] = append([]uint64(nil), t.levels[d]...)
+	}
+
+	return c
+}
+
+// BucketSet is a set of leaf buckets
+type BucketSet map[int]bool
+
+// NewBucketSet returns a set of the given buckets
+func NewBucketSet(buckets []int) BucketSet {
+	set := make(BucketSet, len(buckets))
+	for _, b := range buckets {
+		set[b] = true
+	}
+
+	return set
+}
+
+// Contains checks if key hashes into one of the buckets
+func (b BucketSet) Contains(key string) bool {
+	return b[Bucket(key)]
+}
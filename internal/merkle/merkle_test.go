@@ -0,0 +1,156 @@
+package merkle
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHash(t *testing.T) {
+	// FNV-1a test vectors
+	assert.Equal(t, uint64(0xaf63dc4c8601ec8c), Write(offset64, []byte("a")))
+	assert.Equal(t, uint64(0x85944171f73967e8), WriteString(offset64, "foobar"))
+	assert.Equal(t, Write(offset64, []byte("foobar")), WriteString(offset64, "foobar"))
+
+	assert.Equal(t, Write(offset64, []byte{1, 0, 0, 0, 0, 0, 0, 0}), WriteUint64(offset64, 1))
+
+	// streaming matches hashing at once
+	assert.Equal(t, WriteString(offset64, "foobar"), WriteString(WriteString(offset64, "foo"), "bar"))
+
+	// the key length separates key and value
+	assert.NotEqual(t, WriteString(Init("a"), "bc"), WriteString(Init("ab"), "c"))
+
+	assert.NotEqual(t, Mix(1), Mix(2))
+	assert.Equal(t, uint64(0), Mix(0))
+}
+
+func TestBucket(t *testing.T) {
+	counts := make([]int, Leaves)
+	for i := 0; i < 100*Leaves; i++ {
+		b := Bucket(strconv.Itoa(i))
+		assert.True(t, b >= 0 && b < Leaves)
+		counts[b]++
+	}
+
+	// keys spread over every bucket
+	for _, c := range counts {
+		assert.True(t, c > 50 && c < 150)
+	}
+
+	assert.Equal(t, Bucket("AAPL"), Bucket("AAPL"))
+}
+
+func TestNew(t *testing.T) {
+	tr := New()
+	assert.Len(t, tr.levels[0], 1)
+	assert.Len(t, tr.levels[1], Fanout)
+	assert.Len(t, tr.levels[2], Fanout*Fanout)
+	assert.Len(t, tr.levels[Depth], Leaves)
+	assert.Equal(t, uint64(0), tr.Root())
+	assert.Equal(t, 0, tr.Len())
+}
+
+// sumLevel returns the sum of the node hashes of a level
+func sumLevel(tr *Tree, d int) uint64 {
+	var sum uint64
+	for _, h := range tr.levels[d] {
+		sum += h
+	}
+
+	return sum
+}
+
+func TestTreeSetDelete(t *testing.T) {
+	tr := New()
+	tr.Set("a", WriteUint64(Init("a"), 1))
+	tr.Set("b", WriteUint64(Init("b"), 2))
+	assert.Equal(t, 2, tr.Len())
+
+	root := tr.Root()
+	assert.Equal(t, Mix(WriteUint64(Init("a"), 1))+Mix(WriteUint64(Init("b"), 2)), root)
+
+	// every level sums to the root
+	for d := 0; d <= Depth; d++ {
+		assert.Equal(t, root, sumLevel(tr, d))
+	}
+
+	b := Bucket("a")
+	assert.Equal(t, Mix(WriteUint64(Init("a"), 1)), tr.Node(Depth, b))
+	assert.Equal(t, tr.Node(Depth, b), tr.Node(Depth-1, b/Fanout))
+
+	st, ok := tr.State("a")
+	assert.True(t, ok)
+	assert.Equal(t, WriteUint64(Init("a"), 1), st)
+
+	// replacing a value replaces its hash
+	tr.Set("a", WriteUint64(Init("a"), 3))
+	assert.Equal(t, Mix(WriteUint64(Init("a"), 3))+Mix(WriteUint64(Init("b"), 2)), tr.Root())
+
+	tr.Delete("a")
+	tr.Delete("missing")
+	assert.Equal(t, Mix(WriteUint64(Init("b"), 2)), tr.Root())
+	_, ok = tr.State("a")
+	assert.False(t, ok)
+
+	tr.Delete("b")
+	assert.Equal(t, uint64(0), tr.Root())
+	for d := 0; d <= Depth; d++ {
+		assert.Equal(t, uint64(0), sumLevel(tr, d))
+	}
+}
+
+func TestTreeOrderIndependent(t *testing.T) {
+	a := New()
+	b := New()
+	for i := 0; i < 1000; i++ {
+		a.Set(strconv.Itoa(i), WriteUint64(Init(strconv.Itoa(i)), uint64(i)))
+		b.Set(strconv.Itoa(999-i), WriteUint64(Init(strconv.Itoa(999-i)), uint64(999-i)))
+	}
+
+	assert.Equal(t, a.Root(), b.Root())
+	assert.Equal(t, a.levels, b.levels)
+
+	b.Set("5", WriteUint64(Init("5"), 6))
+	assert.NotEqual(t, a.Root(), b.Root())
+}
+
+func TestTreeCopyAndClear(t *testing.T) {
+	tr := New()
+	tr.Set("a", 1)
+
+	c := tr.Copy()
+	assert.Equal(t, tr.Root(), c.Root())
+	tr.Set("b", 2)
+	assert.NotEqual(t, tr.Root(), c.Root())
+
+	tr.Clear()
+	assert.Equal(t, 0, tr.Len())
+	assert.Equal(t, uint64(0), tr.Root())
+	assert.Equal(t, sumLevel(New(), Depth), sumLevel(tr, Depth))
+}
+
+func TestTreeNil(t *testing.T) {
+	var tr *Tree
+	tr.Set("a", 1)
+	tr.Delete("a")
+	tr.Clear()
+	_, ok := tr.State("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, tr.Len())
+	assert.Equal(t, uint64(0), tr.Root())
+}
+
+func TestBucketSet(t *testing.T) {
+	set := NewBucketSet([]int{Bucket("a"), Bucket("b")})
+	assert.True(t, set.Contains("a"))
+	assert.True(t, set.Contains("b"))
+
+	n := 0
+	for i := 0; i < 1000; i++ {
+		if set.Contains(strconv.Itoa(i)) {
+			n++
+		}
+	}
+	assert.True(t, n < 10)
+}
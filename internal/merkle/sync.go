@@ -0,0 +1,156 @@
+package merkle
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+)
+
+// Replica is the store side of an anti-entropy sync
+// Each func locks the store itself, so the store is never locked while waiting on the connection
+type Replica struct {
+	// Tree returns a copy of the digest of the store
+	Tree func() *Tree
+	// Entries returns the encoded keys and values of the store in the given buckets
+	Entries func(buckets BucketSet) ([]byte, error)
+	// Apply makes the given buckets of the store hold exactly the encoded keys and values
+	// returns the number of keys set or deleted
+	Apply func(buckets BucketSet, entries []byte) (int, error)
+}
+
+// request is sent by the syncing replica
+type request struct {
+	// Level and Nodes select node hashes, or the entries of the leaf buckets in Nodes when Entries is set
+	Level   int
+	Nodes   []int
+	Entries bool
+	Done    bool
+}
+
+// response is sent by the serving replica
+type response struct {
+	Hashes  []uint64
+	Entries []byte
+	// Err is the error the serving replica hit answering the request
+	Err string
+}
+
+// Serve answers the requests of a replica calling Sync on the other end of rw, until it is done
+// The digest compared by the peer is taken when the first request arrives
+func Serve(rw io.ReadWriter, r Replica) error {
+	enc := gob.NewEncoder(rw)
+	dec := gob.NewDecoder(rw)
+
+	var t *Tree
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			return err
+		}
+
+		if req.Done {
+			return nil
+		}
+
+		if t == nil {
+			t = r.Tree()
+		}
+
+		var resp response
+		if req.Entries {
+			var err error
+			if resp.Entries, err = r.Entries(NewBucketSet(req.Nodes)); err != nil {
+				resp.Err = err.Error()
+			}
+		} else if req.Level < 0 || req.Level > Depth {
+			resp.Err = "invalid level"
+		} else {
+			resp.Hashes = make([]uint64, len(req.Nodes))
+			for i, n := range req.Nodes {
+				if n < 0 || n >= len(t.levels[req.Level]) {
+					resp.Err = "invalid node"
+					break
+				}
+
+				resp.Hashes[i] = t.levels[req.Level][n]
+			}
+		}
+
+		if err := enc.Encode(&resp); err != nil {
+			return err
+		}
+	}
+}
+
+// roundTrip sends a request and returns the response
+func roundTrip(enc *gob.Encoder, dec *gob.Decoder, req request) (response, error) {
+	var resp response
+	if err := enc.Encode(&req); err != nil {
+		return resp, err
+	}
+
+	if err := dec.Decode(&resp); err != nil {
+		return resp, err
+	}
+
+	if resp.Err != "" {
+		return resp, errors.New("merkle: serving replica: " + resp.Err)
+	}
+
+	return resp, nil
+}
+
+// Sync updates r to hold the contents of the replica served on the other end of rw
+// Only subtrees whose hashes differ are walked, and only the keys of differing buckets are transferred
+// returns the number of keys set or deleted in r
+func Sync(rw io.ReadWriter, r Replica) (int, error) {
+	enc := gob.NewEncoder(rw)
+	dec := gob.NewDecoder(rw)
+
+	t := r.Tree()
+	nodes := []int{0}
+	for d := 0; d <= Depth && len(nodes) > 0; d++ {
+		resp, err := roundTrip(enc, dec, request{Level: d, Nodes: nodes})
+		if err != nil {
+			return 0, err
+		}
+
+		if len(resp.Hashes) != len(nodes) {
+			return 0, errors.New("merkle: serving replica: wrong number of hashes")
+		}
+
+		var differ []int
+		for i, n := range nodes {
+			if resp.Hashes[i] != t.levels[d][n] {
+				differ = append(differ, n)
+			}
+		}
+
+		if d == Depth {
+			nodes = differ
+			break
+		}
+
+		// walk the children of differing nodes
+		nodes = nodes[:0]
+		for _, n := range differ {
+			for c := 0; c < Fanout; c++ {
+				nodes = append(nodes, n*Fanout+c)
+			}
+		}
+	}
+
+	changed := 0
+	if len(nodes) > 0 {
+		resp, err := roundTrip(enc, dec, request{Nodes: nodes, Entries: true})
+		if err != nil {
+			return 0, err
+		}
+
+		if changed, err = r.Apply(NewBucketSet(nodes), resp.Entries); err != nil {
+			return changed, err
+		}
+	}
+
+	return changed, enc.Encode(&request{Done: true})
+}
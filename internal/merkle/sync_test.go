@@ -0,0 +1,182 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockReplica is a replica of a map of uint64 values
+type mockReplica struct {
+	values  map[string]uint64
+	fetched int // number of entries sent
+}
+
+func newMockReplica(n int) *mockReplica {
+	r := &mockReplica{values: make(map[string]uint64)}
+	for i := 0; i < n; i++ {
+		r.values[strconv.Itoa(i)] = uint64(i)
+	}
+
+	return r
+}
+
+func (r *mockReplica) tree() *Tree {
+	t := New()
+	for k, v := range r.values {
+		t.Set(k, WriteUint64(Init(k), v))
+	}
+
+	return t
+}
+
+func (r *mockReplica) replica() Replica {
+	return Replica{
+		Tree: r.tree,
+		Entries: func(buckets BucketSet) ([]byte, error) {
+			entries := make(map[string]uint64)
+			for k, v := range r.values {
+				if buckets.Contains(k) {
+					entries[k] = v
+				}
+			}
+			r.fetched += len(entries)
+
+			var buf bytes.Buffer
+			err := gob.NewEncoder(&buf).Encode(entries)
+
+			return buf.Bytes(), err
+		},
+		Apply: func(buckets BucketSet, data []byte) (int, error) {
+			var entries map[string]uint64
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			n := 0
+			for k := range r.values {
+				if buckets.Contains(k) {
+					if _, ok := entries[k]; !ok {
+						delete(r.values, k)
+						n++
+					}
+				}
+			}
+
+			for k, v := range entries {
+				if cur, ok := r.values[k]; !ok || cur != v {
+					r.values[k] = v
+					n++
+				}
+			}
+
+			return n, nil
+		},
+	}
+}
+
+// syncPair syncs dst from src over an in-memory connection
+func syncPair(dst, src Replica) (int, error, error) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	served := make(chan error)
+	go func() { served <- Serve(b, src) }()
+
+	n, err := Sync(a, dst)
+
+	return n, err, <-served
+}
+
+func TestSync(t *testing.T) {
+	src := newMockReplica(10000)
+	dst := newMockReplica(10000)
+
+	// update, add and delete a few keys
+	dst.values["5"] = 50
+	dst.values["extra"] = 1
+	delete(dst.values, "777")
+
+	n, err, serveErr := syncPair(dst.replica(), src.replica())
+	assert.Nil(t, err)
+	assert.Nil(t, serveErr)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, src.values, dst.values)
+
+	// only the keys of differing buckets were sent
+	assert.True(t, src.fetched < 20)
+}
+
+func TestSyncIdentical(t *testing.T) {
+	src := newMockReplica(100)
+	dst := newMockReplica(100)
+
+	n, err, serveErr := syncPair(dst.replica(), src.replica())
+	assert.Nil(t, err)
+	assert.Nil(t, serveErr)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 0, src.fetched)
+}
+
+func TestSyncEmpty(t *testing.T) {
+	// empty to full
+	src := newMockReplica(500)
+	dst := newMockReplica(0)
+	n, err, _ := syncPair(dst.replica(), src.replica())
+	assert.Nil(t, err)
+	assert.Equal(t, 500, n)
+	assert.Equal(t, src.values, dst.values)
+
+	// full to empty
+	src = newMockReplica(0)
+	n, err, _ = syncPair(dst.replica(), src.replica())
+	assert.Nil(t, err)
+	assert.Equal(t, 500, n)
+	assert.Len(t, dst.values, 0)
+}
+
+func TestSyncServeError(t *testing.T) {
+	src := newMockReplica(10)
+	dst := newMockReplica(5)
+
+	r := src.replica()
+	r.Entries = func(buckets BucketSet) ([]byte, error) {
+		return nil, errors.New("boom")
+	}
+
+	a, b := net.Pipe()
+	go Serve(b, r)
+
+	_, err := Sync(a, dst.replica())
+	assert.Equal(t, "merkle: serving replica: boom", err.Error())
+	assert.Len(t, dst.values, 5)
+
+	a.Close()
+	b.Close()
+}
+
+func TestServeInvalidRequest(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	go Serve(b, newMockReplica(1).replica())
+
+	enc := gob.NewEncoder(a)
+	dec := gob.NewDecoder(a)
+
+	_, err := roundTrip(enc, dec, request{Level: Depth + 1, Nodes: []int{0}})
+	assert.Equal(t, "merkle: serving replica: invalid level", err.Error())
+
+	_, err = roundTrip(enc, dec, request{Level: 1, Nodes: []int{Fanout}})
+	assert.Equal(t, "merkle: serving replica: invalid node", err.Error())
+
+	resp, err := roundTrip(enc, dec, request{Level: 0, Nodes: []int{0}})
+	assert.Nil(t, err)
+	assert.Equal(t, []uint64{newMockReplica(1).tree().Root()}, resp.Hashes)
+}
@@ -0,0 +1,312 @@
+// Package skiplist provides an indexable skip list of unique members ordered by score then member
+//
+// Scores are float64s, or uint64 order keys for callers ordering values float64 cannot hold exactly,
+// such as int64s beyond 2^53, see InsertKey.
+// Insert, Delete, Rank and rank lookups are all O(log n).
+// List is NOT safe for concurrent use, callers provide locking.
+package skiplist
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	maxLevel = 32
+	// one in four nodes is promoted to the next level
+	promoteMask = 3
+)
+
+type level struct {
+	forward *Node
+	span    int // number of level 0 nodes skipped by forward
+}
+
+// Node is an element of a List
+type Node struct {
+	key      uint64 // order key of the score
+	score    float64
+	member   string
+	backward *Node
+	levels   []level
+}
+
+// Score returns the score of the node, 0 for a node inserted with InsertKey
+func (n *Node) Score() float64 {
+	return n.score
+}
+
+// Key returns the order key of the node
+func (n *Node) Key() uint64 {
+	return n.key
+}
+
+// Member returns the member of the node
+func (n *Node) Member() string {
+	return n.member
+}
+
+// Next returns the following node in order, nil at the end of the list
+func (n *Node) Next() *Node {
+	return n.levels[0].forward
+}
+
+// Prev returns the preceding node in order, nil at the start of the list
+func (n *Node) Prev() *Node {
+	return n.backward
+}
+
+// before checks if the node orders before (key, member)
+func (n *Node) before(key uint64, member string) bool {
+	return n.key < key || (n.key == key && n.member < member)
+}
+
+// after checks if the node orders after (key, member)
+func (n *Node) after(key uint64, member string) bool {
+	return n.key > key || (n.key == key && n.member > member)
+}
+
+// FloatKey returns the order key of a score, keys order as their scores do
+// -0 has the key of 0 and every NaN has a single key ordered after +Inf
+func FloatKey(score float64) uint64 {
+	if math.IsNaN(score) {
+		return math.MaxUint64
+	}
+	if score == 0 {
+		score = 0
+	}
+
+	b := math.Float64bits(score)
+	if b>>63 == 1 {
+		return ^b
+	}
+
+	return b | 1<<63
+}
+
+// List is a skip list ordered by order key, then member for equal keys
+// A list is either used with float64 scores or with order keys, not both
+type List struct {
+	head   *Node
+	tail   *Node
+	level  int
+	length int
+	rnd    *rand.Rand
+}
+
+// New constructs an empty List
+func New() *List {
+	return &List{
+		head:  &Node{levels: make([]level, maxLevel)},
+		level: 1,
+		rnd:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (l *List) randomLevel() int {
+	lvl := 1
+	for lvl < maxLevel && l.rnd.Int63()&promoteMask == 0 {
+		lvl++
+	}
+
+	return lvl
+}
+
+// Len returns the number of nodes in the list
+func (l *List) Len() int {
+	return l.length
+}
+
+// First returns the lowest node, nil if the list is empty
+func (l *List) First() *Node {
+	return l.head.levels[0].forward
+}
+
+// Last returns the highest node, nil if the list is empty
+func (l *List) Last() *Node {
+	return l.tail
+}
+
+// Insert adds member with score to the list and returns its node
+// The member must not already be in the list, see Delete
+func (l *List) Insert(score float64, member string) *Node {
+	return l.insert(FloatKey(score), score, member)
+}
+
+// InsertKey adds member with an order key to the list and returns its node
+// The member must not already be in the list, see DeleteKey
+func (l *List) InsertKey(key uint64, member string) *Node {
+	return l.insert(key, 0, member)
+}
+
+func (l *List) insert(key uint64, score float64, member string) *Node {
+	var update [maxLevel]*Node
+	var rank [maxLevel]int
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		if i < l.level-1 {
+			rank[i] = rank[i+1]
+		}
+		for x.levels[i].forward != nil && x.levels[i].forward.before(key, member) {
+			rank[i] += x.levels[i].span
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	lvl := l.randomLevel()
+	if lvl > l.level {
+		for i := l.level; i < lvl; i++ {
+			rank[i] = 0
+			update[i] = l.head
+			update[i].levels[i].span = l.length
+		}
+		l.level = lvl
+	}
+
+	x = &Node{key: key, score: score, member: member, levels: make([]level, lvl)}
+	for i := 0; i < lvl; i++ {
+		x.levels[i].forward = update[i].levels[i].forward
+		update[i].levels[i].forward = x
+
+		x.levels[i].span = update[i].levels[i].span - (rank[0] - rank[i])
+		update[i].levels[i].span = rank[0] - rank[i] + 1
+	}
+
+	// untouched levels now skip one more node
+	for i := lvl; i < l.level; i++ {
+		update[i].levels[i].span++
+	}
+
+	if update[0] != l.head {
+		x.backward = update[0]
+	}
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x
+	} else {
+		l.tail = x
+	}
+	l.length++
+
+	return x
+}
+
+// Delete removes member with score from the list
+// returns true if it was found
+func (l *List) Delete(score float64, member string) bool {
+	return l.DeleteKey(FloatKey(score), member)
+}
+
+// DeleteKey removes member with an order key from the list
+// returns true if it was found
+func (l *List) DeleteKey(key uint64, member string) bool {
+	var update [maxLevel]*Node
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.before(key, member) {
+			x = x.levels[i].forward
+		}
+		update[i] = x
+	}
+
+	x = x.levels[0].forward
+	if x == nil || x.key != key || x.member != member {
+		return false
+	}
+
+	for i := 0; i < l.level; i++ {
+		if update[i].levels[i].forward == x {
+			update[i].levels[i].span += x.levels[i].span - 1
+			update[i].levels[i].forward = x.levels[i].forward
+		} else {
+			update[i].levels[i].span--
+		}
+	}
+
+	if x.levels[0].forward != nil {
+		x.levels[0].forward.backward = x.backward
+	} else {
+		l.tail = x.backward
+	}
+
+	for l.level > 1 && l.head.levels[l.level-1].forward == nil {
+		l.level--
+	}
+	l.length--
+
+	return true
+}
+
+// Rank returns the zero based position of member with score in the list, -1 if not found
+func (l *List) Rank(score float64, member string) int {
+	key := FloatKey(score)
+	rank := 0
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && !x.levels[i].forward.after(key, member) {
+			rank += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if x != l.head && x.key == key && x.member == member {
+			return rank - 1
+		}
+	}
+
+	return -1
+}
+
+// ByRank returns the node at the zero based rank, nil if out of bounds
+func (l *List) ByRank(rank int) *Node {
+	if rank < 0 || rank >= l.length {
+		return nil
+	}
+
+	traversed := 0
+	target := rank + 1
+
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && traversed+x.levels[i].span <= target {
+			traversed += x.levels[i].span
+			x = x.levels[i].forward
+		}
+
+		if traversed == target {
+			return x
+		}
+	}
+
+	return nil
+}
+
+// Seek returns the first node ordered at or after (score, member), nil if there is none
+func (l *List) Seek(score float64, member string) *Node {
+	return l.seek(FloatKey(score), member)
+}
+
+func (l *List) seek(key uint64, member string) *Node {
+	x := l.head
+	for i := l.level - 1; i >= 0; i-- {
+		for x.levels[i].forward != nil && x.levels[i].forward.before(key, member) {
+			x = x.levels[i].forward
+		}
+	}
+
+	return x.levels[0].forward
+}
+
+// SeekScore returns the first node with a score at or above min, nil if there is none
+func (l *List) SeekScore(min float64) *Node {
+	// the empty member orders first among equal scores
+	return l.Seek(min, "")
+}
+
+// SeekKey returns the first node with an order key at or above min, nil if there is none
+func (l *List) SeekKey(min uint64) *Node {
+	return l.seek(min, "")
+}
@@ -0,0 +1,178 @@
+package skiplist
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	score  float64
+	member string
+}
+
+func sortedEntries(entries map[string]float64) []entry {
+	out := make([]entry, 0, len(entries))
+	for m, s := range entries {
+		out = append(out, entry{s, m})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].score < out[j].score || (out[i].score == out[j].score && out[i].member < out[j].member)
+	})
+
+	return out
+}
+
+// checkList asserts the list holds exactly the expected entries in order, in both directions and by rank
+func checkList(t *testing.T, l *List, entries map[string]float64) {
+	exp := sortedEntries(entries)
+	assert.Equal(t, len(exp), l.Len())
+
+	i := 0
+	for n := l.First(); n != nil; n = n.Next() {
+		assert.Equal(t, exp[i], entry{n.Score(), n.Member()})
+		assert.Equal(t, i, l.Rank(n.Score(), n.Member()))
+		assert.Equal(t, n, l.ByRank(i))
+		i++
+	}
+	assert.Equal(t, len(exp), i)
+
+	for n := l.Last(); n != nil; n = n.Prev() {
+		i--
+		assert.Equal(t, exp[i], entry{n.Score(), n.Member()})
+	}
+	assert.Equal(t, 0, i)
+}
+
+func TestEmpty(t *testing.T) {
+	l := New()
+
+	assert.Equal(t, 0, l.Len())
+	assert.Nil(t, l.First())
+	assert.Nil(t, l.Last())
+	assert.Nil(t, l.ByRank(0))
+	assert.Nil(t, l.Seek(0, ""))
+	assert.Equal(t, -1, l.Rank(1.0, "a"))
+	assert.False(t, l.Delete(1.0, "a"))
+}
+
+func TestInsertOrder(t *testing.T) {
+	l := New()
+
+	l.Insert(2.0, "b")
+	l.Insert(1.0, "z")
+	l.Insert(2.0, "a")
+	l.Insert(-1.0, "c")
+
+	checkList(t, l, map[string]float64{"b": 2.0, "z": 1.0, "a": 2.0, "c": -1.0})
+	assert.Equal(t, "c", l.First().Member())
+	assert.Equal(t, "b", l.Last().Member())
+}
+
+func TestDelete(t *testing.T) {
+	l := New()
+	l.Insert(1.0, "a")
+	l.Insert(2.0, "b")
+	l.Insert(3.0, "c")
+
+	// wrong score
+	assert.False(t, l.Delete(1.5, "a"))
+
+	assert.True(t, l.Delete(2.0, "b"))
+	checkList(t, l, map[string]float64{"a": 1.0, "c": 3.0})
+
+	assert.True(t, l.Delete(3.0, "c"))
+	assert.Equal(t, "a", l.Last().Member())
+
+	assert.True(t, l.Delete(1.0, "a"))
+	checkList(t, l, map[string]float64{})
+}
+
+func TestSeek(t *testing.T) {
+	l := New()
+	l.Insert(1.0, "a")
+	l.Insert(2.0, "b")
+	l.Insert(2.0, "c")
+	l.Insert(4.0, "d")
+
+	assert.Equal(t, "b", l.SeekScore(2.0).Member())
+	assert.Equal(t, "d", l.SeekScore(3.0).Member())
+	assert.Equal(t, "a", l.SeekScore(-10.0).Member())
+	assert.Nil(t, l.SeekScore(5.0))
+
+	assert.Equal(t, "c", l.Seek(2.0, "bb").Member())
+}
+
+func TestFloatKey(t *testing.T) {
+	scores := []float64{math.Inf(-1), -math.MaxFloat64, -1.5, -math.SmallestNonzeroFloat64, 0,
+		math.SmallestNonzeroFloat64, 1, 1.5, math.MaxFloat64, math.Inf(1)}
+	for i := 1; i < len(scores); i++ {
+		assert.True(t, FloatKey(scores[i-1]) < FloatKey(scores[i]))
+	}
+
+	assert.Equal(t, FloatKey(0), FloatKey(math.Copysign(0, -1)))
+	assert.True(t, FloatKey(math.NaN()) > FloatKey(math.Inf(1)))
+}
+
+func TestKeys(t *testing.T) {
+	l := New()
+
+	// keys order beyond the exact integers of a float64
+	l.InsertKey(1<<53+1, "b")
+	l.InsertKey(1<<53, "a")
+	l.InsertKey(math.MaxUint64, "c")
+	l.InsertKey(1<<53, "aa")
+
+	members := make([]string, 0)
+	for n := l.First(); n != nil; n = n.Next() {
+		members = append(members, n.Member())
+	}
+	assert.Equal(t, []string{"a", "aa", "b", "c"}, members)
+	assert.Equal(t, uint64(1<<53+1), l.ByRank(2).Key())
+	assert.Equal(t, 0.0, l.ByRank(2).Score())
+
+	assert.Equal(t, "b", l.SeekKey(1<<53+1).Member())
+	assert.Equal(t, "c", l.SeekKey(1<<53+2).Member())
+
+	assert.False(t, l.DeleteKey(1<<53, "b"))
+	assert.True(t, l.DeleteKey(1<<53+1, "b"))
+	assert.Equal(t, 3, l.Len())
+	assert.Equal(t, "c", l.SeekKey(1<<53+1).Member())
+}
+
+func TestNegativeZero(t *testing.T) {
+	l := New()
+	l.Insert(math.Copysign(0, -1), "a")
+	l.Insert(0, "b")
+
+	// -0 and 0 are equal scores
+	assert.Equal(t, "a", l.SeekScore(0).Member())
+	assert.Equal(t, 0, l.Rank(0, "a"))
+	assert.True(t, l.Delete(0, "a"))
+	assert.Equal(t, 1, l.Len())
+}
+
+func TestRandomized(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	l := New()
+	entries := make(map[string]float64)
+
+	for i := 0; i < 2000; i++ {
+		m := strconv.Itoa(r.Intn(300))
+		if s, ok := entries[m]; ok {
+			assert.True(t, l.Delete(s, m))
+			delete(entries, m)
+			continue
+		}
+
+		s := float64(r.Intn(50))
+		l.Insert(s, m)
+		entries[m] = s
+	}
+
+	checkList(t, l, entries)
+}
@@ -1,7 +1,15 @@
 package primitivestore
 
 import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
 )
 
 // BoolStore is a store of booleans
@@ -9,7 +17,10 @@ import (
 // Embedded sync.Mutex to provide atomic operation ability
 type BoolStore struct {
 	sync.Mutex
-	store map[string]bool
+	store  map[string]bool
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	values *valueIndex     // ordered values, nil unless EnableValueIndex is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
 }
 
 // NewBoolStore constructs and initializes a new BoolStore
@@ -19,7 +30,18 @@ func NewBoolStore() *BoolStore {
 }
 
 func (s *BoolStore) set(key string, value bool) {
+	if s.values != nil {
+		if old, ok := s.store[key]; ok {
+			s.values.delete(boolKey(old), key)
+		}
+		s.values.insert(boolKey(value), key)
+	}
+
 	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashBool(key, s.store[key]))
+	}
 }
 
 // Set stores the given value mapped to the given key
@@ -29,6 +51,25 @@ func (s *BoolStore) Set(key string, value bool) {
 	s.Unlock()
 }
 
+func (s *BoolStore) delete(key string) {
+	v, ok := s.store[key]
+	if !ok {
+		return
+	}
+
+	delete(s.store, key)
+	s.index.Delete(key)
+	s.digest.Delete(key)
+	s.values.delete(boolKey(v), key)
+}
+
+// Delete removes the given key and its value from the store
+func (s *BoolStore) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
 func (s *BoolStore) get(key string) (bool, bool) {
 	// explictly return second return value
 	v, ok := s.store[key]
@@ -45,6 +86,360 @@ func (s *BoolStore) Get(key string) (bool, bool) {
 	return v, ok
 }
 
+func (s *BoolStore) enableValueIndex() {
+	if s.values != nil {
+		return
+	}
+
+	s.values = newValueIndex()
+	for k, v := range s.store {
+		s.values.insert(boolKey(v), k)
+	}
+}
+
+// EnableValueIndex maintains an index of the keys ordered by value alongside the store,
+// so value queries no longer scan and sort every value
+// Setting and deleting keys costs an extra O(log n) once enabled
+func (s *BoolStore) EnableValueIndex() {
+	s.Lock()
+	s.enableValueIndex()
+	s.Unlock()
+}
+
+func (s *BoolStore) keysWhere(op Op, value bool) []string {
+	if s.values != nil {
+		return s.values.where(op, boolKey(value))
+	}
+
+	ks := make([]scoredKey, 0)
+	for k, v := range s.store {
+		if op.holds(boolKey(v), boolKey(value)) {
+			ks = append(ks, scoredKey{boolKey(v), k})
+		}
+	}
+
+	return sortedKeys(ks)
+}
+
+// KeysWhere returns the keys whose value holds op against the given value, in ascending value order
+// e.g. KeysWhere(Gt, x) returns every key with a value greater than x
+func (s *BoolStore) KeysWhere(op Op, value bool) []string {
+	s.Lock()
+	v := s.keysWhere(op, value)
+	s.Unlock()
+
+	return v
+}
+
+func (s *BoolStore) count(pred func(key string, value bool) bool) int {
+	n := 0
+	for k, v := range s.store {
+		if pred(k, v) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Count returns the number of keys whose key and value satisfy pred
+// pred is called under the store lock, so it must not call back into the store
+func (s *BoolStore) Count(pred func(key string, value bool) bool) int {
+	s.Lock()
+	n := s.count(pred)
+	s.Unlock()
+
+	return n
+}
+
+func (s *BoolStore) reduce(init bool, fn func(acc bool, key string, value bool) bool) bool {
+	acc := init
+	for k, v := range s.store {
+		acc = fn(acc, k, v)
+	}
+
+	return acc
+}
+
+// Reduce folds every key and value of the store into init with fn, in no particular order
+// The accumulator has the value type of the store, to fold into any other type update a variable captured by fn
+// fn is called under the store lock, so it must not call back into the store
+func (s *BoolStore) Reduce(init bool, fn func(acc bool, key string, value bool) bool) bool {
+	s.Lock()
+	v := s.reduce(init, fn)
+	s.Unlock()
+
+	return v
+}
+
+func (s *BoolStore) snapshot() map[string]bool {
+	m := make(map[string]bool, len(s.store))
+	for k, v := range s.store {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (s *BoolStore) clone() *BoolStore {
+	c := &BoolStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.values != nil {
+		c.enableValueIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *BoolStore) Clone() *BoolStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *BoolStore) mergeFrom(values map[string]bool, conflictFn func(key string, current, incoming bool) bool) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *BoolStore) MergeFrom(other *BoolStore, conflictFn func(key string, current, incoming bool) bool) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *BoolStore) diff(values map[string]bool) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if v != ov {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *BoolStore) Diff(other *BoolStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *BoolStore) replaceAll(store map[string]bool) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.values != nil {
+		s.values = nil
+		s.enableValueIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *BoolStore) ReplaceAll(values map[string]bool) {
+	store := make(map[string]bool, len(values))
+	for k, v := range values {
+		store[k] = v
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+func hashBool(key string, v bool) uint64 {
+	h := merkle.Init(key)
+	if v {
+		return merkle.WriteUint64(h, 1)
+	}
+
+	return merkle.WriteUint64(h, 0)
+}
+
+func (s *BoolStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashBool(k, v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled
+func (s *BoolStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *BoolStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashBool(k, v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *BoolStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *BoolStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashBool(k, v))
+	}
+
+	return t
+}
+
+func (s *BoolStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string]bool)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *BoolStore) applyEntries(buckets merkle.BucketSet, entries map[string]bool) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			s.delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashBool(k, cur) != hashBool(k, v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *BoolStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string]bool
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *BoolStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *BoolStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
 func (s *BoolStore) size() int {
 	return len(s.store)
 }
@@ -80,6 +475,118 @@ func (s *BoolStore) Members() []string {
 	return mems
 }
 
+func (s *BoolStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *BoolStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *BoolStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *BoolStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *BoolStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *BoolStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *BoolStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *BoolStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *BoolStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *BoolStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
 func (s *BoolStore) isMember(key string) bool {
 	_, ok := s.store[key]
 
@@ -97,6 +604,9 @@ func (s *BoolStore) IsMember(key string) bool {
 
 func (s *BoolStore) clear() {
 	s.store = make(map[string]bool)
+	s.index.Clear()
+	s.digest.Clear()
+	s.values.clear()
 }
 
 // Clear deletes all keys in the store
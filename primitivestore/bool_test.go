@@ -1,6 +1,8 @@
 package primitivestore
 
 import (
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,6 +30,199 @@ func TestBoolGet(t *testing.T) {
 	assert.True(t, v)
 }
 
+func TestBoolDelete(t *testing.T) {
+	s := NewBoolStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = true
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", true)
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func TestBoolKeysWhere(t *testing.T) {
+	s := NewBoolStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysWhere(Eq, true))
+
+	s.store["a"] = true
+	s.store["b"] = false
+	s.store["c"] = true
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"a", "c"}, s.KeysWhere(Eq, true))
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Ne, true))
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Eq, false))
+	}
+}
+
+func TestBoolValueIndex(t *testing.T) {
+	s := NewBoolStore()
+	s.store["a"] = true
+
+	// existing values are indexed
+	s.EnableValueIndex()
+	assert.Equal(t, 1, s.values.len())
+
+	// set and overwrite
+	s.Set("b", true)
+	s.Set("a", false)
+	assert.Equal(t, []string{"b"}, s.KeysWhere(Eq, true))
+
+	// delete
+	s.Delete("b")
+	assert.Equal(t, []string{}, s.KeysWhere(Eq, true))
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.values.len())
+}
+
+func TestBoolCount(t *testing.T) {
+	s := NewBoolStore()
+
+	// no keys
+	assert.Equal(t, 0, s.Count(func(key string, value bool) bool { return value }))
+
+	s.store["a"] = true
+	s.store["b"] = false
+	s.store["c"] = true
+	assert.Equal(t, 2, s.Count(func(key string, value bool) bool { return value }))
+	assert.Equal(t, 1, s.Count(func(key string, value bool) bool { return !value }))
+}
+
+func TestBoolReduce(t *testing.T) {
+	s := NewBoolStore()
+
+	all := func(acc bool, key string, value bool) bool { return acc && value }
+
+	// no keys
+	assert.True(t, s.Reduce(true, all))
+
+	s.store["a"] = true
+	s.store["b"] = true
+	assert.True(t, s.Reduce(true, all))
+
+	s.store["c"] = false
+	assert.False(t, s.Reduce(true, all))
+}
+
+func TestBoolClone(t *testing.T) {
+	s := NewBoolStore()
+	s.store["a"] = true
+	s.store["b"] = false
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+	assert.Equal(t, 2, c.values.len())
+
+	// clone is independent of the store
+	c.Set("c", true)
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestBoolMergeFrom(t *testing.T) {
+	o := NewBoolStore()
+	o.store["b"] = false
+	o.store["c"] = false
+
+	// incoming values win without a conflict func
+	s := NewBoolStore()
+	s.store["a"] = true
+	s.store["b"] = true
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]bool{"a": true, "b": false, "c": false}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewBoolStore()
+	s.store["a"] = true
+	s.store["b"] = true
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming bool) bool {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, true, current)
+		assert.Equal(t, false, incoming)
+		return current
+	})
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": false}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]bool{"b": false, "c": false}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestBoolDiff(t *testing.T) {
+	s := NewBoolStore()
+	s.store["a"] = true
+	s.store["b"] = true
+	s.store["c"] = true
+
+	o := NewBoolStore()
+	o.store["b"] = true
+	o.store["c"] = false
+	o.store["d"] = false
+	o.store["e"] = false
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewBoolStore().Diff(NewBoolStore()).Empty())
+}
+
+func TestBoolReplaceAll(t *testing.T) {
+	s := NewBoolStore()
+	s.store["a"] = true
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	values := map[string]bool{"b": false, "c": true}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+	assert.Equal(t, 2, s.values.len())
+
+	// store holds a copy of the values
+	values["d"] = true
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestBoolSize(t *testing.T) {
 	s := NewBoolStore()
 
@@ -58,6 +253,103 @@ func TestBoolMembers(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestBoolSortedMembers(t *testing.T) {
+	s := NewBoolStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = true
+	s.store["AAPL:bid"] = true
+	s.store["AAPL:ask"] = true
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestBoolMembersWithPrefix(t *testing.T) {
+	s := NewBoolStore()
+
+	s.store["MSFT:bid"] = true
+	s.store["AAPL:bid"] = true
+	s.store["AAPL:ask"] = true
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestBoolMembersMatching(t *testing.T) {
+	s := NewBoolStore()
+
+	s.store["MSFT:bid"] = true
+	s.store["AAPL:bid"] = true
+	s.store["AAPL:ask"] = true
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestBoolKeyIndex(t *testing.T) {
+	s := NewBoolStore()
+	s.store["foo"] = true
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", true)
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestBoolScan(t *testing.T) {
+	s := NewBoolStore()
+
+	s.store["MSFT:bid"] = true
+	s.store["AAPL:bid"] = true
+	s.store["AAPL:ask"] = true
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestBoolIsMember(t *testing.T) {
 	s := NewBoolStore()
 
@@ -82,6 +374,85 @@ func TestBoolClear(t *testing.T) {
 	assert.Equal(t, 0, len(s.store))
 }
 
+func TestBoolDigest(t *testing.T) {
+	s := NewBoolStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", true)
+	s.Set("b", false)
+
+	// write order does not matter
+	o := NewBoolStore()
+	o.Set("b", false)
+	o.Set("a", true)
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", true)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", false)
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestBoolEnableDigest(t *testing.T) {
+	s := NewBoolStore()
+	s.Set("a", true)
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", false)
+	s.Delete("a")
+	o := NewBoolStore()
+	o.Set("b", false)
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]bool{"a": true})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestBoolSyncFrom(t *testing.T) {
+	s := NewBoolStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), true)
+	}
+	s.Set("b", false)
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", true)
+	r.Delete("1")
+	r.Set("c", false)
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestBoolConcurrentGetAndSet(t *testing.T) {
 	s := NewBoolStore()
 
@@ -0,0 +1,515 @@
+package primitivestore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
+)
+
+// BytesStore is a store of byte slices
+// Values are copied on set and get, so callers can never mutate a stored buffer
+// Implements the PrimitiveStore interface
+// Embedded sync.Mutex to provide atomic operation ability
+type BytesStore struct {
+	sync.Mutex
+	store  map[string][]byte
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
+}
+
+// NewBytesStore constructs and initializes a new BytesStore
+// Always use this function when creating a new BytesStore
+func NewBytesStore() *BytesStore {
+	return &BytesStore{store: make(map[string][]byte)}
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	c := make([]byte, len(b))
+	copy(c, b)
+
+	return c
+}
+
+func (s *BytesStore) set(key string, value []byte) {
+	s.store[key] = copyBytes(value)
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashBytes(key, s.store[key]))
+	}
+}
+
+// Set stores the given value mapped to the given key
+func (s *BytesStore) Set(key string, value []byte) {
+	s.Lock()
+	s.set(key, value)
+	s.Unlock()
+}
+
+func (s *BytesStore) delete(key string) {
+	delete(s.store, key)
+	s.index.Delete(key)
+	s.digest.Delete(key)
+}
+
+// Delete removes the given key and its value from the store
+func (s *BytesStore) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
+func (s *BytesStore) get(key string) ([]byte, bool) {
+	// explictly return second return value
+	v, ok := s.store[key]
+
+	return copyBytes(v), ok
+}
+
+// Get returns the value for the given key
+func (s *BytesStore) Get(key string) ([]byte, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *BytesStore) snapshot() map[string][]byte {
+	m := make(map[string][]byte, len(s.store))
+	for k, v := range s.store {
+		m[k] = copyBytes(v)
+	}
+
+	return m
+}
+
+func (s *BytesStore) clone() *BytesStore {
+	c := &BytesStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *BytesStore) Clone() *BytesStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *BytesStore) mergeFrom(values map[string][]byte, conflictFn func(key string, current, incoming []byte) []byte) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			// conflictFn may keep or mutate its arguments, set copies the value it returns
+			v = conflictFn(k, copyBytes(cur), v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *BytesStore) MergeFrom(other *BytesStore, conflictFn func(key string, current, incoming []byte) []byte) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *BytesStore) diff(values map[string][]byte) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !bytes.Equal(v, ov) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *BytesStore) Diff(other *BytesStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *BytesStore) replaceAll(store map[string][]byte) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *BytesStore) ReplaceAll(values map[string][]byte) {
+	store := make(map[string][]byte, len(values))
+	for k, v := range values {
+		store[k] = copyBytes(v)
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+func hashBytes(key string, v []byte) uint64 {
+	return merkle.Write(merkle.Init(key), v)
+}
+
+func (s *BytesStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashBytes(k, v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled
+func (s *BytesStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *BytesStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashBytes(k, v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *BytesStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *BytesStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashBytes(k, v))
+	}
+
+	return t
+}
+
+func (s *BytesStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string][]byte)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *BytesStore) applyEntries(buckets merkle.BucketSet, entries map[string][]byte) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			s.delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashBytes(k, cur) != hashBytes(k, v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *BytesStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string][]byte
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *BytesStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *BytesStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
+func (s *BytesStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *BytesStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *BytesStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *BytesStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+func (s *BytesStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *BytesStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *BytesStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *BytesStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *BytesStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *BytesStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *BytesStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *BytesStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *BytesStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *BytesStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *BytesStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *BytesStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *BytesStore) clear() {
+	s.store = make(map[string][]byte)
+	s.index.Clear()
+	s.digest.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *BytesStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
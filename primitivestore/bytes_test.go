@@ -0,0 +1,453 @@
+package primitivestore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytesSet(t *testing.T) {
+	s := NewBytesStore()
+
+	s.Set("foo", []byte("bar"))
+	assert.Equal(t, []byte("bar"), s.store["foo"])
+}
+
+func TestBytesGet(t *testing.T) {
+	s := NewBytesStore()
+
+	// no key yet
+	v, ok := s.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	s.store["foo"] = []byte("bar")
+	v, ok = s.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("bar"), v)
+}
+
+func TestBytesCopy(t *testing.T) {
+	s := NewBytesStore()
+
+	// mutating the set buffer does not change the store
+	b := []byte("bar")
+	s.Set("foo", b)
+	b[0] = 'x'
+	assert.Equal(t, []byte("bar"), s.store["foo"])
+
+	// mutating the got buffer does not change the store
+	v, _ := s.Get("foo")
+	v[0] = 'y'
+	assert.Equal(t, []byte("bar"), s.store["foo"])
+
+	// nil stays nil
+	s.Set("nil", nil)
+	v, ok := s.Get("nil")
+	assert.True(t, ok)
+	assert.Nil(t, v)
+}
+
+func TestBytesDelete(t *testing.T) {
+	s := NewBytesStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = []byte("bar")
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", []byte("bar"))
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func TestBytesClone(t *testing.T) {
+	s := NewBytesStore()
+	s.store["a"] = []byte("foo")
+	s.store["b"] = []byte("bar")
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", []byte("foo"))
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = 'x'
+	assert.Equal(t, []byte("foo"), s.store["a"])
+}
+
+func TestBytesMergeFrom(t *testing.T) {
+	o := NewBytesStore()
+	o.store["b"] = []byte("bar")
+	o.store["c"] = []byte("bar")
+
+	// incoming values win without a conflict func
+	s := NewBytesStore()
+	s.store["a"] = []byte("foo")
+	s.store["b"] = []byte("foo")
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]byte{"a": []byte("foo"), "b": []byte("bar"), "c": []byte("bar")}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewBytesStore()
+	s.store["a"] = []byte("foo")
+	s.store["b"] = []byte("foo")
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []byte) []byte {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, []byte("foo"), current)
+		assert.Equal(t, []byte("bar"), incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]byte{"a": []byte("foo"), "b": []byte("foo"), "c": []byte("bar")}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]byte{"b": []byte("bar"), "c": []byte("bar")}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestBytesMergeFromConflictCopies(t *testing.T) {
+	o := NewBytesStore()
+	o.store["a"] = []byte("bar")
+
+	s := NewBytesStore()
+	s.store["a"] = []byte("foo")
+
+	var kept []byte
+	s.MergeFrom(o, func(key string, current, incoming []byte) []byte {
+		current[0] = 'x'
+		incoming[0] = 'x'
+		kept = current
+		return current
+	})
+
+	// the store keeps its own copy of the returned value
+	assert.Equal(t, []byte("xoo"), s.store["a"])
+	kept[1] = 'x'
+	assert.Equal(t, []byte("xoo"), s.store["a"])
+
+	// other is untouched
+	assert.Equal(t, []byte("bar"), o.store["a"])
+
+	// current is never the stored buffer
+	s.MergeFrom(o, func(key string, current, incoming []byte) []byte {
+		current[0] = 'y'
+		assert.Equal(t, []byte("xoo"), s.store["a"])
+		return incoming
+	})
+	assert.Equal(t, []byte("bar"), s.store["a"])
+}
+
+func TestBytesDiff(t *testing.T) {
+	s := NewBytesStore()
+	s.store["a"] = []byte("foo")
+	s.store["b"] = []byte("foo")
+	s.store["c"] = []byte("foo")
+
+	o := NewBytesStore()
+	o.store["b"] = []byte("foo")
+	o.store["c"] = []byte("bar")
+	o.store["d"] = []byte("bar")
+	o.store["e"] = []byte("bar")
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewBytesStore().Diff(NewBytesStore()).Empty())
+}
+
+func TestBytesReplaceAll(t *testing.T) {
+	s := NewBytesStore()
+	s.store["a"] = []byte("foo")
+	s.EnableKeyIndex()
+
+	values := map[string][]byte{"b": []byte("bar"), "c": []byte("foo")}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = []byte("foo")
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestBytesSize(t *testing.T) {
+	s := NewBytesStore()
+
+	// no keys
+	size := s.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	s.store["a"] = []byte("bar")
+	s.store["b"] = []byte("baz")
+
+	size = s.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestBytesMembers(t *testing.T) {
+	s := NewBytesStore()
+
+	// no keys
+	mems := s.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	s.store["a"] = []byte("bar")
+	s.store["b"] = []byte("baz")
+
+	mems = s.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestBytesSortedMembers(t *testing.T) {
+	s := NewBytesStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = []byte("bar")
+	s.store["AAPL:bid"] = []byte("bar")
+	s.store["AAPL:ask"] = []byte("bar")
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestBytesMembersWithPrefix(t *testing.T) {
+	s := NewBytesStore()
+
+	s.store["MSFT:bid"] = []byte("bar")
+	s.store["AAPL:bid"] = []byte("bar")
+	s.store["AAPL:ask"] = []byte("bar")
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestBytesMembersMatching(t *testing.T) {
+	s := NewBytesStore()
+
+	s.store["MSFT:bid"] = []byte("bar")
+	s.store["AAPL:bid"] = []byte("bar")
+	s.store["AAPL:ask"] = []byte("bar")
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestBytesKeyIndex(t *testing.T) {
+	s := NewBytesStore()
+	s.store["foo"] = []byte("bar")
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", []byte("bar"))
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestBytesScan(t *testing.T) {
+	s := NewBytesStore()
+
+	s.store["MSFT:bid"] = []byte("bar")
+	s.store["AAPL:bid"] = []byte("bar")
+	s.store["AAPL:ask"] = []byte("bar")
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestBytesIsMember(t *testing.T) {
+	s := NewBytesStore()
+
+	// no keys
+	ok := s.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	s.store["foo"] = []byte("bar")
+
+	ok = s.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestBytesClear(t *testing.T) {
+	s := NewBytesStore()
+
+	s.store["foo"] = []byte("bar")
+	assert.Equal(t, 1, len(s.store))
+
+	s.Clear()
+	assert.Equal(t, 0, len(s.store))
+}
+
+func TestBytesDigest(t *testing.T) {
+	s := NewBytesStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", []byte("foo"))
+	s.Set("b", []byte("bar"))
+
+	// write order does not matter
+	o := NewBytesStore()
+	o.Set("b", []byte("bar"))
+	o.Set("a", []byte("foo"))
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", []byte("foo"))
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", []byte("bar"))
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestBytesEnableDigest(t *testing.T) {
+	s := NewBytesStore()
+	s.Set("a", []byte("foo"))
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", []byte("bar"))
+	s.Delete("a")
+	o := NewBytesStore()
+	o.Set("b", []byte("bar"))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]byte{"a": []byte("foo")})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestBytesSyncFrom(t *testing.T) {
+	s := NewBytesStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), []byte("foo"))
+	}
+	s.Set("b", []byte("bar"))
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", []byte("foo"))
+	r.Delete("1")
+	r.Set("c", []byte("bar"))
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestBytesConcurrentGetAndSet(t *testing.T) {
+	s := NewBytesStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Set("foo", []byte("bar"))
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,503 @@
+package primitivestore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
+)
+
+// Complex128Store is a store of complex128s
+// Implements the PrimitiveStore interface
+// Embedded sync.Mutex to provide atomic operation ability
+type Complex128Store struct {
+	sync.Mutex
+	store  map[string]complex128
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
+}
+
+// NewComplex128Store constructs and initializes a new Complex128Store
+// Always use this function when creating a new Complex128Store
+func NewComplex128Store() *Complex128Store {
+	return &Complex128Store{store: make(map[string]complex128)}
+}
+
+func (s *Complex128Store) set(key string, value complex128) {
+	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashComplex128(key, s.store[key]))
+	}
+}
+
+// Set stores the given value mapped to the given key
+func (s *Complex128Store) Set(key string, value complex128) {
+	s.Lock()
+	s.set(key, value)
+	s.Unlock()
+}
+
+func (s *Complex128Store) delete(key string) {
+	delete(s.store, key)
+	s.index.Delete(key)
+	s.digest.Delete(key)
+}
+
+// Delete removes the given key and its value from the store
+func (s *Complex128Store) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
+func (s *Complex128Store) get(key string) (complex128, bool) {
+	// explictly return second return value
+	v, ok := s.store[key]
+
+	return v, ok
+}
+
+// Get returns the value for the given key
+func (s *Complex128Store) Get(key string) (complex128, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *Complex128Store) snapshot() map[string]complex128 {
+	m := make(map[string]complex128, len(s.store))
+	for k, v := range s.store {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (s *Complex128Store) clone() *Complex128Store {
+	c := &Complex128Store{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *Complex128Store) Clone() *Complex128Store {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *Complex128Store) mergeFrom(values map[string]complex128, conflictFn func(key string, current, incoming complex128) complex128) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *Complex128Store) MergeFrom(other *Complex128Store, conflictFn func(key string, current, incoming complex128) complex128) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *Complex128Store) diff(values map[string]complex128) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if v != ov {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *Complex128Store) Diff(other *Complex128Store) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *Complex128Store) replaceAll(store map[string]complex128) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *Complex128Store) ReplaceAll(values map[string]complex128) {
+	store := make(map[string]complex128, len(values))
+	for k, v := range values {
+		store[k] = v
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+func hashComplex128(key string, v complex128) uint64 {
+	return merkle.WriteUint64(merkle.WriteUint64(merkle.Init(key), math.Float64bits(real(v))), math.Float64bits(imag(v)))
+}
+
+func (s *Complex128Store) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashComplex128(k, v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled
+func (s *Complex128Store) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *Complex128Store) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashComplex128(k, v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *Complex128Store) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *Complex128Store) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashComplex128(k, v))
+	}
+
+	return t
+}
+
+func (s *Complex128Store) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string]complex128)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *Complex128Store) applyEntries(buckets merkle.BucketSet, entries map[string]complex128) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			s.delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashComplex128(k, cur) != hashComplex128(k, v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *Complex128Store) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string]complex128
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *Complex128Store) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *Complex128Store) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
+func (s *Complex128Store) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *Complex128Store) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *Complex128Store) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *Complex128Store) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+func (s *Complex128Store) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *Complex128Store) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *Complex128Store) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *Complex128Store) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *Complex128Store) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *Complex128Store) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Complex128Store) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *Complex128Store) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Complex128Store) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *Complex128Store) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *Complex128Store) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *Complex128Store) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *Complex128Store) clear() {
+	s.store = make(map[string]complex128)
+	s.index.Clear()
+	s.digest.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *Complex128Store) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
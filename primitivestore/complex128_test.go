@@ -0,0 +1,397 @@
+package primitivestore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplex128Set(t *testing.T) {
+	s := NewComplex128Store()
+
+	s.Set("foo", complex(10.5, -1.5))
+	assert.Equal(t, complex(10.5, -1.5), s.store["foo"])
+}
+
+func TestComplex128Get(t *testing.T) {
+	s := NewComplex128Store()
+
+	// no key yet
+	v, ok := s.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	s.store["foo"] = complex(10.5, -1.5)
+	v, ok = s.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, complex(10.5, -1.5), v)
+}
+
+func TestComplex128Delete(t *testing.T) {
+	s := NewComplex128Store()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = complex(10.5, -1.5)
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", complex(10.5, -1.5))
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func TestComplex128Clone(t *testing.T) {
+	s := NewComplex128Store()
+	s.store["a"] = complex(1, 2)
+	s.store["b"] = complex(3, 4)
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", complex(1, 2))
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestComplex128MergeFrom(t *testing.T) {
+	o := NewComplex128Store()
+	o.store["b"] = complex(3, 4)
+	o.store["c"] = complex(3, 4)
+
+	// incoming values win without a conflict func
+	s := NewComplex128Store()
+	s.store["a"] = complex(1, 2)
+	s.store["b"] = complex(1, 2)
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]complex128{"a": complex(1, 2), "b": complex(3, 4), "c": complex(3, 4)}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewComplex128Store()
+	s.store["a"] = complex(1, 2)
+	s.store["b"] = complex(1, 2)
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming complex128) complex128 {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, complex(1, 2), current)
+		assert.Equal(t, complex(3, 4), incoming)
+		return current
+	})
+	assert.Equal(t, map[string]complex128{"a": complex(1, 2), "b": complex(1, 2), "c": complex(3, 4)}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]complex128{"b": complex(3, 4), "c": complex(3, 4)}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestComplex128Diff(t *testing.T) {
+	s := NewComplex128Store()
+	s.store["a"] = complex(1, 2)
+	s.store["b"] = complex(1, 2)
+	s.store["c"] = complex(1, 2)
+
+	o := NewComplex128Store()
+	o.store["b"] = complex(1, 2)
+	o.store["c"] = complex(3, 4)
+	o.store["d"] = complex(3, 4)
+	o.store["e"] = complex(3, 4)
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewComplex128Store().Diff(NewComplex128Store()).Empty())
+}
+
+func TestComplex128ReplaceAll(t *testing.T) {
+	s := NewComplex128Store()
+	s.store["a"] = complex(1, 2)
+	s.EnableKeyIndex()
+
+	values := map[string]complex128{"b": complex(3, 4), "c": complex(1, 2)}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = complex(1, 2)
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestComplex128Size(t *testing.T) {
+	s := NewComplex128Store()
+
+	// no keys
+	size := s.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	s.store["a"] = complex(10.5, -1.5)
+	s.store["b"] = complex(11.5, 2)
+
+	size = s.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestComplex128Members(t *testing.T) {
+	s := NewComplex128Store()
+
+	// no keys
+	mems := s.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	s.store["a"] = complex(10.5, -1.5)
+	s.store["b"] = complex(11.5, 2)
+
+	mems = s.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestComplex128SortedMembers(t *testing.T) {
+	s := NewComplex128Store()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:ask"] = complex(10.5, -1.5)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestComplex128MembersWithPrefix(t *testing.T) {
+	s := NewComplex128Store()
+
+	s.store["MSFT:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:ask"] = complex(10.5, -1.5)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestComplex128MembersMatching(t *testing.T) {
+	s := NewComplex128Store()
+
+	s.store["MSFT:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:ask"] = complex(10.5, -1.5)
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestComplex128KeyIndex(t *testing.T) {
+	s := NewComplex128Store()
+	s.store["foo"] = complex(10.5, -1.5)
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", complex(10.5, -1.5))
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestComplex128Scan(t *testing.T) {
+	s := NewComplex128Store()
+
+	s.store["MSFT:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:bid"] = complex(10.5, -1.5)
+	s.store["AAPL:ask"] = complex(10.5, -1.5)
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestComplex128IsMember(t *testing.T) {
+	s := NewComplex128Store()
+
+	// no keys
+	ok := s.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	s.store["foo"] = complex(10.5, -1.5)
+
+	ok = s.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestComplex128Clear(t *testing.T) {
+	s := NewComplex128Store()
+
+	s.store["foo"] = complex(10.5, -1.5)
+	assert.Equal(t, 1, len(s.store))
+
+	s.Clear()
+	assert.Equal(t, 0, len(s.store))
+}
+
+func TestComplex128Digest(t *testing.T) {
+	s := NewComplex128Store()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", complex(1, 2))
+	s.Set("b", complex(3, 4))
+
+	// write order does not matter
+	o := NewComplex128Store()
+	o.Set("b", complex(3, 4))
+	o.Set("a", complex(1, 2))
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", complex(1, 2))
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", complex(3, 4))
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestComplex128EnableDigest(t *testing.T) {
+	s := NewComplex128Store()
+	s.Set("a", complex(1, 2))
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", complex(3, 4))
+	s.Delete("a")
+	o := NewComplex128Store()
+	o.Set("b", complex(3, 4))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]complex128{"a": complex(1, 2)})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestComplex128SyncFrom(t *testing.T) {
+	s := NewComplex128Store()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), complex(1, 2))
+	}
+	s.Set("b", complex(3, 4))
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", complex(1, 2))
+	r.Delete("1")
+	r.Set("c", complex(3, 4))
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestComplex128ConcurrentGetAndSet(t *testing.T) {
+	s := NewComplex128Store()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Set("foo", complex(10.5, -1.5))
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,702 @@
+package primitivestore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
+)
+
+// DecimalStore is a store of fixed point decimals
+// Implements the PrimitiveStore interface
+// Every value keeps its own scale, so precision is chosen per key
+// Embedded sync.Mutex to provide atomic operation ability
+type DecimalStore struct {
+	sync.Mutex
+	store  map[string]decimal.Decimal
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
+}
+
+// NewDecimalStore constructs and initializes a new DecimalStore
+// Always use this function when creating a new DecimalStore
+func NewDecimalStore() *DecimalStore {
+	return &DecimalStore{store: make(map[string]decimal.Decimal)}
+}
+
+func (s *DecimalStore) set(key string, value decimal.Decimal) {
+	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashDecimal(key, s.store[key]))
+	}
+}
+
+// Set stores the given value mapped to the given key
+func (s *DecimalStore) Set(key string, value decimal.Decimal) {
+	s.Lock()
+	s.set(key, value)
+	s.Unlock()
+}
+
+func (s *DecimalStore) delete(key string) {
+	delete(s.store, key)
+	s.index.Delete(key)
+	s.digest.Delete(key)
+}
+
+// Delete removes the given key and its value from the store
+func (s *DecimalStore) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
+func (s *DecimalStore) get(key string) (decimal.Decimal, bool) {
+	// explictly return second return value
+	v, ok := s.store[key]
+
+	return v, ok
+}
+
+// Get returns the value for the given key
+func (s *DecimalStore) Get(key string) (decimal.Decimal, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *DecimalStore) add(key string, delta decimal.Decimal) (decimal.Decimal, error) {
+	v, err := s.store[key].Add(delta)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	s.store[key] = v
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashDecimal(key, s.store[key]))
+	}
+
+	return v, nil
+}
+
+// Add atomically adds delta to the value of the given key, which starts from zero if it does not exist
+// returns the new value, or decimal.ErrOverflow leaving the value unchanged
+func (s *DecimalStore) Add(key string, delta decimal.Decimal) (decimal.Decimal, error) {
+	s.Lock()
+	v, err := s.add(key, delta)
+	s.Unlock()
+
+	return v, err
+}
+
+// accumulate sums every value of the store in place
+func (s *DecimalStore) accumulate() decimal.Accumulator {
+	var a decimal.Accumulator
+	for _, v := range s.store {
+		a.Add(v)
+	}
+
+	return a
+}
+
+func (s *DecimalStore) sum() (decimal.Decimal, error) {
+	a := s.accumulate()
+
+	return a.Sum()
+}
+
+// Sum returns the exact sum of all values in the store at the largest scale of the values
+// returns decimal.ErrOverflow if the total does not fit, whatever order the values are added in
+func (s *DecimalStore) Sum() (decimal.Decimal, error) {
+	s.Lock()
+	v, err := s.sum()
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *DecimalStore) argMin() (string, decimal.Decimal, bool) {
+	var (
+		key string
+		min decimal.Decimal
+		ok  bool
+	)
+	for k, v := range s.store {
+		if !ok {
+			key, min, ok = k, v, true
+			continue
+		}
+		if c := v.Cmp(min); c < 0 || (c == 0 && k < key) {
+			key, min = k, v
+		}
+	}
+
+	return key, min, ok
+}
+
+// Min returns the lowest value in the store, the value of the lowest key for values equal at different scales
+// returns the value and boolean if the store is not empty
+func (s *DecimalStore) Min() (decimal.Decimal, bool) {
+	s.Lock()
+	_, v, ok := s.argMin()
+	s.Unlock()
+
+	return v, ok
+}
+
+// ArgMin returns the key of the lowest value in the store, the lowest key for equal values
+// returns the key and boolean if the store is not empty
+func (s *DecimalStore) ArgMin() (string, bool) {
+	s.Lock()
+	k, _, ok := s.argMin()
+	s.Unlock()
+
+	return k, ok
+}
+
+func (s *DecimalStore) argMax() (string, decimal.Decimal, bool) {
+	var (
+		key string
+		max decimal.Decimal
+		ok  bool
+	)
+	for k, v := range s.store {
+		if !ok {
+			key, max, ok = k, v, true
+			continue
+		}
+		if c := v.Cmp(max); c > 0 || (c == 0 && k < key) {
+			key, max = k, v
+		}
+	}
+
+	return key, max, ok
+}
+
+// Max returns the highest value in the store, the value of the lowest key for values equal at different scales
+// returns the value and boolean if the store is not empty
+func (s *DecimalStore) Max() (decimal.Decimal, bool) {
+	s.Lock()
+	_, v, ok := s.argMax()
+	s.Unlock()
+
+	return v, ok
+}
+
+// ArgMax returns the key of the highest value in the store, the lowest key for equal values
+// returns the key and boolean if the store is not empty
+func (s *DecimalStore) ArgMax() (string, bool) {
+	s.Lock()
+	k, _, ok := s.argMax()
+	s.Unlock()
+
+	return k, ok
+}
+
+func (s *DecimalStore) mean(scale int) (decimal.Decimal, bool, error) {
+	if len(s.store) == 0 {
+		return decimal.Decimal{}, false, nil
+	}
+
+	a := s.accumulate()
+	v, err := a.Mean(scale)
+
+	return v, true, err
+}
+
+// Mean returns the arithmetic mean of all values in the store rounded half away from zero to the given scale
+// returns the mean and boolean if the store is not empty,
+// or decimal.ErrInvalidScale or decimal.ErrOverflow if the mean does not fit the scale
+func (s *DecimalStore) Mean(scale int) (decimal.Decimal, bool, error) {
+	s.Lock()
+	v, ok, err := s.mean(scale)
+	s.Unlock()
+
+	return v, ok, err
+}
+
+func (s *DecimalStore) count(pred func(key string, value decimal.Decimal) bool) int {
+	n := 0
+	for k, v := range s.store {
+		if pred(k, v) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Count returns the number of keys whose key and value satisfy pred
+// pred is called under the store lock, so it must not call back into the store
+func (s *DecimalStore) Count(pred func(key string, value decimal.Decimal) bool) int {
+	s.Lock()
+	n := s.count(pred)
+	s.Unlock()
+
+	return n
+}
+
+func (s *DecimalStore) reduce(init decimal.Decimal, fn func(acc decimal.Decimal, key string, value decimal.Decimal) decimal.Decimal) decimal.Decimal {
+	acc := init
+	for k, v := range s.store {
+		acc = fn(acc, k, v)
+	}
+
+	return acc
+}
+
+// Reduce folds every key and value of the store into init with fn, in no particular order
+// The accumulator has the value type of the store, to fold into any other type update a variable captured by fn
+// fn is called under the store lock, so it must not call back into the store
+func (s *DecimalStore) Reduce(init decimal.Decimal, fn func(acc decimal.Decimal, key string, value decimal.Decimal) decimal.Decimal) decimal.Decimal {
+	s.Lock()
+	v := s.reduce(init, fn)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalStore) snapshot() map[string]decimal.Decimal {
+	m := make(map[string]decimal.Decimal, len(s.store))
+	for k, v := range s.store {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (s *DecimalStore) clone() *DecimalStore {
+	c := &DecimalStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *DecimalStore) Clone() *DecimalStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *DecimalStore) mergeFrom(values map[string]decimal.Decimal, conflictFn func(key string, current, incoming decimal.Decimal) decimal.Decimal) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *DecimalStore) MergeFrom(other *DecimalStore, conflictFn func(key string, current, incoming decimal.Decimal) decimal.Decimal) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *DecimalStore) diff(values map[string]decimal.Decimal) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !v.Equal(ov) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *DecimalStore) Diff(other *DecimalStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *DecimalStore) replaceAll(store map[string]decimal.Decimal) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *DecimalStore) ReplaceAll(values map[string]decimal.Decimal) {
+	store := make(map[string]decimal.Decimal, len(values))
+	for k, v := range values {
+		store[k] = v
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+func hashDecimal(key string, v decimal.Decimal) uint64 {
+	// drop trailing zeros so equal values of different scales hash the same
+	m, scale := v.Mantissa(), v.Scale()
+	for scale > 0 && m%10 == 0 {
+		m /= 10
+		scale--
+	}
+
+	return merkle.WriteUint64(merkle.WriteUint64(merkle.Init(key), uint64(m)), uint64(scale))
+}
+
+func (s *DecimalStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashDecimal(k, v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled
+func (s *DecimalStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *DecimalStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashDecimal(k, v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *DecimalStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *DecimalStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashDecimal(k, v))
+	}
+
+	return t
+}
+
+func (s *DecimalStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string]decimal.Decimal)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *DecimalStore) applyEntries(buckets merkle.BucketSet, entries map[string]decimal.Decimal) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			s.delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashDecimal(k, cur) != hashDecimal(k, v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *DecimalStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string]decimal.Decimal
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *DecimalStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *DecimalStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
+func (s *DecimalStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *DecimalStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *DecimalStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *DecimalStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+func (s *DecimalStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *DecimalStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *DecimalStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *DecimalStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *DecimalStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *DecimalStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *DecimalStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *DecimalStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *DecimalStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *DecimalStore) clear() {
+	s.store = make(map[string]decimal.Decimal)
+	s.index.Clear()
+	s.digest.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *DecimalStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,552 @@
+package primitivestore
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecimalSet(t *testing.T) {
+	s := NewDecimalStore()
+
+	s.Set("foo", decimal.MustParse("10.50"))
+	assert.Equal(t, decimal.MustParse("10.50"), s.store["foo"])
+}
+
+func TestDecimalGet(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no key yet
+	v, ok := s.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	s.store["foo"] = decimal.MustParse("10.50")
+	v, ok = s.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, decimal.MustParse("10.50"), v)
+}
+
+func TestDecimalAdd(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no key yet starts from zero
+	v, err := s.Add("foo", decimal.MustParse("0.1"))
+	assert.Nil(t, err)
+	assert.Equal(t, decimal.MustParse("0.1"), s.store["foo"])
+
+	v, err = s.Add("foo", decimal.MustParse("0.20"))
+	assert.Nil(t, err)
+	assert.Equal(t, "0.30", v.String())
+	assert.Equal(t, v, s.store["foo"])
+
+	// overflow leaves value unchanged
+	s.store["bar"] = decimal.New(math.MaxInt64, 0)
+	_, err = s.Add("bar", decimal.FromInt(1))
+	assert.Equal(t, decimal.ErrOverflow, err)
+	assert.Equal(t, decimal.New(math.MaxInt64, 0), s.store["bar"])
+}
+
+func TestDecimalDelete(t *testing.T) {
+	s := NewDecimalStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = decimal.MustParse("10.50")
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", decimal.MustParse("10.50"))
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func mockDecimalValues(s *DecimalStore) {
+	s.store["a"] = decimal.MustParse("3.00")
+	s.store["b"] = decimal.MustParse("0.1")
+	s.store["c"] = decimal.MustParse("0.2")
+	s.store["d"] = decimal.MustParse("0.20")
+}
+
+func TestDecimalSum(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	v, err := s.Sum()
+	assert.Nil(t, err)
+	assert.True(t, v.IsZero())
+
+	// exact, no binary floating point drift
+	mockDecimalValues(s)
+	v, err = s.Sum()
+	assert.Nil(t, err)
+	assert.Equal(t, "3.50", v.String())
+
+	// accumulated in place while the total fits an int64
+	assert.Equal(t, 0.0, testing.AllocsPerRun(10, func() { s.Sum() }))
+
+	s.store["e"] = decimal.New(math.MaxInt64, 2)
+	_, err = s.Sum()
+	assert.Equal(t, decimal.ErrOverflow, err)
+}
+
+func TestDecimalMin(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	_, ok := s.Min()
+	assert.False(t, ok)
+	_, ok = s.ArgMin()
+	assert.False(t, ok)
+
+	mockDecimalValues(s)
+	min, ok := s.Min()
+	assert.True(t, ok)
+	assert.Equal(t, decimal.MustParse("0.1"), min)
+
+	key, ok := s.ArgMin()
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	// equal values at any scale return the lowest key
+	s.store["b"] = decimal.MustParse("0.2")
+	key, _ = s.ArgMin()
+	assert.Equal(t, "b", key)
+	s.store["0"] = decimal.MustParse("0.200")
+	key, _ = s.ArgMin()
+	assert.Equal(t, "0", key)
+	min, _ = s.Min()
+	assert.Equal(t, decimal.MustParse("0.200"), min)
+}
+
+func TestDecimalMax(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	_, ok := s.Max()
+	assert.False(t, ok)
+	_, ok = s.ArgMax()
+	assert.False(t, ok)
+
+	mockDecimalValues(s)
+	max, ok := s.Max()
+	assert.True(t, ok)
+	assert.Equal(t, decimal.MustParse("3.00"), max)
+
+	key, ok := s.ArgMax()
+	assert.True(t, ok)
+	assert.Equal(t, "a", key)
+
+	// equal values at any scale return the lowest key
+	s.store["0"] = decimal.MustParse("3")
+	key, _ = s.ArgMax()
+	assert.Equal(t, "0", key)
+}
+
+func TestDecimalMean(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	_, ok, err := s.Mean(2)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	mockDecimalValues(s)
+	mean, ok, err := s.Mean(4)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.8750", mean.String())
+
+	// rounded half away from zero
+	mean, _, err = s.Mean(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "0.88", mean.String())
+
+	_, _, err = s.Mean(decimal.MaxScale + 1)
+	assert.Equal(t, decimal.ErrInvalidScale, err)
+}
+
+func TestDecimalCount(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	assert.Equal(t, 0, s.Count(func(key string, value decimal.Decimal) bool { return true }))
+
+	mockDecimalValues(s)
+	assert.Equal(t, 3, s.Count(func(key string, value decimal.Decimal) bool {
+		return value.Cmp(decimal.MustParse("0.2")) >= 0
+	}))
+	assert.Equal(t, 1, s.Count(func(key string, value decimal.Decimal) bool { return key == "a" }))
+}
+
+func TestDecimalReduce(t *testing.T) {
+	s := NewDecimalStore()
+
+	sum := func(acc decimal.Decimal, key string, value decimal.Decimal) decimal.Decimal {
+		v, _ := acc.Add(value)
+		return v
+	}
+
+	// no keys
+	assert.Equal(t, decimal.FromInt(5), s.Reduce(decimal.FromInt(5), sum))
+
+	mockDecimalValues(s)
+	assert.Equal(t, "8.50", s.Reduce(decimal.FromInt(5), sum).String())
+}
+
+func TestDecimalClone(t *testing.T) {
+	s := NewDecimalStore()
+	s.store["a"] = decimal.MustParse("1.50")
+	s.store["b"] = decimal.MustParse("2.25")
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", decimal.MustParse("1.50"))
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestDecimalMergeFrom(t *testing.T) {
+	o := NewDecimalStore()
+	o.store["b"] = decimal.MustParse("2.25")
+	o.store["c"] = decimal.MustParse("2.25")
+
+	// incoming values win without a conflict func
+	s := NewDecimalStore()
+	s.store["a"] = decimal.MustParse("1.50")
+	s.store["b"] = decimal.MustParse("1.50")
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]decimal.Decimal{"a": decimal.MustParse("1.50"), "b": decimal.MustParse("2.25"), "c": decimal.MustParse("2.25")}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewDecimalStore()
+	s.store["a"] = decimal.MustParse("1.50")
+	s.store["b"] = decimal.MustParse("1.50")
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming decimal.Decimal) decimal.Decimal {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, decimal.MustParse("1.50"), current)
+		assert.Equal(t, decimal.MustParse("2.25"), incoming)
+		return current
+	})
+	assert.Equal(t, map[string]decimal.Decimal{"a": decimal.MustParse("1.50"), "b": decimal.MustParse("1.50"), "c": decimal.MustParse("2.25")}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]decimal.Decimal{"b": decimal.MustParse("2.25"), "c": decimal.MustParse("2.25")}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestDecimalDiff(t *testing.T) {
+	s := NewDecimalStore()
+	s.store["a"] = decimal.MustParse("1.50")
+	s.store["b"] = decimal.MustParse("1.50")
+	s.store["c"] = decimal.MustParse("1.50")
+
+	o := NewDecimalStore()
+	o.store["b"] = decimal.MustParse("1.50")
+	o.store["c"] = decimal.MustParse("2.25")
+	o.store["d"] = decimal.MustParse("2.25")
+	o.store["e"] = decimal.MustParse("2.25")
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewDecimalStore().Diff(NewDecimalStore()).Empty())
+}
+
+func TestDecimalReplaceAll(t *testing.T) {
+	s := NewDecimalStore()
+	s.store["a"] = decimal.MustParse("1.50")
+	s.EnableKeyIndex()
+
+	values := map[string]decimal.Decimal{"b": decimal.MustParse("2.25"), "c": decimal.MustParse("1.50")}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = decimal.MustParse("1.50")
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestDecimalSize(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	size := s.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	s.store["a"] = decimal.MustParse("10.50")
+	s.store["b"] = decimal.MustParse("11.50")
+
+	size = s.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestDecimalMembers(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	mems := s.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	s.store["a"] = decimal.MustParse("10.50")
+	s.store["b"] = decimal.MustParse("11.50")
+
+	mems = s.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestDecimalSortedMembers(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:ask"] = decimal.MustParse("10.50")
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestDecimalMembersWithPrefix(t *testing.T) {
+	s := NewDecimalStore()
+
+	s.store["MSFT:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:ask"] = decimal.MustParse("10.50")
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestDecimalMembersMatching(t *testing.T) {
+	s := NewDecimalStore()
+
+	s.store["MSFT:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:ask"] = decimal.MustParse("10.50")
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestDecimalKeyIndex(t *testing.T) {
+	s := NewDecimalStore()
+	s.store["foo"] = decimal.MustParse("10.50")
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", decimal.MustParse("10.50"))
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestDecimalScan(t *testing.T) {
+	s := NewDecimalStore()
+
+	s.store["MSFT:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:bid"] = decimal.MustParse("10.50")
+	s.store["AAPL:ask"] = decimal.MustParse("10.50")
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestDecimalIsMember(t *testing.T) {
+	s := NewDecimalStore()
+
+	// no keys
+	ok := s.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	s.store["foo"] = decimal.MustParse("10.50")
+
+	ok = s.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestDecimalClear(t *testing.T) {
+	s := NewDecimalStore()
+
+	s.store["foo"] = decimal.MustParse("10.50")
+	assert.Equal(t, 1, len(s.store))
+
+	s.Clear()
+	assert.Equal(t, 0, len(s.store))
+}
+
+func TestDecimalDigest(t *testing.T) {
+	s := NewDecimalStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", decimal.MustParse("1.50"))
+	s.Set("b", decimal.MustParse("2.25"))
+
+	// write order does not matter
+	o := NewDecimalStore()
+	o.Set("b", decimal.MustParse("2.25"))
+	o.Set("a", decimal.MustParse("1.50"))
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", decimal.MustParse("1.50"))
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", decimal.MustParse("2.25"))
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestDecimalEnableDigest(t *testing.T) {
+	s := NewDecimalStore()
+	s.Set("a", decimal.MustParse("1.50"))
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", decimal.MustParse("2.25"))
+	s.Delete("a")
+	o := NewDecimalStore()
+	o.Set("b", decimal.MustParse("2.25"))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]decimal.Decimal{"a": decimal.MustParse("1.50")})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestDecimalSyncFrom(t *testing.T) {
+	s := NewDecimalStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), decimal.MustParse("1.50"))
+	}
+	s.Set("b", decimal.MustParse("2.25"))
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", decimal.MustParse("1.50"))
+	r.Delete("1")
+	r.Set("c", decimal.MustParse("2.25"))
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestDecimalConcurrentGetAndSet(t *testing.T) {
+	s := NewDecimalStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Set("foo", decimal.MustParse("10.50"))
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,783 @@
+package primitivestore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
+)
+
+// DurationStore is a store of durations
+// Implements the PrimitiveStore interface
+// Embedded sync.Mutex to provide atomic operation ability
+type DurationStore struct {
+	sync.Mutex
+	store  map[string]time.Duration
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	values *valueIndex     // ordered values, nil unless EnableValueIndex is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
+}
+
+// NewDurationStore constructs and initializes a new DurationStore
+// Always use this function when creating a new DurationStore
+func NewDurationStore() *DurationStore {
+	return &DurationStore{store: make(map[string]time.Duration)}
+}
+
+func (s *DurationStore) set(key string, value time.Duration) {
+	if s.values != nil {
+		if old, ok := s.store[key]; ok {
+			s.values.delete(int64Key(int64(old)), key)
+		}
+		s.values.insert(int64Key(int64(value)), key)
+	}
+
+	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashDuration(key, s.store[key]))
+	}
+}
+
+// Set stores the given value mapped to the given key
+func (s *DurationStore) Set(key string, value time.Duration) {
+	s.Lock()
+	s.set(key, value)
+	s.Unlock()
+}
+
+func (s *DurationStore) delete(key string) {
+	v, ok := s.store[key]
+	if !ok {
+		return
+	}
+
+	delete(s.store, key)
+	s.index.Delete(key)
+	s.digest.Delete(key)
+	s.values.delete(int64Key(int64(v)), key)
+}
+
+// Delete removes the given key and its value from the store
+func (s *DurationStore) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
+func (s *DurationStore) get(key string) (time.Duration, bool) {
+	// explictly return second return value
+	v, ok := s.store[key]
+
+	return v, ok
+}
+
+// Get returns the value for the given key
+func (s *DurationStore) Get(key string) (time.Duration, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *DurationStore) enableValueIndex() {
+	if s.values != nil {
+		return
+	}
+
+	s.values = newValueIndex()
+	for k, v := range s.store {
+		s.values.insert(int64Key(int64(v)), k)
+	}
+}
+
+// EnableValueIndex maintains an index of the keys ordered by value alongside the store,
+// so value queries no longer scan and sort every value
+// Setting and deleting keys costs an extra O(log n) once enabled
+func (s *DurationStore) EnableValueIndex() {
+	s.Lock()
+	s.enableValueIndex()
+	s.Unlock()
+}
+
+func (s *DurationStore) keysWhere(op Op, value time.Duration) []string {
+	if s.values != nil {
+		return s.values.where(op, int64Key(int64(value)))
+	}
+
+	ks := make([]scoredKey, 0)
+	for k, v := range s.store {
+		if op.holds(int64Key(int64(v)), int64Key(int64(value))) {
+			ks = append(ks, scoredKey{int64Key(int64(v)), k})
+		}
+	}
+
+	return sortedKeys(ks)
+}
+
+// KeysWhere returns the keys whose value holds op against the given value, in ascending value order
+// e.g. KeysWhere(Gt, x) returns every key with a value greater than x
+func (s *DurationStore) KeysWhere(op Op, value time.Duration) []string {
+	s.Lock()
+	v := s.keysWhere(op, value)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) keysInRange(lo, hi time.Duration) []string {
+	if s.values != nil {
+		return s.values.inRange(int64Key(int64(lo)), int64Key(int64(hi)))
+	}
+
+	ks := make([]scoredKey, 0)
+	for k, v := range s.store {
+		if v >= lo && v <= hi {
+			ks = append(ks, scoredKey{int64Key(int64(v)), k})
+		}
+	}
+
+	return sortedKeys(ks)
+}
+
+// KeysInRange returns the keys whose value is within lo and hi (inclusive:inclusive), in ascending value order
+func (s *DurationStore) KeysInRange(lo, hi time.Duration) []string {
+	s.Lock()
+	v := s.keysInRange(lo, hi)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) topN(n int, highest bool) []string {
+	if s.values != nil {
+		if highest {
+			return s.values.top(n)
+		}
+		return s.values.bottom(n)
+	}
+
+	ks := make([]scoredKey, 0, len(s.store))
+	for k, v := range s.store {
+		ks = append(ks, scoredKey{int64Key(int64(v)), k})
+	}
+
+	return topKeys(ks, n, highest)
+}
+
+// TopN returns the keys of the n highest values, highest first
+func (s *DurationStore) TopN(n int) []string {
+	s.Lock()
+	v := s.topN(n, true)
+	s.Unlock()
+
+	return v
+}
+
+// BottomN returns the keys of the n lowest values, lowest first
+func (s *DurationStore) BottomN(n int) []string {
+	s.Lock()
+	v := s.topN(n, false)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) sum() time.Duration {
+	var total time.Duration
+	for _, v := range s.store {
+		total += v
+	}
+
+	return total
+}
+
+// Sum returns the sum of all values in the store
+// The sum wraps around on overflow like the integer addition of the type
+func (s *DurationStore) Sum() time.Duration {
+	s.Lock()
+	v := s.sum()
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) argMin() (string, time.Duration, bool) {
+	var (
+		key string
+		min time.Duration
+		ok  bool
+	)
+	for k, v := range s.store {
+		if !ok || v < min || (v == min && k < key) {
+			key, min, ok = k, v, true
+		}
+	}
+
+	return key, min, ok
+}
+
+// Min returns the lowest value in the store
+// returns the value and boolean if the store is not empty
+func (s *DurationStore) Min() (time.Duration, bool) {
+	s.Lock()
+	_, v, ok := s.argMin()
+	s.Unlock()
+
+	return v, ok
+}
+
+// ArgMin returns the key of the lowest value in the store, the lowest key for equal values
+// returns the key and boolean if the store is not empty
+func (s *DurationStore) ArgMin() (string, bool) {
+	s.Lock()
+	k, _, ok := s.argMin()
+	s.Unlock()
+
+	return k, ok
+}
+
+func (s *DurationStore) argMax() (string, time.Duration, bool) {
+	var (
+		key string
+		max time.Duration
+		ok  bool
+	)
+	for k, v := range s.store {
+		if !ok || v > max || (v == max && k < key) {
+			key, max, ok = k, v, true
+		}
+	}
+
+	return key, max, ok
+}
+
+// Max returns the highest value in the store
+// returns the value and boolean if the store is not empty
+func (s *DurationStore) Max() (time.Duration, bool) {
+	s.Lock()
+	_, v, ok := s.argMax()
+	s.Unlock()
+
+	return v, ok
+}
+
+// ArgMax returns the key of the highest value in the store, the lowest key for equal values
+// returns the key and boolean if the store is not empty
+func (s *DurationStore) ArgMax() (string, bool) {
+	s.Lock()
+	k, _, ok := s.argMax()
+	s.Unlock()
+
+	return k, ok
+}
+
+func (s *DurationStore) mean() (time.Duration, bool) {
+	if len(s.store) == 0 {
+		return 0, false
+	}
+
+	total := 0.0
+	for _, v := range s.store {
+		total += float64(v)
+	}
+
+	return time.Duration(total / float64(len(s.store))), true
+}
+
+// Mean returns the arithmetic mean of all values in the store, truncated to the nanosecond
+// returns the mean and boolean if the store is not empty
+func (s *DurationStore) Mean() (time.Duration, bool) {
+	s.Lock()
+	v, ok := s.mean()
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *DurationStore) count(pred func(key string, value time.Duration) bool) int {
+	n := 0
+	for k, v := range s.store {
+		if pred(k, v) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Count returns the number of keys whose key and value satisfy pred
+// pred is called under the store lock, so it must not call back into the store
+func (s *DurationStore) Count(pred func(key string, value time.Duration) bool) int {
+	s.Lock()
+	n := s.count(pred)
+	s.Unlock()
+
+	return n
+}
+
+func (s *DurationStore) reduce(init time.Duration, fn func(acc time.Duration, key string, value time.Duration) time.Duration) time.Duration {
+	acc := init
+	for k, v := range s.store {
+		acc = fn(acc, k, v)
+	}
+
+	return acc
+}
+
+// Reduce folds every key and value of the store into init with fn, in no particular order
+// The accumulator has the value type of the store, to fold into any other type update a variable captured by fn
+// fn is called under the store lock, so it must not call back into the store
+func (s *DurationStore) Reduce(init time.Duration, fn func(acc time.Duration, key string, value time.Duration) time.Duration) time.Duration {
+	s.Lock()
+	v := s.reduce(init, fn)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) snapshot() map[string]time.Duration {
+	m := make(map[string]time.Duration, len(s.store))
+	for k, v := range s.store {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (s *DurationStore) clone() *DurationStore {
+	c := &DurationStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.values != nil {
+		c.enableValueIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *DurationStore) Clone() *DurationStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *DurationStore) mergeFrom(values map[string]time.Duration, conflictFn func(key string, current, incoming time.Duration) time.Duration) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *DurationStore) MergeFrom(other *DurationStore, conflictFn func(key string, current, incoming time.Duration) time.Duration) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *DurationStore) diff(values map[string]time.Duration) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if v != ov {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *DurationStore) Diff(other *DurationStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *DurationStore) replaceAll(store map[string]time.Duration) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.values != nil {
+		s.values = nil
+		s.enableValueIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *DurationStore) ReplaceAll(values map[string]time.Duration) {
+	store := make(map[string]time.Duration, len(values))
+	for k, v := range values {
+		store[k] = v
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+func hashDuration(key string, v time.Duration) uint64 {
+	return merkle.WriteUint64(merkle.Init(key), uint64(v))
+}
+
+func (s *DurationStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashDuration(k, v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled
+func (s *DurationStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *DurationStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashDuration(k, v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *DurationStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *DurationStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashDuration(k, v))
+	}
+
+	return t
+}
+
+func (s *DurationStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string]time.Duration)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *DurationStore) applyEntries(buckets merkle.BucketSet, entries map[string]time.Duration) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			s.delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashDuration(k, cur) != hashDuration(k, v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *DurationStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string]time.Duration
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *DurationStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *DurationStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
+func (s *DurationStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *DurationStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *DurationStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *DurationStore) Members() []string {
+	s.Lock()
+	mems := s.members()
+	s.Unlock()
+
+	return mems
+}
+
+func (s *DurationStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *DurationStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *DurationStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *DurationStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *DurationStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *DurationStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DurationStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *DurationStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *DurationStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *DurationStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *DurationStore) clear() {
+	s.store = make(map[string]time.Duration)
+	s.index.Clear()
+	s.digest.Clear()
+	s.values.clear()
+}
+
+// Clear deletes all keys in the store
+func (s *DurationStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
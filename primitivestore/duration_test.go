@@ -0,0 +1,638 @@
+package primitivestore
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationSet(t *testing.T) {
+	s := NewDurationStore()
+
+	s.Set("foo", 10*time.Second)
+	assert.Equal(t, 10*time.Second, s.store["foo"])
+}
+
+func TestDurationGet(t *testing.T) {
+	s := NewDurationStore()
+
+	// no key yet
+	v, ok := s.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	s.store["foo"] = 10 * time.Second
+	v, ok = s.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, v)
+}
+
+func TestDurationDelete(t *testing.T) {
+	s := NewDurationStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = 10 * time.Second
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", 10*time.Second)
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func mockDurationValues(s *DurationStore) {
+	s.store["a"] = 3
+	s.store["b"] = 1
+	s.store["c"] = 2
+	s.store["d"] = 2
+}
+
+func TestDurationKeysWhere(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysWhere(Eq, 2))
+
+	mockDurationValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d"}, s.KeysWhere(Eq, 2))
+		assert.Equal(t, []string{"b", "a"}, s.KeysWhere(Ne, 2))
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Lt, 2))
+		assert.Equal(t, []string{"b", "c", "d"}, s.KeysWhere(Le, 2))
+		assert.Equal(t, []string{"a"}, s.KeysWhere(Gt, 2))
+		assert.Equal(t, []string{"c", "d", "a"}, s.KeysWhere(Ge, 2))
+		assert.Equal(t, []string{}, s.KeysWhere(Gt, 3))
+	}
+}
+
+func TestDurationKeysInRange(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysInRange(0, 10))
+
+	mockDurationValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d", "a"}, s.KeysInRange(2, 3))
+		assert.Equal(t, []string{"b"}, s.KeysInRange(1, 1))
+		assert.Equal(t, []string{}, s.KeysInRange(4, 5))
+	}
+}
+
+func TestDurationTopN(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.TopN(2))
+
+	mockDurationValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"a", "d"}, s.TopN(2))
+		assert.Equal(t, []string{"a", "d", "c", "b"}, s.TopN(10))
+		assert.Equal(t, []string{}, s.TopN(0))
+	}
+}
+
+func TestDurationBottomN(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.BottomN(2))
+
+	mockDurationValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b", "c"}, s.BottomN(2))
+		assert.Equal(t, []string{"b", "c", "d", "a"}, s.BottomN(10))
+		assert.Equal(t, []string{}, s.BottomN(-1))
+	}
+}
+
+func TestDurationKeysWhereLarge(t *testing.T) {
+	s := NewDurationStore()
+
+	// beyond 2^53 neighbouring values share a float64
+	s.Set("a", 1<<53)
+	s.Set("b", 1<<53+1)
+	s.Set("c", 1<<53+2)
+	s.Set("max", math.MaxInt64)
+	s.Set("max1", math.MaxInt64-1)
+	s.Set("min", math.MinInt64)
+	s.Set("min1", math.MinInt64+1)
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Eq, 1<<53+1))
+		assert.Equal(t, []string{"c", "max1", "max"}, s.KeysWhere(Gt, 1<<53+1))
+		assert.Equal(t, []string{"min", "min1", "a"}, s.KeysWhere(Lt, 1<<53+1))
+		assert.Equal(t, []string{"b", "c"}, s.KeysInRange(1<<53+1, 1<<53+2))
+		assert.Equal(t, []string{"max"}, s.KeysWhere(Gt, math.MaxInt64-1))
+		assert.Equal(t, []string{"max", "max1"}, s.TopN(2))
+		assert.Equal(t, []string{"min", "min1", "a", "b", "c", "max1", "max"}, s.KeysInRange(math.MinInt64, math.MaxInt64))
+		assert.Equal(t, []string{"min"}, s.KeysWhere(Lt, math.MinInt64+1))
+		assert.Equal(t, []string{"min", "min1"}, s.BottomN(2))
+	}
+}
+
+func TestDurationValueIndex(t *testing.T) {
+	s := NewDurationStore()
+	s.store["a"] = 1
+
+	// existing values are indexed
+	s.EnableValueIndex()
+	assert.Equal(t, 1, s.values.len())
+
+	// set and overwrite
+	s.Set("b", 2)
+	s.Set("a", 3)
+	assert.Equal(t, 2, s.values.len())
+	assert.Equal(t, []string{"b", "a"}, s.values.bottom(10))
+
+	// delete
+	s.Delete("b")
+	assert.Equal(t, []string{"a"}, s.values.bottom(10))
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.values.len())
+}
+
+func TestDurationSum(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, time.Duration(0), s.Sum())
+
+	mockDurationValues(s)
+	assert.Equal(t, time.Duration(8), s.Sum())
+}
+
+func TestDurationMin(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	_, ok := s.Min()
+	assert.False(t, ok)
+	_, ok = s.ArgMin()
+	assert.False(t, ok)
+
+	mockDurationValues(s)
+	min, ok := s.Min()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(1), min)
+
+	key, ok := s.ArgMin()
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	// equal values return the lowest key
+	s.store["e"] = 1
+	s.store["0"] = 1
+	key, _ = s.ArgMin()
+	assert.Equal(t, "0", key)
+}
+
+func TestDurationMax(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	_, ok := s.Max()
+	assert.False(t, ok)
+	_, ok = s.ArgMax()
+	assert.False(t, ok)
+
+	mockDurationValues(s)
+	max, ok := s.Max()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(3), max)
+
+	key, ok := s.ArgMax()
+	assert.True(t, ok)
+	assert.Equal(t, "a", key)
+
+	// equal values return the lowest key
+	s.store["e"] = 3
+	s.store["0"] = 3
+	key, _ = s.ArgMax()
+	assert.Equal(t, "0", key)
+}
+
+func TestDurationMean(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	_, ok := s.Mean()
+	assert.False(t, ok)
+
+	mockDurationValues(s)
+	mean, ok := s.Mean()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Nanosecond, mean)
+
+	// truncated to the nanosecond
+	s.store["e"] = 3
+	mean, _ = s.Mean()
+	assert.Equal(t, 2*time.Nanosecond, mean)
+}
+
+func TestDurationCount(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, 0, s.Count(func(key string, value time.Duration) bool { return true }))
+
+	mockDurationValues(s)
+	assert.Equal(t, 3, s.Count(func(key string, value time.Duration) bool { return value >= 2 }))
+	assert.Equal(t, 1, s.Count(func(key string, value time.Duration) bool { return key == "a" }))
+}
+
+func TestDurationReduce(t *testing.T) {
+	s := NewDurationStore()
+
+	sum := func(acc time.Duration, key string, value time.Duration) time.Duration { return acc + value }
+
+	// no keys
+	assert.Equal(t, time.Duration(5), s.Reduce(5, sum))
+
+	mockDurationValues(s)
+	assert.Equal(t, time.Duration(13), s.Reduce(5, sum))
+}
+
+func TestDurationClone(t *testing.T) {
+	s := NewDurationStore()
+	s.store["a"] = time.Second
+	s.store["b"] = time.Minute
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+	assert.Equal(t, 2, c.values.len())
+
+	// clone is independent of the store
+	c.Set("c", time.Second)
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestDurationMergeFrom(t *testing.T) {
+	o := NewDurationStore()
+	o.store["b"] = time.Minute
+	o.store["c"] = time.Minute
+
+	// incoming values win without a conflict func
+	s := NewDurationStore()
+	s.store["a"] = time.Second
+	s.store["b"] = time.Second
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]time.Duration{"a": time.Second, "b": time.Minute, "c": time.Minute}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewDurationStore()
+	s.store["a"] = time.Second
+	s.store["b"] = time.Second
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming time.Duration) time.Duration {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, time.Second, current)
+		assert.Equal(t, time.Minute, incoming)
+		return current
+	})
+	assert.Equal(t, map[string]time.Duration{"a": time.Second, "b": time.Second, "c": time.Minute}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]time.Duration{"b": time.Minute, "c": time.Minute}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestDurationDiff(t *testing.T) {
+	s := NewDurationStore()
+	s.store["a"] = time.Second
+	s.store["b"] = time.Second
+	s.store["c"] = time.Second
+
+	o := NewDurationStore()
+	o.store["b"] = time.Second
+	o.store["c"] = time.Minute
+	o.store["d"] = time.Minute
+	o.store["e"] = time.Minute
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewDurationStore().Diff(NewDurationStore()).Empty())
+}
+
+func TestDurationReplaceAll(t *testing.T) {
+	s := NewDurationStore()
+	s.store["a"] = time.Second
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	values := map[string]time.Duration{"b": time.Minute, "c": time.Second}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+	assert.Equal(t, 2, s.values.len())
+
+	// store holds a copy of the values
+	values["d"] = time.Second
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestDurationSize(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	size := s.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	s.store["a"] = 10 * time.Second
+	s.store["b"] = time.Minute
+
+	size = s.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestDurationMembers(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	mems := s.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	s.store["a"] = 10 * time.Second
+	s.store["b"] = time.Minute
+
+	mems = s.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestDurationSortedMembers(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = 10 * time.Second
+	s.store["AAPL:bid"] = 10 * time.Second
+	s.store["AAPL:ask"] = 10 * time.Second
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestDurationMembersWithPrefix(t *testing.T) {
+	s := NewDurationStore()
+
+	s.store["MSFT:bid"] = 10 * time.Second
+	s.store["AAPL:bid"] = 10 * time.Second
+	s.store["AAPL:ask"] = 10 * time.Second
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestDurationMembersMatching(t *testing.T) {
+	s := NewDurationStore()
+
+	s.store["MSFT:bid"] = 10 * time.Second
+	s.store["AAPL:bid"] = 10 * time.Second
+	s.store["AAPL:ask"] = 10 * time.Second
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestDurationKeyIndex(t *testing.T) {
+	s := NewDurationStore()
+	s.store["foo"] = 10 * time.Second
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", 10*time.Second)
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestDurationScan(t *testing.T) {
+	s := NewDurationStore()
+
+	s.store["MSFT:bid"] = 10 * time.Second
+	s.store["AAPL:bid"] = 10 * time.Second
+	s.store["AAPL:ask"] = 10 * time.Second
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestDurationIsMember(t *testing.T) {
+	s := NewDurationStore()
+
+	// no keys
+	ok := s.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	s.store["foo"] = 10 * time.Second
+
+	ok = s.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestDurationClear(t *testing.T) {
+	s := NewDurationStore()
+
+	s.store["foo"] = 10 * time.Second
+	assert.Equal(t, 1, len(s.store))
+
+	s.Clear()
+	assert.Equal(t, 0, len(s.store))
+}
+
+func TestDurationDigest(t *testing.T) {
+	s := NewDurationStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", time.Second)
+	s.Set("b", time.Minute)
+
+	// write order does not matter
+	o := NewDurationStore()
+	o.Set("b", time.Minute)
+	o.Set("a", time.Second)
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", time.Second)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", time.Minute)
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestDurationEnableDigest(t *testing.T) {
+	s := NewDurationStore()
+	s.Set("a", time.Second)
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", time.Minute)
+	s.Delete("a")
+	o := NewDurationStore()
+	o.Set("b", time.Minute)
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]time.Duration{"a": time.Second})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestDurationSyncFrom(t *testing.T) {
+	s := NewDurationStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), time.Second)
+	}
+	s.Set("b", time.Minute)
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", time.Second)
+	r.Delete("1")
+	r.Set("c", time.Minute)
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestDurationConcurrentGetAndSet(t *testing.T) {
+	s := NewDurationStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Set("foo", 10*time.Second)
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -1,7 +1,16 @@
 package primitivestore
 
 import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
 )
 
 // FFloat32Store is a store of float32s
@@ -9,7 +18,10 @@ import (
 // Embedded sync.Mutex to provide atomic operation ability
 type Float32Store struct {
 	sync.Mutex
-	store map[string]float32
+	store  map[string]float32
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	values *valueIndex     // ordered values, nil unless EnableValueIndex is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
 }
 
 // NewFloat32Store constructs and initializes a new Float32Store
@@ -19,7 +31,20 @@ func NewFloat32Store() *Float32Store {
 }
 
 func (s *Float32Store) set(key string, value float32) {
+	if s.values != nil {
+		if old, ok := s.store[key]; ok {
+			s.values.delete(floatKey(float64(old)), key)
+		}
+		if !math.IsNaN(float64(value)) {
+			s.values.insert(floatKey(float64(value)), key)
+		}
+	}
+
 	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashFloat32(key, s.store[key]))
+	}
 }
 
 // Set stores the given value mapped to the given key
@@ -29,6 +54,25 @@ func (s *Float32Store) Set(key string, value float32) {
 	s.Unlock()
 }
 
+func (s *Float32Store) delete(key string) {
+	v, ok := s.store[key]
+	if !ok {
+		return
+	}
+
+	delete(s.store, key)
+	s.index.Delete(key)
+	s.digest.Delete(key)
+	s.values.delete(floatKey(float64(v)), key)
+}
+
+// Delete removes the given key and its value from the store
+func (s *Float32Store) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
 func (s *Float32Store) get(key string) (float32, bool) {
 	// explictly return second return value
 	v, ok := s.store[key]
@@ -45,6 +89,545 @@ func (s *Float32Store) Get(key string) (float32, bool) {
 	return v, ok
 }
 
+func (s *Float32Store) enableValueIndex() {
+	if s.values != nil {
+		return
+	}
+
+	s.values = newValueIndex()
+	for k, v := range s.store {
+		if !math.IsNaN(float64(v)) {
+			s.values.insert(floatKey(float64(v)), k)
+		}
+	}
+}
+
+// EnableValueIndex maintains an index of the keys ordered by value alongside the store,
+// so value queries no longer scan and sort every value
+// Setting and deleting keys costs an extra O(log n) once enabled
+func (s *Float32Store) EnableValueIndex() {
+	s.Lock()
+	s.enableValueIndex()
+	s.Unlock()
+}
+
+func (s *Float32Store) keysWhere(op Op, value float32) []string {
+	// NaN is never ordered or equal, so only Ne matches it
+	if math.IsNaN(float64(value)) && op != Ne {
+		return make([]string, 0)
+	}
+
+	if s.values != nil {
+		return s.values.where(op, floatKey(float64(value)))
+	}
+
+	ks := make([]scoredKey, 0)
+	for k, v := range s.store {
+		if !math.IsNaN(float64(v)) && op.holds(floatKey(float64(v)), floatKey(float64(value))) {
+			ks = append(ks, scoredKey{floatKey(float64(v)), k})
+		}
+	}
+
+	return sortedKeys(ks)
+}
+
+// KeysWhere returns the keys whose value holds op against the given value, in ascending value order
+// e.g. KeysWhere(Gt, x) returns every key with a value greater than x
+func (s *Float32Store) KeysWhere(op Op, value float32) []string {
+	s.Lock()
+	v := s.keysWhere(op, value)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) keysInRange(lo, hi float32) []string {
+	if math.IsNaN(float64(lo)) || math.IsNaN(float64(hi)) {
+		return make([]string, 0)
+	}
+
+	if s.values != nil {
+		return s.values.inRange(floatKey(float64(lo)), floatKey(float64(hi)))
+	}
+
+	ks := make([]scoredKey, 0)
+	for k, v := range s.store {
+		if v >= lo && v <= hi {
+			ks = append(ks, scoredKey{floatKey(float64(v)), k})
+		}
+	}
+
+	return sortedKeys(ks)
+}
+
+// KeysInRange returns the keys whose value is within lo and hi (inclusive:inclusive), in ascending value order
+func (s *Float32Store) KeysInRange(lo, hi float32) []string {
+	s.Lock()
+	v := s.keysInRange(lo, hi)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) topN(n int, highest bool) []string {
+	if s.values != nil {
+		if highest {
+			return s.values.top(n)
+		}
+		return s.values.bottom(n)
+	}
+
+	ks := make([]scoredKey, 0, len(s.store))
+	for k, v := range s.store {
+		if !math.IsNaN(float64(v)) {
+			ks = append(ks, scoredKey{floatKey(float64(v)), k})
+		}
+	}
+
+	return topKeys(ks, n, highest)
+}
+
+// TopN returns the keys of the n highest values, highest first
+func (s *Float32Store) TopN(n int) []string {
+	s.Lock()
+	v := s.topN(n, true)
+	s.Unlock()
+
+	return v
+}
+
+// BottomN returns the keys of the n lowest values, lowest first
+func (s *Float32Store) BottomN(n int) []string {
+	s.Lock()
+	v := s.topN(n, false)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) sum() float32 {
+	var total float32
+	for _, v := range s.store {
+		total += v
+	}
+
+	return total
+}
+
+// Sum returns the sum of all values in the store
+func (s *Float32Store) Sum() float32 {
+	s.Lock()
+	v := s.sum()
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) argMin() (string, float32, bool) {
+	var (
+		key string
+		min float32
+		ok  bool
+	)
+	for k, v := range s.store {
+		if math.IsNaN(float64(v)) {
+			continue
+		}
+
+		if !ok || v < min || (v == min && k < key) {
+			key, min, ok = k, v, true
+		}
+	}
+
+	return key, min, ok
+}
+
+// Min returns the lowest value in the store, ignoring NaN values
+// returns the value and boolean if the store is not empty
+func (s *Float32Store) Min() (float32, bool) {
+	s.Lock()
+	_, v, ok := s.argMin()
+	s.Unlock()
+
+	return v, ok
+}
+
+// ArgMin returns the key of the lowest value in the store, the lowest key for equal values, ignoring NaN values
+// returns the key and boolean if the store is not empty
+func (s *Float32Store) ArgMin() (string, bool) {
+	s.Lock()
+	k, _, ok := s.argMin()
+	s.Unlock()
+
+	return k, ok
+}
+
+func (s *Float32Store) argMax() (string, float32, bool) {
+	var (
+		key string
+		max float32
+		ok  bool
+	)
+	for k, v := range s.store {
+		if math.IsNaN(float64(v)) {
+			continue
+		}
+
+		if !ok || v > max || (v == max && k < key) {
+			key, max, ok = k, v, true
+		}
+	}
+
+	return key, max, ok
+}
+
+// Max returns the highest value in the store, ignoring NaN values
+// returns the value and boolean if the store is not empty
+func (s *Float32Store) Max() (float32, bool) {
+	s.Lock()
+	_, v, ok := s.argMax()
+	s.Unlock()
+
+	return v, ok
+}
+
+// ArgMax returns the key of the highest value in the store, the lowest key for equal values, ignoring NaN values
+// returns the key and boolean if the store is not empty
+func (s *Float32Store) ArgMax() (string, bool) {
+	s.Lock()
+	k, _, ok := s.argMax()
+	s.Unlock()
+
+	return k, ok
+}
+
+func (s *Float32Store) mean() (float64, bool) {
+	if len(s.store) == 0 {
+		return 0.0, false
+	}
+
+	total := 0.0
+	for _, v := range s.store {
+		total += float64(v)
+	}
+
+	return total / float64(len(s.store)), true
+}
+
+// Mean returns the arithmetic mean of all values in the store
+// returns the mean and boolean if the store is not empty
+func (s *Float32Store) Mean() (float64, bool) {
+	s.Lock()
+	v, ok := s.mean()
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *Float32Store) count(pred func(key string, value float32) bool) int {
+	n := 0
+	for k, v := range s.store {
+		if pred(k, v) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Count returns the number of keys whose key and value satisfy pred
+// pred is called under the store lock, so it must not call back into the store
+func (s *Float32Store) Count(pred func(key string, value float32) bool) int {
+	s.Lock()
+	n := s.count(pred)
+	s.Unlock()
+
+	return n
+}
+
+func (s *Float32Store) reduce(init float32, fn func(acc float32, key string, value float32) float32) float32 {
+	acc := init
+	for k, v := range s.store {
+		acc = fn(acc, k, v)
+	}
+
+	return acc
+}
+
+// Reduce folds every key and value of the store into init with fn, in no particular order
+// The accumulator has the value type of the store, to fold into any other type update a variable captured by fn
+// fn is called under the store lock, so it must not call back into the store
+func (s *Float32Store) Reduce(init float32, fn func(acc float32, key string, value float32) float32) float32 {
+	s.Lock()
+	v := s.reduce(init, fn)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) snapshot() map[string]float32 {
+	m := make(map[string]float32, len(s.store))
+	for k, v := range s.store {
+		m[k] = v
+	}
+
+	return m
+}
+
+func (s *Float32Store) clone() *Float32Store {
+	c := &Float32Store{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.values != nil {
+		c.enableValueIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *Float32Store) Clone() *Float32Store {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *Float32Store) mergeFrom(values map[string]float32, conflictFn func(key string, current, incoming float32) float32) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *Float32Store) MergeFrom(other *Float32Store, conflictFn func(key string, current, incoming float32) float32) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *Float32Store) diff(values map[string]float32) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if v != ov && !(math.IsNaN(float64(v)) && math.IsNaN(float64(ov))) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *Float32Store) Diff(other *Float32Store) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *Float32Store) replaceAll(store map[string]float32) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.values != nil {
+		s.values = nil
+		s.enableValueIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *Float32Store) ReplaceAll(values map[string]float32) {
+	store := make(map[string]float32, len(values))
+	for k, v := range values {
+		store[k] = v
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+func hashFloat32(key string, v float32) uint64 {
+	return merkle.WriteUint64(merkle.Init(key), uint64(math.Float32bits(v)))
+}
+
+func (s *Float32Store) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashFloat32(k, v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled
+func (s *Float32Store) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *Float32Store) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashFloat32(k, v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *Float32Store) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *Float32Store) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashFloat32(k, v))
+	}
+
+	return t
+}
+
+func (s *Float32Store) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string]float32)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *Float32Store) applyEntries(buckets merkle.BucketSet, entries map[string]float32) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			s.delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashFloat32(k, cur) != hashFloat32(k, v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *Float32Store) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string]float32
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *Float32Store) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *Float32Store) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
 func (s *Float32Store) size() int {
 	return len(s.store)
 }
@@ -80,6 +663,118 @@ func (s *Float32Store) Members() []string {
 	return mems
 }
 
+func (s *Float32Store) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *Float32Store) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *Float32Store) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *Float32Store) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *Float32Store) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *Float32Store) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float32Store) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *Float32Store) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
 func (s *Float32Store) isMember(key string) bool {
 	_, ok := s.store[key]
 
@@ -97,6 +792,9 @@ func (s *Float32Store) IsMember(key string) bool {
 
 func (s *Float32Store) clear() {
 	s.store = make(map[string]float32)
+	s.index.Clear()
+	s.digest.Clear()
+	s.values.clear()
 }
 
 // Clear deletes all keys in the store
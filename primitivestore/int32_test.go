@@ -1,6 +1,8 @@
 package primitivestore
 
 import (
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,6 +30,330 @@ func TestInt32Get(t *testing.T) {
 	assert.Equal(t, int32(10), v)
 }
 
+func TestInt32Delete(t *testing.T) {
+	bs := NewInt32Store()
+
+	// key not exist
+	bs.Delete("foo")
+	assert.Equal(t, 0, len(bs.store))
+
+	bs.store["foo"] = 10
+	bs.Delete("foo")
+	_, ok := bs.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	bs.EnableKeyIndex()
+	bs.Set("bar", 10)
+	bs.Delete("bar")
+	assert.Equal(t, []string{}, bs.index.Keys())
+}
+
+func mockInt32Values(bs *Int32Store) {
+	bs.store["a"] = 3
+	bs.store["b"] = 1
+	bs.store["c"] = 2
+	bs.store["d"] = 2
+}
+
+func TestInt32KeysWhere(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, []string{}, bs.KeysWhere(Eq, 2))
+
+	mockInt32Values(bs)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			bs.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d"}, bs.KeysWhere(Eq, 2))
+		assert.Equal(t, []string{"b", "a"}, bs.KeysWhere(Ne, 2))
+		assert.Equal(t, []string{"b"}, bs.KeysWhere(Lt, 2))
+		assert.Equal(t, []string{"b", "c", "d"}, bs.KeysWhere(Le, 2))
+		assert.Equal(t, []string{"a"}, bs.KeysWhere(Gt, 2))
+		assert.Equal(t, []string{"c", "d", "a"}, bs.KeysWhere(Ge, 2))
+		assert.Equal(t, []string{}, bs.KeysWhere(Gt, 3))
+	}
+}
+
+func TestInt32KeysInRange(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, []string{}, bs.KeysInRange(0, 10))
+
+	mockInt32Values(bs)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			bs.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d", "a"}, bs.KeysInRange(2, 3))
+		assert.Equal(t, []string{"b"}, bs.KeysInRange(1, 1))
+		assert.Equal(t, []string{}, bs.KeysInRange(4, 5))
+	}
+}
+
+func TestInt32TopN(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, []string{}, bs.TopN(2))
+
+	mockInt32Values(bs)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			bs.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"a", "d"}, bs.TopN(2))
+		assert.Equal(t, []string{"a", "d", "c", "b"}, bs.TopN(10))
+		assert.Equal(t, []string{}, bs.TopN(0))
+	}
+}
+
+func TestInt32BottomN(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, []string{}, bs.BottomN(2))
+
+	mockInt32Values(bs)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			bs.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b", "c"}, bs.BottomN(2))
+		assert.Equal(t, []string{"b", "c", "d", "a"}, bs.BottomN(10))
+		assert.Equal(t, []string{}, bs.BottomN(-1))
+	}
+}
+
+func TestInt32ValueIndex(t *testing.T) {
+	bs := NewInt32Store()
+	bs.store["a"] = 1
+
+	// existing values are indexed
+	bs.EnableValueIndex()
+	assert.Equal(t, 1, bs.values.len())
+
+	// set and overwrite
+	bs.Set("b", 2)
+	bs.Set("a", 3)
+	assert.Equal(t, 2, bs.values.len())
+	assert.Equal(t, []string{"b", "a"}, bs.values.bottom(10))
+
+	// delete
+	bs.Delete("b")
+	assert.Equal(t, []string{"a"}, bs.values.bottom(10))
+
+	// clear
+	bs.Clear()
+	assert.Equal(t, 0, bs.values.len())
+}
+
+func TestInt32Sum(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, int32(0), bs.Sum())
+
+	mockInt32Values(bs)
+	assert.Equal(t, int32(8), bs.Sum())
+}
+
+func TestInt32Min(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	_, ok := bs.Min()
+	assert.False(t, ok)
+	_, ok = bs.ArgMin()
+	assert.False(t, ok)
+
+	mockInt32Values(bs)
+	min, ok := bs.Min()
+	assert.True(t, ok)
+	assert.Equal(t, int32(1), min)
+
+	key, ok := bs.ArgMin()
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	// equal values return the lowest key
+	bs.store["e"] = 1
+	bs.store["0"] = 1
+	key, _ = bs.ArgMin()
+	assert.Equal(t, "0", key)
+}
+
+func TestInt32Max(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	_, ok := bs.Max()
+	assert.False(t, ok)
+	_, ok = bs.ArgMax()
+	assert.False(t, ok)
+
+	mockInt32Values(bs)
+	max, ok := bs.Max()
+	assert.True(t, ok)
+	assert.Equal(t, int32(3), max)
+
+	key, ok := bs.ArgMax()
+	assert.True(t, ok)
+	assert.Equal(t, "a", key)
+
+	// equal values return the lowest key
+	bs.store["e"] = 3
+	bs.store["0"] = 3
+	key, _ = bs.ArgMax()
+	assert.Equal(t, "0", key)
+}
+
+func TestInt32Mean(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	_, ok := bs.Mean()
+	assert.False(t, ok)
+
+	mockInt32Values(bs)
+	mean, ok := bs.Mean()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, mean)
+}
+
+func TestInt32Count(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, 0, bs.Count(func(key string, value int32) bool { return true }))
+
+	mockInt32Values(bs)
+	assert.Equal(t, 3, bs.Count(func(key string, value int32) bool { return value >= 2 }))
+	assert.Equal(t, 1, bs.Count(func(key string, value int32) bool { return key == "a" }))
+}
+
+func TestInt32Reduce(t *testing.T) {
+	bs := NewInt32Store()
+
+	sum := func(acc int32, key string, value int32) int32 { return acc + value }
+
+	// no keys
+	assert.Equal(t, int32(5), bs.Reduce(5, sum))
+
+	mockInt32Values(bs)
+	assert.Equal(t, int32(13), bs.Reduce(5, sum))
+}
+
+func TestInt32Clone(t *testing.T) {
+	s := NewInt32Store()
+	s.store["a"] = 1
+	s.store["b"] = 2
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+	assert.Equal(t, 2, c.values.len())
+
+	// clone is independent of the store
+	c.Set("c", 1)
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestInt32MergeFrom(t *testing.T) {
+	o := NewInt32Store()
+	o.store["b"] = 2
+	o.store["c"] = 2
+
+	// incoming values win without a conflict func
+	s := NewInt32Store()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]int32{"a": 1, "b": 2, "c": 2}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewInt32Store()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming int32) int32 {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, int32(1), current)
+		assert.Equal(t, int32(2), incoming)
+		return current
+	})
+	assert.Equal(t, map[string]int32{"a": 1, "b": 1, "c": 2}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]int32{"b": 2, "c": 2}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestInt32Diff(t *testing.T) {
+	s := NewInt32Store()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.store["c"] = 1
+
+	o := NewInt32Store()
+	o.store["b"] = 1
+	o.store["c"] = 2
+	o.store["d"] = 2
+	o.store["e"] = 2
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewInt32Store().Diff(NewInt32Store()).Empty())
+}
+
+func TestInt32ReplaceAll(t *testing.T) {
+	s := NewInt32Store()
+	s.store["a"] = 1
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	values := map[string]int32{"b": 2, "c": 1}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+	assert.Equal(t, 2, s.values.len())
+
+	// store holds a copy of the values
+	values["d"] = 1
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestInt32Size(t *testing.T) {
 	bs := NewInt32Store()
 
@@ -58,6 +384,103 @@ func TestInt32Members(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestInt32SortedMembers(t *testing.T) {
+	bs := NewInt32Store()
+
+	// no keys
+	assert.Equal(t, []string{}, bs.SortedMembers())
+
+	bs.store["MSFT:bid"] = 10
+	bs.store["AAPL:bid"] = 10
+	bs.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, bs.SortedMembers())
+
+	// indexed
+	bs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, bs.SortedMembers())
+}
+
+func TestInt32MembersWithPrefix(t *testing.T) {
+	bs := NewInt32Store()
+
+	bs.store["MSFT:bid"] = 10
+	bs.store["AAPL:bid"] = 10
+	bs.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, bs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, bs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	bs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, bs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, bs.MembersWithPrefix("GOOG"))
+}
+
+func TestInt32MembersMatching(t *testing.T) {
+	bs := NewInt32Store()
+
+	bs.store["MSFT:bid"] = 10
+	bs.store["AAPL:bid"] = 10
+	bs.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, bs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, bs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, bs.MembersMatching("GOOG:*"))
+
+	// indexed
+	bs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, bs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, bs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, bs.MembersMatching("GOOG:*"))
+}
+
+func TestInt32KeyIndex(t *testing.T) {
+	bs := NewInt32Store()
+	bs.store["foo"] = 10
+
+	// existing keys are indexed
+	bs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, bs.index.Keys())
+
+	// created keys are indexed
+	bs.Set("bar", 10)
+	assert.Equal(t, []string{"bar", "foo"}, bs.index.Keys())
+
+	// clear
+	bs.Clear()
+	assert.Equal(t, 0, bs.index.Len())
+}
+
+func TestInt32Scan(t *testing.T) {
+	bs := NewInt32Store()
+
+	bs.store["MSFT:bid"] = 10
+	bs.store["AAPL:bid"] = 10
+	bs.store["AAPL:ask"] = 10
+
+	keys, next := bs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = bs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = bs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, bs.index)
+
+	// no keys
+	bs.Clear()
+	keys, next = bs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestInt32IsMember(t *testing.T) {
 	bs := NewInt32Store()
 
@@ -82,6 +505,85 @@ func TestInt32Clear(t *testing.T) {
 	assert.Equal(t, 0, len(bs.store))
 }
 
+func TestInt32Digest(t *testing.T) {
+	s := NewInt32Store()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	// write order does not matter
+	o := NewInt32Store()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", 1)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", 2)
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestInt32EnableDigest(t *testing.T) {
+	s := NewInt32Store()
+	s.Set("a", 1)
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", 2)
+	s.Delete("a")
+	o := NewInt32Store()
+	o.Set("b", 2)
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]int32{"a": 1})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestInt32SyncFrom(t *testing.T) {
+	s := NewInt32Store()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), 1)
+	}
+	s.Set("b", 2)
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", 1)
+	r.Delete("1")
+	r.Set("c", 2)
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestInt32ConcurrentGetAndSet(t *testing.T) {
 	bs := NewInt32Store()
 
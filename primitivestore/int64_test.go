@@ -1,6 +1,9 @@
 package primitivestore
 
 import (
+	"math"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,6 +31,359 @@ func TestInt64Get(t *testing.T) {
 	assert.Equal(t, int64(10), v)
 }
 
+func TestInt64Delete(t *testing.T) {
+	s := NewInt64Store()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = 10
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", 10)
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func mockInt64Values(s *Int64Store) {
+	s.store["a"] = 3
+	s.store["b"] = 1
+	s.store["c"] = 2
+	s.store["d"] = 2
+}
+
+func TestInt64KeysWhere(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysWhere(Eq, 2))
+
+	mockInt64Values(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d"}, s.KeysWhere(Eq, 2))
+		assert.Equal(t, []string{"b", "a"}, s.KeysWhere(Ne, 2))
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Lt, 2))
+		assert.Equal(t, []string{"b", "c", "d"}, s.KeysWhere(Le, 2))
+		assert.Equal(t, []string{"a"}, s.KeysWhere(Gt, 2))
+		assert.Equal(t, []string{"c", "d", "a"}, s.KeysWhere(Ge, 2))
+		assert.Equal(t, []string{}, s.KeysWhere(Gt, 3))
+	}
+}
+
+func TestInt64KeysInRange(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysInRange(0, 10))
+
+	mockInt64Values(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d", "a"}, s.KeysInRange(2, 3))
+		assert.Equal(t, []string{"b"}, s.KeysInRange(1, 1))
+		assert.Equal(t, []string{}, s.KeysInRange(4, 5))
+	}
+}
+
+func TestInt64TopN(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, []string{}, s.TopN(2))
+
+	mockInt64Values(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"a", "d"}, s.TopN(2))
+		assert.Equal(t, []string{"a", "d", "c", "b"}, s.TopN(10))
+		assert.Equal(t, []string{}, s.TopN(0))
+	}
+}
+
+func TestInt64BottomN(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, []string{}, s.BottomN(2))
+
+	mockInt64Values(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b", "c"}, s.BottomN(2))
+		assert.Equal(t, []string{"b", "c", "d", "a"}, s.BottomN(10))
+		assert.Equal(t, []string{}, s.BottomN(-1))
+	}
+}
+
+func TestInt64KeysWhereLarge(t *testing.T) {
+	s := NewInt64Store()
+
+	// beyond 2^53 neighbouring values share a float64
+	s.Set("a", 1<<53)
+	s.Set("b", 1<<53+1)
+	s.Set("c", 1<<53+2)
+	s.Set("max", math.MaxInt64)
+	s.Set("max1", math.MaxInt64-1)
+	s.Set("min", math.MinInt64)
+	s.Set("min1", math.MinInt64+1)
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Eq, 1<<53+1))
+		assert.Equal(t, []string{"c", "max1", "max"}, s.KeysWhere(Gt, 1<<53+1))
+		assert.Equal(t, []string{"min", "min1", "a"}, s.KeysWhere(Lt, 1<<53+1))
+		assert.Equal(t, []string{"b", "c"}, s.KeysInRange(1<<53+1, 1<<53+2))
+		assert.Equal(t, []string{"max"}, s.KeysWhere(Gt, math.MaxInt64-1))
+		assert.Equal(t, []string{"max", "max1"}, s.TopN(2))
+		assert.Equal(t, []string{"min", "min1", "a", "b", "c", "max1", "max"}, s.KeysInRange(math.MinInt64, math.MaxInt64))
+		assert.Equal(t, []string{"min"}, s.KeysWhere(Lt, math.MinInt64+1))
+		assert.Equal(t, []string{"min", "min1"}, s.BottomN(2))
+	}
+}
+
+func TestInt64ValueIndex(t *testing.T) {
+	s := NewInt64Store()
+	s.store["a"] = 1
+
+	// existing values are indexed
+	s.EnableValueIndex()
+	assert.Equal(t, 1, s.values.len())
+
+	// set and overwrite
+	s.Set("b", 2)
+	s.Set("a", 3)
+	assert.Equal(t, 2, s.values.len())
+	assert.Equal(t, []string{"b", "a"}, s.values.bottom(10))
+
+	// delete
+	s.Delete("b")
+	assert.Equal(t, []string{"a"}, s.values.bottom(10))
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.values.len())
+}
+
+func TestInt64Sum(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, int64(0), s.Sum())
+
+	mockInt64Values(s)
+	assert.Equal(t, int64(8), s.Sum())
+}
+
+func TestInt64Min(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	_, ok := s.Min()
+	assert.False(t, ok)
+	_, ok = s.ArgMin()
+	assert.False(t, ok)
+
+	mockInt64Values(s)
+	min, ok := s.Min()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), min)
+
+	key, ok := s.ArgMin()
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	// equal values return the lowest key
+	s.store["e"] = 1
+	s.store["0"] = 1
+	key, _ = s.ArgMin()
+	assert.Equal(t, "0", key)
+}
+
+func TestInt64Max(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	_, ok := s.Max()
+	assert.False(t, ok)
+	_, ok = s.ArgMax()
+	assert.False(t, ok)
+
+	mockInt64Values(s)
+	max, ok := s.Max()
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), max)
+
+	key, ok := s.ArgMax()
+	assert.True(t, ok)
+	assert.Equal(t, "a", key)
+
+	// equal values return the lowest key
+	s.store["e"] = 3
+	s.store["0"] = 3
+	key, _ = s.ArgMax()
+	assert.Equal(t, "0", key)
+}
+
+func TestInt64Mean(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	_, ok := s.Mean()
+	assert.False(t, ok)
+
+	mockInt64Values(s)
+	mean, ok := s.Mean()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, mean)
+}
+
+func TestInt64Count(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, 0, s.Count(func(key string, value int64) bool { return true }))
+
+	mockInt64Values(s)
+	assert.Equal(t, 3, s.Count(func(key string, value int64) bool { return value >= 2 }))
+	assert.Equal(t, 1, s.Count(func(key string, value int64) bool { return key == "a" }))
+}
+
+func TestInt64Reduce(t *testing.T) {
+	s := NewInt64Store()
+
+	sum := func(acc int64, key string, value int64) int64 { return acc + value }
+
+	// no keys
+	assert.Equal(t, int64(5), s.Reduce(5, sum))
+
+	mockInt64Values(s)
+	assert.Equal(t, int64(13), s.Reduce(5, sum))
+}
+
+func TestInt64Clone(t *testing.T) {
+	s := NewInt64Store()
+	s.store["a"] = 1
+	s.store["b"] = 2
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+	assert.Equal(t, 2, c.values.len())
+
+	// clone is independent of the store
+	c.Set("c", 1)
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestInt64MergeFrom(t *testing.T) {
+	o := NewInt64Store()
+	o.store["b"] = 2
+	o.store["c"] = 2
+
+	// incoming values win without a conflict func
+	s := NewInt64Store()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]int64{"a": 1, "b": 2, "c": 2}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewInt64Store()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming int64) int64 {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, int64(1), current)
+		assert.Equal(t, int64(2), incoming)
+		return current
+	})
+	assert.Equal(t, map[string]int64{"a": 1, "b": 1, "c": 2}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]int64{"b": 2, "c": 2}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestInt64Diff(t *testing.T) {
+	s := NewInt64Store()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.store["c"] = 1
+
+	o := NewInt64Store()
+	o.store["b"] = 1
+	o.store["c"] = 2
+	o.store["d"] = 2
+	o.store["e"] = 2
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewInt64Store().Diff(NewInt64Store()).Empty())
+}
+
+func TestInt64ReplaceAll(t *testing.T) {
+	s := NewInt64Store()
+	s.store["a"] = 1
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	values := map[string]int64{"b": 2, "c": 1}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+	assert.Equal(t, 2, s.values.len())
+
+	// store holds a copy of the values
+	values["d"] = 1
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestInt64Size(t *testing.T) {
 	s := NewInt64Store()
 
@@ -58,6 +414,103 @@ func TestInt64Members(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestInt64SortedMembers(t *testing.T) {
+	s := NewInt64Store()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestInt64MembersWithPrefix(t *testing.T) {
+	s := NewInt64Store()
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestInt64MembersMatching(t *testing.T) {
+	s := NewInt64Store()
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestInt64KeyIndex(t *testing.T) {
+	s := NewInt64Store()
+	s.store["foo"] = 10
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", 10)
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestInt64Scan(t *testing.T) {
+	s := NewInt64Store()
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestInt64IsMember(t *testing.T) {
 	s := NewInt64Store()
 
@@ -82,6 +535,85 @@ func TestInt64Clear(t *testing.T) {
 	assert.Equal(t, 0, len(s.store))
 }
 
+func TestInt64Digest(t *testing.T) {
+	s := NewInt64Store()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	// write order does not matter
+	o := NewInt64Store()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", 1)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", 2)
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestInt64EnableDigest(t *testing.T) {
+	s := NewInt64Store()
+	s.Set("a", 1)
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", 2)
+	s.Delete("a")
+	o := NewInt64Store()
+	o.Set("b", 2)
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]int64{"a": 1})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestInt64SyncFrom(t *testing.T) {
+	s := NewInt64Store()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), 1)
+	}
+	s.Set("b", 2)
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", 1)
+	r.Delete("1")
+	r.Set("c", 2)
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestInt64ConcurrentGetAndSet(t *testing.T) {
 	s := NewInt64Store()
 
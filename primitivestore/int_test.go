@@ -1,6 +1,9 @@
 package primitivestore
 
 import (
+	"math"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -28,6 +31,367 @@ func TestIntGet(t *testing.T) {
 	assert.Equal(t, 10, v)
 }
 
+func TestIntDelete(t *testing.T) {
+	s := NewIntStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = 10
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", 10)
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func mockIntValues(s *IntStore) {
+	s.store["a"] = 3
+	s.store["b"] = 1
+	s.store["c"] = 2
+	s.store["d"] = 2
+}
+
+func TestIntKeysWhere(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysWhere(Eq, 2))
+
+	mockIntValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d"}, s.KeysWhere(Eq, 2))
+		assert.Equal(t, []string{"b", "a"}, s.KeysWhere(Ne, 2))
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Lt, 2))
+		assert.Equal(t, []string{"b", "c", "d"}, s.KeysWhere(Le, 2))
+		assert.Equal(t, []string{"a"}, s.KeysWhere(Gt, 2))
+		assert.Equal(t, []string{"c", "d", "a"}, s.KeysWhere(Ge, 2))
+		assert.Equal(t, []string{}, s.KeysWhere(Gt, 3))
+	}
+}
+
+func TestIntKeysInRange(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.KeysInRange(0, 10))
+
+	mockIntValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"c", "d", "a"}, s.KeysInRange(2, 3))
+		assert.Equal(t, []string{"b"}, s.KeysInRange(1, 1))
+		assert.Equal(t, []string{}, s.KeysInRange(4, 5))
+	}
+}
+
+func TestIntTopN(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.TopN(2))
+
+	mockIntValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"a", "d"}, s.TopN(2))
+		assert.Equal(t, []string{"a", "d", "c", "b"}, s.TopN(10))
+		assert.Equal(t, []string{}, s.TopN(0))
+	}
+}
+
+func TestIntBottomN(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.BottomN(2))
+
+	mockIntValues(s)
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b", "c"}, s.BottomN(2))
+		assert.Equal(t, []string{"b", "c", "d", "a"}, s.BottomN(10))
+		assert.Equal(t, []string{}, s.BottomN(-1))
+	}
+}
+
+func TestIntKeysWhereLarge(t *testing.T) {
+	s := NewIntStore()
+
+	// beyond 2^53 neighbouring values share a float64
+	s.Set("a", 1<<53)
+	s.Set("b", 1<<53+1)
+	s.Set("c", 1<<53+2)
+	s.Set("max", math.MaxInt64)
+	s.Set("max1", math.MaxInt64-1)
+	s.Set("min", math.MinInt64)
+	s.Set("min1", math.MinInt64+1)
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			s.EnableValueIndex()
+		}
+
+		assert.Equal(t, []string{"b"}, s.KeysWhere(Eq, 1<<53+1))
+		assert.Equal(t, []string{"c", "max1", "max"}, s.KeysWhere(Gt, 1<<53+1))
+		assert.Equal(t, []string{"min", "min1", "a"}, s.KeysWhere(Lt, 1<<53+1))
+		assert.Equal(t, []string{"b", "c"}, s.KeysInRange(1<<53+1, 1<<53+2))
+		assert.Equal(t, []string{"max"}, s.KeysWhere(Gt, math.MaxInt64-1))
+		assert.Equal(t, []string{"max", "max1"}, s.TopN(2))
+		assert.Equal(t, []string{"min", "min1", "a", "b", "c", "max1", "max"}, s.KeysInRange(math.MinInt64, math.MaxInt64))
+		assert.Equal(t, []string{"min"}, s.KeysWhere(Lt, math.MinInt64+1))
+		assert.Equal(t, []string{"min", "min1"}, s.BottomN(2))
+	}
+}
+
+func TestIntValueIndex(t *testing.T) {
+	s := NewIntStore()
+	s.store["a"] = 1
+
+	// existing values are indexed
+	s.EnableValueIndex()
+	assert.Equal(t, 1, s.values.len())
+
+	// set and overwrite
+	s.Set("b", 2)
+	s.Set("a", 3)
+	assert.Equal(t, 2, s.values.len())
+	assert.Equal(t, []string{"b", "a"}, s.values.bottom(10))
+
+	// delete
+	s.Delete("b")
+	assert.Equal(t, []string{"a"}, s.values.bottom(10))
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.values.len())
+}
+
+func TestIntSum(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, int(0), s.Sum())
+
+	mockIntValues(s)
+	assert.Equal(t, int(8), s.Sum())
+}
+
+func TestIntMin(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	_, ok := s.Min()
+	assert.False(t, ok)
+	_, ok = s.ArgMin()
+	assert.False(t, ok)
+
+	mockIntValues(s)
+	min, ok := s.Min()
+	assert.True(t, ok)
+	assert.Equal(t, int(1), min)
+
+	key, ok := s.ArgMin()
+	assert.True(t, ok)
+	assert.Equal(t, "b", key)
+
+	// equal values return the lowest key
+	s.store["e"] = 1
+	s.store["0"] = 1
+	key, _ = s.ArgMin()
+	assert.Equal(t, "0", key)
+}
+
+func TestIntMax(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	_, ok := s.Max()
+	assert.False(t, ok)
+	_, ok = s.ArgMax()
+	assert.False(t, ok)
+
+	mockIntValues(s)
+	max, ok := s.Max()
+	assert.True(t, ok)
+	assert.Equal(t, int(3), max)
+
+	key, ok := s.ArgMax()
+	assert.True(t, ok)
+	assert.Equal(t, "a", key)
+
+	// equal values return the lowest key
+	s.store["e"] = 3
+	s.store["0"] = 3
+	key, _ = s.ArgMax()
+	assert.Equal(t, "0", key)
+}
+
+func TestIntMean(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	_, ok := s.Mean()
+	assert.False(t, ok)
+
+	mockIntValues(s)
+	mean, ok := s.Mean()
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, mean)
+}
+
+func TestIntCount(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, 0, s.Count(func(key string, value int) bool { return true }))
+
+	mockIntValues(s)
+	assert.Equal(t, 3, s.Count(func(key string, value int) bool { return value >= 2 }))
+	assert.Equal(t, 1, s.Count(func(key string, value int) bool { return key == "a" }))
+}
+
+func TestIntReduce(t *testing.T) {
+	s := NewIntStore()
+
+	sum := func(acc int, key string, value int) int { return acc + value }
+
+	// no keys
+	assert.Equal(t, int(5), s.Reduce(5, sum))
+
+	mockIntValues(s)
+	assert.Equal(t, int(13), s.Reduce(5, sum))
+
+	// fold into another type through a captured variable
+	keys := make([]string, 0)
+	s.Reduce(0, func(acc int, key string, value int) int {
+		keys = append(keys, key)
+		return acc
+	})
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, keys)
+}
+
+func TestIntClone(t *testing.T) {
+	s := NewIntStore()
+	s.store["a"] = 1
+	s.store["b"] = 2
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+	assert.Equal(t, 2, c.values.len())
+
+	// clone is independent of the store
+	c.Set("c", 1)
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestIntMergeFrom(t *testing.T) {
+	o := NewIntStore()
+	o.store["b"] = 2
+	o.store["c"] = 2
+
+	// incoming values win without a conflict func
+	s := NewIntStore()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2, "c": 2}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewIntStore()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming int) int {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, 1, current)
+		assert.Equal(t, 2, incoming)
+		return current
+	})
+	assert.Equal(t, map[string]int{"a": 1, "b": 1, "c": 2}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]int{"b": 2, "c": 2}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestIntDiff(t *testing.T) {
+	s := NewIntStore()
+	s.store["a"] = 1
+	s.store["b"] = 1
+	s.store["c"] = 1
+
+	o := NewIntStore()
+	o.store["b"] = 1
+	o.store["c"] = 2
+	o.store["d"] = 2
+	o.store["e"] = 2
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewIntStore().Diff(NewIntStore()).Empty())
+}
+
+func TestIntReplaceAll(t *testing.T) {
+	s := NewIntStore()
+	s.store["a"] = 1
+	s.EnableKeyIndex()
+	s.EnableValueIndex()
+
+	values := map[string]int{"b": 2, "c": 1}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+	assert.Equal(t, 2, s.values.len())
+
+	// store holds a copy of the values
+	values["d"] = 1
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestIntSize(t *testing.T) {
 	s := NewIntStore()
 
@@ -58,6 +422,103 @@ func TestIntMembers(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestIntSortedMembers(t *testing.T) {
+	s := NewIntStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestIntMembersWithPrefix(t *testing.T) {
+	s := NewIntStore()
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestIntMembersMatching(t *testing.T) {
+	s := NewIntStore()
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestIntKeyIndex(t *testing.T) {
+	s := NewIntStore()
+	s.store["foo"] = 10
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", 10)
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestIntScan(t *testing.T) {
+	s := NewIntStore()
+
+	s.store["MSFT:bid"] = 10
+	s.store["AAPL:bid"] = 10
+	s.store["AAPL:ask"] = 10
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestIntIsMember(t *testing.T) {
 	s := NewIntStore()
 
@@ -82,6 +543,85 @@ func TestIntClear(t *testing.T) {
 	assert.Equal(t, 0, len(s.store))
 }
 
+func TestIntDigest(t *testing.T) {
+	s := NewIntStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	// write order does not matter
+	o := NewIntStore()
+	o.Set("b", 2)
+	o.Set("a", 1)
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", 1)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", 2)
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestIntEnableDigest(t *testing.T) {
+	s := NewIntStore()
+	s.Set("a", 1)
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", 2)
+	s.Delete("a")
+	o := NewIntStore()
+	o.Set("b", 2)
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]int{"a": 1})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestIntSyncFrom(t *testing.T) {
+	s := NewIntStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), 1)
+	}
+	s.Set("b", 2)
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", 1)
+	r.Delete("1")
+	r.Set("c", 2)
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestIntConcurrentGetAndSet(t *testing.T) {
 	s := NewIntStore()
 
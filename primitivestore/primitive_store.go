@@ -10,12 +10,28 @@ type PrimitiveStore interface {
 	// returns the value and boolean if key exists
 	Get(key string) ([]interface{}, bool)
 
+	// Delete removes the given key and its value from the store
+	Delete(key string)
+
 	// Size returns the size of the store
 	Size() int
 
 	// Members returns a list of string keys in the store
 	Members() []string
 
+	// SortedMembers returns a list of string keys in the store in ascending order
+	SortedMembers() []string
+
+	// MembersWithPrefix returns a sorted list of string keys in the store starting with the given prefix
+	MembersWithPrefix(prefix string) []string
+
+	// MembersMatching returns a sorted list of string keys in the store matching the given glob pattern
+	MembersMatching(pattern string) []string
+
+	// Scan returns a batch of at most count string keys in the store matching the given glob pattern,
+	// and the cursor of the next batch, "" once every key has been returned
+	Scan(cursor string, count int, match string) ([]string, string)
+
 	// IsMember checks if the given key is a member of the store
 	IsMember(key string) bool
 
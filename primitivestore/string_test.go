@@ -0,0 +1,397 @@
+package primitivestore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringSet(t *testing.T) {
+	s := NewStringStore()
+
+	s.Set("foo", "bar")
+	assert.Equal(t, "bar", s.store["foo"])
+}
+
+func TestStringGet(t *testing.T) {
+	s := NewStringStore()
+
+	// no key yet
+	v, ok := s.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	s.store["foo"] = "bar"
+	v, ok = s.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, "bar", v)
+}
+
+func TestStringDelete(t *testing.T) {
+	s := NewStringStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = "bar"
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", "bar")
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func TestStringClone(t *testing.T) {
+	s := NewStringStore()
+	s.store["a"] = "foo"
+	s.store["b"] = "bar"
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", "foo")
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestStringMergeFrom(t *testing.T) {
+	o := NewStringStore()
+	o.store["b"] = "bar"
+	o.store["c"] = "bar"
+
+	// incoming values win without a conflict func
+	s := NewStringStore()
+	s.store["a"] = "foo"
+	s.store["b"] = "foo"
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]string{"a": "foo", "b": "bar", "c": "bar"}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewStringStore()
+	s.store["a"] = "foo"
+	s.store["b"] = "foo"
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming string) string {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, "foo", current)
+		assert.Equal(t, "bar", incoming)
+		return current
+	})
+	assert.Equal(t, map[string]string{"a": "foo", "b": "foo", "c": "bar"}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]string{"b": "bar", "c": "bar"}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestStringDiff(t *testing.T) {
+	s := NewStringStore()
+	s.store["a"] = "foo"
+	s.store["b"] = "foo"
+	s.store["c"] = "foo"
+
+	o := NewStringStore()
+	o.store["b"] = "foo"
+	o.store["c"] = "bar"
+	o.store["d"] = "bar"
+	o.store["e"] = "bar"
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewStringStore().Diff(NewStringStore()).Empty())
+}
+
+func TestStringReplaceAll(t *testing.T) {
+	s := NewStringStore()
+	s.store["a"] = "foo"
+	s.EnableKeyIndex()
+
+	values := map[string]string{"b": "bar", "c": "foo"}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = "foo"
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestStringSize(t *testing.T) {
+	s := NewStringStore()
+
+	// no keys
+	size := s.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	s.store["a"] = "bar"
+	s.store["b"] = "baz"
+
+	size = s.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestStringMembers(t *testing.T) {
+	s := NewStringStore()
+
+	// no keys
+	mems := s.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	s.store["a"] = "bar"
+	s.store["b"] = "baz"
+
+	mems = s.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestStringSortedMembers(t *testing.T) {
+	s := NewStringStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = "bar"
+	s.store["AAPL:bid"] = "bar"
+	s.store["AAPL:ask"] = "bar"
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestStringMembersWithPrefix(t *testing.T) {
+	s := NewStringStore()
+
+	s.store["MSFT:bid"] = "bar"
+	s.store["AAPL:bid"] = "bar"
+	s.store["AAPL:ask"] = "bar"
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestStringMembersMatching(t *testing.T) {
+	s := NewStringStore()
+
+	s.store["MSFT:bid"] = "bar"
+	s.store["AAPL:bid"] = "bar"
+	s.store["AAPL:ask"] = "bar"
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestStringKeyIndex(t *testing.T) {
+	s := NewStringStore()
+	s.store["foo"] = "bar"
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", "bar")
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestStringScan(t *testing.T) {
+	s := NewStringStore()
+
+	s.store["MSFT:bid"] = "bar"
+	s.store["AAPL:bid"] = "bar"
+	s.store["AAPL:ask"] = "bar"
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestStringIsMember(t *testing.T) {
+	s := NewStringStore()
+
+	// no keys
+	ok := s.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	s.store["foo"] = "bar"
+
+	ok = s.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestStringClear(t *testing.T) {
+	s := NewStringStore()
+
+	s.store["foo"] = "bar"
+	assert.Equal(t, 1, len(s.store))
+
+	s.Clear()
+	assert.Equal(t, 0, len(s.store))
+}
+
+func TestStringDigest(t *testing.T) {
+	s := NewStringStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", "foo")
+	s.Set("b", "bar")
+
+	// write order does not matter
+	o := NewStringStore()
+	o.Set("b", "bar")
+	o.Set("a", "foo")
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", "foo")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", "bar")
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestStringEnableDigest(t *testing.T) {
+	s := NewStringStore()
+	s.Set("a", "foo")
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", "bar")
+	s.Delete("a")
+	o := NewStringStore()
+	o.Set("b", "bar")
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]string{"a": "foo"})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestStringSyncFrom(t *testing.T) {
+	s := NewStringStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), "foo")
+	}
+	s.Set("b", "bar")
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", "foo")
+	r.Delete("1")
+	r.Set("c", "bar")
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestStringConcurrentGetAndSet(t *testing.T) {
+	s := NewStringStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Set("foo", "bar")
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,399 @@
+package primitivestore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mockTime = time.Date(2018, 6, 1, 9, 30, 0, 0, time.UTC)
+
+func TestTimeSet(t *testing.T) {
+	s := NewTimeStore()
+
+	s.Set("foo", mockTime)
+	assert.Equal(t, mockTime, s.store["foo"])
+}
+
+func TestTimeGet(t *testing.T) {
+	s := NewTimeStore()
+
+	// no key yet
+	v, ok := s.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	s.store["foo"] = mockTime
+	v, ok = s.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, mockTime, v)
+}
+
+func TestTimeDelete(t *testing.T) {
+	s := NewTimeStore()
+
+	// key not exist
+	s.Delete("foo")
+	assert.Equal(t, 0, len(s.store))
+
+	s.store["foo"] = mockTime
+	s.Delete("foo")
+	_, ok := s.store["foo"]
+	assert.False(t, ok)
+
+	// key index
+	s.EnableKeyIndex()
+	s.Set("bar", mockTime)
+	s.Delete("bar")
+	assert.Equal(t, []string{}, s.index.Keys())
+}
+
+func TestTimeClone(t *testing.T) {
+	s := NewTimeStore()
+	s.store["a"] = mockTime
+	s.store["b"] = mockTime.Add(time.Hour)
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockTime)
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+}
+
+func TestTimeMergeFrom(t *testing.T) {
+	o := NewTimeStore()
+	o.store["b"] = mockTime.Add(time.Hour)
+	o.store["c"] = mockTime.Add(time.Hour)
+
+	// incoming values win without a conflict func
+	s := NewTimeStore()
+	s.store["a"] = mockTime
+	s.store["b"] = mockTime
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string]time.Time{"a": mockTime, "b": mockTime.Add(time.Hour), "c": mockTime.Add(time.Hour)}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewTimeStore()
+	s.store["a"] = mockTime
+	s.store["b"] = mockTime
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming time.Time) time.Time {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockTime, current)
+		assert.Equal(t, mockTime.Add(time.Hour), incoming)
+		return current
+	})
+	assert.Equal(t, map[string]time.Time{"a": mockTime, "b": mockTime, "c": mockTime.Add(time.Hour)}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string]time.Time{"b": mockTime.Add(time.Hour), "c": mockTime.Add(time.Hour)}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestTimeDiff(t *testing.T) {
+	s := NewTimeStore()
+	s.store["a"] = mockTime
+	s.store["b"] = mockTime
+	s.store["c"] = mockTime
+
+	o := NewTimeStore()
+	o.store["b"] = mockTime
+	o.store["c"] = mockTime.Add(time.Hour)
+	o.store["d"] = mockTime.Add(time.Hour)
+	o.store["e"] = mockTime.Add(time.Hour)
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewTimeStore().Diff(NewTimeStore()).Empty())
+}
+
+func TestTimeReplaceAll(t *testing.T) {
+	s := NewTimeStore()
+	s.store["a"] = mockTime
+	s.EnableKeyIndex()
+
+	values := map[string]time.Time{"b": mockTime.Add(time.Hour), "c": mockTime}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockTime
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestTimeSize(t *testing.T) {
+	s := NewTimeStore()
+
+	// no keys
+	size := s.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	s.store["a"] = mockTime
+	s.store["b"] = mockTime.Add(time.Hour)
+
+	size = s.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestTimeMembers(t *testing.T) {
+	s := NewTimeStore()
+
+	// no keys
+	mems := s.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	s.store["a"] = mockTime
+	s.store["b"] = mockTime.Add(time.Hour)
+
+	mems = s.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestTimeSortedMembers(t *testing.T) {
+	s := NewTimeStore()
+
+	// no keys
+	assert.Equal(t, []string{}, s.SortedMembers())
+
+	s.store["MSFT:bid"] = mockTime
+	s.store["AAPL:bid"] = mockTime
+	s.store["AAPL:ask"] = mockTime
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, s.SortedMembers())
+}
+
+func TestTimeMembersWithPrefix(t *testing.T) {
+	s := NewTimeStore()
+
+	s.store["MSFT:bid"] = mockTime
+	s.store["AAPL:bid"] = mockTime
+	s.store["AAPL:ask"] = mockTime
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, s.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, s.MembersWithPrefix("GOOG"))
+}
+
+func TestTimeMembersMatching(t *testing.T) {
+	s := NewTimeStore()
+
+	s.store["MSFT:bid"] = mockTime
+	s.store["AAPL:bid"] = mockTime
+	s.store["AAPL:ask"] = mockTime
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+
+	// indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, s.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, s.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, s.MembersMatching("GOOG:*"))
+}
+
+func TestTimeKeyIndex(t *testing.T) {
+	s := NewTimeStore()
+	s.store["foo"] = mockTime
+
+	// existing keys are indexed
+	s.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, s.index.Keys())
+
+	// created keys are indexed
+	s.Set("bar", mockTime)
+	assert.Equal(t, []string{"bar", "foo"}, s.index.Keys())
+
+	// clear
+	s.Clear()
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestTimeScan(t *testing.T) {
+	s := NewTimeStore()
+
+	s.store["MSFT:bid"] = mockTime
+	s.store["AAPL:bid"] = mockTime
+	s.store["AAPL:ask"] = mockTime
+
+	keys, next := s.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = s.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = s.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, s.index)
+
+	// no keys
+	s.Clear()
+	keys, next = s.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestTimeIsMember(t *testing.T) {
+	s := NewTimeStore()
+
+	// no keys
+	ok := s.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	s.store["foo"] = mockTime
+
+	ok = s.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestTimeClear(t *testing.T) {
+	s := NewTimeStore()
+
+	s.store["foo"] = mockTime
+	assert.Equal(t, 1, len(s.store))
+
+	s.Clear()
+	assert.Equal(t, 0, len(s.store))
+}
+
+func TestTimeDigest(t *testing.T) {
+	s := NewTimeStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockTime)
+	s.Set("b", mockTime.Add(time.Hour))
+
+	// write order does not matter
+	o := NewTimeStore()
+	o.Set("b", mockTime.Add(time.Hour))
+	o.Set("a", mockTime)
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Set("b", mockTime)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", mockTime.Add(time.Hour))
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Delete("a")
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestTimeEnableDigest(t *testing.T) {
+	s := NewTimeStore()
+	s.Set("a", mockTime)
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// maintained by writes
+	s.Set("b", mockTime.Add(time.Hour))
+	s.Delete("a")
+	o := NewTimeStore()
+	o.Set("b", mockTime.Add(time.Hour))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string]time.Time{"a": mockTime})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestTimeSyncFrom(t *testing.T) {
+	s := NewTimeStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockTime)
+	}
+	s.Set("b", mockTime.Add(time.Hour))
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Set("b", mockTime)
+	r.Delete("1")
+	r.Set("c", mockTime.Add(time.Hour))
+
+	for _, want := range []int{3, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestTimeConcurrentGetAndSet(t *testing.T) {
+	s := NewTimeStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Set("foo", mockTime)
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			s.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
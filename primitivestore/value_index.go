@@ -0,0 +1,212 @@
+package primitivestore
+
+import (
+	"sort"
+
+	"github.com/blacklabcapital/safestore/internal/skiplist"
+)
+
+// Op is a comparison of stored values against a given value
+type Op int
+
+const (
+	// Eq matches values equal to the given value
+	Eq Op = iota
+	// Ne matches values not equal to the given value
+	Ne
+	// Lt matches values less than the given value
+	Lt
+	// Le matches values less than or equal to the given value
+	Le
+	// Gt matches values greater than the given value
+	Gt
+	// Ge matches values greater than or equal to the given value
+	Ge
+)
+
+// holds checks if the comparison of the order keys a against b is true
+func (op Op) holds(a, b uint64) bool {
+	switch op {
+	case Eq:
+		return a == b
+	case Ne:
+		return a != b
+	case Lt:
+		return a < b
+	case Le:
+		return a <= b
+	case Gt:
+		return a > b
+	case Ge:
+		return a >= b
+	}
+
+	return false
+}
+
+// Values are indexed and compared by order key, a uint64 that orders and compares equal as the value does,
+// so integers beyond 2^53 keep their exact order
+// Unsigned values are their own order key
+
+func boolKey(v bool) uint64 {
+	if v {
+		return 1
+	}
+
+	return 0
+}
+
+func int64Key(v int64) uint64 {
+	return uint64(v) ^ 1<<63
+}
+
+// floatKey returns the order key of a float, NaN values must not be indexed or compared
+func floatKey(v float64) uint64 {
+	return skiplist.FloatKey(v)
+}
+
+// valueIndex keeps the keys of a store ordered by value, then key for equal values
+// A nil valueIndex is a disabled index and ignores updates
+type valueIndex struct {
+	list *skiplist.List
+}
+
+func newValueIndex() *valueIndex {
+	return &valueIndex{list: skiplist.New()}
+}
+
+func (ix *valueIndex) insert(value uint64, key string) {
+	if ix == nil {
+		return
+	}
+
+	ix.list.InsertKey(value, key)
+}
+
+func (ix *valueIndex) delete(value uint64, key string) {
+	if ix == nil {
+		return
+	}
+
+	ix.list.DeleteKey(value, key)
+}
+
+func (ix *valueIndex) clear() {
+	if ix == nil {
+		return
+	}
+
+	ix.list = skiplist.New()
+}
+
+func (ix *valueIndex) len() int {
+	if ix == nil {
+		return 0
+	}
+
+	return ix.list.Len()
+}
+
+// where returns the keys whose value holds op against value, in ascending value order
+func (ix *valueIndex) where(op Op, value uint64) []string {
+	keys := make([]string, 0)
+
+	var n *skiplist.Node
+	switch op {
+	case Eq, Ge:
+		n = ix.list.SeekKey(value)
+	case Gt:
+		n = ix.list.SeekKey(value)
+		for n != nil && n.Key() == value {
+			n = n.Next()
+		}
+	default:
+		n = ix.list.First()
+	}
+
+	for ; n != nil; n = n.Next() {
+		if op.holds(n.Key(), value) {
+			keys = append(keys, n.Member())
+		} else if op != Ne {
+			// every other op matches a contiguous run of values
+			break
+		}
+	}
+
+	return keys
+}
+
+// inRange returns the keys whose value is within lo and hi (inclusive:inclusive), in ascending value order
+func (ix *valueIndex) inRange(lo, hi uint64) []string {
+	keys := make([]string, 0)
+	for n := ix.list.SeekKey(lo); n != nil && n.Key() <= hi; n = n.Next() {
+		keys = append(keys, n.Member())
+	}
+
+	return keys
+}
+
+// top returns the keys of the n highest values, highest first
+func (ix *valueIndex) top(n int) []string {
+	keys := make([]string, 0)
+	for node := ix.list.Last(); node != nil && len(keys) < n; node = node.Prev() {
+		keys = append(keys, node.Member())
+	}
+
+	return keys
+}
+
+// bottom returns the keys of the n lowest values, lowest first
+func (ix *valueIndex) bottom(n int) []string {
+	keys := make([]string, 0)
+	for node := ix.list.First(); node != nil && len(keys) < n; node = node.Next() {
+		keys = append(keys, node.Member())
+	}
+
+	return keys
+}
+
+// scoredKey is a key and the order key of its value, used to answer value queries of unindexed stores
+type scoredKey struct {
+	value uint64
+	key   string
+}
+
+// sortedKeys returns the keys in the same order as a valueIndex
+func sortedKeys(ks []scoredKey) []string {
+	sort.Slice(ks, func(i, j int) bool {
+		if ks[i].value != ks[j].value {
+			return ks[i].value < ks[j].value
+		}
+		return ks[i].key < ks[j].key
+	})
+
+	keys := make([]string, len(ks))
+	for i, k := range ks {
+		keys[i] = k.key
+	}
+
+	return keys
+}
+
+// topKeys returns the keys of the n highest values highest first, or lowest first if not reverse
+func topKeys(ks []scoredKey, n int, reverse bool) []string {
+	keys := sortedKeys(ks)
+	if n < 0 {
+		n = 0
+	}
+	if n > len(keys) {
+		n = len(keys)
+	}
+
+	if !reverse {
+		return keys[:n]
+	}
+
+	out := make([]string, n)
+	for i := range out {
+		out[i] = keys[len(keys)-1-i]
+	}
+
+	return out
+}
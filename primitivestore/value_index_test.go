@@ -0,0 +1,123 @@
+package primitivestore
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockValueIndex() *valueIndex {
+	ix := newValueIndex()
+	ix.insert(3, "a")
+	ix.insert(1, "b")
+	ix.insert(2, "c")
+	ix.insert(2, "d")
+
+	return ix
+}
+
+func TestOpHolds(t *testing.T) {
+	assert.True(t, Eq.holds(1, 1))
+	assert.False(t, Eq.holds(1, 2))
+	assert.True(t, Ne.holds(1, 2))
+	assert.True(t, Lt.holds(1, 2))
+	assert.False(t, Lt.holds(2, 2))
+	assert.True(t, Le.holds(2, 2))
+	assert.True(t, Gt.holds(3, 2))
+	assert.False(t, Gt.holds(2, 2))
+	assert.True(t, Ge.holds(2, 2))
+	assert.False(t, Op(42).holds(1, 1))
+}
+
+func TestOrderKeys(t *testing.T) {
+	assert.True(t, boolKey(false) < boolKey(true))
+
+	ints := []int64{math.MinInt64, math.MinInt64 + 1, -1 << 53, -1, 0, 1, 1 << 53, 1<<53 + 1, math.MaxInt64 - 1, math.MaxInt64}
+	for i := 1; i < len(ints); i++ {
+		assert.True(t, int64Key(ints[i-1]) < int64Key(ints[i]))
+	}
+
+	floats := []float64{math.Inf(-1), -1.5, 0, 1.5, math.Inf(1)}
+	for i := 1; i < len(floats); i++ {
+		assert.True(t, floatKey(floats[i-1]) < floatKey(floats[i]))
+	}
+	assert.Equal(t, floatKey(0), floatKey(math.Copysign(0, -1)))
+}
+
+func TestValueIndexInsertDelete(t *testing.T) {
+	ix := mockValueIndex()
+
+	// unknown key
+	ix.delete(3, "e")
+	assert.Equal(t, 4, ix.len())
+
+	ix.delete(2, "c")
+	assert.Equal(t, 3, ix.len())
+	assert.Equal(t, []string{"b", "d", "a"}, ix.bottom(10))
+
+	ix.clear()
+	assert.Equal(t, 0, ix.len())
+}
+
+func TestValueIndexNil(t *testing.T) {
+	var ix *valueIndex
+
+	// disabled index ignores updates
+	ix.insert(1, "a")
+	ix.delete(1, "a")
+	ix.clear()
+	assert.Equal(t, 0, ix.len())
+}
+
+func TestValueIndexWhere(t *testing.T) {
+	ix := mockValueIndex()
+
+	assert.Equal(t, []string{"c", "d"}, ix.where(Eq, 2))
+	assert.Equal(t, []string{"b", "a"}, ix.where(Ne, 2))
+	assert.Equal(t, []string{"b"}, ix.where(Lt, 2))
+	assert.Equal(t, []string{"b", "c", "d"}, ix.where(Le, 2))
+	assert.Equal(t, []string{"a"}, ix.where(Gt, 2))
+	assert.Equal(t, []string{"c", "d", "a"}, ix.where(Ge, 2))
+
+	// no matches
+	assert.Equal(t, []string{}, ix.where(Eq, 5))
+	assert.Equal(t, []string{}, ix.where(Gt, 3))
+	assert.Equal(t, []string{}, ix.where(Lt, 1))
+}
+
+func TestValueIndexInRange(t *testing.T) {
+	ix := mockValueIndex()
+
+	assert.Equal(t, []string{"c", "d", "a"}, ix.inRange(2, 3))
+	assert.Equal(t, []string{"b"}, ix.inRange(0, 1))
+	assert.Equal(t, []string{}, ix.inRange(4, 5))
+	assert.Equal(t, []string{}, ix.inRange(3, 2))
+}
+
+func TestValueIndexTopBottom(t *testing.T) {
+	ix := mockValueIndex()
+
+	assert.Equal(t, []string{"a", "d"}, ix.top(2))
+	assert.Equal(t, []string{"a", "d", "c", "b"}, ix.top(10))
+	assert.Equal(t, []string{"b", "c"}, ix.bottom(2))
+	assert.Equal(t, []string{}, ix.top(0))
+	assert.Equal(t, []string{}, ix.bottom(-1))
+}
+
+func TestSortedKeys(t *testing.T) {
+	ks := []scoredKey{{3, "a"}, {1, "b"}, {2, "d"}, {2, "c"}}
+	assert.Equal(t, []string{"b", "c", "d", "a"}, sortedKeys(ks))
+}
+
+func TestTopKeys(t *testing.T) {
+	mock := func() []scoredKey {
+		return []scoredKey{{3, "a"}, {1, "b"}, {2, "d"}, {2, "c"}}
+	}
+
+	// same order as the index
+	assert.Equal(t, mockValueIndex().top(2), topKeys(mock(), 2, true))
+	assert.Equal(t, mockValueIndex().top(10), topKeys(mock(), 10, true))
+	assert.Equal(t, mockValueIndex().bottom(2), topKeys(mock(), 2, false))
+	assert.Equal(t, []string{}, topKeys(mock(), -1, true))
+}
@@ -0,0 +1,282 @@
+package replication
+
+import (
+	"context"
+	"encoding/gob"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMinBackoff is the first delay before a follower reconnects
+	DefaultMinBackoff = 50 * time.Millisecond
+	// DefaultMaxBackoff is the longest delay between follower reconnects
+	DefaultMaxBackoff = 5 * time.Second
+	// DefaultSnapshotTimeout is the longest a follower waits for the next byte of a snapshot
+	DefaultSnapshotTimeout = time.Minute
+)
+
+// FollowerStats describes the replication state of a follower
+type FollowerStats struct {
+	// Seq is the sequence number of the last mutation applied to the replicas
+	Seq uint64
+	// LeaderSeq is the last sequence number the leader reported
+	LeaderSeq uint64
+	// Lag is the number of mutations written to the leader but not yet applied by the follower
+	Lag uint64
+	// Connected checks if the follower is connected to the leader
+	Connected bool
+	// Reconnects is the number of times the follower lost its connection and dialled again
+	Reconnects int
+	// Snapshots is the number of full snapshots loaded, resuming from the log does not load one
+	Snapshots int
+	// LastMessage is when the follower last heard from the leader
+	LastMessage time.Time
+	// LastError is the error that ended the last connection, nil if none has ended
+	LastError error
+}
+
+// Follower mirrors the stores of a leader into local read-only replicas
+// Embedded sync.Mutex to provide atomic operation ability
+type Follower struct {
+	sync.Mutex
+	// Heartbeat must match the leader heartbeat, a leader silent for three intervals is treated as gone
+	Heartbeat time.Duration
+	// MinBackoff and MaxBackoff bound the delay between reconnects, which doubles after each failed attempt
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// SnapshotTimeout replaces the heartbeat timeout while the leader builds and sends a snapshot,
+	// which may take longer than three heartbeats for large stores
+	SnapshotTimeout time.Duration
+
+	addr     string
+	replicas map[string]Store
+	running  bool
+	epoch    int64
+	stats    FollowerStats
+}
+
+// NewFollower constructs and initializes a new Follower of the leader listening on addr
+// Always use this function when creating a new Follower
+func NewFollower(addr string) *Follower {
+	return &Follower{
+		Heartbeat:       DefaultHeartbeat,
+		MinBackoff:      DefaultMinBackoff,
+		MaxBackoff:      DefaultMaxBackoff,
+		SnapshotTimeout: DefaultSnapshotTimeout,
+		addr:            addr,
+		replicas:        make(map[string]Store),
+	}
+}
+
+func (f *Follower) register(name string, s Store) error {
+	f.Lock()
+	if f.running {
+		f.Unlock()
+		return ErrFollowerRunning
+	}
+
+	if _, ok := f.replicas[name]; ok {
+		f.Unlock()
+		return ErrStoreExists
+	}
+
+	f.replicas[name] = s
+	f.Unlock()
+
+	return nil
+}
+
+func (f *Follower) statsCopy() FollowerStats {
+	st := f.stats
+	if st.LeaderSeq > st.Seq {
+		st.Lag = st.LeaderSeq - st.Seq
+	}
+
+	return st
+}
+
+// Stats returns the replication state and lag of the follower
+func (f *Follower) Stats() FollowerStats {
+	f.Lock()
+	st := f.statsCopy()
+	f.Unlock()
+
+	return st
+}
+
+// Seq returns the sequence number of the last mutation applied to the replicas
+func (f *Follower) Seq() uint64 {
+	f.Lock()
+	seq := f.stats.Seq
+	f.Unlock()
+
+	return seq
+}
+
+// Run connects to the leader and applies its writes to the replicas until ctx is done
+// Lost connections are dialled again, resuming after the last applied mutation when the leader still holds it
+// Always returns the ctx error
+func (f *Follower) Run(ctx context.Context) error {
+	f.Lock()
+	if f.running {
+		f.Unlock()
+		return ErrFollowerRunning
+	}
+	f.running = true
+	f.Unlock()
+
+	backoff := f.MinBackoff
+	for {
+		applied, err := f.session(ctx)
+
+		f.Lock()
+		f.stats.Connected = false
+		if ctx.Err() != nil {
+			f.running = false
+			f.Unlock()
+			return ctx.Err()
+		}
+		f.stats.LastError = err
+		f.stats.Reconnects++
+		f.Unlock()
+
+		// only back off while attempts keep failing
+		if applied {
+			backoff = f.MinBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			f.Lock()
+			f.running = false
+			f.Unlock()
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > f.MaxBackoff {
+			backoff = f.MaxBackoff
+		}
+	}
+}
+
+// session runs a single connection to the leader
+// Returns whether any message was applied, and the error that ended the connection
+func (f *Follower) session(ctx context.Context) (bool, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", f.addr)
+	if err != nil {
+		return false, err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		conn.Close()
+	}()
+
+	r := &idleReader{conn: conn, idle: 3 * f.Heartbeat}
+	enc := gob.NewEncoder(conn)
+	dec := gob.NewDecoder(r)
+
+	f.Lock()
+	h := hello{Epoch: f.epoch, Seq: f.stats.Seq}
+	f.Unlock()
+
+	if err := enc.Encode(&h); err != nil {
+		return false, err
+	}
+
+	applied := false
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return applied, err
+		}
+
+		r.idle = 3 * f.Heartbeat
+		if msg.Kind == msgSnapshotStart && f.SnapshotTimeout > r.idle {
+			r.idle = f.SnapshotTimeout
+		}
+
+		f.Lock()
+		err := f.apply(&msg)
+		seq := f.stats.Seq
+		f.Unlock()
+
+		if err != nil {
+			return applied, err
+		}
+		applied = true
+
+		if err := enc.Encode(&ack{Seq: seq}); err != nil {
+			return applied, err
+		}
+	}
+}
+
+// idleReader reads a connection that times out once nothing has arrived for idle
+// The deadline is renewed on every read, so a large message still arriving is never cut off
+type idleReader struct {
+	conn net.Conn
+	idle time.Duration
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.conn.SetReadDeadline(time.Now().Add(r.idle))
+
+	return r.conn.Read(p)
+}
+
+func (f *Follower) apply(msg *message) error {
+	f.stats.Connected = true
+	f.stats.LastMessage = time.Now()
+	if msg.LeaderSeq > f.stats.LeaderSeq || msg.Epoch != f.epoch {
+		f.stats.LeaderSeq = msg.LeaderSeq
+	}
+
+	switch msg.Kind {
+	case msgSnapshot:
+		for name, s := range f.replicas {
+			if err := s.restore(msg.Snapshot[name]); err != nil {
+				f.reset()
+				return err
+			}
+		}
+
+		f.epoch = msg.Epoch
+		f.stats.Seq = msg.Seq
+		f.stats.LeaderSeq = msg.LeaderSeq
+		f.stats.Snapshots++
+	case msgMutation:
+		m := msg.Mutation
+		if msg.Epoch != f.epoch || m == nil || m.Seq != f.stats.Seq+1 {
+			f.reset()
+			return ErrSequenceGap
+		}
+
+		// mutations of stores without a replica are skipped
+		if s, ok := f.replicas[m.Store]; ok {
+			if err := s.apply(m); err != nil {
+				f.reset()
+				return err
+			}
+		}
+
+		f.stats.Seq = m.Seq
+	}
+
+	return nil
+}
+
+// reset forgets the replication position, so the next connection loads a fresh snapshot
+func (f *Follower) reset() {
+	f.epoch = 0
+	f.stats.Seq = 0
+}
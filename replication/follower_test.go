@@ -0,0 +1,379 @@
+package replication
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/blacklabcapital/safestore/primitivestore"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitFor polls cond until it holds, failing the test after 5 seconds
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// serveLeader serves l on a loopback listener and returns its address
+func serveLeader(t *testing.T, l *Leader) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go l.Serve(ln)
+
+	return ln.Addr().String()
+}
+
+// runFollower runs f until the returned stop func is called
+func runFollower(f *Follower) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.Run(ctx)
+		close(done)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func mockFollower(addr string) (*Follower, Float64Reader, Float64SReader) {
+	f := NewFollower(addr)
+	f.Heartbeat = 50 * time.Millisecond
+	f.MinBackoff = 5 * time.Millisecond
+	f.MaxBackoff = 50 * time.Millisecond
+	px, _ := f.Float64Store("px")
+	series, _ := f.Float64SStore("series")
+
+	return f, px, series
+}
+
+func TestNewFollower(t *testing.T) {
+	f := NewFollower("127.0.0.1:1")
+	assert.Equal(t, DefaultHeartbeat, f.Heartbeat)
+	assert.Equal(t, DefaultMinBackoff, f.MinBackoff)
+	assert.Equal(t, DefaultMaxBackoff, f.MaxBackoff)
+	assert.Equal(t, DefaultSnapshotTimeout, f.SnapshotTimeout)
+	assert.Equal(t, FollowerStats{}, f.Stats())
+}
+
+func TestReplicationSnapshotAndStream(t *testing.T) {
+	l, px, _ := mockLeader(64)
+	l.Heartbeat = 50 * time.Millisecond
+	defer l.Close()
+
+	// written before the follower connects, arrives in the snapshot
+	px.Set("untracked", 9)
+	l.Set("px", "a", 1.0)
+	l.Append("series", "a", []float64{1, 2, 3})
+
+	f, rpx, rseries := mockFollower(serveLeader(t, l))
+	stop := runFollower(f)
+	defer stop()
+
+	waitFor(t, func() bool { return f.Seq() == 2 })
+	v, ok := rpx.Get("untracked")
+	assert.True(t, ok)
+	assert.Equal(t, 9.0, v)
+	st := f.Stats()
+	assert.True(t, st.Connected)
+	assert.Equal(t, 1, st.Snapshots)
+	assert.Equal(t, uint64(0), st.Lag)
+
+	// later writes stream in order
+	l.Set("px", "b", 2.0)
+	l.Delete("px", "a")
+	l.Append("series", "a", []float64{4})
+	l.SetIdx("series", "a", 0, 0.5)
+	l.Clear("series")
+	l.Set("series", "b", []float64{7})
+
+	waitFor(t, func() bool { return f.Seq() == 8 })
+	assert.Equal(t, []string{"b", "untracked"}, rpx.SortedMembers())
+	s, _ := rseries.Get("b")
+	assert.Equal(t, []float64{7}, s)
+	assert.False(t, rseries.IsMember("a"))
+	assert.Equal(t, 1, f.Stats().Snapshots)
+
+	// the leader sees the follower catch up
+	waitFor(t, func() bool {
+		fs := l.Followers()
+		return len(fs) == 1 && fs[0].Acked == 8
+	})
+	assert.Equal(t, uint64(0), l.Followers()[0].Lag)
+}
+
+func TestReplicationSlowSnapshot(t *testing.T) {
+	l, _, _ := mockLeader(64)
+	l.Heartbeat = 10 * time.Millisecond
+	defer l.Close()
+	l.Set("px", "a", 1.0)
+
+	// the snapshot takes many heartbeats to arrive
+	release := make(chan struct{})
+	l.sendingSnapshot = func() { <-release }
+
+	f, rpx, _ := mockFollower(serveLeader(t, l))
+	f.Heartbeat = 10 * time.Millisecond
+	stop := runFollower(f)
+	defer stop()
+
+	waitFor(t, func() bool { return f.Stats().Connected })
+	time.Sleep(20 * f.Heartbeat)
+	close(release)
+
+	waitFor(t, func() bool { return f.Seq() == 1 })
+	assert.Equal(t, []string{"a"}, rpx.SortedMembers())
+	st := f.Stats()
+	assert.Equal(t, 1, st.Snapshots)
+	assert.Equal(t, 0, st.Reconnects)
+
+	// writes stream once the snapshot is loaded
+	l.Set("px", "b", 2.0)
+	waitFor(t, func() bool { return f.Seq() == 2 })
+}
+
+func TestReplicationResume(t *testing.T) {
+	l, _, _ := mockLeader(64)
+	l.Heartbeat = 50 * time.Millisecond
+	defer l.Close()
+
+	addr := serveLeader(t, l)
+	l.Set("px", "a", 1.0)
+
+	f, rpx, _ := mockFollower(addr)
+	stop := runFollower(f)
+	waitFor(t, func() bool { return f.Seq() == 1 })
+	stop()
+	assert.False(t, f.Stats().Connected)
+
+	// written while the follower is away
+	l.Set("px", "b", 2.0)
+	l.Set("px", "c", 3.0)
+
+	stop = runFollower(f)
+	defer stop()
+	waitFor(t, func() bool { return f.Seq() == 3 })
+	assert.Equal(t, []string{"a", "b", "c"}, rpx.SortedMembers())
+
+	// resumed from the log without a second snapshot
+	assert.Equal(t, 1, f.Stats().Snapshots)
+}
+
+func TestReplicationResumeBeyondBacklog(t *testing.T) {
+	l, _, _ := mockLeader(2)
+	l.Heartbeat = 50 * time.Millisecond
+	defer l.Close()
+
+	addr := serveLeader(t, l)
+	l.Set("px", "a", 1.0)
+
+	f, rpx, _ := mockFollower(addr)
+	stop := runFollower(f)
+	waitFor(t, func() bool { return f.Seq() == 1 })
+	stop()
+
+	l.Delete("px", "a")
+	for _, k := range []string{"b", "c", "d"} {
+		l.Set("px", k, 1.0)
+	}
+
+	stop = runFollower(f)
+	defer stop()
+	waitFor(t, func() bool { return f.Seq() == 5 })
+	assert.Equal(t, []string{"b", "c", "d"}, rpx.SortedMembers())
+	assert.Equal(t, 2, f.Stats().Snapshots)
+}
+
+func TestReplicationLeaderRestart(t *testing.T) {
+	l, px, _ := mockLeader(64)
+	l.Heartbeat = 50 * time.Millisecond
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	go l.Serve(ln)
+
+	l.Set("px", "a", 1.0)
+	l.Set("px", "b", 1.0)
+
+	f, rpx, _ := mockFollower(addr)
+	stop := runFollower(f)
+	defer stop()
+	waitFor(t, func() bool { return f.Seq() == 2 })
+
+	l.Close()
+	waitFor(t, func() bool { return !f.Stats().Connected })
+
+	// a new leader on the same address restarts the sequence, the follower loads a snapshot
+	l = NewLeader(64)
+	l.Heartbeat = 50 * time.Millisecond
+	defer l.Close()
+	l.Register("px", Float64Store(px))
+	l.Set("px", "c", 1.0)
+	l.Set("px", "d", 1.0)
+	l.Set("px", "e", 1.0)
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("loopback address not reusable: ", err)
+	}
+	go l.Serve(ln)
+
+	waitFor(t, func() bool { return f.Stats().Snapshots == 2 && f.Seq() == 3 })
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, rpx.SortedMembers())
+	assert.True(t, f.Stats().Reconnects > 0)
+}
+
+func TestReplicationMultipleFollowers(t *testing.T) {
+	l, _, _ := mockLeader(64)
+	l.Heartbeat = 50 * time.Millisecond
+	defer l.Close()
+	addr := serveLeader(t, l)
+
+	fs := make([]*Follower, 3)
+	for i := range fs {
+		fs[i], _, _ = mockFollower(addr)
+		stop := runFollower(fs[i])
+		defer stop()
+	}
+
+	for i := 0; i < 100; i++ {
+		l.Set("px", "a", float64(i))
+	}
+
+	for _, f := range fs {
+		f := f
+		waitFor(t, func() bool { return f.Seq() == 100 })
+	}
+	waitFor(t, func() bool { return len(l.Followers()) == 3 })
+}
+
+func TestReplicationFollowerLag(t *testing.T) {
+	l, _, _ := mockLeader(64)
+	l.Heartbeat = 20 * time.Millisecond
+	defer l.Close()
+	addr := serveLeader(t, l)
+
+	f := NewFollower(addr)
+	f.Heartbeat = 20 * time.Millisecond
+	f.MinBackoff = 5 * time.Millisecond
+	r := primitivestore.NewFloat64Store()
+
+	// a replica that blocks its writes holds the follower back
+	f.register("px", Float64Store(r))
+	stop := runFollower(f)
+	defer stop()
+	waitFor(t, func() bool { return f.Stats().Snapshots == 1 })
+
+	r.Lock()
+	l.Set("px", "a", 1.0)
+	l.Set("px", "b", 1.0)
+	waitFor(t, func() bool {
+		fs := l.Followers()
+		return len(fs) == 1 && fs[0].Lag == 2
+	})
+	r.Unlock()
+
+	waitFor(t, func() bool { return f.Seq() == 2 && f.Stats().Lag == 0 })
+	waitFor(t, func() bool { return l.Followers()[0].Lag == 0 })
+}
+
+func TestFollowerReconnectBackoff(t *testing.T) {
+	// nothing listens on the address
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	f := NewFollower(addr)
+	f.MinBackoff = time.Millisecond
+	f.MaxBackoff = 4 * time.Millisecond
+	stop := runFollower(f)
+	waitFor(t, func() bool { return f.Stats().Reconnects >= 5 })
+	stop()
+
+	st := f.Stats()
+	assert.False(t, st.Connected)
+	assert.NotNil(t, st.LastError)
+
+	// a done context stops Run
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, f.Run(ctx))
+}
+
+func TestFollowerApply(t *testing.T) {
+	f := NewFollower("127.0.0.1:1")
+	px := primitivestore.NewFloat64Store()
+	f.register("px", Float64Store(px))
+
+	f.Lock()
+	defer f.Unlock()
+
+	assert.Nil(t, f.apply(&message{Kind: msgSnapshot, Epoch: 1, Seq: 3, LeaderSeq: 4, Snapshot: map[string]map[string]interface{}{
+		"px":    {"a": 1.0},
+		"other": {"a": "ignored"},
+	}}))
+	assert.Equal(t, uint64(3), f.stats.Seq)
+	assert.Equal(t, uint64(1), f.statsCopy().Lag)
+	assert.Equal(t, 1, px.Size())
+
+	// mutations of unknown stores still advance the sequence
+	assert.Nil(t, f.apply(&message{Kind: msgMutation, Epoch: 1, LeaderSeq: 4, Mutation: &Mutation{Seq: 4, Store: "other", Op: OpClear}}))
+	assert.Equal(t, uint64(4), f.stats.Seq)
+
+	// gaps reset the position so the next connection loads a snapshot
+	assert.Equal(t, ErrSequenceGap, f.apply(&message{Kind: msgMutation, Epoch: 1, LeaderSeq: 6, Mutation: &Mutation{Seq: 6, Store: "px", Op: OpClear}}))
+	assert.Equal(t, uint64(0), f.stats.Seq)
+	assert.Equal(t, int64(0), f.epoch)
+	assert.Equal(t, 1, px.Size())
+
+	// heartbeats only report the leader position
+	assert.Nil(t, f.apply(&message{Kind: msgHeartbeat, Epoch: 1, LeaderSeq: 7}))
+	assert.Equal(t, uint64(7), f.statsCopy().Lag)
+}
+
+func TestReplicationConcurrentWrites(t *testing.T) {
+	l, _, _ := mockLeader(16)
+	l.Heartbeat = 50 * time.Millisecond
+	defer l.Close()
+	addr := serveLeader(t, l)
+
+	f, rpx, rseries := mockFollower(addr)
+	stop := runFollower(f)
+	defer stop()
+
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			for j := 0; j < 250; j++ {
+				l.Set("px", "a", float64(j))
+				l.Append("series", "s", []float64{float64(i)})
+			}
+			done <- struct{}{}
+		}(i)
+	}
+
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+
+	waitFor(t, func() bool { return f.Seq() == 2000 })
+	v, _ := rpx.Get("a")
+	assert.Equal(t, 249.0, v)
+	n, _ := rseries.MemberLen("s")
+	assert.Equal(t, 1000, n)
+
+	time.Sleep(2 * time.Second)
+}
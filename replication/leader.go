@@ -0,0 +1,395 @@
+package replication
+
+import (
+	"encoding/gob"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBacklog is the number of mutations a leader keeps for resuming followers
+	DefaultBacklog = 4096
+	// DefaultHeartbeat is the interval at which an idle leader tells its followers its sequence number
+	DefaultHeartbeat = time.Second
+)
+
+// FollowerStatus describes a follower connected to a leader
+type FollowerStatus struct {
+	// Addr is the remote address of the follower connection
+	Addr string
+	// Acked is the last sequence number the follower reported as applied
+	Acked uint64
+	// Lag is the number of mutations written to the leader but not yet acknowledged by the follower
+	Lag uint64
+	// Connected is when the follower connected
+	Connected time.Time
+}
+
+// Leader owns the replicated stores and streams their writes to followers
+// Writes must go through the leader to be replicated, writing to a registered store directly is not seen by followers
+// Embedded sync.Mutex to provide atomic operation ability
+type Leader struct {
+	sync.Mutex
+	// Heartbeat is the interval at which idle followers are sent the leader sequence number
+	// Followers treat a leader silent for three intervals as gone, set it before calling Serve
+	Heartbeat time.Duration
+
+	epoch     int64
+	seq       uint64
+	backlog   int
+	log       []Mutation // the last backlog mutations, ordered by sequence number
+	stores    map[string]Store
+	notify    chan struct{} // closed and replaced on every write
+	followers map[net.Conn]*FollowerStatus
+	listeners map[net.Listener]struct{}
+	closed    bool
+	done      chan struct{}
+	wg        sync.WaitGroup
+
+	sendingSnapshot func() // called between the start of a snapshot and the snapshot, nil outside tests
+}
+
+// NewLeader constructs and initializes a new Leader keeping the last backlog mutations
+// A backlog below 1 uses DefaultBacklog
+// Always use this function when creating a new Leader
+func NewLeader(backlog int) *Leader {
+	if backlog < 1 {
+		backlog = DefaultBacklog
+	}
+
+	return &Leader{
+		Heartbeat: DefaultHeartbeat,
+		epoch:     time.Now().UnixNano(),
+		backlog:   backlog,
+		stores:    make(map[string]Store),
+		notify:    make(chan struct{}),
+		followers: make(map[net.Conn]*FollowerStatus),
+		listeners: make(map[net.Listener]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+func (l *Leader) register(name string, s Store) error {
+	if _, ok := l.stores[name]; ok {
+		return ErrStoreExists
+	}
+
+	l.stores[name] = s
+
+	return nil
+}
+
+// Register replicates the given store under name
+// Followers holding a replica of the same name receive its contents and writes
+func (l *Leader) Register(name string, s Store) error {
+	l.Lock()
+	err := l.register(name, s)
+	l.Unlock()
+
+	return err
+}
+
+func (l *Leader) write(m Mutation) error {
+	if l.closed {
+		return ErrLeaderClosed
+	}
+
+	s, ok := l.stores[m.Store]
+	if !ok {
+		return ErrStoreDoesNotExist
+	}
+
+	if err := s.apply(&m); err != nil {
+		return err
+	}
+
+	l.seq++
+	m.Seq = l.seq
+	l.log = append(l.log, m)
+	if len(l.log) > l.backlog {
+		l.log = l.log[len(l.log)-l.backlog:]
+	}
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+
+	return nil
+}
+
+// Set sets key in the named store to value and replicates the write
+// value must have the value type of the store, such as float64 for a Float64Store
+func (l *Leader) Set(store, key string, value interface{}) error {
+	l.Lock()
+	err := l.write(Mutation{Store: store, Op: OpSet, Key: key, Value: value})
+	l.Unlock()
+
+	return err
+}
+
+// Delete removes key from the named primitive store and replicates the write
+func (l *Leader) Delete(store, key string) error {
+	l.Lock()
+	err := l.write(Mutation{Store: store, Op: OpDelete, Key: key})
+	l.Unlock()
+
+	return err
+}
+
+// Clear deletes all keys in the named store and replicates the write
+func (l *Leader) Clear(store string) error {
+	l.Lock()
+	err := l.write(Mutation{Store: store, Op: OpClear})
+	l.Unlock()
+
+	return err
+}
+
+// Append adds values to the end of the series of key in the named series store and replicates the write
+// values must be a slice of the value type of the store, such as []float64 for a Float64SStore
+func (l *Leader) Append(store, key string, values interface{}) error {
+	l.Lock()
+	err := l.write(Mutation{Store: store, Op: OpAppend, Key: key, Value: values})
+	l.Unlock()
+
+	return err
+}
+
+// SetIdx sets the value at idx of the series of key in the named series store and replicates the write
+// Returns ErrKeyDoesNotExist or ErrIdxOutOfBounds from the store without replicating anything
+func (l *Leader) SetIdx(store, key string, idx int, value interface{}) error {
+	l.Lock()
+	err := l.write(Mutation{Store: store, Op: OpSetIdx, Key: key, Idx: idx, Value: value})
+	l.Unlock()
+
+	return err
+}
+
+// Seq returns the sequence number of the last write
+func (l *Leader) Seq() uint64 {
+	l.Lock()
+	seq := l.seq
+	l.Unlock()
+
+	return seq
+}
+
+func (l *Leader) followerStatuses() []FollowerStatus {
+	fs := make([]FollowerStatus, 0, len(l.followers))
+	for _, f := range l.followers {
+		st := *f
+		st.Lag = l.seq - st.Acked
+		fs = append(fs, st)
+	}
+
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Addr < fs[j].Addr })
+
+	return fs
+}
+
+// Followers returns the status of every connected follower ordered by address
+func (l *Leader) Followers() []FollowerStatus {
+	l.Lock()
+	fs := l.followerStatuses()
+	l.Unlock()
+
+	return fs
+}
+
+func (l *Leader) snapshot() map[string]map[string]interface{} {
+	snap := make(map[string]map[string]interface{}, len(l.stores))
+	for name, s := range l.stores {
+		snap[name] = s.snapshot()
+	}
+
+	return snap
+}
+
+// canResume checks if the log holds every mutation after the given position
+func (l *Leader) canResume(epoch int64, seq uint64) bool {
+	if epoch != l.epoch || seq > l.seq {
+		return false
+	}
+
+	if seq == l.seq {
+		return true
+	}
+
+	return len(l.log) > 0 && seq+1 >= l.log[0].Seq
+}
+
+// since returns a copy of the logged mutations after seq
+func (l *Leader) since(seq uint64) []Mutation {
+	if len(l.log) == 0 || seq >= l.seq {
+		return nil
+	}
+
+	i := int(seq + 1 - l.log[0].Seq)
+
+	return append([]Mutation(nil), l.log[i:]...)
+}
+
+// Serve accepts follower connections on ln until the leader is closed
+// Returns nil once Close is called, or the error that stopped ln from accepting
+func (l *Leader) Serve(ln net.Listener) error {
+	l.Lock()
+	if l.closed {
+		l.Unlock()
+		ln.Close()
+		return ErrLeaderClosed
+	}
+	l.listeners[ln] = struct{}{}
+	l.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-l.done:
+				return nil
+			default:
+			}
+
+			l.Lock()
+			delete(l.listeners, ln)
+			l.Unlock()
+
+			return err
+		}
+
+		l.Lock()
+		if l.closed {
+			l.Unlock()
+			conn.Close()
+			return nil
+		}
+		l.followers[conn] = &FollowerStatus{Addr: conn.RemoteAddr().String(), Connected: time.Now()}
+		l.wg.Add(1)
+		l.Unlock()
+
+		go l.serveConn(conn)
+	}
+}
+
+func (l *Leader) serveConn(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		conn.Close()
+		l.Lock()
+		delete(l.followers, conn)
+		l.Unlock()
+	}()
+
+	enc := gob.NewEncoder(conn)
+	dec := gob.NewDecoder(conn)
+
+	var h hello
+	conn.SetReadDeadline(time.Now().Add(3 * l.Heartbeat))
+	if err := dec.Decode(&h); err != nil {
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	// acks arrive on their own goroutine, a failed read closes the connection and stops the writes below
+	go func() {
+		var a ack
+		for dec.Decode(&a) == nil {
+			l.Lock()
+			if f, ok := l.followers[conn]; ok {
+				f.Acked = a.Seq
+			}
+			l.Unlock()
+		}
+		conn.Close()
+	}()
+
+	l.Lock()
+	if h.Seq > 0 && l.canResume(h.Epoch, h.Seq) {
+		l.followers[conn].Acked = h.Seq
+	} else {
+		h.Seq = 0
+	}
+	l.Unlock()
+
+	heartbeat := time.NewTicker(l.Heartbeat)
+	defer heartbeat.Stop()
+
+	next := h.Seq
+	resume := h.Seq > 0
+	for {
+		l.Lock()
+		if !resume || !l.canResume(l.epoch, next) {
+			// a new follower, or one that fell further behind than the backlog
+			start := message{Kind: msgSnapshotStart, Epoch: l.epoch, LeaderSeq: l.seq}
+			msg := message{Kind: msgSnapshot, Epoch: l.epoch, Seq: l.seq, LeaderSeq: l.seq, Snapshot: l.snapshot()}
+			l.Unlock()
+			if enc.Encode(&start) != nil {
+				return
+			}
+			if l.sendingSnapshot != nil {
+				l.sendingSnapshot()
+			}
+			if enc.Encode(&msg) != nil {
+				return
+			}
+
+			next = msg.Seq
+			resume = true
+			continue
+		}
+
+		muts := l.since(next)
+		head := l.seq
+		epoch := l.epoch
+		wait := l.notify
+		l.Unlock()
+
+		for i := range muts {
+			msg := message{Kind: msgMutation, Epoch: epoch, LeaderSeq: head, Mutation: &muts[i]}
+			if enc.Encode(&msg) != nil {
+				return
+			}
+
+			next = muts[i].Seq
+		}
+
+		if len(muts) > 0 {
+			continue
+		}
+
+		select {
+		case <-wait:
+		case <-heartbeat.C:
+			if enc.Encode(&message{Kind: msgHeartbeat, Epoch: epoch, LeaderSeq: head}) != nil {
+				return
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// Close stops serving, disconnects every follower and rejects further writes
+// Registered stores keep their contents
+func (l *Leader) Close() error {
+	l.Lock()
+	if l.closed {
+		l.Unlock()
+		return nil
+	}
+
+	l.closed = true
+	close(l.done)
+	for ln := range l.listeners {
+		ln.Close()
+	}
+	for conn := range l.followers {
+		conn.Close()
+	}
+	l.Unlock()
+
+	l.wg.Wait()
+
+	return nil
+}
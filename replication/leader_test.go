@@ -0,0 +1,137 @@
+package replication
+
+import (
+	"net"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/primitivestore"
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockLeader(backlog int) (*Leader, *primitivestore.Float64Store, *seriesstore.Float64SStore) {
+	l := NewLeader(backlog)
+	px := primitivestore.NewFloat64Store()
+	series := seriesstore.NewFloat64SStore()
+	l.Register("px", Float64Store(px))
+	l.Register("series", Float64SStore(series))
+
+	return l, px, series
+}
+
+func TestNewLeader(t *testing.T) {
+	l := NewLeader(0)
+	assert.Equal(t, DefaultBacklog, l.backlog)
+	assert.Equal(t, DefaultHeartbeat, l.Heartbeat)
+	assert.True(t, l.epoch != 0)
+	assert.Equal(t, uint64(0), l.Seq())
+
+	assert.Equal(t, 8, NewLeader(8).backlog)
+}
+
+func TestLeaderRegister(t *testing.T) {
+	l, _, _ := mockLeader(8)
+	assert.Equal(t, ErrStoreExists, l.Register("px", Float64Store(primitivestore.NewFloat64Store())))
+	assert.Nil(t, l.Register("other", Float64Store(primitivestore.NewFloat64Store())))
+}
+
+func TestLeaderWrites(t *testing.T) {
+	l, px, series := mockLeader(8)
+
+	assert.Nil(t, l.Set("px", "a", 1.5))
+	assert.Nil(t, l.Set("px", "b", 2.5))
+	assert.Nil(t, l.Delete("px", "b"))
+	assert.Nil(t, l.Append("series", "a", []float64{1, 2}))
+	assert.Nil(t, l.SetIdx("series", "a", 1, 3.0))
+	assert.Nil(t, l.Clear("px"))
+	assert.Equal(t, uint64(6), l.Seq())
+
+	assert.Equal(t, 0, px.Size())
+	v, _ := series.Get("a")
+	assert.Equal(t, []float64{1, 3}, v)
+
+	ops := make([]Op, 0)
+	for i, m := range l.log {
+		assert.Equal(t, uint64(i+1), m.Seq)
+		ops = append(ops, m.Op)
+	}
+	assert.Equal(t, []Op{OpSet, OpSet, OpDelete, OpAppend, OpSetIdx, OpClear}, ops)
+
+	// failed writes are not logged
+	assert.Equal(t, ErrStoreDoesNotExist, l.Set("missing", "a", 1.0))
+	assert.Equal(t, ErrValueType, l.Set("px", "a", 1))
+	assert.Equal(t, ErrUnsupportedOp, l.Delete("series", "a"))
+	assert.Equal(t, seriesstore.ErrIdxOutOfBounds, l.SetIdx("series", "a", 2, 1.0))
+	assert.Equal(t, uint64(6), l.Seq())
+	assert.Len(t, l.log, 6)
+}
+
+func TestLeaderBacklog(t *testing.T) {
+	l, _, _ := mockLeader(3)
+	for i := 0; i < 5; i++ {
+		l.Set("px", "a", float64(i))
+	}
+
+	assert.Len(t, l.log, 3)
+	assert.Equal(t, uint64(3), l.log[0].Seq)
+	assert.Equal(t, uint64(5), l.log[2].Seq)
+
+	// resuming needs every mutation after the follower position
+	assert.False(t, l.canResume(l.epoch, 1))
+	assert.True(t, l.canResume(l.epoch, 2))
+	assert.True(t, l.canResume(l.epoch, 4))
+	assert.True(t, l.canResume(l.epoch, 5))
+	assert.False(t, l.canResume(l.epoch, 6))
+	assert.False(t, l.canResume(l.epoch+1, 4))
+
+	muts := l.since(3)
+	assert.Len(t, muts, 2)
+	assert.Equal(t, uint64(4), muts[0].Seq)
+	assert.Equal(t, 4.0, muts[1].Value)
+	assert.Len(t, l.since(5), 0)
+}
+
+func TestLeaderNotify(t *testing.T) {
+	l, _, _ := mockLeader(8)
+	wait := l.notify
+
+	select {
+	case <-wait:
+		t.Fatal("notified before a write")
+	default:
+	}
+
+	l.Set("px", "a", 1.0)
+	_, open := <-wait
+	assert.False(t, open)
+	assert.NotEqual(t, wait, l.notify)
+}
+
+func TestLeaderClose(t *testing.T) {
+	l, px, _ := mockLeader(8)
+	l.Set("px", "a", 1.0)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	served := make(chan error)
+	go func() { served <- l.Serve(ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+	waitFor(t, func() bool { return len(l.Followers()) == 1 })
+
+	assert.Nil(t, l.Close())
+	assert.Len(t, l.Followers(), 0)
+	assert.Nil(t, <-served)
+	assert.Nil(t, l.Close())
+
+	// writes are rejected, stores are kept
+	assert.Equal(t, ErrLeaderClosed, l.Set("px", "b", 1.0))
+	assert.Equal(t, 1, px.Size())
+
+	ln, err = net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	assert.Equal(t, ErrLeaderClosed, l.Serve(ln))
+}
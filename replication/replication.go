@@ -0,0 +1,127 @@
+// Package replication mirrors safestore stores from a leader process to follower processes over TCP
+//
+// A leader owns the writable stores. Every write made through the leader is applied locally,
+// numbered with the next sequence number and kept in a bounded mutation log.
+// A follower connects, receives a snapshot of every store and then streams the mutation log in order,
+// applying each mutation to its own read-only replicas.
+// When the connection drops the follower reconnects and resumes after the last sequence number it applied,
+// falling back to a fresh snapshot when the leader no longer holds that part of the log.
+//
+// Messages are gob encoded, so a leader and its followers must run the same version of this package
+package replication
+
+import (
+	"errors"
+)
+
+var (
+	// ErrStoreExists is thrown when registering a store under a name already in use
+	ErrStoreExists = errors.New("store already registered")
+	// ErrStoreDoesNotExist is thrown when a store name is not registered
+	ErrStoreDoesNotExist = errors.New("store does not exist")
+	// ErrValueType is thrown when a replicated value does not match the type of its store
+	ErrValueType = errors.New("value does not match store type")
+	// ErrUnsupportedOp is thrown when a store cannot apply a mutation op
+	ErrUnsupportedOp = errors.New("op not supported by store")
+	// ErrLeaderClosed is thrown when serving or writing through a closed leader
+	ErrLeaderClosed = errors.New("leader closed")
+	// ErrFollowerRunning is thrown when registering a replica or running a follower that is already running
+	ErrFollowerRunning = errors.New("follower already running")
+	// ErrSequenceGap is thrown when a follower receives a mutation out of order
+	ErrSequenceGap = errors.New("mutation sequence gap")
+)
+
+// Op is the kind of write carried by a Mutation
+type Op int
+
+const (
+	// OpSet sets a key to a value
+	OpSet Op = iota
+	// OpDelete removes a key
+	OpDelete
+	// OpClear removes every key
+	OpClear
+	// OpAppend appends values to the series of a key
+	OpAppend
+	// OpSetIdx sets the value at an index of the series of a key
+	OpSetIdx
+)
+
+// String returns the name of the op
+func (op Op) String() string {
+	switch op {
+	case OpSet:
+		return "set"
+	case OpDelete:
+		return "delete"
+	case OpClear:
+		return "clear"
+	case OpAppend:
+		return "append"
+	case OpSetIdx:
+		return "setidx"
+	default:
+		return "unknown"
+	}
+}
+
+// Mutation is a single numbered write to a replicated store
+type Mutation struct {
+	// Seq is the position of the mutation in the leader's log, starting at 1
+	Seq uint64
+	// Store is the registered name of the written store
+	Store string
+	Op    Op
+	Key   string
+	// Idx is the series index written by OpSetIdx
+	Idx int
+	// Value is the value written by OpSet, OpAppend and OpSetIdx
+	Value interface{}
+}
+
+// Store is a store adapted for replication
+// Use the adapter matching the store type, such as Float64Store or OHLCSStore
+type Store interface {
+	// snapshot returns a copy of every key and value in the store
+	snapshot() map[string]interface{}
+	// restore replaces the contents of the store with the given keys and values
+	restore(values map[string]interface{}) error
+	// apply applies a mutation to the store
+	// Slice values are replaced by a copy, so the caller can keep using its slice
+	apply(m *Mutation) error
+}
+
+type messageKind int
+
+const (
+	msgSnapshot messageKind = iota
+	msgMutation
+	msgHeartbeat
+	// msgSnapshotStart is sent before a snapshot is taken, as a large snapshot leaves no room for heartbeats
+	msgSnapshotStart
+)
+
+// hello is sent by a follower when it connects
+type hello struct {
+	// Epoch and Seq identify the last mutation the follower applied, both zero when it has none
+	Epoch int64
+	Seq   uint64
+}
+
+// message is sent by a leader to its followers
+type message struct {
+	Kind messageKind
+	// Epoch identifies the leader's log, it changes whenever a leader is created
+	Epoch int64
+	// Seq is the last mutation covered by a snapshot
+	Seq uint64
+	// LeaderSeq is the last mutation written to the leader when the message was sent
+	LeaderSeq uint64
+	Snapshot  map[string]map[string]interface{}
+	Mutation  *Mutation
+}
+
+// ack is sent by a follower after applying a message
+type ack struct {
+	Seq uint64
+}
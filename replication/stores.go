@@ -0,0 +1,1765 @@
+package replication
+
+import (
+	"encoding/gob"
+	"time"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/blacklabcapital/safestore/primitivestore"
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// Each store type has an adapter for registering a store with a leader, a reader interface,
+// and a Follower method creating a replica of the type
+// Replicas are returned wrapped in a struct holding only the reader interface,
+// so they cannot be asserted back to a writable store
+
+func init() {
+	// basic types and their slices are registered by gob itself
+	gob.Register(time.Duration(0))
+	gob.Register(time.Time{})
+	gob.Register(decimal.Decimal{})
+	gob.Register([]decimal.Decimal(nil))
+	gob.Register(seriesstore.OHLC{})
+	gob.Register([]seriesstore.OHLC(nil))
+	gob.Register(seriesstore.OHLCV{})
+	gob.Register([]seriesstore.OHLCV(nil))
+}
+
+// BoolReader is the read-only view of a replicated primitivestore.BoolStore
+type BoolReader interface {
+	Get(key string) (bool, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type boolStore struct {
+	s *primitivestore.BoolStore
+}
+
+// BoolStore adapts a primitivestore.BoolStore for replication
+func BoolStore(s *primitivestore.BoolStore) Store {
+	return boolStore{s: s}
+}
+
+func (a boolStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a boolStore) restore(values map[string]interface{}) error {
+	store := make(map[string]bool, len(values))
+	for k, v := range values {
+		x, ok := v.(bool)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a boolStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(bool)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// BoolStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) BoolStore(name string) (BoolReader, error) {
+	s := primitivestore.NewBoolStore()
+	if err := f.register(name, BoolStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ BoolReader }{s}, nil
+}
+
+// BytesReader is the read-only view of a replicated primitivestore.BytesStore
+type BytesReader interface {
+	Get(key string) ([]byte, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type bytesStore struct {
+	s *primitivestore.BytesStore
+}
+
+// BytesStore adapts a primitivestore.BytesStore for replication
+func BytesStore(s *primitivestore.BytesStore) Store {
+	return bytesStore{s: s}
+}
+
+func (a bytesStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a bytesStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]byte, len(values))
+	for k, v := range values {
+		x, ok := v.([]byte)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a bytesStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.([]byte)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]byte(nil), v...)
+		m.Value = v
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// BytesStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) BytesStore(name string) (BytesReader, error) {
+	s := primitivestore.NewBytesStore()
+	if err := f.register(name, BytesStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ BytesReader }{s}, nil
+}
+
+// Complex128Reader is the read-only view of a replicated primitivestore.Complex128Store
+type Complex128Reader interface {
+	Get(key string) (complex128, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type complex128Store struct {
+	s *primitivestore.Complex128Store
+}
+
+// Complex128Store adapts a primitivestore.Complex128Store for replication
+func Complex128Store(s *primitivestore.Complex128Store) Store {
+	return complex128Store{s: s}
+}
+
+func (a complex128Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a complex128Store) restore(values map[string]interface{}) error {
+	store := make(map[string]complex128, len(values))
+	for k, v := range values {
+		x, ok := v.(complex128)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a complex128Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(complex128)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Complex128Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Complex128Store(name string) (Complex128Reader, error) {
+	s := primitivestore.NewComplex128Store()
+	if err := f.register(name, Complex128Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Complex128Reader }{s}, nil
+}
+
+// DecimalReader is the read-only view of a replicated primitivestore.DecimalStore
+type DecimalReader interface {
+	Get(key string) (decimal.Decimal, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type decimalStore struct {
+	s *primitivestore.DecimalStore
+}
+
+// DecimalStore adapts a primitivestore.DecimalStore for replication
+func DecimalStore(s *primitivestore.DecimalStore) Store {
+	return decimalStore{s: s}
+}
+
+func (a decimalStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a decimalStore) restore(values map[string]interface{}) error {
+	store := make(map[string]decimal.Decimal, len(values))
+	for k, v := range values {
+		x, ok := v.(decimal.Decimal)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a decimalStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(decimal.Decimal)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// DecimalStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) DecimalStore(name string) (DecimalReader, error) {
+	s := primitivestore.NewDecimalStore()
+	if err := f.register(name, DecimalStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ DecimalReader }{s}, nil
+}
+
+// DurationReader is the read-only view of a replicated primitivestore.DurationStore
+type DurationReader interface {
+	Get(key string) (time.Duration, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type durationStore struct {
+	s *primitivestore.DurationStore
+}
+
+// DurationStore adapts a primitivestore.DurationStore for replication
+func DurationStore(s *primitivestore.DurationStore) Store {
+	return durationStore{s: s}
+}
+
+func (a durationStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a durationStore) restore(values map[string]interface{}) error {
+	store := make(map[string]time.Duration, len(values))
+	for k, v := range values {
+		x, ok := v.(time.Duration)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a durationStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(time.Duration)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// DurationStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) DurationStore(name string) (DurationReader, error) {
+	s := primitivestore.NewDurationStore()
+	if err := f.register(name, DurationStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ DurationReader }{s}, nil
+}
+
+// Float32Reader is the read-only view of a replicated primitivestore.Float32Store
+type Float32Reader interface {
+	Get(key string) (float32, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type float32Store struct {
+	s *primitivestore.Float32Store
+}
+
+// Float32Store adapts a primitivestore.Float32Store for replication
+func Float32Store(s *primitivestore.Float32Store) Store {
+	return float32Store{s: s}
+}
+
+func (a float32Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a float32Store) restore(values map[string]interface{}) error {
+	store := make(map[string]float32, len(values))
+	for k, v := range values {
+		x, ok := v.(float32)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a float32Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(float32)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Float32Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Float32Store(name string) (Float32Reader, error) {
+	s := primitivestore.NewFloat32Store()
+	if err := f.register(name, Float32Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Float32Reader }{s}, nil
+}
+
+// Float64Reader is the read-only view of a replicated primitivestore.Float64Store
+type Float64Reader interface {
+	Get(key string) (float64, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type float64Store struct {
+	s *primitivestore.Float64Store
+}
+
+// Float64Store adapts a primitivestore.Float64Store for replication
+func Float64Store(s *primitivestore.Float64Store) Store {
+	return float64Store{s: s}
+}
+
+func (a float64Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a float64Store) restore(values map[string]interface{}) error {
+	store := make(map[string]float64, len(values))
+	for k, v := range values {
+		x, ok := v.(float64)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a float64Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(float64)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Float64Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Float64Store(name string) (Float64Reader, error) {
+	s := primitivestore.NewFloat64Store()
+	if err := f.register(name, Float64Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Float64Reader }{s}, nil
+}
+
+// IntReader is the read-only view of a replicated primitivestore.IntStore
+type IntReader interface {
+	Get(key string) (int, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type intStore struct {
+	s *primitivestore.IntStore
+}
+
+// IntStore adapts a primitivestore.IntStore for replication
+func IntStore(s *primitivestore.IntStore) Store {
+	return intStore{s: s}
+}
+
+func (a intStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a intStore) restore(values map[string]interface{}) error {
+	store := make(map[string]int, len(values))
+	for k, v := range values {
+		x, ok := v.(int)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a intStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(int)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// IntStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) IntStore(name string) (IntReader, error) {
+	s := primitivestore.NewIntStore()
+	if err := f.register(name, IntStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ IntReader }{s}, nil
+}
+
+// Int32Reader is the read-only view of a replicated primitivestore.Int32Store
+type Int32Reader interface {
+	Get(key string) (int32, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type int32Store struct {
+	s *primitivestore.Int32Store
+}
+
+// Int32Store adapts a primitivestore.Int32Store for replication
+func Int32Store(s *primitivestore.Int32Store) Store {
+	return int32Store{s: s}
+}
+
+func (a int32Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a int32Store) restore(values map[string]interface{}) error {
+	store := make(map[string]int32, len(values))
+	for k, v := range values {
+		x, ok := v.(int32)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a int32Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(int32)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Int32Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Int32Store(name string) (Int32Reader, error) {
+	s := primitivestore.NewInt32Store()
+	if err := f.register(name, Int32Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Int32Reader }{s}, nil
+}
+
+// Int64Reader is the read-only view of a replicated primitivestore.Int64Store
+type Int64Reader interface {
+	Get(key string) (int64, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type int64Store struct {
+	s *primitivestore.Int64Store
+}
+
+// Int64Store adapts a primitivestore.Int64Store for replication
+func Int64Store(s *primitivestore.Int64Store) Store {
+	return int64Store{s: s}
+}
+
+func (a int64Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a int64Store) restore(values map[string]interface{}) error {
+	store := make(map[string]int64, len(values))
+	for k, v := range values {
+		x, ok := v.(int64)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a int64Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(int64)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Int64Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Int64Store(name string) (Int64Reader, error) {
+	s := primitivestore.NewInt64Store()
+	if err := f.register(name, Int64Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Int64Reader }{s}, nil
+}
+
+// StringReader is the read-only view of a replicated primitivestore.StringStore
+type StringReader interface {
+	Get(key string) (string, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type stringStore struct {
+	s *primitivestore.StringStore
+}
+
+// StringStore adapts a primitivestore.StringStore for replication
+func StringStore(s *primitivestore.StringStore) Store {
+	return stringStore{s: s}
+}
+
+func (a stringStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a stringStore) restore(values map[string]interface{}) error {
+	store := make(map[string]string, len(values))
+	for k, v := range values {
+		x, ok := v.(string)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a stringStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(string)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// StringStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) StringStore(name string) (StringReader, error) {
+	s := primitivestore.NewStringStore()
+	if err := f.register(name, StringStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ StringReader }{s}, nil
+}
+
+// TimeReader is the read-only view of a replicated primitivestore.TimeStore
+type TimeReader interface {
+	Get(key string) (time.Time, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type timeStore struct {
+	s *primitivestore.TimeStore
+}
+
+// TimeStore adapts a primitivestore.TimeStore for replication
+func TimeStore(s *primitivestore.TimeStore) Store {
+	return timeStore{s: s}
+}
+
+func (a timeStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a timeStore) restore(values map[string]interface{}) error {
+	store := make(map[string]time.Time, len(values))
+	for k, v := range values {
+		x, ok := v.(time.Time)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a timeStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(time.Time)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// TimeStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) TimeStore(name string) (TimeReader, error) {
+	s := primitivestore.NewTimeStore()
+	if err := f.register(name, TimeStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ TimeReader }{s}, nil
+}
+
+// Uint32Reader is the read-only view of a replicated primitivestore.Uint32Store
+type Uint32Reader interface {
+	Get(key string) (uint32, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type uint32Store struct {
+	s *primitivestore.Uint32Store
+}
+
+// Uint32Store adapts a primitivestore.Uint32Store for replication
+func Uint32Store(s *primitivestore.Uint32Store) Store {
+	return uint32Store{s: s}
+}
+
+func (a uint32Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a uint32Store) restore(values map[string]interface{}) error {
+	store := make(map[string]uint32, len(values))
+	for k, v := range values {
+		x, ok := v.(uint32)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a uint32Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(uint32)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Uint32Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Uint32Store(name string) (Uint32Reader, error) {
+	s := primitivestore.NewUint32Store()
+	if err := f.register(name, Uint32Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Uint32Reader }{s}, nil
+}
+
+// Uint64Reader is the read-only view of a replicated primitivestore.Uint64Store
+type Uint64Reader interface {
+	Get(key string) (uint64, bool)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type uint64Store struct {
+	s *primitivestore.Uint64Store
+}
+
+// Uint64Store adapts a primitivestore.Uint64Store for replication
+func Uint64Store(s *primitivestore.Uint64Store) Store {
+	return uint64Store{s: s}
+}
+
+func (a uint64Store) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a uint64Store) restore(values map[string]interface{}) error {
+	store := make(map[string]uint64, len(values))
+	for k, v := range values {
+		x, ok := v.(uint64)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a uint64Store) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet:
+		v, ok := m.Value.(uint64)
+		if !ok {
+			return ErrValueType
+		}
+
+		a.s.Set(m.Key, v)
+	case OpDelete:
+		a.s.Delete(m.Key)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Uint64Store creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Uint64Store(name string) (Uint64Reader, error) {
+	s := primitivestore.NewUint64Store()
+	if err := f.register(name, Uint64Store(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Uint64Reader }{s}, nil
+}
+
+// DecimalSReader is the read-only view of a replicated seriesstore.DecimalSStore
+type DecimalSReader interface {
+	Get(key string) ([]decimal.Decimal, bool)
+	GetIdx(key string, idx int) (decimal.Decimal, error)
+	GetRange(key string, lower, upper int) ([]decimal.Decimal, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type decimalSStore struct {
+	s *seriesstore.DecimalSStore
+}
+
+// DecimalSStore adapts a seriesstore.DecimalSStore for replication
+func DecimalSStore(s *seriesstore.DecimalSStore) Store {
+	return decimalSStore{s: s}
+}
+
+func (a decimalSStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a decimalSStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]decimal.Decimal, len(values))
+	for k, v := range values {
+		x, ok := v.([]decimal.Decimal)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a decimalSStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]decimal.Decimal)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]decimal.Decimal(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(decimal.Decimal)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// DecimalSStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) DecimalSStore(name string) (DecimalSReader, error) {
+	s := seriesstore.NewDecimalSStore()
+	if err := f.register(name, DecimalSStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ DecimalSReader }{s}, nil
+}
+
+// Float32SReader is the read-only view of a replicated seriesstore.Float32SStore
+type Float32SReader interface {
+	Get(key string) ([]float32, bool)
+	GetIdx(key string, idx int) (float32, error)
+	GetRange(key string, lower, upper int) ([]float32, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type float32SStore struct {
+	s *seriesstore.Float32SStore
+}
+
+// Float32SStore adapts a seriesstore.Float32SStore for replication
+func Float32SStore(s *seriesstore.Float32SStore) Store {
+	return float32SStore{s: s}
+}
+
+func (a float32SStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a float32SStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]float32, len(values))
+	for k, v := range values {
+		x, ok := v.([]float32)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a float32SStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]float32)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]float32(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(float32)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Float32SStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Float32SStore(name string) (Float32SReader, error) {
+	s := seriesstore.NewFloat32SStore()
+	if err := f.register(name, Float32SStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Float32SReader }{s}, nil
+}
+
+// Float64SReader is the read-only view of a replicated seriesstore.Float64SStore
+type Float64SReader interface {
+	Get(key string) ([]float64, bool)
+	GetIdx(key string, idx int) (float64, error)
+	GetRange(key string, lower, upper int) ([]float64, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type float64SStore struct {
+	s *seriesstore.Float64SStore
+}
+
+// Float64SStore adapts a seriesstore.Float64SStore for replication
+func Float64SStore(s *seriesstore.Float64SStore) Store {
+	return float64SStore{s: s}
+}
+
+func (a float64SStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a float64SStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]float64, len(values))
+	for k, v := range values {
+		x, ok := v.([]float64)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a float64SStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]float64)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]float64(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(float64)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Float64SStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Float64SStore(name string) (Float64SReader, error) {
+	s := seriesstore.NewFloat64SStore()
+	if err := f.register(name, Float64SStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Float64SReader }{s}, nil
+}
+
+// IntSReader is the read-only view of a replicated seriesstore.IntSStore
+type IntSReader interface {
+	Get(key string) ([]int, bool)
+	GetIdx(key string, idx int) (int, error)
+	GetRange(key string, lower, upper int) ([]int, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type intSStore struct {
+	s *seriesstore.IntSStore
+}
+
+// IntSStore adapts a seriesstore.IntSStore for replication
+func IntSStore(s *seriesstore.IntSStore) Store {
+	return intSStore{s: s}
+}
+
+func (a intSStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a intSStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]int, len(values))
+	for k, v := range values {
+		x, ok := v.([]int)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a intSStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]int)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]int(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(int)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// IntSStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) IntSStore(name string) (IntSReader, error) {
+	s := seriesstore.NewIntSStore()
+	if err := f.register(name, IntSStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ IntSReader }{s}, nil
+}
+
+// OHLCSReader is the read-only view of a replicated seriesstore.OHLCSStore
+type OHLCSReader interface {
+	Get(key string) ([]seriesstore.OHLC, bool)
+	GetIdx(key string, idx int) (seriesstore.OHLC, error)
+	GetRange(key string, lower, upper int) ([]seriesstore.OHLC, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type ohlcSStore struct {
+	s *seriesstore.OHLCSStore
+}
+
+// OHLCSStore adapts a seriesstore.OHLCSStore for replication
+func OHLCSStore(s *seriesstore.OHLCSStore) Store {
+	return ohlcSStore{s: s}
+}
+
+func (a ohlcSStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a ohlcSStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]seriesstore.OHLC, len(values))
+	for k, v := range values {
+		x, ok := v.([]seriesstore.OHLC)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a ohlcSStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]seriesstore.OHLC)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]seriesstore.OHLC(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(seriesstore.OHLC)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, &v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// OHLCSStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) OHLCSStore(name string) (OHLCSReader, error) {
+	s := seriesstore.NewOHLCSStore()
+	if err := f.register(name, OHLCSStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ OHLCSReader }{s}, nil
+}
+
+// OHLCVSReader is the read-only view of a replicated seriesstore.OHLCVSStore
+type OHLCVSReader interface {
+	Get(key string) ([]seriesstore.OHLCV, bool)
+	GetIdx(key string, idx int) (seriesstore.OHLCV, error)
+	GetRange(key string, lower, upper int) ([]seriesstore.OHLCV, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type ohlcvSStore struct {
+	s *seriesstore.OHLCVSStore
+}
+
+// OHLCVSStore adapts a seriesstore.OHLCVSStore for replication
+func OHLCVSStore(s *seriesstore.OHLCVSStore) Store {
+	return ohlcvSStore{s: s}
+}
+
+func (a ohlcvSStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a ohlcvSStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]seriesstore.OHLCV, len(values))
+	for k, v := range values {
+		x, ok := v.([]seriesstore.OHLCV)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a ohlcvSStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]seriesstore.OHLCV)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]seriesstore.OHLCV(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(seriesstore.OHLCV)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, &v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// OHLCVSStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) OHLCVSStore(name string) (OHLCVSReader, error) {
+	s := seriesstore.NewOHLCVSStore()
+	if err := f.register(name, OHLCVSStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ OHLCVSReader }{s}, nil
+}
+
+// Uint64SReader is the read-only view of a replicated seriesstore.Uint64SStore
+type Uint64SReader interface {
+	Get(key string) ([]uint64, bool)
+	GetIdx(key string, idx int) (uint64, error)
+	GetRange(key string, lower, upper int) ([]uint64, error)
+	Size() int
+	Members() []string
+	IsMember(key string) bool
+	MemberLen(key string) (int, error)
+	SortedMembers() []string
+	MembersWithPrefix(prefix string) []string
+	MembersMatching(pattern string) []string
+	Scan(cursor string, count int, match string) ([]string, string)
+}
+
+type uint64SStore struct {
+	s *seriesstore.Uint64SStore
+}
+
+// Uint64SStore adapts a seriesstore.Uint64SStore for replication
+func Uint64SStore(s *seriesstore.Uint64SStore) Store {
+	return uint64SStore{s: s}
+}
+
+func (a uint64SStore) snapshot() map[string]interface{} {
+	c := a.s.Clone()
+	m := make(map[string]interface{}, c.Size())
+	for _, k := range c.Members() {
+		m[k], _ = c.Get(k)
+	}
+
+	return m
+}
+
+func (a uint64SStore) restore(values map[string]interface{}) error {
+	store := make(map[string][]uint64, len(values))
+	for k, v := range values {
+		x, ok := v.([]uint64)
+		if !ok {
+			return ErrValueType
+		}
+
+		store[k] = x
+	}
+
+	a.s.ReplaceAll(store)
+
+	return nil
+}
+
+func (a uint64SStore) apply(m *Mutation) error {
+	switch m.Op {
+	case OpSet, OpAppend:
+		v, ok := m.Value.([]uint64)
+		if !ok {
+			return ErrValueType
+		}
+
+		v = append([]uint64(nil), v...)
+		m.Value = v
+		if m.Op == OpSet {
+			a.s.Set(m.Key, v)
+		} else {
+			a.s.Append(m.Key, v...)
+		}
+	case OpSetIdx:
+		v, ok := m.Value.(uint64)
+		if !ok {
+			return ErrValueType
+		}
+
+		return a.s.SetIdx(m.Key, m.Idx, v)
+	case OpClear:
+		a.s.Clear()
+	default:
+		return ErrUnsupportedOp
+	}
+
+	return nil
+}
+
+// Uint64SStore creates a read-only replica of the leader store registered under name
+// Replicas must be created before Run is called
+func (f *Follower) Uint64SStore(name string) (Uint64SReader, error) {
+	s := seriesstore.NewUint64SStore()
+	if err := f.register(name, Uint64SStore(s)); err != nil {
+		return nil, err
+	}
+
+	return struct{ Uint64SReader }{s}, nil
+}
@@ -0,0 +1,169 @@
+package replication
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/blacklabcapital/safestore/primitivestore"
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockTime = time.Date(2018, 6, 1, 9, 30, 0, 0, time.UTC)
+
+func TestFloat64StoreApply(t *testing.T) {
+	s := primitivestore.NewFloat64Store()
+	a := Float64Store(s)
+
+	assert.Nil(t, a.apply(&Mutation{Op: OpSet, Key: "a", Value: 1.5}))
+	assert.Nil(t, a.apply(&Mutation{Op: OpSet, Key: "b", Value: 2.5}))
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, v)
+
+	assert.Nil(t, a.apply(&Mutation{Op: OpDelete, Key: "a"}))
+	assert.False(t, s.IsMember("a"))
+
+	// wrong value type
+	assert.Equal(t, ErrValueType, a.apply(&Mutation{Op: OpSet, Key: "c", Value: "1.5"}))
+	assert.False(t, s.IsMember("c"))
+
+	// series ops
+	assert.Equal(t, ErrUnsupportedOp, a.apply(&Mutation{Op: OpAppend, Key: "b", Value: []float64{1}}))
+
+	assert.Nil(t, a.apply(&Mutation{Op: OpClear}))
+	assert.Equal(t, 0, s.Size())
+}
+
+func TestBytesStoreApplyCopies(t *testing.T) {
+	s := primitivestore.NewBytesStore()
+	a := BytesStore(s)
+
+	b := []byte("foo")
+	m := &Mutation{Op: OpSet, Key: "a", Value: b}
+	assert.Nil(t, a.apply(m))
+
+	// the logged value no longer shares the caller's slice
+	b[0] = 'x'
+	assert.Equal(t, []byte("foo"), m.Value)
+	v, _ := s.Get("a")
+	assert.Equal(t, []byte("foo"), v)
+}
+
+func TestFloat64StoreSnapshotRestore(t *testing.T) {
+	s := primitivestore.NewFloat64Store()
+	s.Set("a", 1)
+	s.Set("b", 2)
+
+	snap := Float64Store(s).snapshot()
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": 2.0}, snap)
+
+	r := primitivestore.NewFloat64Store()
+	r.Set("c", 3)
+	assert.Nil(t, Float64Store(r).restore(snap))
+	assert.Equal(t, []string{"a", "b"}, r.SortedMembers())
+
+	// snapshot is a copy
+	s.Set("a", 5)
+	assert.Equal(t, 1.0, snap["a"])
+
+	// nil restores an empty store
+	assert.Nil(t, Float64Store(r).restore(nil))
+	assert.Equal(t, 0, r.Size())
+
+	// wrong value type leaves the store as it was
+	r.Set("c", 3)
+	assert.Equal(t, ErrValueType, Float64Store(r).restore(map[string]interface{}{"a": 1}))
+	assert.Equal(t, []string{"c"}, r.Members())
+}
+
+func TestOHLCSStoreApply(t *testing.T) {
+	s := seriesstore.NewOHLCSStore()
+	a := OHLCSStore(s)
+
+	bars := []seriesstore.OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+	m := &Mutation{Op: OpSet, Key: "a", Value: bars}
+	assert.Nil(t, a.apply(m))
+
+	bars[0].Open = 9
+	assert.Equal(t, float32(1), m.Value.([]seriesstore.OHLC)[0].Open)
+
+	assert.Nil(t, a.apply(&Mutation{Op: OpAppend, Key: "a", Value: []seriesstore.OHLC{{Open: 2}, {Open: 3}}}))
+	n, err := s.MemberLen("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	assert.Nil(t, a.apply(&Mutation{Op: OpSetIdx, Key: "a", Idx: 1, Value: seriesstore.OHLC{Open: 4}}))
+	v, err := s.GetIdx("a", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, float32(4), v.Open)
+
+	// store errors are passed through
+	assert.Equal(t, seriesstore.ErrIdxOutOfBounds, a.apply(&Mutation{Op: OpSetIdx, Key: "a", Idx: 3, Value: seriesstore.OHLC{}}))
+	assert.Equal(t, ErrValueType, a.apply(&Mutation{Op: OpSetIdx, Key: "a", Idx: 0, Value: &seriesstore.OHLC{}}))
+	assert.Equal(t, ErrValueType, a.apply(&Mutation{Op: OpAppend, Key: "a", Value: seriesstore.OHLC{}}))
+	assert.Equal(t, ErrUnsupportedOp, a.apply(&Mutation{Op: OpDelete, Key: "a"}))
+
+	assert.Nil(t, a.apply(&Mutation{Op: OpClear}))
+	assert.Equal(t, 0, s.Size())
+}
+
+func TestSnapshotGobRoundTrip(t *testing.T) {
+	snap := map[string]map[string]interface{}{
+		"bool":     {"a": true},
+		"bytes":    {"a": []byte("foo")},
+		"decimal":  {"a": decimal.MustParse("1.25")},
+		"duration": {"a": time.Second},
+		"time":     {"a": mockTime},
+		"uint32":   {"a": uint32(7)},
+		"ohlc":     {"a": []seriesstore.OHLC{{Open: 1}}},
+		"ohlcv":    {"a": []seriesstore.OHLCV{{Time: mockTime, Volume: 10}}},
+		"decimals": {"a": []decimal.Decimal{decimal.MustParse("0.10")}},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(&message{Kind: msgSnapshot, Snapshot: snap}))
+
+	var msg message
+	assert.Nil(t, gob.NewDecoder(&buf).Decode(&msg))
+	assert.Equal(t, true, msg.Snapshot["bool"]["a"])
+	assert.Equal(t, []byte("foo"), msg.Snapshot["bytes"]["a"])
+	assert.True(t, decimal.MustParse("1.25").Equal(msg.Snapshot["decimal"]["a"].(decimal.Decimal)))
+	assert.Equal(t, time.Second, msg.Snapshot["duration"]["a"])
+	assert.True(t, mockTime.Equal(msg.Snapshot["time"]["a"].(time.Time)))
+	assert.Equal(t, uint32(7), msg.Snapshot["uint32"]["a"])
+	assert.Equal(t, []seriesstore.OHLC{{Open: 1}}, msg.Snapshot["ohlc"]["a"])
+	assert.Equal(t, 10.0, msg.Snapshot["ohlcv"]["a"].([]seriesstore.OHLCV)[0].Volume)
+	assert.Equal(t, "0.10", msg.Snapshot["decimals"]["a"].([]decimal.Decimal)[0].String())
+
+	// decoded values restore into replicas
+	r := primitivestore.NewDurationStore()
+	assert.Nil(t, DurationStore(r).restore(msg.Snapshot["duration"]))
+	v, _ := r.Get("a")
+	assert.Equal(t, time.Second, v)
+}
+
+func TestFollowerReplicaReadOnly(t *testing.T) {
+	f := NewFollower("127.0.0.1:0")
+
+	r, err := f.Float64Store("px")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, r.Size())
+
+	// replicas are read-only views
+	_, writable := r.(interface {
+		Set(key string, value float64)
+	})
+	assert.False(t, writable)
+
+	_, err = f.OHLCSStore("px")
+	assert.Equal(t, ErrStoreExists, err)
+
+	s, err := f.OHLCSStore("bars")
+	assert.Nil(t, err)
+	_, err = s.GetIdx("a", 0)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+}
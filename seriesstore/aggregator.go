@@ -0,0 +1,260 @@
+package seriesstore
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Tick is a single trade of a symbol
+type Tick struct {
+	Symbol string
+	Price  float64
+	Size   float64
+	Time   time.Time
+}
+
+// BarType determines when a BarAggregator closes a bar
+type BarType int
+
+const (
+	// TimeBars close when a tick arrives in a later time interval
+	TimeBars BarType = iota
+	// TickBars close after a fixed number of ticks
+	TickBars
+	// VolumeBars close once the traded size reaches a threshold
+	VolumeBars
+	// DollarBars close once the traded price * size reaches a threshold
+	DollarBars
+)
+
+// BarCloseFunc is called with every bar closed by a BarAggregator
+// start is the interval start for time bars, else the time of the first tick of the bar
+type BarCloseFunc func(symbol string, start time.Time, bar OHLC)
+
+type barState struct {
+	bar     OHLC
+	start   time.Time
+	ticks   int
+	volume  float64
+	dollars float64
+}
+
+// BarAggregator builds OHLC bars per symbol from ticks
+// Completed bars are appended to the series of the symbol in an OHLCSStore
+// Volume and dollar bars close on the tick that reaches the threshold, ticks are never split across bars
+// Embedded sync.Mutex to provide atomic operation ability
+type BarAggregator struct {
+	sync.Mutex
+	barType   BarType
+	interval  time.Duration
+	threshold float64
+	bars      *OHLCSStore
+	onClose   BarCloseFunc
+	current   map[string]*barState
+}
+
+func newBarAggregator(barType BarType, bars *OHLCSStore, onClose BarCloseFunc) *BarAggregator {
+	return &BarAggregator{barType: barType, bars: bars, onClose: onClose, current: make(map[string]*barState)}
+}
+
+// NewTimeBarAggregator constructs a BarAggregator of bars spanning the given interval, e.g. time.Minute
+// Completed bars are appended to bars and passed to onClose if it is not nil
+// Returns ErrInvalidInterval if interval is not positive
+func NewTimeBarAggregator(bars *OHLCSStore, interval time.Duration, onClose BarCloseFunc) (*BarAggregator, error) {
+	if interval <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	a := newBarAggregator(TimeBars, bars, onClose)
+	a.interval = interval
+
+	return a, nil
+}
+
+// NewTickBarAggregator constructs a BarAggregator of bars made of the given number of ticks
+// Completed bars are appended to bars and passed to onClose if it is not nil
+// Returns ErrInvalidThreshold if ticks is not positive
+func NewTickBarAggregator(bars *OHLCSStore, ticks int, onClose BarCloseFunc) (*BarAggregator, error) {
+	if ticks < 1 {
+		return nil, ErrInvalidThreshold
+	}
+
+	a := newBarAggregator(TickBars, bars, onClose)
+	a.threshold = float64(ticks)
+
+	return a, nil
+}
+
+// NewVolumeBarAggregator constructs a BarAggregator of bars closing once the given size has traded
+// Completed bars are appended to bars and passed to onClose if it is not nil
+// Returns ErrInvalidThreshold if volume is not a positive number
+func NewVolumeBarAggregator(bars *OHLCSStore, volume float64, onClose BarCloseFunc) (*BarAggregator, error) {
+	if !(volume > 0) || math.IsInf(volume, 1) {
+		return nil, ErrInvalidThreshold
+	}
+
+	a := newBarAggregator(VolumeBars, bars, onClose)
+	a.threshold = volume
+
+	return a, nil
+}
+
+// NewDollarBarAggregator constructs a BarAggregator of bars closing once the given notional has traded
+// Completed bars are appended to bars and passed to onClose if it is not nil
+// Returns ErrInvalidThreshold if dollars is not a positive number
+func NewDollarBarAggregator(bars *OHLCSStore, dollars float64, onClose BarCloseFunc) (*BarAggregator, error) {
+	if !(dollars > 0) || math.IsInf(dollars, 1) {
+		return nil, ErrInvalidThreshold
+	}
+
+	a := newBarAggregator(DollarBars, bars, onClose)
+	a.threshold = dollars
+
+	return a, nil
+}
+
+// Type returns the bar type of the aggregator
+func (a *BarAggregator) Type() BarType {
+	return a.barType
+}
+
+type closedBar struct {
+	symbol string
+	start  time.Time
+	bar    OHLC
+}
+
+func (a *BarAggregator) closeBar(symbol string, st *barState) closedBar {
+	delete(a.current, symbol)
+	a.bars.Append(symbol, st.bar)
+
+	return closedBar{symbol, st.start, st.bar}
+}
+
+func (a *BarAggregator) add(tick *Tick) []closedBar {
+	var closed []closedBar
+	price := float32(tick.Price)
+
+	st, ok := a.current[tick.Symbol]
+
+	// a tick in a later interval closes the in progress time bar
+	if ok && a.barType == TimeBars && tick.Time.Truncate(a.interval).After(st.start) {
+		closed = append(closed, a.closeBar(tick.Symbol, st))
+		ok = false
+	}
+
+	if !ok {
+		st = &barState{bar: OHLC{price, price, price, price}, start: tick.Time}
+		if a.barType == TimeBars {
+			st.start = tick.Time.Truncate(a.interval)
+		}
+		a.current[tick.Symbol] = st
+	}
+
+	if price > st.bar.High {
+		st.bar.High = price
+	}
+	if price < st.bar.Low {
+		st.bar.Low = price
+	}
+	st.bar.Close = price
+	st.ticks++
+	st.volume += tick.Size
+	st.dollars += tick.Price * tick.Size
+
+	if a.full(st) {
+		closed = append(closed, a.closeBar(tick.Symbol, st))
+	}
+
+	return closed
+}
+
+func (a *BarAggregator) full(st *barState) bool {
+	switch a.barType {
+	case TickBars:
+		return float64(st.ticks) >= a.threshold
+	case VolumeBars:
+		return st.volume >= a.threshold
+	case DollarBars:
+		return st.dollars >= a.threshold
+	}
+
+	// time bars only close on later ticks or flushes
+	return false
+}
+
+func (a *BarAggregator) notify(closed []closedBar) {
+	if a.onClose == nil {
+		return
+	}
+
+	for _, c := range closed {
+		a.onClose(c.symbol, c.start, c.bar)
+	}
+}
+
+// Add aggregates the tick into the in progress bar of its symbol,
+// closing bars as required by the bar type of the aggregator
+// onClose is called after the aggregator is unlocked, so it may safely call back into the aggregator
+func (a *BarAggregator) Add(tick Tick) {
+	a.Lock()
+	closed := a.add(&tick)
+	a.Unlock()
+
+	a.notify(closed)
+}
+
+func (a *BarAggregator) flush(now time.Time) []closedBar {
+	var closed []closedBar
+	for sym, st := range a.current {
+		if a.barType == TimeBars && st.start.Add(a.interval).After(now) {
+			continue
+		}
+		closed = append(closed, a.closeBar(sym, st))
+	}
+
+	return closed
+}
+
+// Flush closes every in progress time bar whose interval ended at or before now
+// Use Flush to close time bars of symbols that stopped trading
+// For other bar types Flush closes every in progress bar
+func (a *BarAggregator) Flush(now time.Time) {
+	a.Lock()
+	closed := a.flush(now)
+	a.Unlock()
+
+	a.notify(closed)
+}
+
+// FlushAll closes every in progress bar regardless of its interval or threshold, e.g. at session end
+func (a *BarAggregator) FlushAll() {
+	a.Lock()
+	closed := make([]closedBar, 0, len(a.current))
+	for sym, st := range a.current {
+		closed = append(closed, a.closeBar(sym, st))
+	}
+	a.Unlock()
+
+	a.notify(closed)
+}
+
+func (a *BarAggregator) currentBar(symbol string) (OHLC, bool) {
+	st, ok := a.current[symbol]
+	if !ok {
+		return OHLC{}, false
+	}
+
+	return st.bar, true
+}
+
+// Current returns the in progress bar of the given symbol
+// returns the bar and boolean if a bar is in progress
+func (a *BarAggregator) Current(symbol string) (OHLC, bool) {
+	a.Lock()
+	bar, ok := a.currentBar(symbol)
+	a.Unlock()
+
+	return bar, ok
+}
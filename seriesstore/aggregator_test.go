@@ -0,0 +1,193 @@
+package seriesstore
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mockTickEpoch = time.Date(2018, 6, 1, 14, 30, 0, 0, time.UTC)
+
+func mockTick(price, size float64, offset time.Duration) Tick {
+	return Tick{Symbol: "foo", Price: price, Size: size, Time: mockTickEpoch.Add(offset)}
+}
+
+func TestAggregatorTimeBars(t *testing.T) {
+	ss := NewOHLCSStore()
+	starts := make([]time.Time, 0)
+	a, err := NewTimeBarAggregator(ss, time.Minute, func(symbol string, start time.Time, bar OHLC) {
+		assert.Equal(t, "foo", symbol)
+		starts = append(starts, start)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, TimeBars, a.Type())
+
+	a.Add(mockTick(10.0, 1, time.Second))
+	a.Add(mockTick(12.0, 1, 20*time.Second))
+	a.Add(mockTick(9.0, 1, 40*time.Second))
+	a.Add(mockTick(11.0, 1, 59*time.Second))
+
+	// nothing closed yet
+	assert.False(t, ss.isMember("foo"))
+	bar, ok := a.Current("foo")
+	assert.True(t, ok)
+	assert.Equal(t, OHLC{10.0, 12.0, 9.0, 11.0}, bar)
+
+	// next interval closes the first bar
+	a.Add(mockTick(13.0, 1, time.Minute+time.Second))
+	assert.Equal(t, []OHLC{{10.0, 12.0, 9.0, 11.0}}, ss.store["foo"])
+	assert.Equal(t, []time.Time{mockTickEpoch}, starts)
+
+	bar, ok = a.Current("foo")
+	assert.True(t, ok)
+	assert.Equal(t, OHLC{13.0, 13.0, 13.0, 13.0}, bar)
+
+	// skipped intervals produce no bars
+	a.Add(mockTick(14.0, 1, 5*time.Minute))
+	assert.Equal(t, 2, len(ss.store["foo"]))
+	assert.Equal(t, mockTickEpoch.Add(time.Minute), starts[1])
+}
+
+func TestAggregatorTickBars(t *testing.T) {
+	ss := NewOHLCSStore()
+	a, _ := NewTickBarAggregator(ss, 3, nil)
+
+	for i, p := range []float64{5.0, 7.0, 6.0, 8.0, 4.0} {
+		a.Add(mockTick(p, 1, time.Duration(i)*time.Second))
+	}
+
+	assert.Equal(t, []OHLC{{5.0, 7.0, 5.0, 6.0}}, ss.store["foo"])
+	bar, ok := a.Current("foo")
+	assert.True(t, ok)
+	assert.Equal(t, OHLC{8.0, 8.0, 4.0, 4.0}, bar)
+}
+
+func TestAggregatorVolumeBars(t *testing.T) {
+	ss := NewOHLCSStore()
+	a, _ := NewVolumeBarAggregator(ss, 100, nil)
+
+	a.Add(mockTick(10.0, 60, 0))
+	assert.False(t, ss.isMember("foo"))
+
+	// crossing tick closes the bar
+	a.Add(mockTick(11.0, 50, time.Second))
+	assert.Equal(t, []OHLC{{10.0, 11.0, 10.0, 11.0}}, ss.store["foo"])
+
+	_, ok := a.Current("foo")
+	assert.False(t, ok)
+}
+
+func TestAggregatorDollarBars(t *testing.T) {
+	ss := NewOHLCSStore()
+	a, _ := NewDollarBarAggregator(ss, 1000, nil)
+
+	a.Add(mockTick(10.0, 50, 0))
+	a.Add(mockTick(20.0, 10, time.Second))
+	assert.False(t, ss.isMember("foo"))
+
+	a.Add(mockTick(25.0, 12, 2*time.Second))
+	assert.Equal(t, []OHLC{{10.0, 25.0, 10.0, 25.0}}, ss.store["foo"])
+}
+
+func TestAggregatorInvalid(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	for _, d := range []time.Duration{0, -time.Minute} {
+		a, err := NewTimeBarAggregator(ss, d, nil)
+		assert.Nil(t, a)
+		assert.Equal(t, ErrInvalidInterval, err)
+	}
+
+	for _, n := range []int{0, -1} {
+		a, err := NewTickBarAggregator(ss, n, nil)
+		assert.Nil(t, a)
+		assert.Equal(t, ErrInvalidThreshold, err)
+	}
+
+	for _, v := range []float64{0, -100, math.NaN(), math.Inf(1)} {
+		a, err := NewVolumeBarAggregator(ss, v, nil)
+		assert.Nil(t, a)
+		assert.Equal(t, ErrInvalidThreshold, err)
+
+		a, err = NewDollarBarAggregator(ss, v, nil)
+		assert.Nil(t, a)
+		assert.Equal(t, ErrInvalidThreshold, err)
+	}
+}
+
+func TestAggregatorSymbols(t *testing.T) {
+	ss := NewOHLCSStore()
+	a, _ := NewTickBarAggregator(ss, 2, nil)
+
+	a.Add(Tick{Symbol: "a", Price: 1.0, Size: 1, Time: mockTickEpoch})
+	a.Add(Tick{Symbol: "b", Price: 2.0, Size: 1, Time: mockTickEpoch})
+	a.Add(Tick{Symbol: "a", Price: 3.0, Size: 1, Time: mockTickEpoch})
+
+	assert.Equal(t, []OHLC{{1.0, 3.0, 1.0, 3.0}}, ss.store["a"])
+	assert.False(t, ss.isMember("b"))
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	ss := NewOHLCSStore()
+	closed := 0
+	a, _ := NewTimeBarAggregator(ss, time.Minute, func(string, time.Time, OHLC) { closed++ })
+
+	a.Add(mockTick(10.0, 1, 10*time.Second))
+
+	// interval not over yet
+	a.Flush(mockTickEpoch.Add(30 * time.Second))
+	assert.Equal(t, 0, closed)
+
+	a.Flush(mockTickEpoch.Add(time.Minute))
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, 1, len(ss.store["foo"]))
+
+	_, ok := a.Current("foo")
+	assert.False(t, ok)
+}
+
+func TestAggregatorFlushAll(t *testing.T) {
+	ss := NewOHLCSStore()
+	a, _ := NewVolumeBarAggregator(ss, 1000, nil)
+
+	a.Add(mockTick(10.0, 1, 0))
+	a.Add(Tick{Symbol: "bar", Price: 2.0, Size: 1, Time: mockTickEpoch})
+
+	a.FlushAll()
+	assert.Equal(t, 2, ss.size())
+	assert.Equal(t, 0, len(a.current))
+}
+
+func TestAggregatorCallbackReentrant(t *testing.T) {
+	ss := NewOHLCSStore()
+	var a *BarAggregator
+	a, _ = NewTickBarAggregator(ss, 1, func(symbol string, start time.Time, bar OHLC) {
+		// must not deadlock
+		a.Current(symbol)
+	})
+
+	a.Add(mockTick(10.0, 1, 0))
+	assert.Equal(t, 1, len(ss.store["foo"]))
+}
+
+func TestAggregatorConcurrentAdd(t *testing.T) {
+	ss := NewOHLCSStore()
+	a, _ := NewTickBarAggregator(ss, 10, nil)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				a.Add(mockTick(float64(i), 1, 0))
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 40, len(ss.store["foo"]))
+}
@@ -0,0 +1,418 @@
+package seriesstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// CompressedFloat64SStore is a store of compressed float64 time series, for long histories
+// Every key is a CompressedSeries of the store chunk size, regular series take a few bits per point
+// Indexed reads return the same errors as Float64SStore
+// Embedded sync.Mutex to provide atomic operation ability
+type CompressedFloat64SStore struct {
+	sync.Mutex
+	chunkSize int
+	store     map[string]*CompressedSeries
+	index     *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewCompressedFloat64SStore constructs and initializes a new CompressedFloat64SStore
+// compressing every chunkSize points of a series together, or DefaultChunkSize points if chunkSize < 1
+// Always use this function to init new CompressedFloat64SStores
+func NewCompressedFloat64SStore(chunkSize int) *CompressedFloat64SStore {
+	if chunkSize < 1 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &CompressedFloat64SStore{chunkSize: chunkSize, store: make(map[string]*CompressedSeries)}
+}
+
+func (s *CompressedFloat64SStore) append(key string, points ...Point) error {
+	c, ok := s.store[key]
+	if !ok {
+		// never create an empty series
+		if len(points) == 0 {
+			return nil
+		}
+
+		c = NewCompressedSeries(s.chunkSize)
+		if err := c.Append(points...); err != nil {
+			return err
+		}
+
+		s.store[key] = c
+		s.index.Insert(key)
+
+		return nil
+	}
+
+	return c.Append(points...)
+}
+
+// Append adds the given points to the end of the series mapped to the given key in the store
+// The key is created if it does not exist
+// returns ErrOutOfOrder without appending any point if a point is older than the one before it
+func (s *CompressedFloat64SStore) Append(key string, points ...Point) error {
+	s.Lock()
+	err := s.append(key, points...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *CompressedFloat64SStore) get(key string) ([]Point, bool) {
+	c, ok := s.store[key]
+	if !ok {
+		return nil, false
+	}
+
+	points, _ := c.Range(0, c.Len())
+
+	return points, true
+}
+
+// Get returns all points of the series for the given key, decompressed
+func (s *CompressedFloat64SStore) Get(key string) ([]Point, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *CompressedFloat64SStore) getIdx(key string, idx int) (Point, error) {
+	c, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return Point{}, ErrKeyDoesNotExist
+	}
+
+	return c.At(idx)
+}
+
+// GetIdx returns the point for the given key at the specified index
+func (s *CompressedFloat64SStore) GetIdx(key string, idx int) (Point, error) {
+	s.Lock()
+	v, err := s.getIdx(key, idx)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *CompressedFloat64SStore) getRange(key string, lower, upper int) ([]Point, error) {
+	c, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return c.Range(lower, upper)
+}
+
+// GetRange returns all points for the given key within the specified range (inclusive:exclusive)
+func (s *CompressedFloat64SStore) GetRange(key string, lower, upper int) ([]Point, error) {
+	s.Lock()
+	v, err := s.getRange(key, lower, upper)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *CompressedFloat64SStore) getBetween(key string, start, end time.Time) ([]Point, error) {
+	c, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return c.Between(start, end), nil
+}
+
+// GetBetween returns the points for the given key from start up to, but not including, end
+// Only the chunks overlapping the time range are decompressed
+func (s *CompressedFloat64SStore) GetBetween(key string, start, end time.Time) ([]Point, error) {
+	s.Lock()
+	v, err := s.getBetween(key, start, end)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *CompressedFloat64SStore) iterator(key string) (*CompressedIterator, error) {
+	c, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return c.Iterator(), nil
+}
+
+// Iterator returns an iterator decompressing the points of the series for the given key in order,
+// without holding the store lock, appends made after the call are not seen
+func (s *CompressedFloat64SStore) Iterator(key string) (*CompressedIterator, error) {
+	s.Lock()
+	it, err := s.iterator(key)
+	s.Unlock()
+
+	return it, err
+}
+
+func (s *CompressedFloat64SStore) memberLen(key string) (int, error) {
+	c, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return c.Len(), nil
+}
+
+// MemberLen returns the number of points in the series for the given key
+func (s *CompressedFloat64SStore) MemberLen(key string) (int, error) {
+	s.Lock()
+	n, err := s.memberLen(key)
+	s.Unlock()
+
+	return n, err
+}
+
+func (s *CompressedFloat64SStore) memberCompressionRatio(key string) (float64, error) {
+	c, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return c.CompressionRatio(), nil
+}
+
+// MemberCompressionRatio returns the compression ratio of the series for the given key,
+// see CompressedSeries.CompressionRatio
+func (s *CompressedFloat64SStore) MemberCompressionRatio(key string) (float64, error) {
+	s.Lock()
+	r, err := s.memberCompressionRatio(key)
+	s.Unlock()
+
+	return r, err
+}
+
+func (s *CompressedFloat64SStore) compressionRatio() float64 {
+	points, size := 0, 0
+	for _, c := range s.store {
+		points += c.Len()
+		size += c.Bytes()
+	}
+
+	if points == 0 {
+		return 0
+	}
+
+	return float64(points*rawPointSize) / float64(size)
+}
+
+// CompressionRatio returns the size of all points of the store uncompressed, 16 bytes each, over their size in the store
+// returns 0 for an empty store
+func (s *CompressedFloat64SStore) CompressionRatio() float64 {
+	s.Lock()
+	r := s.compressionRatio()
+	s.Unlock()
+
+	return r
+}
+
+func (s *CompressedFloat64SStore) delete(key string) {
+	delete(s.store, key)
+	s.index.Delete(key)
+}
+
+// Delete removes the given key and its series from the store
+func (s *CompressedFloat64SStore) Delete(key string) {
+	s.Lock()
+	s.delete(key)
+	s.Unlock()
+}
+
+func (s *CompressedFloat64SStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *CompressedFloat64SStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *CompressedFloat64SStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *CompressedFloat64SStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *CompressedFloat64SStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *CompressedFloat64SStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *CompressedFloat64SStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *CompressedFloat64SStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *CompressedFloat64SStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *CompressedFloat64SStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *CompressedFloat64SStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *CompressedFloat64SStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *CompressedFloat64SStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *CompressedFloat64SStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *CompressedFloat64SStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *CompressedFloat64SStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *CompressedFloat64SStore) clear() {
+	s.store = make(map[string]*CompressedSeries)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *CompressedFloat64SStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
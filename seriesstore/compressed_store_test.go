@@ -0,0 +1,354 @@
+package seriesstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockCompressedStore() *CompressedFloat64SStore {
+	return NewCompressedFloat64SStore(4)
+}
+
+func mockCompressedSeries() *CompressedSeries {
+	c := NewCompressedSeries(4)
+	c.Append(mockPoints(10)...)
+
+	return c
+}
+
+func TestNewCompressedFloat64SStore(t *testing.T) {
+	assert.Equal(t, DefaultChunkSize, NewCompressedFloat64SStore(0).chunkSize)
+
+	hs := mockCompressedStore()
+	assert.Equal(t, 4, hs.chunkSize)
+	assert.NotNil(t, hs.store)
+}
+
+func TestCompressedStoreAppend(t *testing.T) {
+	hs := mockCompressedStore()
+	points := mockPoints(10)
+
+	// no points does not create key
+	assert.Nil(t, hs.Append("foo"))
+	assert.False(t, hs.IsMember("foo"))
+
+	// out of order does not create key
+	assert.Equal(t, ErrOutOfOrder, hs.Append("foo", points[1], points[0]))
+	assert.False(t, hs.IsMember("foo"))
+
+	assert.Nil(t, hs.Append("foo", points[:6]...))
+	assert.Nil(t, hs.Append("foo", points[6:]...))
+	assert.Equal(t, 4, hs.store["foo"].ChunkSize())
+	assert.Equal(t, 10, hs.store["foo"].Len())
+
+	assert.Equal(t, ErrOutOfOrder, hs.Append("foo", points[0]))
+	assert.Equal(t, 10, hs.store["foo"].Len())
+}
+
+func TestCompressedStoreGet(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no key
+	_, ok := hs.Get("foo")
+	assert.False(t, ok)
+
+	hs.store["foo"] = mockCompressedSeries()
+	v, ok := hs.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, mockPoints(10), v)
+}
+
+func TestCompressedStoreGetIdx(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no key
+	_, err := hs.GetIdx("foo", 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["foo"] = mockCompressedSeries()
+
+	// out of bounds
+	_, err = hs.GetIdx("foo", -1)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = hs.GetIdx("foo", 10)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	v, err := hs.GetIdx("foo", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, mockPoints(10)[0], v)
+	v, err = hs.GetIdx("foo", 9)
+	assert.Nil(t, err)
+	assert.Equal(t, mockPoints(10)[9], v)
+}
+
+func TestCompressedStoreGetRange(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no key
+	_, err := hs.GetRange("foo", 0, 1)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["foo"] = mockCompressedSeries()
+
+	// out of bounds
+	_, err = hs.GetRange("foo", -1, 2)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = hs.GetRange("foo", 0, 11)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	v, err := hs.GetRange("foo", 2, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, mockPoints(10)[2:7], v)
+}
+
+func TestCompressedStoreGetBetween(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no key
+	_, err := hs.GetBetween("foo", mockPointStart, mockPointStart.Add(time.Hour))
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["foo"] = mockCompressedSeries()
+	v, err := hs.GetBetween("foo", mockPointStart.Add(3*time.Second), mockPointStart.Add(5*time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, mockPoints(10)[3:5], v)
+}
+
+func TestCompressedStoreIterator(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no key
+	_, err := hs.Iterator("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.Append("foo", mockPoints(10)...)
+	it, err := hs.Iterator("foo")
+	assert.Nil(t, err)
+
+	// appends after the iterator is created are not seen
+	hs.Append("foo", mockPoints(11)[10])
+
+	n := 0
+	for it.Next() {
+		assert.Equal(t, mockPoints(10)[n], it.At())
+		n++
+	}
+	assert.Equal(t, 10, n)
+}
+
+func TestCompressedStoreMemberLen(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no key
+	_, err := hs.MemberLen("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["foo"] = mockCompressedSeries()
+	n, err := hs.MemberLen("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 10, n)
+}
+
+func TestCompressedStoreCompressionRatio(t *testing.T) {
+	hs := NewCompressedFloat64SStore(0)
+
+	// no keys
+	assert.Equal(t, 0.0, hs.CompressionRatio())
+	_, err := hs.MemberCompressionRatio("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.Append("foo", mockPoints(1200)...)
+	hs.Append("bar", mockPoints(120)...)
+	r, err := hs.MemberCompressionRatio("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, hs.store["foo"].CompressionRatio(), r)
+	assert.True(t, r > 8)
+
+	total := float64(1320*16) / float64(hs.store["foo"].Bytes()+hs.store["bar"].Bytes())
+	assert.Equal(t, total, hs.CompressionRatio())
+}
+
+func TestCompressedStoreDelete(t *testing.T) {
+	hs := mockCompressedStore()
+	hs.EnableKeyIndex()
+	hs.Append("foo", mockPoints(1)...)
+
+	hs.Delete("foo")
+	assert.False(t, hs.IsMember("foo"))
+	assert.Equal(t, 0, hs.index.Len())
+
+	// no key
+	hs.Delete("foo")
+}
+
+func TestCompressedStoreSize(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no keys
+	size := hs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	hs.store["a"] = mockCompressedSeries()
+	hs.store["b"] = mockCompressedSeries()
+
+	size = hs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestCompressedStoreMembers(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no keys
+	mems := hs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	hs.store["a"] = mockCompressedSeries()
+	hs.store["b"] = mockCompressedSeries()
+
+	mems = hs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestCompressedStoreSortedMembers(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no keys
+	assert.Equal(t, []string{}, hs.SortedMembers())
+
+	hs.store["MSFT:bid"] = mockCompressedSeries()
+	hs.store["AAPL:bid"] = mockCompressedSeries()
+	hs.store["AAPL:ask"] = mockCompressedSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, hs.SortedMembers())
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, hs.SortedMembers())
+}
+
+func TestCompressedStoreMembersWithPrefix(t *testing.T) {
+	hs := mockCompressedStore()
+
+	hs.store["MSFT:bid"] = mockCompressedSeries()
+	hs.store["AAPL:bid"] = mockCompressedSeries()
+	hs.store["AAPL:ask"] = mockCompressedSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, hs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, hs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, hs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, hs.MembersWithPrefix("GOOG"))
+}
+
+func TestCompressedStoreMembersMatching(t *testing.T) {
+	hs := mockCompressedStore()
+
+	hs.store["MSFT:bid"] = mockCompressedSeries()
+	hs.store["AAPL:bid"] = mockCompressedSeries()
+	hs.store["AAPL:ask"] = mockCompressedSeries()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, hs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, hs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, hs.MembersMatching("GOOG:*"))
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, hs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, hs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, hs.MembersMatching("GOOG:*"))
+}
+
+func TestCompressedStoreKeyIndex(t *testing.T) {
+	hs := mockCompressedStore()
+	hs.store["foo"] = mockCompressedSeries()
+
+	// existing keys are indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, hs.index.Keys())
+
+	// created keys are indexed
+	hs.Append("bar", mockPoints(1)...)
+	assert.Equal(t, []string{"bar", "foo"}, hs.index.Keys())
+
+	// clear
+	hs.Clear()
+	assert.Equal(t, 0, hs.index.Len())
+}
+
+func TestCompressedStoreScan(t *testing.T) {
+	hs := mockCompressedStore()
+
+	hs.store["MSFT:bid"] = mockCompressedSeries()
+	hs.store["AAPL:bid"] = mockCompressedSeries()
+	hs.store["AAPL:ask"] = mockCompressedSeries()
+
+	keys, next := hs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = hs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = hs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, hs.index)
+
+	// no keys
+	hs.Clear()
+	keys, next = hs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestCompressedStoreIsMember(t *testing.T) {
+	hs := mockCompressedStore()
+
+	// no keys
+	ok := hs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	hs.store["foo"] = mockCompressedSeries()
+
+	ok = hs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestCompressedStoreClear(t *testing.T) {
+	hs := mockCompressedStore()
+
+	hs.store["foo"] = mockCompressedSeries()
+	assert.Equal(t, 1, len(hs.store))
+
+	hs.Clear()
+	assert.Equal(t, 0, len(hs.store))
+}
+
+func TestCompressedStoreConcurrentAppendAndGet(t *testing.T) {
+	hs := mockCompressedStore()
+	points := mockPoints(100)
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			hs.Append("foo", points[i])
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			hs.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,115 @@
+package seriesstore
+
+import (
+	"math"
+	"sort"
+)
+
+// rankedValue is a key of a cross section and its value
+type rankedValue struct {
+	key   string
+	value float64
+}
+
+// sortSection returns the non NaN values of xs in value order, highest first if descending,
+// then ascending key order for equal values
+func sortSection(xs map[string]float64, descending bool) []rankedValue {
+	out := make([]rankedValue, 0, len(xs))
+	for k, v := range xs {
+		if !math.IsNaN(v) {
+			out = append(out, rankedValue{k, v})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].value != out[j].value {
+			return (out[i].value < out[j].value) != descending
+		}
+		return out[i].key < out[j].key
+	})
+
+	return out
+}
+
+// SortedKeys returns the keys of the cross section ordered by value, highest first if descending
+// Equal values are ordered by key, NaN values are excluded
+func SortedKeys(xs map[string]float64, descending bool) []string {
+	sorted := sortSection(xs, descending)
+
+	keys := make([]string, len(sorted))
+	for i, rv := range sorted {
+		keys[i] = rv.key
+	}
+
+	return keys
+}
+
+// Ranks returns the ascending rank of every key of the cross section, from 1 for the lowest value
+// Equal values share the mean of their ranks, e.g. 2.5 for a tie in 2nd and 3rd, NaN values are excluded
+func Ranks(xs map[string]float64) map[string]float64 {
+	sorted := sortSection(xs, false)
+	ranks := make(map[string]float64, len(sorted))
+
+	for i := 0; i < len(sorted); {
+		// find the run of ties starting at i
+		j := i + 1
+		for j < len(sorted) && sorted[j].value == sorted[i].value {
+			j++
+		}
+
+		// mean of ranks i+1 to j
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			ranks[sorted[k].key] = rank
+		}
+		i = j
+	}
+
+	return ranks
+}
+
+// PercentileRanks returns the percentile rank in (0:1) of every key of the cross section,
+// the fraction of values below it plus half the fraction equal to it
+// NaN values are excluded
+func PercentileRanks(xs map[string]float64) map[string]float64 {
+	ranks := Ranks(xs)
+	n := float64(len(ranks))
+
+	for k, r := range ranks {
+		ranks[k] = (r - 0.5) / n
+	}
+
+	return ranks
+}
+
+// Percentile returns the value at quantile q (0 to 1) of the cross section, e.g. 0.5 for the median,
+// interpolating linearly between the closest values
+// NaN values are excluded
+// returns ErrInvalidQuantile if q is not within 0 and 1, or ErrEmptyCrossSection if there are no values
+func Percentile(xs map[string]float64, q float64) (float64, error) {
+	if !(q >= 0 && q <= 1) {
+		return 0.0, ErrInvalidQuantile
+	}
+
+	sorted := sortSection(xs, false)
+	if len(sorted) == 0 {
+		return 0.0, ErrEmptyCrossSection
+	}
+
+	pos := q * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+
+	return sorted[lower].value + frac*(sorted[upper].value-sorted[lower].value), nil
+}
+
+// OHLCCloses returns the close price of every bar of an OHLC cross section, e.g. to rank Latest bars
+func OHLCCloses(xs map[string]OHLC) map[string]float64 {
+	out := make(map[string]float64, len(xs))
+	for k, bar := range xs {
+		out[k] = float64(bar.Close)
+	}
+
+	return out
+}
@@ -0,0 +1,73 @@
+package seriesstore
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockCrossSection() map[string]float64 {
+	return map[string]float64{"AAPL": 3.0, "MSFT": 1.0, "GOOG": 3.0, "AMZN": -2.0, "HALT": math.NaN()}
+}
+
+func TestSortedKeys(t *testing.T) {
+	// empty
+	assert.Equal(t, []string{}, SortedKeys(map[string]float64{}, false))
+
+	// ties ordered by key, NaN excluded
+	assert.Equal(t, []string{"AMZN", "MSFT", "AAPL", "GOOG"}, SortedKeys(mockCrossSection(), false))
+	assert.Equal(t, []string{"AAPL", "GOOG", "MSFT", "AMZN"}, SortedKeys(mockCrossSection(), true))
+
+	// descending keeps ties in key order
+	tied := map[string]float64{"b": 1, "a": 1, "d": 2, "c": 2}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, SortedKeys(tied, false))
+	assert.Equal(t, []string{"c", "d", "a", "b"}, SortedKeys(tied, true))
+}
+
+func TestRanks(t *testing.T) {
+	// empty
+	assert.Equal(t, map[string]float64{}, Ranks(map[string]float64{}))
+
+	ranks := Ranks(mockCrossSection())
+	assert.Equal(t, map[string]float64{"AMZN": 1, "MSFT": 2, "AAPL": 3.5, "GOOG": 3.5}, ranks)
+}
+
+func TestPercentileRanks(t *testing.T) {
+	// single value is the median
+	assert.Equal(t, map[string]float64{"AAPL": 0.5}, PercentileRanks(map[string]float64{"AAPL": 1}))
+
+	pr := PercentileRanks(mockCrossSection())
+	assert.Equal(t, map[string]float64{"AMZN": 0.125, "MSFT": 0.375, "AAPL": 0.75, "GOOG": 0.75}, pr)
+}
+
+func TestPercentile(t *testing.T) {
+	// invalid
+	_, err := Percentile(mockCrossSection(), 1.5)
+	assert.Equal(t, ErrInvalidQuantile, err)
+	_, err = Percentile(mockCrossSection(), math.NaN())
+	assert.Equal(t, ErrInvalidQuantile, err)
+
+	// empty
+	_, err = Percentile(map[string]float64{"HALT": math.NaN()}, 0.5)
+	assert.Equal(t, ErrEmptyCrossSection, err)
+
+	// values -2, 1, 3, 3
+	v, err := Percentile(mockCrossSection(), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, -2.0, v)
+
+	v, _ = Percentile(mockCrossSection(), 1)
+	assert.Equal(t, 3.0, v)
+
+	v, _ = Percentile(mockCrossSection(), 0.5)
+	assert.Equal(t, 2.0, v)
+
+	v, _ = Percentile(mockCrossSection(), 0.25)
+	assert.InDelta(t, 0.25, v, 1e-12)
+}
+
+func TestOHLCCloses(t *testing.T) {
+	closes := OHLCCloses(map[string]OHLC{"AAPL": {1, 2, 0.5, 1.5}, "MSFT": {3, 4, 2, 3.25}})
+	assert.Equal(t, map[string]float64{"AAPL": 1.5, "MSFT": 3.25}, closes)
+}
@@ -0,0 +1,646 @@
+package seriesstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
+)
+
+// DecimalSStore is a store of fixed point decimal slices
+// Implements the SeriesStore interface
+// All getter and setter functions provide bound checks where applicable
+// Every value keeps its own scale, so precision is chosen per key
+// Embedded sync.Mutex to provide atomic operation ability
+type DecimalSStore struct {
+	sync.Mutex
+	store  map[string][]decimal.Decimal
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
+}
+
+// NewDecimalSStore constructs and initializes a new DecimalSStore
+// Always use this function to init new DecimalSStores
+func NewDecimalSStore() *DecimalSStore {
+	return &DecimalSStore{store: make(map[string][]decimal.Decimal)}
+}
+
+func (s *DecimalSStore) set(key string, value []decimal.Decimal) {
+	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashDecimals(merkle.Init(key), value))
+	}
+}
+
+// Set stores the given value mapped to the given key in the store
+func (s *DecimalSStore) Set(key string, value []decimal.Decimal) {
+	s.Lock()
+	s.set(key, value)
+	s.Unlock()
+}
+
+func (s *DecimalSStore) append(key string, values ...decimal.Decimal) {
+	s.store[key] = append(s.store[key], values...)
+	s.index.Insert(key)
+
+	// extend the hash of the series instead of hashing it again
+	if s.digest != nil {
+		h, ok := s.digest.State(key)
+		if !ok {
+			h = merkle.Init(key)
+		}
+		s.digest.Set(key, hashDecimals(h, values))
+	}
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store
+// The key is created if it does not exist
+func (s *DecimalSStore) Append(key string, values ...decimal.Decimal) {
+	s.Lock()
+	s.append(key, values...)
+	s.Unlock()
+}
+
+func (s *DecimalSStore) setIdx(key string, idx int, value decimal.Decimal) error {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	if idx < 0 || idx >= len(v) {
+		return ErrIdxOutOfBounds
+	}
+
+	s.store[key][idx] = value
+	if s.digest != nil {
+		s.digest.Set(key, hashDecimals(merkle.Init(key), s.store[key]))
+	}
+
+	return nil
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *DecimalSStore) SetIdx(key string, idx int, value decimal.Decimal) error {
+	s.Lock()
+	err := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *DecimalSStore) get(key string) ([]decimal.Decimal, bool) {
+	// explicitly return second return value
+	v, ok := s.store[key]
+
+	return v, ok
+}
+
+// Get returns the value for the given key
+func (s *DecimalSStore) Get(key string) ([]decimal.Decimal, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *DecimalSStore) getIdx(key string, idx int) (decimal.Decimal, error) {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return decimal.Decimal{}, ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	if idx < 0 || idx >= len(v) {
+		return decimal.Decimal{}, ErrIdxOutOfBounds
+	}
+
+	return s.store[key][idx], nil
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *DecimalSStore) GetIdx(key string, idx int) (decimal.Decimal, error) {
+	s.Lock()
+	v, err := s.getIdx(key, idx)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *DecimalSStore) getRange(key string, lower, upper int) ([]decimal.Decimal, error) {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	if lower < 0 || lower > len(v) || upper < 0 || upper > len(v) {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.store[key][lower:upper], nil
+}
+
+// GetRange returns all values for the given key within the specified range (inclusive:exclusive)
+func (s *DecimalSStore) GetRange(key string, lower, upper int) ([]decimal.Decimal, error) {
+	s.Lock()
+	v, err := s.getRange(key, lower, upper)
+	s.Unlock()
+
+	return v, err
+}
+
+func copyDecimals(xs []decimal.Decimal) []decimal.Decimal {
+	return append(make([]decimal.Decimal, 0, len(xs)), xs...)
+}
+
+func equalDecimals(a, b []decimal.Decimal) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *DecimalSStore) snapshot() map[string][]decimal.Decimal {
+	m := make(map[string][]decimal.Decimal, len(s.store))
+	for k, v := range s.store {
+		m[k] = copyDecimals(v)
+	}
+
+	return m
+}
+
+func (s *DecimalSStore) clone() *DecimalSStore {
+	c := &DecimalSStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *DecimalSStore) Clone() *DecimalSStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *DecimalSStore) mergeFrom(values map[string][]decimal.Decimal, conflictFn func(key string, current, incoming []decimal.Decimal) []decimal.Decimal) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *DecimalSStore) MergeFrom(other *DecimalSStore, conflictFn func(key string, current, incoming []decimal.Decimal) []decimal.Decimal) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *DecimalSStore) diff(values map[string][]decimal.Decimal) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !equalDecimals(v, ov) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *DecimalSStore) Diff(other *DecimalSStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *DecimalSStore) replaceAll(store map[string][]decimal.Decimal) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *DecimalSStore) ReplaceAll(values map[string][]decimal.Decimal) {
+	store := make(map[string][]decimal.Decimal, len(values))
+	for k, v := range values {
+		store[k] = copyDecimals(v)
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+// hashDecimals adds the values of a series to the hash state of its key
+func hashDecimals(h uint64, xs []decimal.Decimal) uint64 {
+	for _, x := range xs {
+		// drop trailing zeros so equal values of different scales hash the same
+		m, scale := x.Mantissa(), x.Scale()
+		for scale > 0 && m%10 == 0 {
+			m /= 10
+			scale--
+		}
+		h = merkle.WriteUint64(merkle.WriteUint64(h, uint64(m)), uint64(scale))
+	}
+
+	return h
+}
+
+func (s *DecimalSStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashDecimals(merkle.Init(k), v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled, Append only hashes the appended values
+// Values changed through a slice given to Set or returned by Get are not seen by the digest
+func (s *DecimalSStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *DecimalSStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashDecimals(merkle.Init(k), v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *DecimalSStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *DecimalSStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashDecimals(merkle.Init(k), v))
+	}
+
+	return t
+}
+
+func (s *DecimalSStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string][]decimal.Decimal)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *DecimalSStore) applyEntries(buckets merkle.BucketSet, entries map[string][]decimal.Decimal) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			delete(s.store, k)
+			s.index.Delete(k)
+			s.digest.Delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashDecimals(merkle.Init(k), cur) != hashDecimals(merkle.Init(k), v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *DecimalSStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string][]decimal.Decimal
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *DecimalSStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *DecimalSStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
+func (s *DecimalSStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *DecimalSStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *DecimalSStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *DecimalSStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalSStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *DecimalSStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *DecimalSStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *DecimalSStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalSStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *DecimalSStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalSStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *DecimalSStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *DecimalSStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *DecimalSStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *DecimalSStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *DecimalSStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *DecimalSStore) memberLen(key string) (int, error) {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return len(v), nil
+}
+
+// MemberLen returns the length of the series value stored at the given key
+func (s *DecimalSStore) MemberLen(key string) (int, error) {
+	s.Lock()
+	l, err := s.memberLen(key)
+	s.Unlock()
+
+	return l, err
+}
+
+func (s *DecimalSStore) clear() {
+	s.store = make(map[string][]decimal.Decimal)
+	s.index.Clear()
+	s.digest.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *DecimalSStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
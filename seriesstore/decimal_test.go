@@ -0,0 +1,525 @@
+package seriesstore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/blacklabcapital/safestore/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockDecimalTen = decimal.MustParse("10.00")
+
+func mockDecimalSeries() []decimal.Decimal {
+	return []decimal.Decimal{
+		decimal.MustParse("1.00"), decimal.MustParse("2.00"), decimal.MustParse("3.00"),
+		decimal.MustParse("4.00"), decimal.MustParse("5.00"),
+	}
+}
+
+func TestDecimalSet(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	ss.Set("foo", mockDecimalSeries())
+	assert.Equal(t, ss.store["foo"], mockDecimalSeries())
+}
+
+func TestDecimalAppend(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// key not exist
+	ss.Append("foo", mockDecimalSeries()...)
+	assert.Equal(t, mockDecimalSeries(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", mockDecimalTen)
+	assert.Equal(t, len(mockDecimalSeries())+1, len(ss.store["foo"]))
+	assert.Equal(t, mockDecimalTen, ss.store["foo"][len(mockDecimalSeries())])
+}
+
+func TestDecimalSetIdx(t *testing.T) {
+	// key not exist
+	ss := NewDecimalSStore()
+
+	err := ss.SetIdx("foo", 1, mockDecimalTen)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockDecimalSeries()
+	err = ss.SetIdx("foo", 1, mockDecimalTen)
+	assert.Nil(t, err)
+	assert.Equal(t, mockDecimalTen, ss.store["foo"][1])
+
+	// last idx
+	err = ss.SetIdx("foo", 4, mockDecimalTen)
+	assert.Nil(t, err)
+	assert.Equal(t, mockDecimalTen, ss.store["foo"][4])
+
+	// out of bounds
+	// lower
+	err = ss.SetIdx("foo", -1, mockDecimalTen)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	// upper
+	err = ss.SetIdx("foo", 5, mockDecimalTen)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestDecimalGet(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no key yet
+	series, ok := ss.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	ss.store["foo"] = mockDecimalSeries()
+	series, ok = ss.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, series, mockDecimalSeries())
+}
+
+func TestDecimalGetIdx(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no key
+	v, err := ss.GetIdx("foo", 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockDecimalSeries()
+	v, err = ss.GetIdx("foo", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, mockDecimalSeries()[0], v)
+
+	// last idx
+	v, err = ss.GetIdx("foo", 4)
+	assert.Nil(t, err)
+	assert.Equal(t, mockDecimalSeries()[4], v)
+
+	// out of bounds
+	// lower
+	v, err = ss.GetIdx("foo", -1)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	// upper
+	v, err = ss.GetIdx("foo", 10)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestDecimalGetRange(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no key
+	rng, err := ss.GetRange("foo", 0, 5)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockDecimalSeries()
+
+	// full range
+	rng, err = ss.GetRange("foo", 0, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, mockDecimalSeries(), rng)
+
+	// partial range
+	rng, err = ss.GetRange("foo", 0, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, mockDecimalSeries()[0:3], rng)
+
+	// out of bounds
+	// lower
+	rng, err = ss.GetRange("foo", -1, 3)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	// upper
+	rng, err = ss.GetRange("foo", 0, 10)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestDecimalClone(t *testing.T) {
+	s := NewDecimalSStore()
+	s.store["a"] = mockDecimalSeries()
+	s.store["b"] = []decimal.Decimal{mockDecimalTen}
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockDecimalSeries())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = mockDecimalTen
+	assert.Equal(t, mockDecimalSeries(), s.store["a"])
+}
+
+func TestDecimalMergeFrom(t *testing.T) {
+	o := NewDecimalSStore()
+	o.store["b"] = []decimal.Decimal{mockDecimalTen}
+	o.store["c"] = []decimal.Decimal{mockDecimalTen}
+
+	// incoming values win without a conflict func
+	s := NewDecimalSStore()
+	s.store["a"] = mockDecimalSeries()
+	s.store["b"] = mockDecimalSeries()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]decimal.Decimal{"a": mockDecimalSeries(), "b": []decimal.Decimal{mockDecimalTen}, "c": []decimal.Decimal{mockDecimalTen}}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewDecimalSStore()
+	s.store["a"] = mockDecimalSeries()
+	s.store["b"] = mockDecimalSeries()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []decimal.Decimal) []decimal.Decimal {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockDecimalSeries(), current)
+		assert.Equal(t, []decimal.Decimal{mockDecimalTen}, incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]decimal.Decimal{"a": mockDecimalSeries(), "b": mockDecimalSeries(), "c": []decimal.Decimal{mockDecimalTen}}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]decimal.Decimal{"b": []decimal.Decimal{mockDecimalTen}, "c": []decimal.Decimal{mockDecimalTen}}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestDecimalDiff(t *testing.T) {
+	s := NewDecimalSStore()
+	s.store["a"] = mockDecimalSeries()
+	s.store["b"] = mockDecimalSeries()
+	s.store["c"] = mockDecimalSeries()
+
+	o := NewDecimalSStore()
+	o.store["b"] = mockDecimalSeries()
+	o.store["c"] = []decimal.Decimal{mockDecimalTen}
+	o.store["d"] = []decimal.Decimal{mockDecimalTen}
+	o.store["e"] = []decimal.Decimal{mockDecimalTen}
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewDecimalSStore().Diff(NewDecimalSStore()).Empty())
+}
+
+func TestDecimalReplaceAll(t *testing.T) {
+	s := NewDecimalSStore()
+	s.store["a"] = mockDecimalSeries()
+	s.EnableKeyIndex()
+
+	values := map[string][]decimal.Decimal{"b": []decimal.Decimal{mockDecimalTen}, "c": mockDecimalSeries()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockDecimalSeries()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestDecimalSize(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no keys
+	size := ss.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	ss.store["a"] = mockDecimalSeries()
+	ss.store["b"] = mockDecimalSeries()
+
+	size = ss.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestDecimalMembers(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no keys
+	mems := ss.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	ss.store["a"] = mockDecimalSeries()
+	ss.store["b"] = mockDecimalSeries()
+
+	mems = ss.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestDecimalSortedMembers(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockDecimalSeries()
+	ss.store["AAPL:bid"] = mockDecimalSeries()
+	ss.store["AAPL:ask"] = mockDecimalSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestDecimalMembersWithPrefix(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	ss.store["MSFT:bid"] = mockDecimalSeries()
+	ss.store["AAPL:bid"] = mockDecimalSeries()
+	ss.store["AAPL:ask"] = mockDecimalSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestDecimalMembersMatching(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	ss.store["MSFT:bid"] = mockDecimalSeries()
+	ss.store["AAPL:bid"] = mockDecimalSeries()
+	ss.store["AAPL:ask"] = mockDecimalSeries()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestDecimalKeyIndex(t *testing.T) {
+	ss := NewDecimalSStore()
+	ss.store["foo"] = mockDecimalSeries()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockDecimalSeries())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestDecimalScan(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	ss.store["MSFT:bid"] = mockDecimalSeries()
+	ss.store["AAPL:bid"] = mockDecimalSeries()
+	ss.store["AAPL:ask"] = mockDecimalSeries()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestDecimalIsMember(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no keys
+	ok := ss.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	ss.store["foo"] = mockDecimalSeries()
+
+	ok = ss.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestDecimalMemberLen(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// no keys
+	length, err := ss.MemberLen("foo")
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockDecimalSeries()
+
+	length, err = ss.MemberLen("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, length)
+}
+
+func TestDecimalClear(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	ss.store["foo"] = mockDecimalSeries()
+	assert.Equal(t, 1, len(ss.store))
+
+	ss.Clear()
+	assert.Equal(t, 0, len(ss.store))
+}
+
+func TestDecimalDigest(t *testing.T) {
+	s := NewDecimalSStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockDecimalSeries())
+	s.Set("b", []decimal.Decimal{mockDecimalTen})
+
+	// write order does not matter
+	o := NewDecimalSStore()
+	o.Set("b", []decimal.Decimal{mockDecimalTen})
+	o.Set("a", mockDecimalSeries())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", mockDecimalTen)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", []decimal.Decimal{mockDecimalTen})
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestDecimalEnableDigest(t *testing.T) {
+	s := NewDecimalSStore()
+	s.Set("a", mockDecimalSeries())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", mockDecimalTen, mockDecimalTen)
+	s.Append("b", mockDecimalTen)
+	o := NewDecimalSStore()
+	o.Set("a", append(mockDecimalSeries(), mockDecimalTen, mockDecimalTen))
+	o.Set("b", []decimal.Decimal{mockDecimalTen})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, mockDecimalTen))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, mockDecimalTen))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]decimal.Decimal{"a": mockDecimalSeries()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestDecimalSyncFrom(t *testing.T) {
+	s := NewDecimalSStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockDecimalSeries())
+	}
+	s.Set("b", []decimal.Decimal{mockDecimalTen})
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", mockDecimalTen)
+	r.ReplaceAll(map[string][]decimal.Decimal{"1": mockDecimalSeries(), "b": []decimal.Decimal{mockDecimalTen}, "c": []decimal.Decimal{mockDecimalTen}})
+	r.Set("2", []decimal.Decimal{mockDecimalTen})
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestDecimalConcurrentGetAndSet(t *testing.T) {
+	ss := NewDecimalSStore()
+
+	// set
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Set("foo", mockDecimalSeries())
+		}
+	}()
+
+	// get
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,30 @@
+package seriesstore
+
+import (
+	"sort"
+)
+
+// KeyDiff lists the keys that differ going from one store to another, each in ascending order
+type KeyDiff struct {
+	// Added keys are only in the other store
+	Added []string
+	// Removed keys are only in the store
+	Removed []string
+	// Changed keys are in both stores with different values
+	Changed []string
+}
+
+func newKeyDiff() KeyDiff {
+	return KeyDiff{Added: make([]string, 0), Removed: make([]string, 0), Changed: make([]string, 0)}
+}
+
+func (d *KeyDiff) sort() {
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+}
+
+// Empty checks if the stores hold the same keys and values
+func (d KeyDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
@@ -1,6 +1,9 @@
 package seriesstore
 
 import (
+	"math"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -18,6 +21,19 @@ func TestFloat32Set(t *testing.T) {
 	assert.Equal(t, ss.store["foo"], mockFloat32Series())
 }
 
+func TestFloat32Append(t *testing.T) {
+	ss := NewFloat32SStore()
+
+	// key not exist
+	ss.Append("foo", mockFloat32Series()...)
+	assert.Equal(t, mockFloat32Series(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", 10.0)
+	assert.Equal(t, len(mockFloat32Series())+1, len(ss.store["foo"]))
+	assert.Equal(t, float32(10.0), ss.store["foo"][len(mockFloat32Series())])
+}
+
 func TestFloat32SetIdx(t *testing.T) {
 	// key not exist
 	ss := NewFloat32SStore()
@@ -127,6 +143,119 @@ func TestFloat32GetRange(t *testing.T) {
 	assert.Equal(t, ErrIdxOutOfBounds, err)
 }
 
+func TestFloat32Clone(t *testing.T) {
+	s := NewFloat32SStore()
+	s.store["a"] = mockFloat32Series()
+	s.store["b"] = []float32{7}
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockFloat32Series())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = 7
+	assert.Equal(t, mockFloat32Series(), s.store["a"])
+}
+
+func TestFloat32MergeFrom(t *testing.T) {
+	o := NewFloat32SStore()
+	o.store["b"] = []float32{7}
+	o.store["c"] = []float32{7}
+
+	// incoming values win without a conflict func
+	s := NewFloat32SStore()
+	s.store["a"] = mockFloat32Series()
+	s.store["b"] = mockFloat32Series()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]float32{"a": mockFloat32Series(), "b": []float32{7}, "c": []float32{7}}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewFloat32SStore()
+	s.store["a"] = mockFloat32Series()
+	s.store["b"] = mockFloat32Series()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []float32) []float32 {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockFloat32Series(), current)
+		assert.Equal(t, []float32{7}, incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]float32{"a": mockFloat32Series(), "b": mockFloat32Series(), "c": []float32{7}}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]float32{"b": []float32{7}, "c": []float32{7}}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestFloat32Diff(t *testing.T) {
+	s := NewFloat32SStore()
+	s.store["a"] = mockFloat32Series()
+	s.store["b"] = mockFloat32Series()
+	s.store["c"] = mockFloat32Series()
+
+	o := NewFloat32SStore()
+	o.store["b"] = mockFloat32Series()
+	o.store["c"] = []float32{7}
+	o.store["d"] = []float32{7}
+	o.store["e"] = []float32{7}
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewFloat32SStore().Diff(NewFloat32SStore()).Empty())
+}
+
+func TestFloat32DiffNaN(t *testing.T) {
+	s := NewFloat32SStore()
+	s.store["a"] = []float32{1, float32(math.NaN()), 3}
+
+	o := s.Clone()
+	assert.True(t, s.Diff(o).Empty())
+
+	o.store["a"][0] = float32(math.NaN())
+	assert.Equal(t, []string{"a"}, s.Diff(o).Changed)
+}
+
+func TestFloat32ReplaceAll(t *testing.T) {
+	s := NewFloat32SStore()
+	s.store["a"] = mockFloat32Series()
+	s.EnableKeyIndex()
+
+	values := map[string][]float32{"b": []float32{7}, "c": mockFloat32Series()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockFloat32Series()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestFloat32Size(t *testing.T) {
 	ss := NewFloat32SStore()
 
@@ -157,6 +286,103 @@ func TestFloat32Members(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestFloat32SortedMembers(t *testing.T) {
+	ss := NewFloat32SStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockFloat32Series()
+	ss.store["AAPL:bid"] = mockFloat32Series()
+	ss.store["AAPL:ask"] = mockFloat32Series()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestFloat32MembersWithPrefix(t *testing.T) {
+	ss := NewFloat32SStore()
+
+	ss.store["MSFT:bid"] = mockFloat32Series()
+	ss.store["AAPL:bid"] = mockFloat32Series()
+	ss.store["AAPL:ask"] = mockFloat32Series()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestFloat32MembersMatching(t *testing.T) {
+	ss := NewFloat32SStore()
+
+	ss.store["MSFT:bid"] = mockFloat32Series()
+	ss.store["AAPL:bid"] = mockFloat32Series()
+	ss.store["AAPL:ask"] = mockFloat32Series()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestFloat32KeyIndex(t *testing.T) {
+	ss := NewFloat32SStore()
+	ss.store["foo"] = mockFloat32Series()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockFloat32Series())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestFloat32Scan(t *testing.T) {
+	ss := NewFloat32SStore()
+
+	ss.store["MSFT:bid"] = mockFloat32Series()
+	ss.store["AAPL:bid"] = mockFloat32Series()
+	ss.store["AAPL:ask"] = mockFloat32Series()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestFloat32IsMember(t *testing.T) {
 	ss := NewFloat32SStore()
 
@@ -197,6 +423,93 @@ func TestFloat32Clear(t *testing.T) {
 	assert.Equal(t, 0, len(ss.store))
 }
 
+func TestFloat32Digest(t *testing.T) {
+	s := NewFloat32SStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockFloat32Series())
+	s.Set("b", []float32{7})
+
+	// write order does not matter
+	o := NewFloat32SStore()
+	o.Set("b", []float32{7})
+	o.Set("a", mockFloat32Series())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", float32(7))
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", []float32{7})
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestFloat32EnableDigest(t *testing.T) {
+	s := NewFloat32SStore()
+	s.Set("a", mockFloat32Series())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", float32(7), float32(7))
+	s.Append("b", float32(7))
+	o := NewFloat32SStore()
+	o.Set("a", append(mockFloat32Series(), float32(7), float32(7)))
+	o.Set("b", []float32{float32(7)})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, float32(7)))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, float32(7)))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]float32{"a": mockFloat32Series()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestFloat32SyncFrom(t *testing.T) {
+	s := NewFloat32SStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockFloat32Series())
+	}
+	s.Set("b", []float32{7})
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", float32(7))
+	r.ReplaceAll(map[string][]float32{"1": mockFloat32Series(), "b": []float32{7}, "c": []float32{7}})
+	r.Set("2", []float32{7})
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestFloat32ConcurrentGetAndSet(t *testing.T) {
 	ss := NewFloat32SStore()
 
@@ -1,7 +1,16 @@
 package seriesstore
 
 import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
 )
 
 // Float64SStore is a store of float64 slices
@@ -10,7 +19,9 @@ import (
 // Embedded sync.Mutex to provide atomic operation ability
 type Float64SStore struct {
 	sync.Mutex
-	store map[string][]float64
+	store  map[string][]float64
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
 }
 
 // NewFloat64SStore constructs and initializes a new Float64SStore
@@ -21,6 +32,10 @@ func NewFloat64SStore() *Float64SStore {
 
 func (s *Float64SStore) set(key string, value []float64) {
 	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashFloat64s(merkle.Init(key), value))
+	}
 }
 
 // Set stores the given value mapped to the given key in the store
@@ -30,6 +45,28 @@ func (s *Float64SStore) Set(key string, value []float64) {
 	s.Unlock()
 }
 
+func (s *Float64SStore) append(key string, values ...float64) {
+	s.store[key] = append(s.store[key], values...)
+	s.index.Insert(key)
+
+	// extend the hash of the series instead of hashing it again
+	if s.digest != nil {
+		h, ok := s.digest.State(key)
+		if !ok {
+			h = merkle.Init(key)
+		}
+		s.digest.Set(key, hashFloat64s(h, values))
+	}
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store
+// The key is created if it does not exist
+func (s *Float64SStore) Append(key string, values ...float64) {
+	s.Lock()
+	s.append(key, values...)
+	s.Unlock()
+}
+
 func (s *Float64SStore) setIdx(key string, idx int, value float64) error {
 	v, ok := s.store[key]
 
@@ -44,6 +81,9 @@ func (s *Float64SStore) setIdx(key string, idx int, value float64) error {
 	}
 
 	s.store[key][idx] = value
+	if s.digest != nil {
+		s.digest.Set(key, hashFloat64s(merkle.Init(key), s.store[key]))
+	}
 
 	return nil
 }
@@ -123,6 +163,353 @@ func (s *Float64SStore) GetRange(key string, lower, upper int) ([]float64, error
 	return v, err
 }
 
+func (s *Float64SStore) column(idx int) map[string]float64 {
+	out := make(map[string]float64)
+	for k, v := range s.store {
+		if idx >= 0 && idx < len(v) {
+			out[k] = v[idx]
+		}
+	}
+
+	return out
+}
+
+// Column returns the value at the specified index of every key whose series is long enough,
+// taken under a single lock so all values are from the same point in time
+func (s *Float64SStore) Column(idx int) map[string]float64 {
+	s.Lock()
+	v := s.column(idx)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float64SStore) latest() map[string]float64 {
+	out := make(map[string]float64, len(s.store))
+	for k, v := range s.store {
+		if len(v) > 0 {
+			out[k] = v[len(v)-1]
+		}
+	}
+
+	return out
+}
+
+// Latest returns the last value of every key with a non empty series,
+// taken under a single lock so all values are from the same point in time
+func (s *Float64SStore) Latest() map[string]float64 {
+	s.Lock()
+	v := s.latest()
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float64SStore) crossSection(keys []string, idx int) (map[string]float64, error) {
+	out := make(map[string]float64, len(keys))
+	for _, k := range keys {
+		v, err := s.getIdx(k, idx)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+
+	return out, nil
+}
+
+// CrossSection returns the value at the specified index of each of the given keys,
+// taken under a single lock so all values are from the same point in time
+// returns ErrKeyDoesNotExist or ErrIdxOutOfBounds if any key is missing or too short
+func (s *Float64SStore) CrossSection(keys []string, idx int) (map[string]float64, error) {
+	s.Lock()
+	v, err := s.crossSection(keys, idx)
+	s.Unlock()
+
+	return v, err
+}
+
+func copyFloat64s(xs []float64) []float64 {
+	return append(make([]float64, 0, len(xs)), xs...)
+}
+
+func equalFloat64s(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		// NaN matches NaN, so an unchanged series never shows as changed
+		if a[i] != b[i] && (a[i] == a[i] || b[i] == b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Float64SStore) snapshot() map[string][]float64 {
+	m := make(map[string][]float64, len(s.store))
+	for k, v := range s.store {
+		m[k] = copyFloat64s(v)
+	}
+
+	return m
+}
+
+func (s *Float64SStore) clone() *Float64SStore {
+	c := &Float64SStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *Float64SStore) Clone() *Float64SStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *Float64SStore) mergeFrom(values map[string][]float64, conflictFn func(key string, current, incoming []float64) []float64) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *Float64SStore) MergeFrom(other *Float64SStore, conflictFn func(key string, current, incoming []float64) []float64) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *Float64SStore) diff(values map[string][]float64) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !equalFloat64s(v, ov) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *Float64SStore) Diff(other *Float64SStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *Float64SStore) replaceAll(store map[string][]float64) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *Float64SStore) ReplaceAll(values map[string][]float64) {
+	store := make(map[string][]float64, len(values))
+	for k, v := range values {
+		store[k] = copyFloat64s(v)
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+// hashFloat64s adds the values of a series to the hash state of its key
+func hashFloat64s(h uint64, xs []float64) uint64 {
+	for _, x := range xs {
+		h = merkle.WriteUint64(h, math.Float64bits(x))
+	}
+
+	return h
+}
+
+func (s *Float64SStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashFloat64s(merkle.Init(k), v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled, Append only hashes the appended values
+// Values changed through a slice given to Set or returned by Get are not seen by the digest
+func (s *Float64SStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *Float64SStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashFloat64s(merkle.Init(k), v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *Float64SStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *Float64SStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashFloat64s(merkle.Init(k), v))
+	}
+
+	return t
+}
+
+func (s *Float64SStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string][]float64)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *Float64SStore) applyEntries(buckets merkle.BucketSet, entries map[string][]float64) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			delete(s.store, k)
+			s.index.Delete(k)
+			s.digest.Delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashFloat64s(merkle.Init(k), cur) != hashFloat64s(merkle.Init(k), v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *Float64SStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string][]float64
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *Float64SStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *Float64SStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
 func (s *Float64SStore) size() int {
 	return len(s.store)
 }
@@ -158,6 +545,118 @@ func (s *Float64SStore) Members() []string {
 	return v
 }
 
+func (s *Float64SStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *Float64SStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *Float64SStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *Float64SStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float64SStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *Float64SStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float64SStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *Float64SStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *Float64SStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *Float64SStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
 func (s *Float64SStore) isMember(key string) bool {
 	_, ok := s.store[key]
 
@@ -195,6 +694,8 @@ func (s *Float64SStore) MemberLen(key string) (int, error) {
 
 func (s *Float64SStore) clear() {
 	s.store = make(map[string][]float64)
+	s.index.Clear()
+	s.digest.Clear()
 }
 
 // Clear deletes all keys in the store
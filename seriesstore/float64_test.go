@@ -1,6 +1,9 @@
 package seriesstore
 
 import (
+	"math"
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -18,6 +21,19 @@ func TestFloat64Set(t *testing.T) {
 	assert.Equal(t, ss.store["foo"], mockFloat64Series())
 }
 
+func TestFloat64Append(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	// key not exist
+	ss.Append("foo", mockFloat64Series()...)
+	assert.Equal(t, mockFloat64Series(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", 10.0)
+	assert.Equal(t, len(mockFloat64Series())+1, len(ss.store["foo"]))
+	assert.Equal(t, 10.0, ss.store["foo"][len(mockFloat64Series())])
+}
+
 func TestFloat64SetIdx(t *testing.T) {
 	// key not exist
 	ss := NewFloat64SStore()
@@ -127,6 +143,172 @@ func TestFloat64GetRange(t *testing.T) {
 	assert.Equal(t, ErrIdxOutOfBounds, err)
 }
 
+func TestFloat64Column(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	// no keys
+	assert.Equal(t, map[string]float64{}, ss.Column(0))
+
+	ss.store["a"] = []float64{1.0, 2.0, 3.0}
+	ss.store["b"] = []float64{4.0}
+	ss.store["c"] = []float64{}
+
+	// short series are skipped
+	assert.Equal(t, map[string]float64{"a": 1.0, "b": 4.0}, ss.Column(0))
+	assert.Equal(t, map[string]float64{"a": 2.0}, ss.Column(1))
+	assert.Equal(t, map[string]float64{}, ss.Column(-1))
+}
+
+func TestFloat64Latest(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	// no keys
+	assert.Equal(t, map[string]float64{}, ss.Latest())
+
+	ss.store["a"] = []float64{1.0, 2.0, 3.0}
+	ss.store["b"] = []float64{4.0}
+	ss.store["c"] = []float64{}
+
+	// empty series are skipped
+	assert.Equal(t, map[string]float64{"a": 3.0, "b": 4.0}, ss.Latest())
+}
+
+func TestFloat64CrossSection(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	ss.store["a"] = []float64{1.0, 2.0, 3.0}
+	ss.store["b"] = []float64{4.0}
+
+	// no key
+	_, err := ss.CrossSection([]string{"a", "foo"}, 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// out of bounds
+	_, err = ss.CrossSection([]string{"a", "b"}, 1)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	v, err := ss.CrossSection([]string{"a", "b"}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"a": 1.0, "b": 4.0}, v)
+
+	v, err = ss.CrossSection([]string{"a"}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]float64{"a": 3.0}, v)
+}
+
+func TestFloat64Clone(t *testing.T) {
+	s := NewFloat64SStore()
+	s.store["a"] = mockFloat64Series()
+	s.store["b"] = []float64{7}
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockFloat64Series())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = 7
+	assert.Equal(t, mockFloat64Series(), s.store["a"])
+}
+
+func TestFloat64MergeFrom(t *testing.T) {
+	o := NewFloat64SStore()
+	o.store["b"] = []float64{7}
+	o.store["c"] = []float64{7}
+
+	// incoming values win without a conflict func
+	s := NewFloat64SStore()
+	s.store["a"] = mockFloat64Series()
+	s.store["b"] = mockFloat64Series()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]float64{"a": mockFloat64Series(), "b": []float64{7}, "c": []float64{7}}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewFloat64SStore()
+	s.store["a"] = mockFloat64Series()
+	s.store["b"] = mockFloat64Series()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []float64) []float64 {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockFloat64Series(), current)
+		assert.Equal(t, []float64{7}, incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]float64{"a": mockFloat64Series(), "b": mockFloat64Series(), "c": []float64{7}}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]float64{"b": []float64{7}, "c": []float64{7}}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestFloat64Diff(t *testing.T) {
+	s := NewFloat64SStore()
+	s.store["a"] = mockFloat64Series()
+	s.store["b"] = mockFloat64Series()
+	s.store["c"] = mockFloat64Series()
+
+	o := NewFloat64SStore()
+	o.store["b"] = mockFloat64Series()
+	o.store["c"] = []float64{7}
+	o.store["d"] = []float64{7}
+	o.store["e"] = []float64{7}
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewFloat64SStore().Diff(NewFloat64SStore()).Empty())
+}
+
+func TestFloat64DiffNaN(t *testing.T) {
+	s := NewFloat64SStore()
+	s.store["a"] = []float64{1, math.NaN(), 3}
+
+	o := s.Clone()
+	assert.True(t, s.Diff(o).Empty())
+
+	o.store["a"][0] = math.NaN()
+	assert.Equal(t, []string{"a"}, s.Diff(o).Changed)
+}
+
+func TestFloat64ReplaceAll(t *testing.T) {
+	s := NewFloat64SStore()
+	s.store["a"] = mockFloat64Series()
+	s.EnableKeyIndex()
+
+	values := map[string][]float64{"b": []float64{7}, "c": mockFloat64Series()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockFloat64Series()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestFloat64Size(t *testing.T) {
 	ss := NewFloat64SStore()
 
@@ -157,6 +339,103 @@ func TestFloat64Members(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestFloat64SortedMembers(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockFloat64Series()
+	ss.store["AAPL:bid"] = mockFloat64Series()
+	ss.store["AAPL:ask"] = mockFloat64Series()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestFloat64MembersWithPrefix(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	ss.store["MSFT:bid"] = mockFloat64Series()
+	ss.store["AAPL:bid"] = mockFloat64Series()
+	ss.store["AAPL:ask"] = mockFloat64Series()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestFloat64MembersMatching(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	ss.store["MSFT:bid"] = mockFloat64Series()
+	ss.store["AAPL:bid"] = mockFloat64Series()
+	ss.store["AAPL:ask"] = mockFloat64Series()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestFloat64KeyIndex(t *testing.T) {
+	ss := NewFloat64SStore()
+	ss.store["foo"] = mockFloat64Series()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockFloat64Series())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestFloat64Scan(t *testing.T) {
+	ss := NewFloat64SStore()
+
+	ss.store["MSFT:bid"] = mockFloat64Series()
+	ss.store["AAPL:bid"] = mockFloat64Series()
+	ss.store["AAPL:ask"] = mockFloat64Series()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestFloat64IsMember(t *testing.T) {
 	ss := NewFloat64SStore()
 
@@ -197,6 +476,93 @@ func TestFloat64Clear(t *testing.T) {
 	assert.Equal(t, 0, len(ss.store))
 }
 
+func TestFloat64Digest(t *testing.T) {
+	s := NewFloat64SStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockFloat64Series())
+	s.Set("b", []float64{7})
+
+	// write order does not matter
+	o := NewFloat64SStore()
+	o.Set("b", []float64{7})
+	o.Set("a", mockFloat64Series())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", 7.0)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", []float64{7})
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestFloat64EnableDigest(t *testing.T) {
+	s := NewFloat64SStore()
+	s.Set("a", mockFloat64Series())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", 7.0, 7.0)
+	s.Append("b", 7.0)
+	o := NewFloat64SStore()
+	o.Set("a", append(mockFloat64Series(), 7.0, 7.0))
+	o.Set("b", []float64{7.0})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, 7.0))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, 7.0))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]float64{"a": mockFloat64Series()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestFloat64SyncFrom(t *testing.T) {
+	s := NewFloat64SStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockFloat64Series())
+	}
+	s.Set("b", []float64{7})
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", 7.0)
+	r.ReplaceAll(map[string][]float64{"1": mockFloat64Series(), "b": []float64{7}, "c": []float64{7}})
+	r.Set("2", []float64{7})
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestFloat64ConcurrentGetAndSet(t *testing.T) {
 	ss := NewFloat64SStore()
 
@@ -0,0 +1,326 @@
+package seriesstore
+
+import (
+	"time"
+)
+
+// ColumnType is the value type of a frame column
+type ColumnType int
+
+const (
+	// Float64Column holds float64 values
+	Float64Column ColumnType = iota
+	// Int64Column holds int64 values
+	Int64Column
+	// StringColumn holds string values
+	StringColumn
+	// BoolColumn holds bool values
+	BoolColumn
+	// TimeColumn holds time.Time values
+	TimeColumn
+)
+
+// ColumnDef names a frame column and its value type
+type ColumnDef struct {
+	Name string
+	Type ColumnType
+}
+
+// Frame is a table of aligned named columns of mixed primitive types
+// Rows are only ever added or sliced whole, so every column always has the same length
+// Note: Frame is NOT safe for concurrent use, see FrameStore
+type Frame struct {
+	columns []ColumnDef
+	index   map[string]int
+	data    []interface{} // one typed slice per column, e.g. []float64
+	length  int
+}
+
+// NewFrame constructs and initializes a new empty Frame with the given columns
+// returns ErrDuplicateColumn if a column name is repeated
+// Always use this function when creating a new Frame
+func NewFrame(columns ...ColumnDef) (*Frame, error) {
+	f := &Frame{
+		columns: make([]ColumnDef, len(columns)),
+		index:   make(map[string]int, len(columns)),
+		data:    make([]interface{}, len(columns)),
+	}
+	copy(f.columns, columns)
+
+	for i, c := range columns {
+		if _, ok := f.index[c.Name]; ok {
+			return nil, ErrDuplicateColumn
+		}
+		f.index[c.Name] = i
+		f.data[i] = sliceColumn(c.Type, nil, 0, 0)
+	}
+
+	return f, nil
+}
+
+// sliceColumn returns a copy of data[lower:upper] of the given column type,
+// an empty column if data is nil
+func sliceColumn(t ColumnType, data interface{}, lower, upper int) interface{} {
+	switch t {
+	case Float64Column:
+		out := make([]float64, upper-lower)
+		if data != nil {
+			copy(out, data.([]float64)[lower:upper])
+		}
+		return out
+	case Int64Column:
+		out := make([]int64, upper-lower)
+		if data != nil {
+			copy(out, data.([]int64)[lower:upper])
+		}
+		return out
+	case StringColumn:
+		out := make([]string, upper-lower)
+		if data != nil {
+			copy(out, data.([]string)[lower:upper])
+		}
+		return out
+	case BoolColumn:
+		out := make([]bool, upper-lower)
+		if data != nil {
+			copy(out, data.([]bool)[lower:upper])
+		}
+		return out
+	case TimeColumn:
+		out := make([]time.Time, upper-lower)
+		if data != nil {
+			copy(out, data.([]time.Time)[lower:upper])
+		}
+		return out
+	}
+
+	return nil
+}
+
+// matches checks if value is of the given column type
+func matches(t ColumnType, value interface{}) bool {
+	switch value.(type) {
+	case float64:
+		return t == Float64Column
+	case int64:
+		return t == Int64Column
+	case string:
+		return t == StringColumn
+	case bool:
+		return t == BoolColumn
+	case time.Time:
+		return t == TimeColumn
+	}
+
+	return false
+}
+
+func appendValue(data, value interface{}) interface{} {
+	switch d := data.(type) {
+	case []float64:
+		return append(d, value.(float64))
+	case []int64:
+		return append(d, value.(int64))
+	case []string:
+		return append(d, value.(string))
+	case []bool:
+		return append(d, value.(bool))
+	case []time.Time:
+		return append(d, value.(time.Time))
+	}
+
+	return data
+}
+
+func valueAt(data interface{}, idx int) interface{} {
+	switch d := data.(type) {
+	case []float64:
+		return d[idx]
+	case []int64:
+		return d[idx]
+	case []string:
+		return d[idx]
+	case []bool:
+		return d[idx]
+	case []time.Time:
+		return d[idx]
+	}
+
+	return nil
+}
+
+// Columns returns a copy of the column definitions of the frame, in order
+func (f *Frame) Columns() []ColumnDef {
+	out := make([]ColumnDef, len(f.columns))
+	copy(out, f.columns)
+
+	return out
+}
+
+// Len returns the number of rows in the frame
+func (f *Frame) Len() int {
+	return f.length
+}
+
+func (f *Frame) checkRow(row []interface{}) error {
+	if len(row) != len(f.columns) {
+		return ErrRowLength
+	}
+
+	for i, v := range row {
+		if !matches(f.columns[i].Type, v) {
+			return ErrColumnType
+		}
+	}
+
+	return nil
+}
+
+// AppendRow adds a row with one value per column, in column order
+// The frame is unchanged if the row has the wrong length or a value of the wrong type
+func (f *Frame) AppendRow(row ...interface{}) error {
+	return f.AppendRows(row)
+}
+
+// AppendRows adds the given rows, all or none
+// The frame is unchanged if any row has the wrong length or a value of the wrong type
+func (f *Frame) AppendRows(rows ...[]interface{}) error {
+	for _, row := range rows {
+		if err := f.checkRow(row); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range rows {
+		for i, v := range row {
+			f.data[i] = appendValue(f.data[i], v)
+		}
+		f.length++
+	}
+
+	return nil
+}
+
+// Row returns the values of the row at idx, in column order
+func (f *Frame) Row(idx int) ([]interface{}, error) {
+	// bounds check
+	if idx < 0 || idx >= f.length {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	row := make([]interface{}, len(f.columns))
+	for i := range f.columns {
+		row[i] = valueAt(f.data[i], idx)
+	}
+
+	return row, nil
+}
+
+// Column returns a copy of the named column as its typed slice, e.g. []float64
+func (f *Frame) Column(name string) (interface{}, error) {
+	i, ok := f.index[name]
+	if !ok {
+		return nil, ErrColumnDoesNotExist
+	}
+
+	return sliceColumn(f.columns[i].Type, f.data[i], 0, f.length), nil
+}
+
+func (f *Frame) typedColumn(name string, t ColumnType) (interface{}, error) {
+	i, ok := f.index[name]
+	if !ok {
+		return nil, ErrColumnDoesNotExist
+	}
+	if f.columns[i].Type != t {
+		return nil, ErrColumnType
+	}
+
+	return sliceColumn(t, f.data[i], 0, f.length), nil
+}
+
+// Float64Column returns a copy of the named float64 column
+func (f *Frame) Float64Column(name string) ([]float64, error) {
+	v, err := f.typedColumn(name, Float64Column)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]float64), nil
+}
+
+// Int64Column returns a copy of the named int64 column
+func (f *Frame) Int64Column(name string) ([]int64, error) {
+	v, err := f.typedColumn(name, Int64Column)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]int64), nil
+}
+
+// StringColumn returns a copy of the named string column
+func (f *Frame) StringColumn(name string) ([]string, error) {
+	v, err := f.typedColumn(name, StringColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]string), nil
+}
+
+// BoolColumn returns a copy of the named bool column
+func (f *Frame) BoolColumn(name string) ([]bool, error) {
+	v, err := f.typedColumn(name, BoolColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]bool), nil
+}
+
+// TimeColumn returns a copy of the named time.Time column
+func (f *Frame) TimeColumn(name string) ([]time.Time, error) {
+	v, err := f.typedColumn(name, TimeColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]time.Time), nil
+}
+
+// Slice returns a copy of the rows within the specified range (inclusive:exclusive) as a new Frame
+func (f *Frame) Slice(lower, upper int) (*Frame, error) {
+	// bounds check
+	if lower < 0 || lower > f.length || upper < 0 || upper > f.length || lower > upper {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	out := &Frame{columns: f.columns, index: f.index, data: make([]interface{}, len(f.columns)), length: upper - lower}
+	for i, c := range f.columns {
+		out.data[i] = sliceColumn(c.Type, f.data[i], lower, upper)
+	}
+
+	return out, nil
+}
+
+// Clone returns a deep copy of the frame
+func (f *Frame) Clone() *Frame {
+	out, _ := f.Slice(0, f.length)
+
+	return out
+}
+
+// sameColumns checks if both frames have the same column definitions in the same order
+func (f *Frame) sameColumns(other *Frame) bool {
+	if len(f.columns) != len(other.columns) {
+		return false
+	}
+
+	for i, c := range f.columns {
+		if other.columns[i] != c {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,444 @@
+package seriesstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// FrameStore is a store of frames, e.g. bid, ask, last and volume columns per symbol
+// Every key holds a Frame with the columns of the store, so all columns of a key stay aligned
+// Getters return copies, so callers can not break the alignment of stored frames
+// Embedded sync.Mutex to provide atomic operation ability
+type FrameStore struct {
+	sync.Mutex
+	schema *Frame // empty frame holding the store columns
+	store  map[string]*Frame
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewFrameStore constructs and initializes a new FrameStore whose frames have the given columns
+// returns ErrDuplicateColumn if a column name is repeated
+// Always use this function to init new FrameStores
+func NewFrameStore(columns ...ColumnDef) (*FrameStore, error) {
+	schema, err := NewFrame(columns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FrameStore{schema: schema, store: make(map[string]*Frame)}, nil
+}
+
+// Columns returns the column definitions of the store, in order
+func (s *FrameStore) Columns() []ColumnDef {
+	return s.schema.Columns()
+}
+
+func (s *FrameStore) set(key string, value *Frame) error {
+	if !s.schema.sameColumns(value) {
+		return ErrSchemaMismatch
+	}
+
+	s.store[key] = value.Clone()
+	s.index.Insert(key)
+
+	return nil
+}
+
+// Set stores a copy of the given frame mapped to the given key in the store
+// returns ErrSchemaMismatch if the frame columns differ from the store columns
+func (s *FrameStore) Set(key string, value *Frame) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *FrameStore) appendRows(key string, rows ...[]interface{}) error {
+	f, ok := s.store[key]
+	if !ok {
+		f = s.schema.Clone()
+	}
+
+	if err := f.AppendRows(rows...); err != nil {
+		return err
+	}
+
+	s.store[key] = f
+	s.index.Insert(key)
+
+	return nil
+}
+
+// AppendRow adds a row with one value per column, in column order, to the frame of the given key
+// The key is created if it does not exist
+// returns ErrRowLength or ErrColumnType, leaving the frame unchanged, if the row does not match the columns
+func (s *FrameStore) AppendRow(key string, row ...interface{}) error {
+	s.Lock()
+	err := s.appendRows(key, row)
+	s.Unlock()
+
+	return err
+}
+
+// AppendRows adds the given rows to the frame of the given key, all or none
+// The key is created if it does not exist
+func (s *FrameStore) AppendRows(key string, rows ...[]interface{}) error {
+	s.Lock()
+	err := s.appendRows(key, rows...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *FrameStore) get(key string) (*Frame, bool) {
+	f, ok := s.store[key]
+	if !ok {
+		return nil, false
+	}
+
+	return f.Clone(), true
+}
+
+// Get returns a copy of the frame of the given key
+func (s *FrameStore) Get(key string) (*Frame, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *FrameStore) getIdx(key string, idx int) ([]interface{}, error) {
+	f, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return f.Row(idx)
+}
+
+// GetIdx returns the row at the specified index of the frame of the given key, in column order
+func (s *FrameStore) GetIdx(key string, idx int) ([]interface{}, error) {
+	s.Lock()
+	v, err := s.getIdx(key, idx)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *FrameStore) getRange(key string, lower, upper int) (*Frame, error) {
+	f, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return f.Slice(lower, upper)
+}
+
+// GetRange returns a copy of the rows of the given key within the specified range (inclusive:exclusive)
+func (s *FrameStore) GetRange(key string, lower, upper int) (*Frame, error) {
+	s.Lock()
+	v, err := s.getRange(key, lower, upper)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *FrameStore) getColumn(key, name string) (interface{}, error) {
+	f, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return f.Column(name)
+}
+
+// GetColumn returns a copy of the named column of the given key as its typed slice, e.g. []float64
+func (s *FrameStore) GetColumn(key, name string) (interface{}, error) {
+	s.Lock()
+	v, err := s.getColumn(key, name)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *FrameStore) getTypedColumn(key, name string, t ColumnType) (interface{}, error) {
+	f, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	return f.typedColumn(name, t)
+}
+
+// GetFloat64Column returns a copy of the named float64 column of the given key
+func (s *FrameStore) GetFloat64Column(key, name string) ([]float64, error) {
+	s.Lock()
+	v, err := s.getTypedColumn(key, name, Float64Column)
+	s.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]float64), nil
+}
+
+// GetInt64Column returns a copy of the named int64 column of the given key
+func (s *FrameStore) GetInt64Column(key, name string) ([]int64, error) {
+	s.Lock()
+	v, err := s.getTypedColumn(key, name, Int64Column)
+	s.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]int64), nil
+}
+
+// GetStringColumn returns a copy of the named string column of the given key
+func (s *FrameStore) GetStringColumn(key, name string) ([]string, error) {
+	s.Lock()
+	v, err := s.getTypedColumn(key, name, StringColumn)
+	s.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]string), nil
+}
+
+// GetBoolColumn returns a copy of the named bool column of the given key
+func (s *FrameStore) GetBoolColumn(key, name string) ([]bool, error) {
+	s.Lock()
+	v, err := s.getTypedColumn(key, name, BoolColumn)
+	s.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]bool), nil
+}
+
+// GetTimeColumn returns a copy of the named time.Time column of the given key
+func (s *FrameStore) GetTimeColumn(key, name string) ([]time.Time, error) {
+	s.Lock()
+	v, err := s.getTypedColumn(key, name, TimeColumn)
+	s.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return v.([]time.Time), nil
+}
+
+func (s *FrameStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *FrameStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *FrameStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *FrameStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *FrameStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *FrameStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *FrameStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *FrameStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *FrameStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *FrameStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *FrameStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *FrameStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *FrameStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *FrameStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *FrameStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *FrameStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *FrameStore) memberLen(key string) (int, error) {
+	f, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return f.Len(), nil
+}
+
+// MemberLen returns the number of rows in the frame of the given key
+func (s *FrameStore) MemberLen(key string) (int, error) {
+	s.Lock()
+	l, err := s.memberLen(key)
+	s.Unlock()
+
+	return l, err
+}
+
+func (s *FrameStore) clear() {
+	s.store = make(map[string]*Frame)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *FrameStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,376 @@
+package seriesstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockFrameStore() *FrameStore {
+	fs, _ := NewFrameStore(mockColumns...)
+
+	return fs
+}
+
+func mockFrameRow(i int) []interface{} {
+	return []interface{}{float64(i), float64(i) + 0.5, int64(i * 100), "XNAS", i%2 == 1, time.Unix(int64(i), 0)}
+}
+
+func TestNewFrameStore(t *testing.T) {
+	_, err := NewFrameStore(ColumnDef{"bid", Float64Column}, ColumnDef{"bid", Float64Column})
+	assert.Equal(t, ErrDuplicateColumn, err)
+
+	fs, err := NewFrameStore(mockColumns...)
+	assert.Nil(t, err)
+	assert.Equal(t, mockColumns, fs.Columns())
+}
+
+func TestFrameStoreSet(t *testing.T) {
+	fs := mockFrameStore()
+
+	// schema mismatch
+	other, _ := NewFrame(ColumnDef{"bid", Float64Column})
+	assert.Equal(t, ErrSchemaMismatch, fs.Set("AAPL", other))
+	assert.False(t, fs.isMember("AAPL"))
+
+	f := mockFrame(2)
+	assert.Nil(t, fs.Set("AAPL", f))
+	assert.Equal(t, 2, fs.store["AAPL"].Len())
+
+	// stored frame is a copy
+	f.AppendRow(mockFrameRow(2)...)
+	assert.Equal(t, 2, fs.store["AAPL"].Len())
+}
+
+func TestFrameStoreAppendRow(t *testing.T) {
+	fs := mockFrameStore()
+
+	// invalid row does not create key
+	assert.Equal(t, ErrRowLength, fs.AppendRow("AAPL", 1.0))
+	assert.False(t, fs.isMember("AAPL"))
+
+	assert.Nil(t, fs.AppendRow("AAPL", mockFrameRow(0)...))
+	assert.Nil(t, fs.AppendRow("AAPL", mockFrameRow(1)...))
+	assert.Equal(t, 2, fs.store["AAPL"].Len())
+
+	// invalid row leaves frame unchanged
+	assert.Equal(t, ErrColumnType, fs.AppendRow("AAPL", 1.0, 2.0, 3.0, "XNAS", true, time.Unix(0, 0)))
+	assert.Equal(t, 2, fs.store["AAPL"].Len())
+	for _, col := range fs.store["AAPL"].data {
+		assert.Len(t, col, 2)
+	}
+
+	// new keys do not share the schema frame
+	assert.Equal(t, 0, fs.schema.Len())
+}
+
+func TestFrameStoreAppendRows(t *testing.T) {
+	fs := mockFrameStore()
+
+	// all or none
+	assert.Equal(t, ErrRowLength, fs.AppendRows("AAPL", mockFrameRow(0), []interface{}{1.0}))
+	assert.False(t, fs.isMember("AAPL"))
+
+	assert.Nil(t, fs.AppendRows("AAPL", mockFrameRow(0), mockFrameRow(1), mockFrameRow(2)))
+	assert.Equal(t, 3, fs.store["AAPL"].Len())
+}
+
+func TestFrameStoreGet(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no key
+	_, ok := fs.Get("AAPL")
+	assert.False(t, ok)
+
+	fs.store["AAPL"] = mockFrame(2)
+
+	f, ok := fs.Get("AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, 2, f.Len())
+
+	// returned frame is a copy
+	f.AppendRow(mockFrameRow(2)...)
+	assert.Equal(t, 2, fs.store["AAPL"].Len())
+}
+
+func TestFrameStoreGetIdx(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no key
+	_, err := fs.GetIdx("AAPL", 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	fs.store["AAPL"] = mockFrame(2)
+
+	// out of bounds
+	_, err = fs.GetIdx("AAPL", 2)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	row, err := fs.GetIdx("AAPL", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, mockFrameRow(1), row)
+}
+
+func TestFrameStoreGetRange(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no key
+	_, err := fs.GetRange("AAPL", 0, 1)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	fs.store["AAPL"] = mockFrame(4)
+
+	// out of bounds
+	_, err = fs.GetRange("AAPL", 0, 5)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	f, err := fs.GetRange("AAPL", 1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, f.Len())
+	row, _ := f.Row(0)
+	assert.Equal(t, mockFrameRow(1), row)
+}
+
+func TestFrameStoreGetColumn(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no key
+	_, err := fs.GetColumn("AAPL", "bid")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	fs.store["AAPL"] = mockFrame(2)
+
+	// no column
+	_, err = fs.GetColumn("AAPL", "last")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	v, err := fs.GetColumn("AAPL", "ask")
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0.5, 1.5}, v)
+}
+
+func TestFrameStoreGetTypedColumns(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no key
+	_, err := fs.GetFloat64Column("AAPL", "bid")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = fs.GetInt64Column("AAPL", "volume")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = fs.GetStringColumn("AAPL", "venue")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = fs.GetBoolColumn("AAPL", "halted")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = fs.GetTimeColumn("AAPL", "time")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	fs.store["AAPL"] = mockFrame(2)
+
+	// wrong type
+	_, err = fs.GetInt64Column("AAPL", "bid")
+	assert.Equal(t, ErrColumnType, err)
+
+	bids, err := fs.GetFloat64Column("AAPL", "bid")
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0, 1}, bids)
+
+	vols, err := fs.GetInt64Column("AAPL", "volume")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 100}, vols)
+
+	venues, err := fs.GetStringColumn("AAPL", "venue")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"XNAS", "XNAS"}, venues)
+
+	halted, err := fs.GetBoolColumn("AAPL", "halted")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{false, true}, halted)
+
+	times, err := fs.GetTimeColumn("AAPL", "time")
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{time.Unix(0, 0), time.Unix(1, 0)}, times)
+}
+
+func TestFrameStoreSize(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no keys
+	size := fs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	fs.store["a"] = mockFrame(1)
+	fs.store["b"] = mockFrame(1)
+
+	size = fs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestFrameStoreMembers(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no keys
+	mems := fs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	fs.store["a"] = mockFrame(1)
+	fs.store["b"] = mockFrame(1)
+
+	mems = fs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestFrameStoreSortedMembers(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no keys
+	assert.Equal(t, []string{}, fs.SortedMembers())
+
+	fs.store["MSFT:bid"] = mockFrame(1)
+	fs.store["AAPL:bid"] = mockFrame(1)
+	fs.store["AAPL:ask"] = mockFrame(1)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, fs.SortedMembers())
+
+	// indexed
+	fs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, fs.SortedMembers())
+}
+
+func TestFrameStoreMembersWithPrefix(t *testing.T) {
+	fs := mockFrameStore()
+
+	fs.store["MSFT:bid"] = mockFrame(1)
+	fs.store["AAPL:bid"] = mockFrame(1)
+	fs.store["AAPL:ask"] = mockFrame(1)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, fs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, fs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	fs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, fs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, fs.MembersWithPrefix("GOOG"))
+}
+
+func TestFrameStoreMembersMatching(t *testing.T) {
+	fs := mockFrameStore()
+
+	fs.store["MSFT:bid"] = mockFrame(1)
+	fs.store["AAPL:bid"] = mockFrame(1)
+	fs.store["AAPL:ask"] = mockFrame(1)
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, fs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, fs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, fs.MembersMatching("GOOG:*"))
+
+	// indexed
+	fs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, fs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, fs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, fs.MembersMatching("GOOG:*"))
+}
+
+func TestFrameStoreKeyIndex(t *testing.T) {
+	fs := mockFrameStore()
+	fs.store["foo"] = mockFrame(1)
+
+	// existing keys are indexed
+	fs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, fs.index.Keys())
+
+	// created keys are indexed
+	fs.AppendRow("bar", mockFrameRow(0)...)
+	assert.Equal(t, []string{"bar", "foo"}, fs.index.Keys())
+
+	// clear
+	fs.Clear()
+	assert.Equal(t, 0, fs.index.Len())
+}
+
+func TestFrameStoreScan(t *testing.T) {
+	fs := mockFrameStore()
+
+	fs.store["MSFT:bid"] = mockFrame(1)
+	fs.store["AAPL:bid"] = mockFrame(1)
+	fs.store["AAPL:ask"] = mockFrame(1)
+
+	keys, next := fs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = fs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = fs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, fs.index)
+
+	// no keys
+	fs.Clear()
+	keys, next = fs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestFrameStoreIsMember(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no keys
+	ok := fs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	fs.store["foo"] = mockFrame(1)
+
+	ok = fs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestFrameStoreMemberLen(t *testing.T) {
+	fs := mockFrameStore()
+
+	// no key
+	_, err := fs.MemberLen("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	fs.store["foo"] = mockFrame(3)
+
+	l, err := fs.MemberLen("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, l)
+}
+
+func TestFrameStoreClear(t *testing.T) {
+	fs := mockFrameStore()
+
+	fs.store["foo"] = mockFrame(1)
+	assert.Equal(t, 1, len(fs.store))
+
+	fs.Clear()
+	assert.Equal(t, 0, len(fs.store))
+}
+
+func TestFrameStoreConcurrentAppendAndGet(t *testing.T) {
+	fs := mockFrameStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			fs.AppendRow("foo", mockFrameRow(i)...)
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			fs.GetFloat64Column("foo", "bid")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,203 @@
+package seriesstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mockColumns = []ColumnDef{
+	{"bid", Float64Column},
+	{"ask", Float64Column},
+	{"volume", Int64Column},
+	{"venue", StringColumn},
+	{"halted", BoolColumn},
+	{"time", TimeColumn},
+}
+
+func mockFrame(rows int) *Frame {
+	f, _ := NewFrame(mockColumns...)
+	for i := 0; i < rows; i++ {
+		f.AppendRow(float64(i), float64(i)+0.5, int64(i*100), "XNAS", i%2 == 1, time.Unix(int64(i), 0))
+	}
+
+	return f
+}
+
+func TestNewFrame(t *testing.T) {
+	_, err := NewFrame(ColumnDef{"bid", Float64Column}, ColumnDef{"bid", Int64Column})
+	assert.Equal(t, ErrDuplicateColumn, err)
+
+	f, err := NewFrame(mockColumns...)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, f.Len())
+	assert.Equal(t, mockColumns, f.Columns())
+	assert.Equal(t, []float64{}, f.data[0])
+	assert.Equal(t, []time.Time{}, f.data[5])
+
+	// columns are copied
+	cols := f.Columns()
+	cols[0].Name = "foo"
+	assert.Equal(t, "bid", f.columns[0].Name)
+}
+
+func TestFrameAppendRow(t *testing.T) {
+	f := mockFrame(0)
+
+	// wrong length
+	assert.Equal(t, ErrRowLength, f.AppendRow(1.0, 2.0))
+
+	// wrong type, e.g. untyped int constant
+	assert.Equal(t, ErrColumnType, f.AppendRow(1.0, 2.0, 3, "XNAS", false, time.Unix(0, 0)))
+	assert.Equal(t, 0, f.Len())
+	assert.Equal(t, 0, len(f.data[0].([]float64)))
+
+	now := time.Unix(10, 0)
+	assert.Nil(t, f.AppendRow(1.0, 2.0, int64(3), "XNAS", true, now))
+	assert.Equal(t, 1, f.Len())
+	assert.Equal(t, []float64{1.0}, f.data[0])
+	assert.Equal(t, []int64{3}, f.data[2])
+	assert.Equal(t, []string{"XNAS"}, f.data[3])
+	assert.Equal(t, []bool{true}, f.data[4])
+	assert.Equal(t, []time.Time{now}, f.data[5])
+}
+
+func TestFrameAppendRows(t *testing.T) {
+	f := mockFrame(0)
+
+	good := []interface{}{1.0, 2.0, int64(3), "XNAS", true, time.Unix(0, 0)}
+	bad := []interface{}{1.0, 2.0, int64(3), "XNAS", "true", time.Unix(0, 0)}
+
+	// all or none
+	assert.Equal(t, ErrColumnType, f.AppendRows(good, bad))
+	assert.Equal(t, 0, f.Len())
+
+	assert.Nil(t, f.AppendRows(good, good))
+	assert.Equal(t, 2, f.Len())
+	for _, col := range f.data {
+		assert.Len(t, col, 2)
+	}
+}
+
+func TestFrameRow(t *testing.T) {
+	f := mockFrame(3)
+
+	// out of bounds
+	_, err := f.Row(-1)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = f.Row(3)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	row, err := f.Row(1)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1.0, 1.5, int64(100), "XNAS", true, time.Unix(1, 0)}, row)
+}
+
+func TestFrameColumn(t *testing.T) {
+	f := mockFrame(3)
+
+	// no column
+	_, err := f.Column("last")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	v, err := f.Column("volume")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 100, 200}, v)
+
+	// column is a copy
+	v.([]int64)[0] = 42
+	assert.Equal(t, int64(0), f.data[2].([]int64)[0])
+}
+
+func TestFrameTypedColumns(t *testing.T) {
+	f := mockFrame(2)
+
+	// no column
+	_, err := f.Float64Column("last")
+	assert.Equal(t, ErrColumnDoesNotExist, err)
+
+	// wrong type
+	_, err = f.Float64Column("volume")
+	assert.Equal(t, ErrColumnType, err)
+	_, err = f.Int64Column("bid")
+	assert.Equal(t, ErrColumnType, err)
+	_, err = f.StringColumn("bid")
+	assert.Equal(t, ErrColumnType, err)
+	_, err = f.BoolColumn("bid")
+	assert.Equal(t, ErrColumnType, err)
+	_, err = f.TimeColumn("bid")
+	assert.Equal(t, ErrColumnType, err)
+
+	bids, err := f.Float64Column("bid")
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{0, 1}, bids)
+
+	vols, err := f.Int64Column("volume")
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{0, 100}, vols)
+
+	venues, err := f.StringColumn("venue")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"XNAS", "XNAS"}, venues)
+
+	halted, err := f.BoolColumn("halted")
+	assert.Nil(t, err)
+	assert.Equal(t, []bool{false, true}, halted)
+
+	times, err := f.TimeColumn("time")
+	assert.Nil(t, err)
+	assert.Equal(t, []time.Time{time.Unix(0, 0), time.Unix(1, 0)}, times)
+}
+
+func TestFrameSlice(t *testing.T) {
+	f := mockFrame(5)
+
+	// out of bounds
+	_, err := f.Slice(-1, 2)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = f.Slice(0, 6)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = f.Slice(3, 2)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	s, err := f.Slice(1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, s.Len())
+	bids, _ := s.Float64Column("bid")
+	assert.Equal(t, []float64{1, 2}, bids)
+
+	// slice is a copy, appending does not touch the source
+	s.AppendRow(9.0, 9.5, int64(900), "XNYS", false, time.Unix(9, 0))
+	assert.Equal(t, 3, s.Len())
+	assert.Equal(t, 5, f.Len())
+	assert.Equal(t, 3.0, f.data[0].([]float64)[3])
+
+	// empty
+	s, err = f.Slice(2, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestFrameClone(t *testing.T) {
+	f := mockFrame(2)
+
+	c := f.Clone()
+	assert.Equal(t, f.data, c.data)
+
+	c.data[0].([]float64)[0] = 42
+	assert.Equal(t, 0.0, f.data[0].([]float64)[0])
+}
+
+func TestFrameSameColumns(t *testing.T) {
+	f := mockFrame(0)
+
+	other, _ := NewFrame(mockColumns...)
+	assert.True(t, f.sameColumns(other))
+
+	other, _ = NewFrame(mockColumns[:2]...)
+	assert.False(t, f.sameColumns(other))
+
+	other, _ = NewFrame(ColumnDef{"bid", Float64Column}, ColumnDef{"ask", Int64Column})
+	assert.False(t, other.sameColumns(mockFrame(0)))
+}
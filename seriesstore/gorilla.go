@@ -0,0 +1,485 @@
+package seriesstore
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// DefaultChunkSize is the number of points compressed together when a CompressedSeries is created with a chunk size below 1
+const DefaultChunkSize = 120
+
+// rawPointSize is the size of an uncompressed point, an int64 timestamp and a float64 value
+const rawPointSize = 16
+
+// Point is a float64 value at a point in time
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// sample is a point as stored, with the timestamp in unix nanoseconds
+type sample struct {
+	t int64
+	v float64
+}
+
+func (s sample) point() Point {
+	return Point{Time: time.Unix(0, s.t).UTC(), Value: s.v}
+}
+
+// bitWriter appends bits to a byte slice, most significant bit first
+type bitWriter struct {
+	buf  []byte
+	free uint // bits not yet written in the last byte
+}
+
+// writeBits writes the low n bits of v
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	v <<= 64 - n
+	for n > 0 {
+		if w.free == 0 {
+			w.buf = append(w.buf, 0)
+			w.free = 8
+		}
+
+		k := n
+		if k > w.free {
+			k = w.free
+		}
+
+		w.buf[len(w.buf)-1] |= byte(v>>(64-k)) << (w.free - k)
+		v <<= k
+		n -= k
+		w.free -= k
+	}
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if bit {
+		w.writeBits(1, 1)
+	} else {
+		w.writeBits(0, 1)
+	}
+}
+
+// bitReader reads the bits written by a bitWriter
+type bitReader struct {
+	buf []byte
+	pos uint // bits read
+}
+
+func (r *bitReader) readBits(n uint) uint64 {
+	var v uint64
+	for n > 0 {
+		off := r.pos & 7
+		k := 8 - off
+		if k > n {
+			k = n
+		}
+
+		b := r.buf[r.pos>>3] << off >> (8 - k)
+		v = v<<k | uint64(b)
+		n -= k
+		r.pos += k
+	}
+
+	return v
+}
+
+func (r *bitReader) readBit() bool {
+	return r.readBits(1) == 1
+}
+
+// timestamp delta of delta buckets, after a prefix of i one bits the delta of delta takes dodBits[i] bits
+// A delta of delta of 0 is a single zero bit, the last bucket has no terminating zero bit
+var dodBits = [...]uint{0, 14, 17, 20, 32, 64}
+
+func fitsBits(v int64, n uint) bool {
+	return n == 64 || (v >= -(1<<(n-1)) && v < 1<<(n-1))
+}
+
+// chunk is an immutable run of compressed points
+// Timestamps are delta of delta encoded and values are XORed with the previous value, as in Facebook's Gorilla
+type chunk struct {
+	data  []byte
+	count int
+	last  int64 // timestamp of the last point, to skip chunks by time
+}
+
+// encodeChunk compresses a non empty run of points with non decreasing timestamps
+func encodeChunk(samples []sample) *chunk {
+	var w bitWriter
+	var delta int64
+	var lead, trail uint = 0xff, 0
+
+	w.writeBits(uint64(samples[0].t), 64)
+	w.writeBits(math.Float64bits(samples[0].v), 64)
+
+	for i := 1; i < len(samples); i++ {
+		d := samples[i].t - samples[i-1].t
+		dod := d - delta
+		delta = d
+
+		if dod == 0 {
+			w.writeBit(false)
+		} else {
+			for j := 1; j < len(dodBits); j++ {
+				if fitsBits(dod, dodBits[j]) {
+					// j one bits, then a zero bit unless this is the last bucket
+					w.writeBits(1<<uint(j)-1, uint(j))
+					if j < len(dodBits)-1 {
+						w.writeBit(false)
+					}
+					w.writeBits(uint64(dod), dodBits[j])
+					break
+				}
+			}
+		}
+
+		x := math.Float64bits(samples[i].v) ^ math.Float64bits(samples[i-1].v)
+		if x == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+
+		l, t := uint(bits.LeadingZeros64(x)), uint(bits.TrailingZeros64(x))
+		if l > 31 {
+			// the leading zero count is written in 5 bits
+			l = 31
+		}
+
+		// reuse the previous window of meaningful bits when x fits in it
+		if lead != 0xff && l >= lead && t >= trail {
+			w.writeBit(false)
+			w.writeBits(x>>trail, 64-lead-trail)
+			continue
+		}
+
+		lead, trail = l, t
+		sig := 64 - lead - trail
+		w.writeBit(true)
+		w.writeBits(uint64(lead), 5)
+		// 64 meaningful bits do not fit in 6 bits and are written as 0
+		w.writeBits(uint64(sig&63), 6)
+		w.writeBits(x>>trail, sig)
+	}
+
+	return &chunk{data: w.buf, count: len(samples), last: samples[len(samples)-1].t}
+}
+
+// chunkIterator decodes the points of a chunk in order
+type chunkIterator struct {
+	r     bitReader
+	count int
+	i     int
+	t     int64
+	delta int64
+	v     uint64
+	lead  uint
+	trail uint
+}
+
+func newChunkIterator(c *chunk) chunkIterator {
+	return chunkIterator{r: bitReader{buf: c.data}, count: c.count}
+}
+
+func (it *chunkIterator) next() bool {
+	if it.i >= it.count {
+		return false
+	}
+
+	if it.i == 0 {
+		it.t = int64(it.r.readBits(64))
+		it.v = it.r.readBits(64)
+		it.i++
+
+		return true
+	}
+
+	ones := 0
+	for ones < len(dodBits)-1 && it.r.readBit() {
+		ones++
+	}
+
+	if n := dodBits[ones]; n > 0 {
+		// sign extend
+		it.delta += int64(it.r.readBits(n)<<(64-n)) >> (64 - n)
+	}
+	it.t += it.delta
+
+	if it.r.readBit() {
+		if it.r.readBit() {
+			it.lead = uint(it.r.readBits(5))
+			sig := uint(it.r.readBits(6))
+			if sig == 0 {
+				sig = 64
+			}
+			it.trail = 64 - it.lead - sig
+		}
+
+		it.v ^= it.r.readBits(64-it.lead-it.trail) << it.trail
+	}
+	it.i++
+
+	return true
+}
+
+func (it *chunkIterator) at() sample {
+	return sample{t: it.t, v: math.Float64frombits(it.v)}
+}
+
+// CompressedIterator decodes the points of a CompressedSeries in order
+// An iterator reads a snapshot of the series taken when it was created, later appends are not seen
+type CompressedIterator struct {
+	chunks []*chunk
+	head   []sample
+	ci     int // index of the chunk being decoded, len(chunks) once decoding the head
+	it     chunkIterator
+	hi     int // index of the next head point
+	cur    sample
+}
+
+func newCompressedIterator(chunks []*chunk, head []sample) *CompressedIterator {
+	it := &CompressedIterator{chunks: chunks, head: head}
+	if len(chunks) > 0 {
+		it.it = newChunkIterator(chunks[0])
+	}
+
+	return it
+}
+
+// seekChunk moves the iterator to the start of chunk ci, or of the head if ci is len(chunks)
+func (it *CompressedIterator) seekChunk(ci int) {
+	it.ci = ci
+	if ci < len(it.chunks) {
+		it.it = newChunkIterator(it.chunks[ci])
+	}
+}
+
+// Next advances the iterator to the next point
+// returns false once every point has been read
+func (it *CompressedIterator) Next() bool {
+	for it.ci < len(it.chunks) {
+		if it.it.next() {
+			it.cur = it.it.at()
+			return true
+		}
+
+		it.seekChunk(it.ci + 1)
+	}
+
+	if it.hi < len(it.head) {
+		it.cur = it.head[it.hi]
+		it.hi++
+		return true
+	}
+
+	return false
+}
+
+// At returns the current point, only valid after Next returned true
+func (it *CompressedIterator) At() Point {
+	return it.cur.point()
+}
+
+// CompressedSeries is a float64 time series compressed in memory
+// Points are appended to an uncompressed head, which is compressed into an immutable chunk
+// once it holds chunk size points, so regular series take a few bits per point
+// Timestamps are held in unix nanoseconds, so they must be within the years 1678 and 2262, and are read back in UTC
+// Note: CompressedSeries is NOT safe for concurrent use, see CompressedFloat64SStore
+type CompressedSeries struct {
+	chunkSize int
+	chunks    []*chunk
+	head      []sample
+	bytes     int // size of the chunk data
+}
+
+// NewCompressedSeries constructs and initializes a new CompressedSeries compressing every chunkSize points together,
+// or DefaultChunkSize points if chunkSize < 1
+// Larger chunks compress better, but indexed reads decode up to a whole chunk
+// Always use this function when creating a new CompressedSeries
+func NewCompressedSeries(chunkSize int) *CompressedSeries {
+	if chunkSize < 1 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &CompressedSeries{chunkSize: chunkSize, head: make([]sample, 0, chunkSize)}
+}
+
+// ChunkSize returns the number of points compressed together
+func (c *CompressedSeries) ChunkSize() int {
+	return c.chunkSize
+}
+
+func (c *CompressedSeries) lastTime() (int64, bool) {
+	if len(c.head) > 0 {
+		return c.head[len(c.head)-1].t, true
+	}
+
+	if len(c.chunks) > 0 {
+		return c.chunks[len(c.chunks)-1].last, true
+	}
+
+	return 0, false
+}
+
+// checkOrder checks points can be appended without going back in time
+func (c *CompressedSeries) checkOrder(points []Point) error {
+	last, ok := c.lastTime()
+	for _, p := range points {
+		t := p.Time.UnixNano()
+		if ok && t < last {
+			return ErrOutOfOrder
+		}
+		last, ok = t, true
+	}
+
+	return nil
+}
+
+func (c *CompressedSeries) append(p Point) {
+	c.head = append(c.head, sample{t: p.Time.UnixNano(), v: p.Value})
+	if len(c.head) < c.chunkSize {
+		return
+	}
+
+	ch := encodeChunk(c.head)
+	c.chunks = append(c.chunks, ch)
+	c.bytes += len(ch.data)
+
+	// iterators hold copies of the head, so its array can be reused
+	c.head = c.head[:0]
+}
+
+// Append adds the given points to the end of the series
+// returns ErrOutOfOrder without appending any point if a point is older than the one before it
+func (c *CompressedSeries) Append(points ...Point) error {
+	if err := c.checkOrder(points); err != nil {
+		return err
+	}
+
+	for _, p := range points {
+		c.append(p)
+	}
+
+	return nil
+}
+
+// Len returns the number of points in the series
+func (c *CompressedSeries) Len() int {
+	return len(c.chunks)*c.chunkSize + len(c.head)
+}
+
+// iterator returns an iterator reading the head in place rather than a copy,
+// for reads that finish before the series is next appended to
+func (c *CompressedSeries) iterator() *CompressedIterator {
+	return newCompressedIterator(c.chunks, c.head)
+}
+
+// iteratorAt returns an iterator whose next point is point idx, reading the head in place
+func (c *CompressedSeries) iteratorAt(idx int) *CompressedIterator {
+	it := c.iterator()
+
+	// skip whole chunks without decoding them
+	ci := idx / c.chunkSize
+	if ci > len(c.chunks) {
+		ci = len(c.chunks)
+	}
+	it.seekChunk(ci)
+
+	skip := idx - ci*c.chunkSize
+	if ci == len(c.chunks) {
+		// head points are indexed directly
+		it.hi = skip
+		return it
+	}
+
+	for ; skip > 0; skip-- {
+		it.Next()
+	}
+
+	return it
+}
+
+// Iterator returns an iterator over every point of the series
+// The head is copied once here, so the iterator may be used while the series is appended to
+func (c *CompressedSeries) Iterator() *CompressedIterator {
+	head := make([]sample, len(c.head))
+	copy(head, c.head)
+
+	return newCompressedIterator(c.chunks[:len(c.chunks):len(c.chunks)], head)
+}
+
+// At returns the point at the specified index
+// returns ErrIdxOutOfBounds if idx is not within 0 and Len
+func (c *CompressedSeries) At(idx int) (Point, error) {
+	// bounds check
+	if idx < 0 || idx >= c.Len() {
+		return Point{}, ErrIdxOutOfBounds
+	}
+
+	it := c.iteratorAt(idx)
+	it.Next()
+
+	return it.At(), nil
+}
+
+// Range returns the points within the specified index range (inclusive:exclusive)
+// returns ErrIdxOutOfBounds if the bounds are not within 0 and Len
+func (c *CompressedSeries) Range(lower, upper int) ([]Point, error) {
+	n := c.Len()
+
+	// bounds check
+	if lower < 0 || lower > n || upper < 0 || upper > n || lower > upper {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	points := make([]Point, 0, upper-lower)
+	it := c.iteratorAt(lower)
+	for i := lower; i < upper && it.Next(); i++ {
+		points = append(points, it.At())
+	}
+
+	return points, nil
+}
+
+// Between returns the points from start up to, but not including, end
+func (c *CompressedSeries) Between(start, end time.Time) []Point {
+	from, to := start.UnixNano(), end.UnixNano()
+
+	// skip the chunks ending before start
+	ci := sort.Search(len(c.chunks), func(i int) bool { return c.chunks[i].last >= from })
+	it := c.iterator()
+	it.seekChunk(ci)
+
+	points := make([]Point, 0)
+	for it.Next() {
+		if it.cur.t >= to {
+			break
+		}
+
+		if it.cur.t >= from {
+			points = append(points, it.At())
+		}
+	}
+
+	return points
+}
+
+// Bytes returns the memory used by the points of the series, compressed chunks and the uncompressed head
+func (c *CompressedSeries) Bytes() int {
+	return c.bytes + len(c.head)*rawPointSize
+}
+
+// CompressionRatio returns the size of the points uncompressed, 16 bytes each, over their size in the series
+// returns 0 for an empty series
+func (c *CompressedSeries) CompressionRatio() float64 {
+	if c.Len() == 0 {
+		return 0
+	}
+
+	return float64(c.Len()*rawPointSize) / float64(c.Bytes())
+}
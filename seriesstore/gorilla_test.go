@@ -0,0 +1,224 @@
+package seriesstore
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mockPointStart = time.Date(2018, 6, 1, 9, 30, 0, 0, time.UTC)
+
+// mockPoints returns n points a second apart drifting from a price of 100
+func mockPoints(n int) []Point {
+	points := make([]Point, n)
+	for i := range points {
+		points[i] = Point{Time: mockPointStart.Add(time.Duration(i) * time.Second), Value: 100 + float64(i%7)*0.25}
+	}
+
+	return points
+}
+
+func TestBitWriterReader(t *testing.T) {
+	var w bitWriter
+	w.writeBit(true)
+	w.writeBits(0x5, 3)
+	w.writeBits(math.MaxUint64, 64)
+	w.writeBits(0, 7)
+	w.writeBits(0x2a, 6)
+	assert.Len(t, w.buf, 11)
+
+	r := bitReader{buf: w.buf}
+	assert.True(t, r.readBit())
+	assert.Equal(t, uint64(0x5), r.readBits(3))
+	assert.Equal(t, uint64(math.MaxUint64), r.readBits(64))
+	assert.Equal(t, uint64(0), r.readBits(7))
+	assert.Equal(t, uint64(0x2a), r.readBits(6))
+}
+
+func TestFitsBits(t *testing.T) {
+	assert.True(t, fitsBits(8191, 14))
+	assert.True(t, fitsBits(-8192, 14))
+	assert.False(t, fitsBits(8192, 14))
+	assert.False(t, fitsBits(-8193, 14))
+	assert.True(t, fitsBits(math.MinInt64, 64))
+}
+
+func decodeChunk(c *chunk) []sample {
+	out := make([]sample, 0)
+	it := newChunkIterator(c)
+	for it.next() {
+		out = append(out, it.at())
+	}
+
+	return out
+}
+
+func TestChunkRoundTrip(t *testing.T) {
+	cases := map[string][]sample{
+		"single":   {{t: 5, v: 1.5}},
+		"constant": {{t: 0, v: 1}, {t: 10, v: 1}, {t: 20, v: 1}, {t: 30, v: 1}},
+		// every delta of delta bucket
+		"jitter":   {{t: 0}, {t: 1000}, {t: 2000}, {t: 3001}, {t: 3001 + 1e4}, {t: 3001 + 1e5}, {t: 1e6}, {t: 1e9}, {t: 1e12}, {t: math.MaxInt64}},
+		"negative": {{t: -1e18, v: -1}, {t: 0, v: 0}, {t: 1e18, v: 1}},
+		"special": {
+			{t: 0, v: math.NaN()}, {t: 1, v: math.Inf(1)}, {t: 2, v: math.Inf(-1)}, {t: 3, v: math.Copysign(0, -1)},
+			{t: 4, v: math.SmallestNonzeroFloat64}, {t: 5, v: math.MaxFloat64}, {t: 6, v: 0},
+		},
+	}
+
+	r := rand.New(rand.NewSource(1))
+	random := make([]sample, 1000)
+	for i := range random {
+		random[i] = sample{t: int64(i)*1e9 + r.Int63n(1e6), v: r.NormFloat64() * 100}
+	}
+	cases["random"] = random
+
+	for name, samples := range cases {
+		got := decodeChunk(encodeChunk(samples))
+		assert.Len(t, got, len(samples), name)
+		for i := range samples {
+			assert.Equal(t, samples[i].t, got[i].t, name)
+			assert.Equal(t, math.Float64bits(samples[i].v), math.Float64bits(got[i].v), name)
+		}
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	// a regular constant series takes 2 bits a point after the first
+	samples := make([]sample, 121)
+	for i := range samples {
+		samples[i] = sample{t: int64(i) * 1e9, v: 100}
+	}
+
+	c := encodeChunk(samples)
+	assert.Equal(t, 121, c.count)
+	assert.Equal(t, int64(120e9), c.last)
+
+	// the first point takes 16 bytes, the delta of delta of the second takes a 5 bit prefix and 32 bits
+	assert.Equal(t, 16+(5+32+1+119*2+7)/8, len(c.data))
+}
+
+func TestNewCompressedSeries(t *testing.T) {
+	assert.Equal(t, DefaultChunkSize, NewCompressedSeries(0).ChunkSize())
+	assert.Equal(t, 8, NewCompressedSeries(8).ChunkSize())
+
+	c := NewCompressedSeries(8)
+	assert.Equal(t, 0, c.Len())
+	assert.Equal(t, 0, c.Bytes())
+	assert.Equal(t, 0.0, c.CompressionRatio())
+	assert.False(t, c.Iterator().Next())
+}
+
+func TestCompressedSeriesAppend(t *testing.T) {
+	c := NewCompressedSeries(4)
+	points := mockPoints(10)
+	assert.Nil(t, c.Append(points[:3]...))
+	assert.Len(t, c.chunks, 0)
+	assert.Len(t, c.head, 3)
+
+	// a full head is compressed into a chunk
+	assert.Nil(t, c.Append(points[3:]...))
+	assert.Len(t, c.chunks, 2)
+	assert.Len(t, c.head, 2)
+	assert.Equal(t, 10, c.Len())
+
+	// out of order batches are rejected whole
+	late := Point{Time: mockPointStart, Value: 1}
+	next := Point{Time: mockPointStart.Add(time.Hour), Value: 1}
+	assert.Equal(t, ErrOutOfOrder, c.Append(next, late))
+	assert.Equal(t, ErrOutOfOrder, c.Append(late))
+	assert.Equal(t, 10, c.Len())
+
+	// equal timestamps are allowed
+	assert.Nil(t, c.Append(points[9], points[9]))
+	assert.Equal(t, 12, c.Len())
+}
+
+func TestCompressedSeriesIterator(t *testing.T) {
+	c := NewCompressedSeries(4)
+	points := mockPoints(10)
+	c.Append(points...)
+
+	it := c.Iterator()
+
+	// appends after the iterator is created are not seen
+	c.Append(mockPoints(12)[10:]...)
+
+	got := make([]Point, 0)
+	for it.Next() {
+		got = append(got, it.At())
+	}
+	assert.Equal(t, points, got)
+	assert.False(t, it.Next())
+}
+
+func TestCompressedSeriesAt(t *testing.T) {
+	c := NewCompressedSeries(4)
+	points := mockPoints(10)
+	c.Append(points...)
+
+	for i, p := range points {
+		v, err := c.At(i)
+		assert.Nil(t, err)
+		assert.Equal(t, p, v)
+	}
+
+	// reads of the head do not copy it
+	assert.True(t, &c.head[0] == &c.iteratorAt(8).head[0])
+	assert.False(t, &c.head[0] == &c.Iterator().head[0])
+
+	_, err := c.At(10)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = c.At(-1)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestCompressedSeriesRange(t *testing.T) {
+	c := NewCompressedSeries(4)
+	points := mockPoints(10)
+	c.Append(points...)
+
+	for _, b := range [][2]int{{0, 10}, {0, 0}, {3, 9}, {4, 8}, {8, 10}, {10, 10}} {
+		v, err := c.Range(b[0], b[1])
+		assert.Nil(t, err)
+		assert.Equal(t, points[b[0]:b[1]], v)
+	}
+
+	for _, b := range [][2]int{{-1, 2}, {0, 11}, {11, 11}, {5, 4}} {
+		_, err := c.Range(b[0], b[1])
+		assert.Equal(t, ErrIdxOutOfBounds, err)
+	}
+}
+
+func TestCompressedSeriesBetween(t *testing.T) {
+	c := NewCompressedSeries(4)
+	points := mockPoints(10)
+	c.Append(points...)
+
+	at := func(i int) time.Time { return mockPointStart.Add(time.Duration(i) * time.Second) }
+	assert.Equal(t, points[2:7], c.Between(at(2), at(7)))
+	assert.Equal(t, points[5:], c.Between(at(5), at(100)))
+	assert.Equal(t, points[:1], c.Between(at(-5), at(1)))
+	assert.Len(t, c.Between(at(7), at(7)), 0)
+	assert.Len(t, c.Between(at(20), at(30)), 0)
+
+	// within a second
+	assert.Equal(t, points[3:4], c.Between(at(3), at(3).Add(time.Millisecond)))
+}
+
+func TestCompressedSeriesCompressionRatio(t *testing.T) {
+	c := NewCompressedSeries(120)
+	c.Append(mockPoints(1200)...)
+
+	// regular timestamps and few distinct values compress well
+	assert.Equal(t, 0, len(c.head))
+	assert.True(t, c.CompressionRatio() > 8, c.CompressionRatio())
+	assert.Equal(t, float64(1200*16)/float64(c.Bytes()), c.CompressionRatio())
+
+	// the head is counted uncompressed
+	c.Append(Point{Time: mockPointStart.Add(time.Hour), Value: 1})
+	assert.Equal(t, c.bytes+16, c.Bytes())
+}
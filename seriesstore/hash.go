@@ -0,0 +1,402 @@
+package seriesstore
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// HashStore is a store of string field to string value maps, like Redis hashes
+// Each key holds the fields of one record, e.g. the exchange, lot size and tick size of a symbol
+// All operations on the fields of a key are atomic
+// A key is deleted when its last field is deleted
+// Embedded sync.Mutex to provide atomic operation ability
+type HashStore struct {
+	sync.Mutex
+	store map[string]map[string]string
+	index *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewHashStore constructs and initializes a new HashStore
+// Always use this function to init new HashStores
+func NewHashStore() *HashStore {
+	return &HashStore{store: make(map[string]map[string]string)}
+}
+
+func (s *HashStore) hash(key string) map[string]string {
+	h, ok := s.store[key]
+	if !ok {
+		h = make(map[string]string)
+		s.store[key] = h
+		s.index.Insert(key)
+	}
+
+	return h
+}
+
+func (s *HashStore) hSet(key, field, value string) bool {
+	h := s.hash(key)
+	_, ok := h[field]
+	h[field] = value
+
+	return !ok
+}
+
+// HSet sets the field of the given key to value, creating the key if it does not exist
+// returns true if the field is new
+func (s *HashStore) HSet(key, field, value string) bool {
+	s.Lock()
+	added := s.hSet(key, field, value)
+	s.Unlock()
+
+	return added
+}
+
+func (s *HashStore) hMSet(key string, fields map[string]string) {
+	// never create an empty hash
+	if len(fields) == 0 {
+		return
+	}
+
+	h := s.hash(key)
+	for f, v := range fields {
+		h[f] = v
+	}
+}
+
+// HMSet sets all the given fields of the given key in one atomic operation
+func (s *HashStore) HMSet(key string, fields map[string]string) {
+	s.Lock()
+	s.hMSet(key, fields)
+	s.Unlock()
+}
+
+func (s *HashStore) hGet(key, field string) (string, bool) {
+	// explicitly return second return value
+	v, ok := s.store[key][field]
+
+	return v, ok
+}
+
+// HGet returns the value of the field of the given key
+// returns the value and boolean if the field exists
+func (s *HashStore) HGet(key, field string) (string, bool) {
+	s.Lock()
+	v, ok := s.hGet(key, field)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *HashStore) hDel(key string, fields ...string) int {
+	h, ok := s.store[key]
+	if !ok {
+		return 0
+	}
+
+	n := 0
+	for _, f := range fields {
+		if _, ok := h[f]; ok {
+			delete(h, f)
+			n++
+		}
+	}
+
+	if len(h) == 0 {
+		delete(s.store, key)
+		s.index.Delete(key)
+	}
+
+	return n
+}
+
+// HDel deletes the given fields of the given key
+// returns the number of fields deleted
+func (s *HashStore) HDel(key string, fields ...string) int {
+	s.Lock()
+	n := s.hDel(key, fields...)
+	s.Unlock()
+
+	return n
+}
+
+func (s *HashStore) hGetAll(key string) (map[string]string, bool) {
+	h, ok := s.store[key]
+	if !ok {
+		return nil, false
+	}
+
+	// copy, the hash may change after unlock
+	c := make(map[string]string, len(h))
+	for f, v := range h {
+		c[f] = v
+	}
+
+	return c, true
+}
+
+// HGetAll returns a copy of all fields and values of the given key
+// returns the fields and boolean if key exists
+func (s *HashStore) HGetAll(key string) (map[string]string, bool) {
+	s.Lock()
+	h, ok := s.hGetAll(key)
+	s.Unlock()
+
+	return h, ok
+}
+
+func (s *HashStore) hKeys(key string) ([]string, error) {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	fields := make([]string, len(h))
+
+	i := 0
+	for f := range h {
+		fields[i] = f
+		i++
+	}
+
+	return fields, nil
+}
+
+// HKeys returns all fields of the given key
+func (s *HashStore) HKeys(key string) ([]string, error) {
+	s.Lock()
+	fields, err := s.hKeys(key)
+	s.Unlock()
+
+	return fields, err
+}
+
+func (s *HashStore) hLen(key string) (int, error) {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return len(h), nil
+}
+
+// HLen returns the number of fields of the given key
+func (s *HashStore) HLen(key string) (int, error) {
+	s.Lock()
+	l, err := s.hLen(key)
+	s.Unlock()
+
+	return l, err
+}
+
+func (s *HashStore) hIncrBy(key, field string, delta int64) (int64, error) {
+	var cur int64
+	if v, ok := s.store[key][field]; ok {
+		var err error
+		cur, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+	}
+
+	n := cur + delta
+	if (delta > 0 && n < cur) || (delta < 0 && n > cur) {
+		return 0, ErrIntegerOverflow
+	}
+
+	s.hash(key)[field] = strconv.FormatInt(n, 10)
+
+	return n, nil
+}
+
+// HIncrBy atomically increments the integer value of the field of the given key by delta
+// A missing field starts from zero
+// returns the new value, or ErrNotInteger if the field does not hold a base 10 integer
+func (s *HashStore) HIncrBy(key, field string, delta int64) (int64, error) {
+	s.Lock()
+	v, err := s.hIncrBy(key, field, delta)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *HashStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *HashStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *HashStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *HashStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *HashStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *HashStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *HashStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *HashStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *HashStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *HashStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *HashStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *HashStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *HashStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *HashStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *HashStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *HashStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *HashStore) clear() {
+	s.store = make(map[string]map[string]string)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *HashStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
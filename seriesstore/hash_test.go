@@ -0,0 +1,345 @@
+package seriesstore
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockHash() map[string]string {
+	return map[string]string{"exchange": "NASDAQ", "lot": "100", "tick": "0.01"}
+}
+
+func TestHashHSet(t *testing.T) {
+	hs := NewHashStore()
+
+	// new key and field
+	added := hs.HSet("AAPL", "exchange", "NASDAQ")
+	assert.True(t, added)
+	assert.Equal(t, "NASDAQ", hs.store["AAPL"]["exchange"])
+
+	// overwrite field
+	added = hs.HSet("AAPL", "exchange", "NYSE")
+	assert.False(t, added)
+	assert.Equal(t, "NYSE", hs.store["AAPL"]["exchange"])
+}
+
+func TestHashHMSet(t *testing.T) {
+	hs := NewHashStore()
+
+	hs.HMSet("AAPL", mockHash())
+	assert.Equal(t, mockHash(), hs.store["AAPL"])
+
+	// no fields
+	hs.HMSet("MSFT", map[string]string{})
+	assert.False(t, hs.isMember("MSFT"))
+
+	hs.HMSet("AAPL", map[string]string{"lot": "1"})
+	assert.Equal(t, "1", hs.store["AAPL"]["lot"])
+	assert.Equal(t, 3, len(hs.store["AAPL"]))
+}
+
+func TestHashHGet(t *testing.T) {
+	hs := NewHashStore()
+
+	// no key
+	_, ok := hs.HGet("AAPL", "lot")
+	assert.False(t, ok)
+
+	hs.store["AAPL"] = mockHash()
+
+	// no field
+	_, ok = hs.HGet("AAPL", "foo")
+	assert.False(t, ok)
+
+	v, ok := hs.HGet("AAPL", "lot")
+	assert.True(t, ok)
+	assert.Equal(t, "100", v)
+}
+
+func TestHashHDel(t *testing.T) {
+	hs := NewHashStore()
+
+	// no key
+	assert.Equal(t, 0, hs.HDel("AAPL", "lot"))
+
+	hs.store["AAPL"] = mockHash()
+	assert.Equal(t, 2, hs.HDel("AAPL", "lot", "tick", "foo"))
+	assert.Equal(t, map[string]string{"exchange": "NASDAQ"}, hs.store["AAPL"])
+
+	// last field deletes key
+	assert.Equal(t, 1, hs.HDel("AAPL", "exchange"))
+	assert.False(t, hs.isMember("AAPL"))
+}
+
+func TestHashHGetAll(t *testing.T) {
+	hs := NewHashStore()
+
+	// no key
+	h, ok := hs.HGetAll("AAPL")
+	assert.False(t, ok)
+	assert.Nil(t, h)
+
+	hs.store["AAPL"] = mockHash()
+	h, ok = hs.HGetAll("AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, mockHash(), h)
+
+	// returns a copy
+	h["lot"] = "1"
+	assert.Equal(t, "100", hs.store["AAPL"]["lot"])
+}
+
+func TestHashHKeys(t *testing.T) {
+	hs := NewHashStore()
+
+	// no key
+	_, err := hs.HKeys("AAPL")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["AAPL"] = mockHash()
+	fields, err := hs.HKeys("AAPL")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"exchange", "lot", "tick"}, fields)
+}
+
+func TestHashHLen(t *testing.T) {
+	hs := NewHashStore()
+
+	// no key
+	_, err := hs.HLen("AAPL")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["AAPL"] = mockHash()
+	l, err := hs.HLen("AAPL")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, l)
+}
+
+func TestHashHIncrBy(t *testing.T) {
+	hs := NewHashStore()
+
+	// missing field starts from zero
+	v, err := hs.HIncrBy("AAPL", "trades", 5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), v)
+	assert.Equal(t, "5", hs.store["AAPL"]["trades"])
+
+	v, err = hs.HIncrBy("AAPL", "trades", -7)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-2), v)
+
+	// not an integer
+	hs.store["AAPL"]["tick"] = "0.01"
+	_, err = hs.HIncrBy("AAPL", "tick", 1)
+	assert.Equal(t, ErrNotInteger, err)
+	assert.Equal(t, "0.01", hs.store["AAPL"]["tick"])
+
+	// overflow
+	hs.store["AAPL"]["max"] = strconv.FormatInt(math.MaxInt64, 10)
+	_, err = hs.HIncrBy("AAPL", "max", 1)
+	assert.Equal(t, ErrIntegerOverflow, err)
+
+	// failed increment of a missing key does not create it
+	hs.store["MSFT"] = map[string]string{"max": strconv.FormatInt(math.MinInt64, 10)}
+	_, err = hs.HIncrBy("MSFT", "max", -1)
+	assert.Equal(t, ErrIntegerOverflow, err)
+	_, err = hs.HIncrBy("IBM", "max", 0)
+	assert.Nil(t, err)
+}
+
+func TestHashSize(t *testing.T) {
+	hs := NewHashStore()
+
+	// no keys
+	size := hs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	hs.store["a"] = mockHash()
+	hs.store["b"] = mockHash()
+
+	size = hs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestHashMembers(t *testing.T) {
+	hs := NewHashStore()
+
+	// no keys
+	mems := hs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	hs.store["a"] = mockHash()
+	hs.store["b"] = mockHash()
+
+	mems = hs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestHashSortedMembers(t *testing.T) {
+	hs := NewHashStore()
+
+	// no keys
+	assert.Equal(t, []string{}, hs.SortedMembers())
+
+	hs.store["MSFT:bid"] = mockHash()
+	hs.store["AAPL:bid"] = mockHash()
+	hs.store["AAPL:ask"] = mockHash()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, hs.SortedMembers())
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, hs.SortedMembers())
+}
+
+func TestHashMembersWithPrefix(t *testing.T) {
+	hs := NewHashStore()
+
+	hs.store["MSFT:bid"] = mockHash()
+	hs.store["AAPL:bid"] = mockHash()
+	hs.store["AAPL:ask"] = mockHash()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, hs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, hs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, hs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, hs.MembersWithPrefix("GOOG"))
+}
+
+func TestHashMembersMatching(t *testing.T) {
+	hs := NewHashStore()
+
+	hs.store["MSFT:bid"] = mockHash()
+	hs.store["AAPL:bid"] = mockHash()
+	hs.store["AAPL:ask"] = mockHash()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, hs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, hs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, hs.MembersMatching("GOOG:*"))
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, hs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, hs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, hs.MembersMatching("GOOG:*"))
+}
+
+func TestHashKeyIndex(t *testing.T) {
+	hs := NewHashStore()
+	hs.store["foo"] = mockHash()
+
+	// existing keys are indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, hs.index.Keys())
+
+	// created keys are indexed
+	hs.HSet("bar", "f", "v")
+	assert.Equal(t, []string{"bar", "foo"}, hs.index.Keys())
+
+	// deleted keys are unindexed
+	hs.HDel("bar", "f")
+	assert.Equal(t, []string{"foo"}, hs.index.Keys())
+
+	// clear
+	hs.Clear()
+	assert.Equal(t, 0, hs.index.Len())
+}
+
+func TestHashScan(t *testing.T) {
+	hs := NewHashStore()
+
+	hs.store["MSFT:bid"] = mockHash()
+	hs.store["AAPL:bid"] = mockHash()
+	hs.store["AAPL:ask"] = mockHash()
+
+	keys, next := hs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = hs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = hs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, hs.index)
+
+	// no keys
+	hs.Clear()
+	keys, next = hs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestHashIsMember(t *testing.T) {
+	hs := NewHashStore()
+
+	// no keys
+	ok := hs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	hs.store["foo"] = mockHash()
+
+	ok = hs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestHashClear(t *testing.T) {
+	hs := NewHashStore()
+
+	hs.store["foo"] = mockHash()
+	assert.Equal(t, 1, len(hs.store))
+
+	hs.Clear()
+	assert.Equal(t, 0, len(hs.store))
+}
+
+func TestHashConcurrentHIncrBy(t *testing.T) {
+	hs := NewHashStore()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				hs.HIncrBy("foo", "n", 1)
+				hs.HGetAll("foo")
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, "400", hs.store["foo"]["n"])
+}
+
+func TestHashConcurrentGetAndSet(t *testing.T) {
+	hs := NewHashStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			hs.HMSet("foo", mockHash())
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			hs.HGet("foo", "lot")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
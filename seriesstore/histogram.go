@@ -0,0 +1,241 @@
+package seriesstore
+
+import (
+	"math"
+	"math/bits"
+)
+
+// HistogramBucket is a range of equivalent values (inclusive:inclusive) and the number of values recorded in it
+type HistogramBucket struct {
+	Low   int64
+	High  int64
+	Count int64
+}
+
+// HistogramSnapshot is a point in time copy of a Histogram
+// Buckets holds only the non empty buckets, in ascending value order
+type HistogramSnapshot struct {
+	Count   int64
+	Min     int64
+	Max     int64
+	Mean    float64
+	Buckets []HistogramBucket
+}
+
+// Histogram counts non negative integer values, e.g. latencies in nanoseconds,
+// in log-linear buckets like an HDR histogram
+// Values below 2^subBits are counted exactly, larger values share a bucket
+// with values within a relative error of 10^-sigFigs, so memory is constant for a given range and precision
+// Note: Histogram is NOT safe for concurrent use, see HistogramStore
+type Histogram struct {
+	highest int64
+	sigFigs int
+	subBits uint // bits of the linear sub buckets in every power of two
+	counts  []int64
+	total   int64
+	sum     float64
+	min     int64
+	max     int64
+}
+
+// NewHistogram constructs and initializes a new Histogram tracking values from 0 to highest
+// with sigFigs (1 to 5) significant decimal figures of precision
+// Always use this function when creating a new Histogram
+func NewHistogram(highest int64, sigFigs int) (*Histogram, error) {
+	if sigFigs < 1 || sigFigs > 5 {
+		return nil, ErrInvalidPrecision
+	}
+	if highest < 1 {
+		return nil, ErrValueOutOfRange
+	}
+
+	// half of the sub buckets must distinguish 10^sigFigs values
+	subBits := uint(bits.Len64(uint64(math.Pow10(sigFigs)))) + 1
+
+	h := &Histogram{highest: highest, sigFigs: sigFigs, subBits: subBits, min: math.MaxInt64}
+	h.counts = make([]int64, h.index(highest)+1)
+
+	return h, nil
+}
+
+// index returns the bucket index of value
+func (h *Histogram) index(value int64) int {
+	subCount := int64(1) << h.subBits
+	if value < subCount {
+		return int(value)
+	}
+
+	shift := uint(bits.Len64(uint64(value))) - h.subBits
+	half := subCount >> 1
+	mantissa := value >> shift
+
+	return int(subCount + int64(shift-1)*half + mantissa - half)
+}
+
+// bucketRange returns the lowest and highest values counted in the bucket at idx
+func (h *Histogram) bucketRange(idx int) (int64, int64) {
+	subCount := int64(1) << h.subBits
+	i := int64(idx)
+	if i < subCount {
+		return i, i
+	}
+
+	half := subCount >> 1
+	k := i - subCount
+	shift := uint(k/half) + 1
+	low := (half + k%half) << shift
+
+	return low, low + (int64(1) << shift) - 1
+}
+
+// Highest returns the highest trackable value
+func (h *Histogram) Highest() int64 {
+	return h.highest
+}
+
+// SigFigs returns the number of significant decimal figures of precision
+func (h *Histogram) SigFigs() int {
+	return h.sigFigs
+}
+
+// Record counts the value
+// returns ErrValueOutOfRange if value is negative or above the highest trackable value
+func (h *Histogram) Record(value int64) error {
+	return h.RecordN(value, 1)
+}
+
+// RecordN counts the value n times, e.g. to correct for coordinated omission
+// returns ErrValueOutOfRange if value is negative or above the highest trackable value
+func (h *Histogram) RecordN(value, n int64) error {
+	if value < 0 || value > h.highest {
+		return ErrValueOutOfRange
+	}
+	if n < 1 {
+		return nil
+	}
+
+	h.counts[h.index(value)] += n
+	h.total += n
+	h.sum += float64(value) * float64(n)
+	if value < h.min {
+		h.min = value
+	}
+	if value > h.max {
+		h.max = value
+	}
+
+	return nil
+}
+
+// Count returns the number of recorded values
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+// Min returns the exact lowest recorded value, 0 if empty
+func (h *Histogram) Min() int64 {
+	if h.total == 0 {
+		return 0
+	}
+
+	return h.min
+}
+
+// Max returns the exact highest recorded value, 0 if empty
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// Mean returns the exact mean of the recorded values, 0 if empty
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0.0
+	}
+
+	return h.sum / float64(h.total)
+}
+
+// Quantile returns the value at quantile q, e.g. 0.99 for the 99th percentile,
+// as the highest value equivalent to it within the histogram precision
+// Quantile 0 is the exact min and 1 the exact max, 0 if empty
+// returns ErrInvalidQuantile if q is not within 0 and 1
+func (h *Histogram) Quantile(q float64) (int64, error) {
+	if !(q >= 0 && q <= 1) {
+		return 0, ErrInvalidQuantile
+	}
+	if h.total == 0 {
+		return 0, nil
+	}
+	if q == 0 {
+		return h.min, nil
+	}
+
+	rank := int64(math.Ceil(q * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	var seen int64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			_, high := h.bucketRange(i)
+			if high > h.max {
+				high = h.max
+			}
+			return high, nil
+		}
+	}
+
+	return h.max, nil
+}
+
+// Merge adds every value recorded in other to the histogram
+// returns ErrIncompatibleHistogram if other has a different range or precision
+func (h *Histogram) Merge(other *Histogram) error {
+	if other.highest != h.highest || other.sigFigs != h.sigFigs {
+		return ErrIncompatibleHistogram
+	}
+	if other.total == 0 {
+		return nil
+	}
+
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	if other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+
+	return nil
+}
+
+// Reset empties the histogram, keeping its range and precision
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i] = 0
+	}
+	h.total = 0
+	h.sum = 0.0
+	h.min = math.MaxInt64
+	h.max = 0
+}
+
+// Snapshot returns a copy of the summary and non empty buckets of the histogram
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	snap := HistogramSnapshot{Count: h.total, Min: h.Min(), Max: h.max, Mean: h.Mean(), Buckets: make([]HistogramBucket, 0)}
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		low, high := h.bucketRange(i)
+		snap.Buckets = append(snap.Buckets, HistogramBucket{low, high, c})
+	}
+
+	return snap
+}
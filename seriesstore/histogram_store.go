@@ -0,0 +1,393 @@
+package seriesstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// HistogramStore is a store of latency histograms keyed by name, e.g. per endpoint
+// Every key is a Histogram of the store range and precision, using constant memory
+// Embedded sync.Mutex to provide atomic operation ability
+type HistogramStore struct {
+	sync.Mutex
+	highest int64
+	sigFigs int
+	store   map[string]*Histogram
+	index   *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewHistogramStore constructs and initializes a new HistogramStore whose histograms track values
+// from 0 to highest with sigFigs (1 to 5) significant decimal figures of precision
+// returns ErrInvalidPrecision or ErrValueOutOfRange for an invalid precision or range
+// Always use this function to init new HistogramStores
+func NewHistogramStore(highest int64, sigFigs int) (*HistogramStore, error) {
+	// validate once so later histograms can not fail
+	if _, err := NewHistogram(highest, sigFigs); err != nil {
+		return nil, err
+	}
+
+	return &HistogramStore{highest: highest, sigFigs: sigFigs, store: make(map[string]*Histogram)}, nil
+}
+
+func (s *HistogramStore) histogram(key string) *Histogram {
+	h, ok := s.store[key]
+	if !ok {
+		h, _ = NewHistogram(s.highest, s.sigFigs)
+		s.store[key] = h
+		s.index.Insert(key)
+	}
+
+	return h
+}
+
+func (s *HistogramStore) record(key string, value, n int64) error {
+	if value < 0 || value > s.highest {
+		return ErrValueOutOfRange
+	}
+
+	return s.histogram(key).RecordN(value, n)
+}
+
+// Record counts the value in the histogram of the given key, creating the key if it does not exist
+// returns ErrValueOutOfRange if value is negative or above the highest trackable value
+func (s *HistogramStore) Record(key string, value int64) error {
+	s.Lock()
+	err := s.record(key, value, 1)
+	s.Unlock()
+
+	return err
+}
+
+// RecordN counts the value n times in the histogram of the given key, creating the key if it does not exist
+// returns ErrValueOutOfRange if value is negative or above the highest trackable value
+func (s *HistogramStore) RecordN(key string, value, n int64) error {
+	s.Lock()
+	err := s.record(key, value, n)
+	s.Unlock()
+
+	return err
+}
+
+func (s *HistogramStore) quantile(key string, q float64) (int64, error) {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return h.Quantile(q)
+}
+
+// Quantile returns the value at quantile q of the histogram of the given key, e.g. 0.99 for p99
+// returns ErrInvalidQuantile if q is not within 0 and 1
+func (s *HistogramStore) Quantile(key string, q float64) (int64, error) {
+	s.Lock()
+	v, err := s.quantile(key, q)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *HistogramStore) aggregate(key string, agg func(h *Histogram) int64) (int64, error) {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return agg(h), nil
+}
+
+// Count returns the number of values recorded in the histogram of the given key
+func (s *HistogramStore) Count(key string) (int64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*Histogram).Count)
+	s.Unlock()
+
+	return v, err
+}
+
+// Min returns the exact lowest value recorded in the histogram of the given key
+func (s *HistogramStore) Min(key string) (int64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*Histogram).Min)
+	s.Unlock()
+
+	return v, err
+}
+
+// Max returns the exact highest value recorded in the histogram of the given key
+func (s *HistogramStore) Max(key string) (int64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*Histogram).Max)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *HistogramStore) mean(key string) (float64, error) {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0.0, ErrKeyDoesNotExist
+	}
+
+	return h.Mean(), nil
+}
+
+// Mean returns the exact mean of the values recorded in the histogram of the given key
+func (s *HistogramStore) Mean(key string) (float64, error) {
+	s.Lock()
+	v, err := s.mean(key)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *HistogramStore) merge(dst, src string) error {
+	h, ok := s.store[src]
+
+	// check exists
+	if !ok {
+		return ErrKeyDoesNotExist
+	}
+
+	// merging a histogram into itself would double count
+	if dst == src {
+		return nil
+	}
+
+	return s.histogram(dst).Merge(h)
+}
+
+// Merge adds every value recorded in the histogram of src to the histogram of dst,
+// creating dst if it does not exist, e.g. to roll per host histograms up into a total
+func (s *HistogramStore) Merge(dst, src string) error {
+	s.Lock()
+	err := s.merge(dst, src)
+	s.Unlock()
+
+	return err
+}
+
+func (s *HistogramStore) reset(key string) error {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return ErrKeyDoesNotExist
+	}
+
+	h.Reset()
+
+	return nil
+}
+
+// Reset empties the histogram of the given key, e.g. at the start of a reporting interval
+func (s *HistogramStore) Reset(key string) error {
+	s.Lock()
+	err := s.reset(key)
+	s.Unlock()
+
+	return err
+}
+
+func (s *HistogramStore) snapshot(key string) (HistogramSnapshot, error) {
+	h, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return HistogramSnapshot{}, ErrKeyDoesNotExist
+	}
+
+	return h.Snapshot(), nil
+}
+
+// Snapshot returns a copy of the summary and non empty buckets of the histogram of the given key for export
+func (s *HistogramStore) Snapshot(key string) (HistogramSnapshot, error) {
+	s.Lock()
+	v, err := s.snapshot(key)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *HistogramStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *HistogramStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *HistogramStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *HistogramStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *HistogramStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *HistogramStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *HistogramStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *HistogramStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *HistogramStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *HistogramStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *HistogramStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *HistogramStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *HistogramStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *HistogramStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *HistogramStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *HistogramStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *HistogramStore) clear() {
+	s.store = make(map[string]*Histogram)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *HistogramStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,311 @@
+package seriesstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockHistogramStore() *HistogramStore {
+	hs, _ := NewHistogramStore(1000000, 3)
+
+	return hs
+}
+
+func mockHistogram(values ...int64) *Histogram {
+	h, _ := NewHistogram(1000000, 3)
+	for _, v := range values {
+		h.Record(v)
+	}
+
+	return h
+}
+
+func TestNewHistogramStore(t *testing.T) {
+	_, err := NewHistogramStore(1000, 9)
+	assert.Equal(t, ErrInvalidPrecision, err)
+	_, err = NewHistogramStore(-1, 3)
+	assert.Equal(t, ErrValueOutOfRange, err)
+
+	hs, err := NewHistogramStore(1000, 3)
+	assert.Nil(t, err)
+	assert.NotNil(t, hs.store)
+}
+
+func TestHistogramStoreRecord(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// out of range does not create key
+	assert.Equal(t, ErrValueOutOfRange, hs.Record("api", 1000001))
+	assert.False(t, hs.isMember("api"))
+
+	assert.Nil(t, hs.Record("api", 10))
+	assert.Nil(t, hs.RecordN("api", 20, 3))
+	assert.Equal(t, int64(4), hs.store["api"].Count())
+}
+
+func TestHistogramStoreQuantile(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no key
+	_, err := hs.Quantile("api", 0.5)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["api"] = mockHistogram(1, 2, 3, 4)
+
+	v, err := hs.Quantile("api", 0.5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), v)
+
+	_, err = hs.Quantile("api", 2)
+	assert.Equal(t, ErrInvalidQuantile, err)
+}
+
+func TestHistogramStoreAggregations(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no key
+	_, err := hs.Count("api")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = hs.Min("api")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = hs.Max("api")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = hs.Mean("api")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["api"] = mockHistogram(10, 20, 60)
+
+	c, err := hs.Count("api")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), c)
+
+	min, err := hs.Min("api")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), min)
+
+	max, err := hs.Max("api")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), max)
+
+	mean, err := hs.Mean("api")
+	assert.Nil(t, err)
+	assert.Equal(t, 30.0, mean)
+}
+
+func TestHistogramStoreMerge(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no src
+	assert.Equal(t, ErrKeyDoesNotExist, hs.Merge("total", "api"))
+
+	hs.store["api"] = mockHistogram(10, 20)
+	hs.store["web"] = mockHistogram(30)
+
+	// dst created
+	assert.Nil(t, hs.Merge("total", "api"))
+	assert.Nil(t, hs.Merge("total", "web"))
+	assert.Equal(t, int64(3), hs.store["total"].Count())
+	assert.Equal(t, int64(30), hs.store["total"].Max())
+
+	// self merge is a noop
+	assert.Nil(t, hs.Merge("api", "api"))
+	assert.Equal(t, int64(2), hs.store["api"].Count())
+}
+
+func TestHistogramStoreReset(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no key
+	assert.Equal(t, ErrKeyDoesNotExist, hs.Reset("api"))
+
+	hs.store["api"] = mockHistogram(10, 20)
+	assert.Nil(t, hs.Reset("api"))
+	assert.True(t, hs.isMember("api"))
+	assert.Equal(t, int64(0), hs.store["api"].Count())
+}
+
+func TestHistogramStoreSnapshot(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no key
+	_, err := hs.Snapshot("api")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	hs.store["api"] = mockHistogram(10, 10, 20)
+
+	snap, err := hs.Snapshot("api")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), snap.Count)
+	assert.Equal(t, []HistogramBucket{{10, 10, 2}, {20, 20, 1}}, snap.Buckets)
+}
+
+func TestHistogramStoreSize(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no keys
+	size := hs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	hs.store["a"] = mockHistogram()
+	hs.store["b"] = mockHistogram()
+
+	size = hs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestHistogramStoreMembers(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no keys
+	mems := hs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	hs.store["a"] = mockHistogram()
+	hs.store["b"] = mockHistogram()
+
+	mems = hs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestHistogramStoreSortedMembers(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no keys
+	assert.Equal(t, []string{}, hs.SortedMembers())
+
+	hs.store["MSFT:bid"] = mockHistogram()
+	hs.store["AAPL:bid"] = mockHistogram()
+	hs.store["AAPL:ask"] = mockHistogram()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, hs.SortedMembers())
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, hs.SortedMembers())
+}
+
+func TestHistogramStoreMembersWithPrefix(t *testing.T) {
+	hs := mockHistogramStore()
+
+	hs.store["MSFT:bid"] = mockHistogram()
+	hs.store["AAPL:bid"] = mockHistogram()
+	hs.store["AAPL:ask"] = mockHistogram()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, hs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, hs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, hs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, hs.MembersWithPrefix("GOOG"))
+}
+
+func TestHistogramStoreMembersMatching(t *testing.T) {
+	hs := mockHistogramStore()
+
+	hs.store["MSFT:bid"] = mockHistogram()
+	hs.store["AAPL:bid"] = mockHistogram()
+	hs.store["AAPL:ask"] = mockHistogram()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, hs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, hs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, hs.MembersMatching("GOOG:*"))
+
+	// indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, hs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, hs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, hs.MembersMatching("GOOG:*"))
+}
+
+func TestHistogramStoreKeyIndex(t *testing.T) {
+	hs := mockHistogramStore()
+	hs.store["foo"] = mockHistogram()
+
+	// existing keys are indexed
+	hs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, hs.index.Keys())
+
+	// created keys are indexed
+	hs.Record("bar", 1)
+	assert.Equal(t, []string{"bar", "foo"}, hs.index.Keys())
+
+	// clear
+	hs.Clear()
+	assert.Equal(t, 0, hs.index.Len())
+}
+
+func TestHistogramStoreScan(t *testing.T) {
+	hs := mockHistogramStore()
+
+	hs.store["MSFT:bid"] = mockHistogram()
+	hs.store["AAPL:bid"] = mockHistogram()
+	hs.store["AAPL:ask"] = mockHistogram()
+
+	keys, next := hs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = hs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = hs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, hs.index)
+
+	// no keys
+	hs.Clear()
+	keys, next = hs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestHistogramStoreIsMember(t *testing.T) {
+	hs := mockHistogramStore()
+
+	// no keys
+	ok := hs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	hs.store["foo"] = mockHistogram()
+
+	ok = hs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestHistogramStoreClear(t *testing.T) {
+	hs := mockHistogramStore()
+
+	hs.store["foo"] = mockHistogram()
+	assert.Equal(t, 1, len(hs.store))
+
+	hs.Clear()
+	assert.Equal(t, 0, len(hs.store))
+}
+
+func TestHistogramStoreConcurrentRecordAndQuantile(t *testing.T) {
+	hs := mockHistogramStore()
+
+	go func() {
+		for i := int64(0); i < 100; i++ {
+			hs.Record("foo", i)
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			hs.Quantile("foo", 0.99)
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
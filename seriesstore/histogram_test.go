@@ -0,0 +1,161 @@
+package seriesstore
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHistogram(t *testing.T) {
+	// invalid
+	_, err := NewHistogram(1000, 0)
+	assert.Equal(t, ErrInvalidPrecision, err)
+	_, err = NewHistogram(1000, 6)
+	assert.Equal(t, ErrInvalidPrecision, err)
+	_, err = NewHistogram(0, 3)
+	assert.Equal(t, ErrValueOutOfRange, err)
+
+	h, err := NewHistogram(3600e9, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3600e9), h.Highest())
+	assert.Equal(t, 3, h.SigFigs())
+	assert.Equal(t, uint(11), h.subBits)
+}
+
+func TestHistogramIndex(t *testing.T) {
+	h, _ := NewHistogram(math.MaxInt64, 2)
+
+	// every value falls within its bucket, and buckets are contiguous
+	prevHigh := int64(-1)
+	for i := 0; i < len(h.counts); i++ {
+		low, high := h.bucketRange(i)
+		assert.Equal(t, prevHigh+1, low)
+		assert.Equal(t, i, h.index(low))
+		assert.Equal(t, i, h.index(high))
+		prevHigh = high
+	}
+	assert.Equal(t, int64(math.MaxInt64), prevHigh)
+}
+
+func TestHistogramRelativeError(t *testing.T) {
+	for sigFigs := 1; sigFigs <= 5; sigFigs++ {
+		h, _ := NewHistogram(1e12, sigFigs)
+		for _, v := range []int64{1, 999, 12345, 987654321, 1e12} {
+			low, high := h.bucketRange(h.index(v))
+			assert.True(t, float64(high-low)/float64(v) <= math.Pow10(-sigFigs), "sigFigs %d value %d", sigFigs, v)
+		}
+	}
+}
+
+func TestHistogramRecord(t *testing.T) {
+	h, _ := NewHistogram(1000000, 3)
+
+	// out of range
+	assert.Equal(t, ErrValueOutOfRange, h.Record(-1))
+	assert.Equal(t, ErrValueOutOfRange, h.Record(1000001))
+
+	// empty
+	assert.Equal(t, int64(0), h.Count())
+	assert.Equal(t, int64(0), h.Min())
+	assert.Equal(t, int64(0), h.Max())
+	assert.Equal(t, 0.0, h.Mean())
+
+	assert.Nil(t, h.Record(10))
+	assert.Nil(t, h.Record(30))
+	assert.Nil(t, h.RecordN(500000, 2))
+
+	// n < 1 is a noop
+	assert.Nil(t, h.RecordN(7, 0))
+
+	assert.Equal(t, int64(4), h.Count())
+	assert.Equal(t, int64(10), h.Min())
+	assert.Equal(t, int64(500000), h.Max())
+	assert.Equal(t, 250010.0, h.Mean())
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	h, _ := NewHistogram(3600e9, 3)
+
+	// empty
+	v, err := h.Quantile(0.5)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), v)
+
+	// invalid
+	_, err = h.Quantile(-0.1)
+	assert.Equal(t, ErrInvalidQuantile, err)
+	_, err = h.Quantile(1.1)
+	assert.Equal(t, ErrInvalidQuantile, err)
+	_, err = h.Quantile(math.NaN())
+	assert.Equal(t, ErrInvalidQuantile, err)
+
+	// 1ms to 100ms in ns
+	for i := int64(1); i <= 100; i++ {
+		h.Record(i * 1e6)
+	}
+
+	v, _ = h.Quantile(0)
+	assert.Equal(t, int64(1e6), v)
+	v, _ = h.Quantile(1)
+	assert.Equal(t, int64(100e6), v)
+
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		v, err = h.Quantile(q)
+		assert.Nil(t, err)
+		want := q * 100e6
+		assert.InDelta(t, want, float64(v), want*1e-3)
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a, _ := NewHistogram(1000, 2)
+	b, _ := NewHistogram(1000, 2)
+	c, _ := NewHistogram(1000, 3)
+
+	assert.Equal(t, ErrIncompatibleHistogram, a.Merge(c))
+
+	a.Record(100)
+	b.Record(5)
+	b.Record(900)
+
+	assert.Nil(t, a.Merge(b))
+	assert.Equal(t, int64(3), a.Count())
+	assert.Equal(t, int64(5), a.Min())
+	assert.Equal(t, int64(900), a.Max())
+	assert.Equal(t, 335.0, a.Mean())
+
+	// source untouched
+	assert.Equal(t, int64(2), b.Count())
+}
+
+func TestHistogramReset(t *testing.T) {
+	h, _ := NewHistogram(1000, 2)
+	h.Record(5)
+	h.Record(500)
+
+	h.Reset()
+	assert.Equal(t, int64(0), h.Count())
+	assert.Equal(t, int64(0), h.Min())
+	assert.Equal(t, int64(0), h.Max())
+	assert.Equal(t, 0, len(h.Snapshot().Buckets))
+
+	h.Record(7)
+	assert.Equal(t, int64(7), h.Min())
+}
+
+func TestHistogramSnapshot(t *testing.T) {
+	h, _ := NewHistogram(100000, 1)
+	h.Record(3)
+	h.Record(3)
+	h.Record(1000)
+
+	snap := h.Snapshot()
+	assert.Equal(t, int64(3), snap.Count)
+	assert.Equal(t, int64(3), snap.Min)
+	assert.Equal(t, int64(1000), snap.Max)
+	assert.Equal(t, 2, len(snap.Buckets))
+	assert.Equal(t, HistogramBucket{3, 3, 2}, snap.Buckets[0])
+	assert.True(t, snap.Buckets[1].Low <= 1000 && snap.Buckets[1].High >= 1000)
+	assert.Equal(t, int64(1), snap.Buckets[1].Count)
+}
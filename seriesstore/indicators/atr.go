@@ -0,0 +1,90 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// ATR is a streaming average true range using Wilder's smoothing
+type ATR struct {
+	period    int
+	count     int
+	prevClose float64
+	value     float64
+}
+
+// NewATR constructs a new ATR over the given period
+func NewATR(period int) (*ATR, error) {
+	if period < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	return &ATR{period: period}, nil
+}
+
+// trueRange returns the greatest of the bar range and the gaps from the previous close
+func trueRange(bar seriesstore.OHLC, prevClose float64, first bool) float64 {
+	high, low := float64(bar.High), float64(bar.Low)
+	if first {
+		return high - low
+	}
+
+	return math.Max(high-low, math.Max(math.Abs(high-prevClose), math.Abs(low-prevClose)))
+}
+
+// Update adds the next bar and returns the current average true range, NaN until ready
+func (i *ATR) Update(bar seriesstore.OHLC) float64 {
+	tr := trueRange(bar, i.prevClose, i.count == 0)
+	i.prevClose = float64(bar.Close)
+	i.count++
+
+	n := float64(i.period)
+	if i.count <= i.period {
+		// simple average of the first period true ranges
+		i.value += tr / n
+	} else {
+		i.value = (i.value*(n-1) + tr) / n
+	}
+
+	return i.Value()
+}
+
+// Value returns the current average true range, NaN until ready
+func (i *ATR) Value() float64 {
+	if !i.Ready() {
+		return math.NaN()
+	}
+
+	return i.value
+}
+
+// Ready checks if a full period of bars has been seen
+func (i *ATR) Ready() bool {
+	return i.count >= i.period
+}
+
+// ATRBatch computes the average true range of bars over the given period
+func ATRBatch(bars []seriesstore.OHLC, period int) ([]float64, error) {
+	ind, err := NewATR(period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(bars))
+	for j, b := range bars {
+		out[j] = ind.Update(b)
+	}
+
+	return out, nil
+}
+
+// ATRRange computes the average true range over the range [lower:upper) of the bars stored at key
+func ATRRange(s *seriesstore.OHLCSStore, key string, lower, upper, period int) ([]float64, error) {
+	bars, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return ATRBatch(bars, period)
+}
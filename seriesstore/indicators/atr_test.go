@@ -0,0 +1,65 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockBars() []seriesstore.OHLC {
+	return []seriesstore.OHLC{
+		{Open: 10, High: 12, Low: 9, Close: 11},
+		{Open: 11, High: 14, Low: 11, Close: 13},
+		{Open: 13, High: 13, Low: 10, Close: 10},
+		{Open: 10, High: 11, Low: 7, Close: 8},
+		{Open: 8, High: 12, Low: 8, Close: 12},
+	}
+}
+
+func TestNewATR(t *testing.T) {
+	_, err := NewATR(0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewATR(14)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+}
+
+func TestATRUpdate(t *testing.T) {
+	ind, _ := NewATR(2)
+	bars := mockBars()
+
+	// true ranges are 3, 3, 3, 4, 4
+	assert.True(t, math.IsNaN(ind.Update(bars[0])))
+	assert.Equal(t, 3.0, ind.Update(bars[1]))
+	assert.True(t, ind.Ready())
+	assert.Equal(t, 3.0, ind.Update(bars[2]))
+	assert.Equal(t, 3.5, ind.Update(bars[3]))
+	assert.Equal(t, 3.75, ind.Update(bars[4]))
+}
+
+func TestATRBatch(t *testing.T) {
+	_, err := ATRBatch(mockBars(), 0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	out, err := ATRBatch(mockBars(), 3)
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(out[1]))
+	assert.Equal(t, 3.0, out[2])
+	assert.InDelta(t, 10.0/3.0, out[3], 1e-12)
+}
+
+func TestATRRange(t *testing.T) {
+	ss := seriesstore.NewOHLCSStore()
+
+	// no key
+	_, err := ATRRange(ss, "foo", 0, 5, 2)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockBars())
+	out, err := ATRRange(ss, "foo", 0, 5, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 3.75, out[4])
+}
@@ -0,0 +1,80 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// BollingerValue is a single Bollinger Bands reading
+type BollingerValue struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+}
+
+// Bollinger is streaming Bollinger Bands
+// The middle band is the simple moving average, the outer bands are offset
+// by k population standard deviations of the same window
+type Bollinger struct {
+	k      float64
+	window *seriesstore.RollingWindow
+}
+
+// NewBollinger constructs new Bollinger Bands over the given period and width k, typically 20 and 2
+func NewBollinger(period int, k float64) (*Bollinger, error) {
+	if period < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	return &Bollinger{k: k, window: seriesstore.NewRollingWindow(period)}, nil
+}
+
+// Update adds the next value and returns the current bands, NaN until ready
+func (i *Bollinger) Update(value float64) BollingerValue {
+	i.window.Push(value)
+
+	return i.Value()
+}
+
+// Value returns the current bands, NaN until ready
+func (i *Bollinger) Value() BollingerValue {
+	if !i.Ready() {
+		return BollingerValue{math.NaN(), math.NaN(), math.NaN()}
+	}
+
+	mid := i.window.Mean()
+	width := i.k * i.window.StdDev()
+
+	return BollingerValue{Upper: mid + width, Middle: mid, Lower: mid - width}
+}
+
+// Ready checks if a full period of values has been seen
+func (i *Bollinger) Ready() bool {
+	return i.window.Full()
+}
+
+// BollingerBatch computes the Bollinger Bands of values
+func BollingerBatch(values []float64, period int, k float64) ([]BollingerValue, error) {
+	ind, err := NewBollinger(period, k)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]BollingerValue, len(values))
+	for j, v := range values {
+		out[j] = ind.Update(v)
+	}
+
+	return out, nil
+}
+
+// BollingerRange computes the Bollinger Bands over the range [lower:upper) of the series stored at key
+func BollingerRange(s *seriesstore.Float64SStore, key string, lower, upper, period int, k float64) ([]BollingerValue, error) {
+	values, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return BollingerBatch(values, period, k)
+}
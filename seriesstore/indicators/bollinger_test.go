@@ -0,0 +1,58 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBollinger(t *testing.T) {
+	_, err := NewBollinger(0, 2.0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewBollinger(20, 2.0)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+	assert.True(t, math.IsNaN(ind.Value().Middle))
+}
+
+func TestBollingerUpdate(t *testing.T) {
+	ind, _ := NewBollinger(8, 2.0)
+
+	var v BollingerValue
+	for _, x := range []float64{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0} {
+		v = ind.Update(x)
+	}
+
+	// mean 5, population std dev 2
+	assert.True(t, ind.Ready())
+	assert.InDelta(t, 5.0, v.Middle, 1e-12)
+	assert.InDelta(t, 9.0, v.Upper, 1e-12)
+	assert.InDelta(t, 1.0, v.Lower, 1e-12)
+}
+
+func TestBollingerBatch(t *testing.T) {
+	_, err := BollingerBatch(mockSeries(), 0, 2.0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	out, err := BollingerBatch([]float64{3.0, 3.0, 3.0}, 2, 2.0)
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(out[0].Upper))
+	assert.Equal(t, BollingerValue{3.0, 3.0, 3.0}, out[2])
+}
+
+func TestBollingerRange(t *testing.T) {
+	ss := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := BollingerRange(ss, "foo", 0, 5, 3, 2.0)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockSeries())
+	out, err := BollingerRange(ss, "foo", 0, 5, 5, 1.0)
+	assert.Nil(t, err)
+	assert.InDelta(t, 3.0, out[4].Middle, 1e-12)
+	assert.InDelta(t, 3.0+math.Sqrt(2.0), out[4].Upper, 1e-12)
+}
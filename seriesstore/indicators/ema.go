@@ -0,0 +1,81 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// EMA is a streaming exponential moving average
+// The average is seeded with the simple average of the first period values
+type EMA struct {
+	period int
+	alpha  float64
+	count  int
+	sum    float64
+	value  float64
+}
+
+// NewEMA constructs a new EMA over the given period, with smoothing factor 2/(period+1)
+func NewEMA(period int) (*EMA, error) {
+	if period < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	return &EMA{period: period, alpha: 2.0 / float64(period+1)}, nil
+}
+
+// Update adds the next value and returns the current average, NaN until ready
+func (i *EMA) Update(value float64) float64 {
+	i.count++
+	switch {
+	case i.count < i.period:
+		i.sum += value
+	case i.count == i.period:
+		i.sum += value
+		i.value = i.sum / float64(i.period)
+	default:
+		i.value += i.alpha * (value - i.value)
+	}
+
+	return i.Value()
+}
+
+// Value returns the current average, NaN until ready
+func (i *EMA) Value() float64 {
+	if !i.Ready() {
+		return math.NaN()
+	}
+
+	return i.value
+}
+
+// Ready checks if a full period of values has been seen
+func (i *EMA) Ready() bool {
+	return i.count >= i.period
+}
+
+// EMABatch computes the exponential moving average of values over the given period
+func EMABatch(values []float64, period int) ([]float64, error) {
+	ind, err := NewEMA(period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(values))
+	for j, v := range values {
+		out[j] = ind.Update(v)
+	}
+
+	return out, nil
+}
+
+// EMARange computes the exponential moving average over the range [lower:upper) of the series stored at key
+func EMARange(s *seriesstore.Float64SStore, key string, lower, upper, period int) ([]float64, error) {
+	values, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return EMABatch(values, period)
+}
@@ -0,0 +1,56 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEMA(t *testing.T) {
+	_, err := NewEMA(0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewEMA(3)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, ind.alpha)
+	assert.False(t, ind.Ready())
+}
+
+func TestEMAUpdate(t *testing.T) {
+	ind, _ := NewEMA(3)
+
+	assert.True(t, math.IsNaN(ind.Update(1.0)))
+	assert.True(t, math.IsNaN(ind.Update(2.0)))
+
+	// seeded with the simple average
+	assert.Equal(t, 2.0, ind.Update(3.0))
+	assert.True(t, ind.Ready())
+
+	assert.Equal(t, 3.0, ind.Update(4.0))
+	assert.Equal(t, 2.0, ind.Update(1.0))
+}
+
+func TestEMABatch(t *testing.T) {
+	_, err := EMABatch(mockSeries(), 0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	out, err := EMABatch(mockSeries(), 3)
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(out[1]))
+	assert.Equal(t, []float64{2.0, 3.0, 4.0}, out[2:])
+}
+
+func TestEMARange(t *testing.T) {
+	ss := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := EMARange(ss, "foo", 0, 5, 3)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockSeries())
+	out, err := EMARange(ss, "foo", 0, 5, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 4.0, out[4])
+}
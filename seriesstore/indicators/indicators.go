@@ -0,0 +1,18 @@
+// Package indicators computes technical indicators over seriesstore data
+//
+// Every indicator is available as a streaming type, updated incrementally with each new value or bar,
+// and as a batch function over a slice or over a range of a stored series.
+// Batch results are aligned with their input, positions before the indicator is ready hold NaN.
+// Streaming types are NOT safe for concurrent use.
+package indicators
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidPeriod is thrown when an indicator period is not positive
+	ErrInvalidPeriod = errors.New("period must be positive")
+	// ErrLengthMismatch is thrown when paired input series differ in length
+	ErrLengthMismatch = errors.New("input series lengths differ")
+)
@@ -0,0 +1,88 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// MACDValue is a single moving average convergence divergence reading
+type MACDValue struct {
+	MACD      float64
+	Signal    float64
+	Histogram float64
+}
+
+// MACD is a streaming moving average convergence divergence
+// MACD is the fast EMA minus the slow EMA, Signal is the EMA of MACD
+// and Histogram is MACD minus Signal
+type MACD struct {
+	fast   *EMA
+	slow   *EMA
+	signal *EMA
+	macd   float64
+}
+
+// NewMACD constructs a new MACD with the given fast, slow and signal periods, typically 12, 26 and 9
+func NewMACD(fast, slow, signal int) (*MACD, error) {
+	if fast < 1 || slow < 1 || signal < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	f, _ := NewEMA(fast)
+	s, _ := NewEMA(slow)
+	sig, _ := NewEMA(signal)
+
+	return &MACD{fast: f, slow: s, signal: sig, macd: math.NaN()}, nil
+}
+
+// Update adds the next value and returns the current reading
+// Fields are NaN until their averages are ready
+func (i *MACD) Update(value float64) MACDValue {
+	f := i.fast.Update(value)
+	s := i.slow.Update(value)
+
+	if i.fast.Ready() && i.slow.Ready() {
+		i.macd = f - s
+		i.signal.Update(i.macd)
+	}
+
+	return i.Value()
+}
+
+// Value returns the current reading, fields are NaN until their averages are ready
+func (i *MACD) Value() MACDValue {
+	sig := i.signal.Value()
+
+	return MACDValue{MACD: i.macd, Signal: sig, Histogram: i.macd - sig}
+}
+
+// Ready checks if the signal line is ready
+func (i *MACD) Ready() bool {
+	return i.signal.Ready()
+}
+
+// MACDBatch computes the MACD, signal and histogram series of values
+func MACDBatch(values []float64, fast, slow, signal int) ([]MACDValue, error) {
+	ind, err := NewMACD(fast, slow, signal)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MACDValue, len(values))
+	for j, v := range values {
+		out[j] = ind.Update(v)
+	}
+
+	return out, nil
+}
+
+// MACDRange computes the MACD over the range [lower:upper) of the series stored at key
+func MACDRange(s *seriesstore.Float64SStore, key string, lower, upper, fast, slow, signal int) ([]MACDValue, error) {
+	values, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return MACDBatch(values, fast, slow, signal)
+}
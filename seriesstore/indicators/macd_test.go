@@ -0,0 +1,70 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockTrend(n int) []float64 {
+	values := make([]float64, n)
+	for j := range values {
+		values[j] = 100.0 + 10.0*math.Sin(float64(j)/5.0) + float64(j)*0.5
+	}
+
+	return values
+}
+
+func TestNewMACD(t *testing.T) {
+	_, err := NewMACD(12, 0, 9)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewMACD(12, 26, 9)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+	assert.True(t, math.IsNaN(ind.Value().MACD))
+}
+
+func TestMACDBatch(t *testing.T) {
+	_, err := MACDBatch(mockSeries(), 0, 26, 9)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	values := mockTrend(60)
+	out, err := MACDBatch(values, 3, 6, 4)
+	assert.Nil(t, err)
+
+	fast, _ := EMABatch(values, 3)
+	slow, _ := EMABatch(values, 6)
+
+	// macd line ready with the slow average
+	assert.True(t, math.IsNaN(out[4].MACD))
+	macd := make([]float64, 0)
+	for j := 5; j < len(values); j++ {
+		assert.InDelta(t, fast[j]-slow[j], out[j].MACD, 1e-9)
+		macd = append(macd, out[j].MACD)
+	}
+
+	// signal is the average of the macd line
+	signal, _ := EMABatch(macd, 4)
+	assert.True(t, math.IsNaN(out[7].Signal))
+	for j := 8; j < len(values); j++ {
+		assert.InDelta(t, signal[j-5], out[j].Signal, 1e-9)
+		assert.InDelta(t, out[j].MACD-out[j].Signal, out[j].Histogram, 1e-9)
+	}
+}
+
+func TestMACDRange(t *testing.T) {
+	ss := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := MACDRange(ss, "foo", 0, 5, 12, 26, 9)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockTrend(40))
+	out, err := MACDRange(ss, "foo", 0, 40, 12, 26, 9)
+	assert.Nil(t, err)
+	assert.Equal(t, 40, len(out))
+	assert.False(t, math.IsNaN(out[33].Signal))
+}
@@ -0,0 +1,105 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// RSI is a streaming relative strength index using Wilder's smoothing
+// Values range from 0 to 100
+type RSI struct {
+	period  int
+	count   int // number of changes seen
+	prev    float64
+	avgGain float64
+	avgLoss float64
+}
+
+// NewRSI constructs a new RSI over the given period
+func NewRSI(period int) (*RSI, error) {
+	if period < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	return &RSI{period: period, count: -1}, nil
+}
+
+// Update adds the next value and returns the current index, NaN until ready
+func (i *RSI) Update(value float64) float64 {
+	i.count++
+	if i.count == 0 {
+		i.prev = value
+		return math.NaN()
+	}
+
+	change := value - i.prev
+	i.prev = value
+
+	gain, loss := 0.0, 0.0
+	if change > 0 {
+		gain = change
+	} else {
+		loss = -change
+	}
+
+	n := float64(i.period)
+	if i.count <= i.period {
+		// simple average of the first period changes
+		i.avgGain += gain / n
+		i.avgLoss += loss / n
+	} else {
+		i.avgGain = (i.avgGain*(n-1) + gain) / n
+		i.avgLoss = (i.avgLoss*(n-1) + loss) / n
+	}
+
+	return i.Value()
+}
+
+// Value returns the current index, NaN until ready
+func (i *RSI) Value() float64 {
+	if !i.Ready() {
+		return math.NaN()
+	}
+
+	if i.avgLoss == 0 {
+		if i.avgGain == 0 {
+			// no movement at all
+			return 50.0
+		}
+
+		return 100.0
+	}
+
+	return 100.0 - 100.0/(1.0+i.avgGain/i.avgLoss)
+}
+
+// Ready checks if period changes, i.e. period+1 values, have been seen
+func (i *RSI) Ready() bool {
+	return i.count >= i.period
+}
+
+// RSIBatch computes the relative strength index of values over the given period
+func RSIBatch(values []float64, period int) ([]float64, error) {
+	ind, err := NewRSI(period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(values))
+	for j, v := range values {
+		out[j] = ind.Update(v)
+	}
+
+	return out, nil
+}
+
+// RSIRange computes the relative strength index over the range [lower:upper) of the series stored at key
+func RSIRange(s *seriesstore.Float64SStore, key string, lower, upper, period int) ([]float64, error) {
+	values, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return RSIBatch(values, period)
+}
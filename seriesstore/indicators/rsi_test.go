@@ -0,0 +1,68 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRSI(t *testing.T) {
+	_, err := NewRSI(0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewRSI(14)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+}
+
+func TestRSIUpdate(t *testing.T) {
+	ind, _ := NewRSI(2)
+
+	// needs period+1 values
+	assert.True(t, math.IsNaN(ind.Update(1.0)))
+	assert.True(t, math.IsNaN(ind.Update(2.0)))
+
+	// only gains
+	assert.Equal(t, 100.0, ind.Update(3.0))
+	assert.True(t, ind.Ready())
+
+	// wilder smoothing, avg gain 0.5 and avg loss 0.5
+	assert.Equal(t, 50.0, ind.Update(2.0))
+
+	// avg gain 0.25 and avg loss 1.25
+	assert.InDelta(t, 100.0-100.0/(1.0+0.2), ind.Update(0.0), 1e-12)
+}
+
+func TestRSIFlat(t *testing.T) {
+	ind, _ := NewRSI(3)
+
+	for j := 0; j < 5; j++ {
+		ind.Update(10.0)
+	}
+	assert.Equal(t, 50.0, ind.Value())
+}
+
+func TestRSIBatch(t *testing.T) {
+	_, err := RSIBatch(mockSeries(), 0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	out, err := RSIBatch([]float64{5.0, 4.0, 3.0, 2.0}, 2)
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(out[1]))
+	assert.Equal(t, []float64{0.0, 0.0}, out[2:])
+}
+
+func TestRSIRange(t *testing.T) {
+	ss := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := RSIRange(ss, "foo", 0, 5, 2)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockSeries())
+	out, err := RSIRange(ss, "foo", 0, 5, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 100.0, out[4])
+}
@@ -0,0 +1,67 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// SMA is a streaming simple moving average
+type SMA struct {
+	window *seriesstore.RollingWindow
+}
+
+// NewSMA constructs a new SMA over the given period
+func NewSMA(period int) (*SMA, error) {
+	if period < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	return &SMA{window: seriesstore.NewRollingWindow(period)}, nil
+}
+
+// Update adds the next value and returns the current average, NaN until ready
+func (i *SMA) Update(value float64) float64 {
+	i.window.Push(value)
+
+	return i.Value()
+}
+
+// Value returns the current average, NaN until ready
+func (i *SMA) Value() float64 {
+	if !i.Ready() {
+		return math.NaN()
+	}
+
+	return i.window.Mean()
+}
+
+// Ready checks if a full period of values has been seen
+func (i *SMA) Ready() bool {
+	return i.window.Full()
+}
+
+// SMABatch computes the simple moving average of values over the given period
+func SMABatch(values []float64, period int) ([]float64, error) {
+	ind, err := NewSMA(period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(values))
+	for j, v := range values {
+		out[j] = ind.Update(v)
+	}
+
+	return out, nil
+}
+
+// SMARange computes the simple moving average over the range [lower:upper) of the series stored at key
+func SMARange(s *seriesstore.Float64SStore, key string, lower, upper, period int) ([]float64, error) {
+	values, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return SMABatch(values, period)
+}
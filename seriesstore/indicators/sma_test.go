@@ -0,0 +1,63 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSeries() []float64 {
+	return []float64{1.0, 2.0, 3.0, 4.0, 5.0}
+}
+
+func TestNewSMA(t *testing.T) {
+	_, err := NewSMA(0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewSMA(3)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+	assert.True(t, math.IsNaN(ind.Value()))
+}
+
+func TestSMAUpdate(t *testing.T) {
+	ind, _ := NewSMA(3)
+
+	assert.True(t, math.IsNaN(ind.Update(1.0)))
+	assert.True(t, math.IsNaN(ind.Update(2.0)))
+	assert.Equal(t, 2.0, ind.Update(3.0))
+	assert.True(t, ind.Ready())
+	assert.Equal(t, 3.0, ind.Update(4.0))
+}
+
+func TestSMABatch(t *testing.T) {
+	_, err := SMABatch(mockSeries(), -1)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	out, err := SMABatch(mockSeries(), 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(out))
+	assert.True(t, math.IsNaN(out[0]))
+	assert.True(t, math.IsNaN(out[1]))
+	assert.Equal(t, []float64{2.0, 3.0, 4.0}, out[2:])
+}
+
+func TestSMARange(t *testing.T) {
+	ss := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := SMARange(ss, "foo", 0, 5, 3)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockSeries())
+
+	// out of bounds
+	_, err = SMARange(ss, "foo", 0, 10, 3)
+	assert.Equal(t, seriesstore.ErrIdxOutOfBounds, err)
+
+	out, err := SMARange(ss, "foo", 1, 5, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{2.5, 3.5, 4.5}, out[1:])
+}
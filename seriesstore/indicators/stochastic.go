@@ -0,0 +1,93 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// StochasticValue is a single stochastic oscillator reading
+type StochasticValue struct {
+	K float64
+	D float64
+}
+
+// Stochastic is a streaming stochastic oscillator
+// %K is the position of the close within the highest high and lowest low of the last kPeriod bars,
+// from 0 to 100, and %D is the simple moving average of %K over dPeriod
+// %K is 50 when the range is flat
+type Stochastic struct {
+	highs *seriesstore.RollingWindow
+	lows  *seriesstore.RollingWindow
+	d     *SMA
+	k     float64
+}
+
+// NewStochastic constructs a new Stochastic with the given %K and %D periods, typically 14 and 3
+func NewStochastic(kPeriod, dPeriod int) (*Stochastic, error) {
+	if kPeriod < 1 || dPeriod < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	d, _ := NewSMA(dPeriod)
+
+	return &Stochastic{
+		highs: seriesstore.NewRollingWindow(kPeriod),
+		lows:  seriesstore.NewRollingWindow(kPeriod),
+		d:     d,
+		k:     math.NaN(),
+	}, nil
+}
+
+// Update adds the next bar and returns the current reading, fields are NaN until ready
+func (i *Stochastic) Update(bar seriesstore.OHLC) StochasticValue {
+	i.highs.Push(float64(bar.High))
+	i.lows.Push(float64(bar.Low))
+
+	if i.highs.Full() {
+		hh, ll := i.highs.Max(), i.lows.Min()
+		if hh == ll {
+			i.k = 50.0
+		} else {
+			i.k = 100.0 * (float64(bar.Close) - ll) / (hh - ll)
+		}
+		i.d.Update(i.k)
+	}
+
+	return i.Value()
+}
+
+// Value returns the current reading, fields are NaN until ready
+func (i *Stochastic) Value() StochasticValue {
+	return StochasticValue{K: i.k, D: i.d.Value()}
+}
+
+// Ready checks if %D is ready
+func (i *Stochastic) Ready() bool {
+	return i.d.Ready()
+}
+
+// StochasticBatch computes the stochastic oscillator of bars
+func StochasticBatch(bars []seriesstore.OHLC, kPeriod, dPeriod int) ([]StochasticValue, error) {
+	ind, err := NewStochastic(kPeriod, dPeriod)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]StochasticValue, len(bars))
+	for j, b := range bars {
+		out[j] = ind.Update(b)
+	}
+
+	return out, nil
+}
+
+// StochasticRange computes the stochastic oscillator over the range [lower:upper) of the bars stored at key
+func StochasticRange(s *seriesstore.OHLCSStore, key string, lower, upper, kPeriod, dPeriod int) ([]StochasticValue, error) {
+	bars, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return StochasticBatch(bars, kPeriod, dPeriod)
+}
@@ -0,0 +1,73 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStochastic(t *testing.T) {
+	_, err := NewStochastic(14, 0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewStochastic(14, 3)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+	assert.True(t, math.IsNaN(ind.Value().K))
+}
+
+func TestStochasticUpdate(t *testing.T) {
+	ind, _ := NewStochastic(3, 2)
+	bars := mockBars()
+
+	v := ind.Update(bars[0])
+	assert.True(t, math.IsNaN(v.K))
+	ind.Update(bars[1])
+
+	// highest high 14, lowest low 9, close 10
+	v = ind.Update(bars[2])
+	assert.Equal(t, 20.0, v.K)
+	assert.True(t, math.IsNaN(v.D))
+
+	// highest high 14, lowest low 7, close 8
+	v = ind.Update(bars[3])
+	assert.InDelta(t, 100.0/7.0, v.K, 1e-12)
+	assert.InDelta(t, (20.0+100.0/7.0)/2.0, v.D, 1e-12)
+	assert.True(t, ind.Ready())
+}
+
+func TestStochasticFlat(t *testing.T) {
+	ind, _ := NewStochastic(2, 1)
+	bar := seriesstore.OHLC{Open: 5, High: 5, Low: 5, Close: 5}
+
+	ind.Update(bar)
+	assert.Equal(t, StochasticValue{50.0, 50.0}, ind.Update(bar))
+}
+
+func TestStochasticBatch(t *testing.T) {
+	_, err := StochasticBatch(mockBars(), 0, 3)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	out, err := StochasticBatch(mockBars(), 3, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(out))
+
+	// highest high 13, lowest low 7, close 12
+	assert.InDelta(t, 500.0/6.0, out[4].K, 1e-12)
+	assert.Equal(t, out[4].K, out[4].D)
+}
+
+func TestStochasticRange(t *testing.T) {
+	ss := seriesstore.NewOHLCSStore()
+
+	// no key
+	_, err := StochasticRange(ss, "foo", 0, 5, 3, 2)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockBars())
+	out, err := StochasticRange(ss, "foo", 0, 3, 3, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 20.0, out[2].K)
+}
@@ -0,0 +1,88 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// VWAP is a streaming volume weighted average price
+// Each bar is priced at its typical price (high + low + close) / 3
+type VWAP struct {
+	pv  *seriesstore.RollingWindow // price * volume
+	vol *seriesstore.RollingWindow
+	// 1 for each bar traded at non zero volume, its sum is exact where
+	// the rolling volume sum can be left with rounding error once every traded bar is evicted
+	traded *seriesstore.RollingWindow
+}
+
+// NewVWAP constructs a new VWAP over the last period bars, or over every bar if period < 1
+func NewVWAP(period int) *VWAP {
+	return &VWAP{
+		pv:     seriesstore.NewRollingWindow(period),
+		vol:    seriesstore.NewRollingWindow(period),
+		traded: seriesstore.NewRollingWindow(period),
+	}
+}
+
+// Update adds the next bar traded at the given volume and returns the current average price,
+// NaN while no volume has been seen
+func (i *VWAP) Update(bar seriesstore.OHLC, volume float64) float64 {
+	typical := (float64(bar.High) + float64(bar.Low) + float64(bar.Close)) / 3.0
+	i.pv.Push(typical * volume)
+	i.vol.Push(volume)
+	if volume != 0 {
+		i.traded.Push(1.0)
+	} else {
+		i.traded.Push(0.0)
+	}
+
+	return i.Value()
+}
+
+// Value returns the current average price, NaN while no volume has been seen
+func (i *VWAP) Value() float64 {
+	if !i.Ready() {
+		return math.NaN()
+	}
+
+	return i.pv.Sum() / i.vol.Sum()
+}
+
+// Ready checks if the average has been weighted by any volume
+func (i *VWAP) Ready() bool {
+	return i.traded.Sum() != 0
+}
+
+// VWAPBatch computes the volume weighted average price of bars traded at the paired volumes
+// over the last period bars, or cumulatively if period < 1
+func VWAPBatch(bars []seriesstore.OHLC, volumes []float64, period int) ([]float64, error) {
+	if len(bars) != len(volumes) {
+		return nil, ErrLengthMismatch
+	}
+
+	ind := NewVWAP(period)
+
+	out := make([]float64, len(bars))
+	for j, b := range bars {
+		out[j] = ind.Update(b, volumes[j])
+	}
+
+	return out, nil
+}
+
+// VWAPRange computes the volume weighted average price over the range [lower:upper)
+// of the bars and volumes stored at key in their respective stores
+func VWAPRange(bars *seriesstore.OHLCSStore, volumes *seriesstore.Float64SStore, key string, lower, upper, period int) ([]float64, error) {
+	b, err := bars.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := volumes.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return VWAPBatch(b, v, period)
+}
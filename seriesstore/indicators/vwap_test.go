@@ -0,0 +1,74 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVWAPUpdate(t *testing.T) {
+	ind := NewVWAP(0)
+	assert.False(t, ind.Ready())
+	assert.True(t, math.IsNaN(ind.Value()))
+
+	// typical price 10
+	assert.Equal(t, 10.0, ind.Update(seriesstore.OHLC{Open: 9, High: 11, Low: 9, Close: 10}, 100))
+	assert.True(t, ind.Ready())
+
+	// typical price 13
+	assert.Equal(t, 12.0, ind.Update(seriesstore.OHLC{Open: 12, High: 14, Low: 12, Close: 13}, 200))
+
+	// no volume leaves the average unchanged
+	assert.Equal(t, 12.0, ind.Update(seriesstore.OHLC{Open: 50, High: 50, Low: 50, Close: 50}, 0))
+}
+
+func TestVWAPRolling(t *testing.T) {
+	ind := NewVWAP(1)
+
+	ind.Update(seriesstore.OHLC{Open: 9, High: 11, Low: 9, Close: 10}, 100)
+	assert.Equal(t, 13.0, ind.Update(seriesstore.OHLC{Open: 12, High: 14, Low: 12, Close: 13}, 200))
+}
+
+func TestVWAPRollsOntoNoVolume(t *testing.T) {
+	ind := NewVWAP(2)
+
+	ind.Update(seriesstore.OHLC{Open: 1, High: 1, Low: 1, Close: 1}, 0.1)
+	ind.Update(seriesstore.OHLC{Open: 1, High: 1, Low: 1, Close: 1}, 0.2)
+	ind.Update(seriesstore.OHLC{Open: 1, High: 1, Low: 1, Close: 1}, 0)
+
+	// the volume sum is left with rounding error once the window holds no traded bars
+	assert.True(t, math.IsNaN(ind.Update(seriesstore.OHLC{Open: 1, High: 1, Low: 1, Close: 1}, 0)))
+	assert.False(t, ind.Ready())
+}
+
+func TestVWAPBatch(t *testing.T) {
+	_, err := VWAPBatch(mockBars(), []float64{1.0}, 0)
+	assert.Equal(t, ErrLengthMismatch, err)
+
+	out, err := VWAPBatch(mockBars()[:2], []float64{0.0, 10.0}, 0)
+	assert.Nil(t, err)
+	assert.True(t, math.IsNaN(out[0]))
+	assert.InDelta(t, 38.0/3.0, out[1], 1e-6)
+}
+
+func TestVWAPRange(t *testing.T) {
+	bars := seriesstore.NewOHLCSStore()
+	volumes := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := VWAPRange(bars, volumes, "foo", 0, 2, 0)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	bars.Set("foo", mockBars())
+
+	// no volume key
+	_, err = VWAPRange(bars, volumes, "foo", 0, 2, 0)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	volumes.Set("foo", []float64{1.0, 1.0, 1.0, 1.0, 1.0})
+	out, err := VWAPRange(bars, volumes, "foo", 0, 2, 0)
+	assert.Nil(t, err)
+	assert.InDelta(t, (32.0/3.0+38.0/3.0)/2.0, out[1], 1e-6)
+}
@@ -0,0 +1,76 @@
+package indicators
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// WMA is a streaming linearly weighted moving average
+// The most recent value has weight period, the oldest weight 1
+type WMA struct {
+	period   int
+	window   *seriesstore.RollingWindow
+	weighted float64 // sum of value * weight over the window
+}
+
+// NewWMA constructs a new WMA over the given period
+func NewWMA(period int) (*WMA, error) {
+	if period < 1 {
+		return nil, ErrInvalidPeriod
+	}
+
+	return &WMA{period: period, window: seriesstore.NewRollingWindow(period)}, nil
+}
+
+// Update adds the next value and returns the current average, NaN until ready
+func (i *WMA) Update(value float64) float64 {
+	if i.window.Full() {
+		// every weight drops by one, the oldest value drops out at weight zero
+		i.weighted += float64(i.period)*value - i.window.Sum()
+	} else {
+		i.weighted += float64(i.window.Count()+1) * value
+	}
+	i.window.Push(value)
+
+	return i.Value()
+}
+
+// Value returns the current average, NaN until ready
+func (i *WMA) Value() float64 {
+	if !i.Ready() {
+		return math.NaN()
+	}
+
+	return i.weighted / float64(i.period*(i.period+1)/2)
+}
+
+// Ready checks if a full period of values has been seen
+func (i *WMA) Ready() bool {
+	return i.window.Full()
+}
+
+// WMABatch computes the weighted moving average of values over the given period
+func WMABatch(values []float64, period int) ([]float64, error) {
+	ind, err := NewWMA(period)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]float64, len(values))
+	for j, v := range values {
+		out[j] = ind.Update(v)
+	}
+
+	return out, nil
+}
+
+// WMARange computes the weighted moving average over the range [lower:upper) of the series stored at key
+func WMARange(s *seriesstore.Float64SStore, key string, lower, upper, period int) ([]float64, error) {
+	values, err := s.GetRange(key, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+
+	return WMABatch(values, period)
+}
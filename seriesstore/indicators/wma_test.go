@@ -0,0 +1,67 @@
+package indicators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWMA(t *testing.T) {
+	_, err := NewWMA(0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	ind, err := NewWMA(3)
+	assert.Nil(t, err)
+	assert.False(t, ind.Ready())
+}
+
+func TestWMAUpdate(t *testing.T) {
+	ind, _ := NewWMA(3)
+
+	assert.True(t, math.IsNaN(ind.Update(1.0)))
+	assert.True(t, math.IsNaN(ind.Update(2.0)))
+	assert.InDelta(t, 14.0/6.0, ind.Update(3.0), 1e-12)
+	assert.InDelta(t, 20.0/6.0, ind.Update(4.0), 1e-12)
+	assert.InDelta(t, 26.0/6.0, ind.Update(5.0), 1e-12)
+}
+
+func TestWMABatch(t *testing.T) {
+	_, err := WMABatch(mockSeries(), 0)
+	assert.Equal(t, ErrInvalidPeriod, err)
+
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, 200)
+	for j := range values {
+		values[j] = r.Float64() * 100
+	}
+
+	period := 7
+	out, err := WMABatch(values, period)
+	assert.Nil(t, err)
+
+	// compare against the direct weighted sum
+	for j := period - 1; j < len(values); j++ {
+		exp := 0.0
+		for w := 1; w <= period; w++ {
+			exp += float64(w) * values[j-period+w]
+		}
+		exp /= float64(period * (period + 1) / 2)
+		assert.InDelta(t, exp, out[j], 1e-9)
+	}
+}
+
+func TestWMARange(t *testing.T) {
+	ss := seriesstore.NewFloat64SStore()
+
+	// no key
+	_, err := WMARange(ss, "foo", 0, 5, 3)
+	assert.Equal(t, seriesstore.ErrKeyDoesNotExist, err)
+
+	ss.Set("foo", mockSeries())
+	out, err := WMARange(ss, "foo", 0, 3, 3)
+	assert.Nil(t, err)
+	assert.InDelta(t, 14.0/6.0, out[2], 1e-12)
+}
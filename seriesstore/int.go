@@ -1,7 +1,15 @@
 package seriesstore
 
 import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"sort"
+	"strings"
 	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
 )
 
 // IntSStore is a store of int slices
@@ -10,7 +18,9 @@ import (
 // Embedded sync.Mutex to provide atomic operation ability
 type IntSStore struct {
 	sync.Mutex
-	store map[string][]int
+	store  map[string][]int
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
 }
 
 // NewIntSStore constructs and initializes a new IntSStore
@@ -21,6 +31,10 @@ func NewIntSStore() *IntSStore {
 
 func (s *IntSStore) set(key string, value []int) {
 	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashInts(merkle.Init(key), value))
+	}
 }
 
 // Set stores the given value mapped to the given key in the store
@@ -30,6 +44,28 @@ func (s *IntSStore) Set(key string, value []int) {
 	s.Unlock()
 }
 
+func (s *IntSStore) append(key string, values ...int) {
+	s.store[key] = append(s.store[key], values...)
+	s.index.Insert(key)
+
+	// extend the hash of the series instead of hashing it again
+	if s.digest != nil {
+		h, ok := s.digest.State(key)
+		if !ok {
+			h = merkle.Init(key)
+		}
+		s.digest.Set(key, hashInts(h, values))
+	}
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store
+// The key is created if it does not exist
+func (s *IntSStore) Append(key string, values ...int) {
+	s.Lock()
+	s.append(key, values...)
+	s.Unlock()
+}
+
 func (s *IntSStore) setIdx(key string, idx int, value int) error {
 	v, ok := s.store[key]
 
@@ -44,6 +80,9 @@ func (s *IntSStore) setIdx(key string, idx int, value int) error {
 	}
 
 	s.store[key][idx] = value
+	if s.digest != nil {
+		s.digest.Set(key, hashInts(merkle.Init(key), s.store[key]))
+	}
 
 	return nil
 }
@@ -123,6 +162,286 @@ func (s *IntSStore) GetRange(key string, lower, upper int) ([]int, error) {
 	return v, err
 }
 
+func copyInts(xs []int) []int {
+	return append(make([]int, 0, len(xs)), xs...)
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *IntSStore) snapshot() map[string][]int {
+	m := make(map[string][]int, len(s.store))
+	for k, v := range s.store {
+		m[k] = copyInts(v)
+	}
+
+	return m
+}
+
+func (s *IntSStore) clone() *IntSStore {
+	c := &IntSStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *IntSStore) Clone() *IntSStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *IntSStore) mergeFrom(values map[string][]int, conflictFn func(key string, current, incoming []int) []int) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *IntSStore) MergeFrom(other *IntSStore, conflictFn func(key string, current, incoming []int) []int) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *IntSStore) diff(values map[string][]int) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !equalInts(v, ov) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *IntSStore) Diff(other *IntSStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *IntSStore) replaceAll(store map[string][]int) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *IntSStore) ReplaceAll(values map[string][]int) {
+	store := make(map[string][]int, len(values))
+	for k, v := range values {
+		store[k] = copyInts(v)
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+// hashInts adds the values of a series to the hash state of its key
+func hashInts(h uint64, xs []int) uint64 {
+	for _, x := range xs {
+		h = merkle.WriteUint64(h, uint64(x))
+	}
+
+	return h
+}
+
+func (s *IntSStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashInts(merkle.Init(k), v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled, Append only hashes the appended values
+// Values changed through a slice given to Set or returned by Get are not seen by the digest
+func (s *IntSStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *IntSStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashInts(merkle.Init(k), v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *IntSStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *IntSStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashInts(merkle.Init(k), v))
+	}
+
+	return t
+}
+
+func (s *IntSStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string][]int)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *IntSStore) applyEntries(buckets merkle.BucketSet, entries map[string][]int) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			delete(s.store, k)
+			s.index.Delete(k)
+			s.digest.Delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashInts(merkle.Init(k), cur) != hashInts(merkle.Init(k), v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *IntSStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string][]int
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *IntSStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *IntSStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
 func (s *IntSStore) size() int {
 	return len(s.store)
 }
@@ -158,6 +477,118 @@ func (s *IntSStore) Members() []string {
 	return v
 }
 
+func (s *IntSStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *IntSStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *IntSStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *IntSStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *IntSStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *IntSStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *IntSStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *IntSStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *IntSStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *IntSStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
 func (s *IntSStore) isMember(key string) bool {
 	_, ok := s.store[key]
 
@@ -195,6 +626,8 @@ func (s *IntSStore) MemberLen(key string) (int, error) {
 
 func (s *IntSStore) clear() {
 	s.store = make(map[string][]int)
+	s.index.Clear()
+	s.digest.Clear()
 }
 
 // Clear deletes all keys in the store
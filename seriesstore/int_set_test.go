@@ -0,0 +1,311 @@
+package seriesstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockIntSet(members ...int) map[int]struct{} {
+	set := make(map[int]struct{})
+	for _, m := range members {
+		set[m] = struct{}{}
+	}
+
+	return set
+}
+
+func TestIntSetAdd(t *testing.T) {
+	ss := NewIntSetStore()
+
+	assert.Equal(t, 2, ss.Add("tech", 1, 2))
+	assert.Equal(t, mockIntSet(1, 2), ss.store["tech"])
+
+	// existing members
+	assert.Equal(t, 1, ss.Add("tech", 1, 3))
+	assert.Equal(t, 3, len(ss.store["tech"]))
+
+	// no members
+	assert.Equal(t, 0, ss.Add("energy"))
+	assert.False(t, ss.isMember("energy"))
+}
+
+func TestIntSetRemove(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no key
+	assert.Equal(t, 0, ss.Remove("tech", 1))
+
+	ss.store["tech"] = mockIntSet(1, 2, 3)
+	assert.Equal(t, 2, ss.Remove("tech", 1, 3, 5))
+	assert.Equal(t, mockIntSet(2), ss.store["tech"])
+
+	// last member deletes key
+	assert.Equal(t, 1, ss.Remove("tech", 2))
+	assert.False(t, ss.isMember("tech"))
+}
+
+func TestIntSetContains(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no key
+	assert.False(t, ss.Contains("tech", 1))
+
+	ss.store["tech"] = mockIntSet(1)
+	assert.True(t, ss.Contains("tech", 1))
+	assert.False(t, ss.Contains("tech", 3))
+}
+
+func TestIntSetCard(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no key
+	_, err := ss.Card("tech")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	ss.store["tech"] = mockIntSet(1, 2)
+	c, err := ss.Card("tech")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, c)
+}
+
+func TestIntSetSMembers(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no key
+	_, err := ss.SMembers("tech")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	ss.store["tech"] = mockIntSet(1, 2)
+	mems, err := ss.SMembers("tech")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{1, 2}, mems)
+}
+
+func TestIntSetSUnion(t *testing.T) {
+	ss := NewIntSetStore()
+	ss.store["a"] = mockIntSet(1, 2)
+	ss.store["b"] = mockIntSet(2, 3)
+
+	assert.ElementsMatch(t, []int{1, 2, 3}, ss.SUnion("a", "b", "missing"))
+	assert.Equal(t, 0, len(ss.SUnion()))
+}
+
+func TestIntSetSInter(t *testing.T) {
+	ss := NewIntSetStore()
+	ss.store["a"] = mockIntSet(1, 2, 3)
+	ss.store["b"] = mockIntSet(2, 3, 4)
+	ss.store["c"] = mockIntSet(3, 2)
+
+	assert.ElementsMatch(t, []int{2, 3}, ss.SInter("a", "b", "c"))
+	assert.ElementsMatch(t, []int{1, 2, 3}, ss.SInter("a"))
+
+	// missing key is empty
+	assert.Equal(t, 0, len(ss.SInter("a", "missing")))
+	assert.Equal(t, 0, len(ss.SInter()))
+}
+
+func TestIntSetSDiff(t *testing.T) {
+	ss := NewIntSetStore()
+	ss.store["a"] = mockIntSet(1, 2, 3)
+	ss.store["b"] = mockIntSet(2)
+	ss.store["c"] = mockIntSet(3, 4)
+
+	assert.ElementsMatch(t, []int{1}, ss.SDiff("a", "b", "c"))
+	assert.ElementsMatch(t, []int{1, 2, 3}, ss.SDiff("a", "missing"))
+	assert.Equal(t, 0, len(ss.SDiff("missing", "a")))
+}
+
+func TestIntSetSize(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no keys
+	size := ss.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	ss.store["a"] = mockIntSet(1)
+	ss.store["b"] = mockIntSet(1)
+
+	size = ss.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestIntSetMembers(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no keys
+	mems := ss.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	ss.store["a"] = mockIntSet(1)
+	ss.store["b"] = mockIntSet(1)
+
+	mems = ss.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestIntSetSortedMembers(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockIntSet(1)
+	ss.store["AAPL:bid"] = mockIntSet(1)
+	ss.store["AAPL:ask"] = mockIntSet(1)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestIntSetMembersWithPrefix(t *testing.T) {
+	ss := NewIntSetStore()
+
+	ss.store["MSFT:bid"] = mockIntSet(1)
+	ss.store["AAPL:bid"] = mockIntSet(1)
+	ss.store["AAPL:ask"] = mockIntSet(1)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestIntSetMembersMatching(t *testing.T) {
+	ss := NewIntSetStore()
+
+	ss.store["MSFT:bid"] = mockIntSet(1)
+	ss.store["AAPL:bid"] = mockIntSet(1)
+	ss.store["AAPL:ask"] = mockIntSet(1)
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestIntSetKeyIndex(t *testing.T) {
+	ss := NewIntSetStore()
+	ss.store["foo"] = mockIntSet(1)
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Add("bar", 1)
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// deleted keys are unindexed
+	ss.Remove("bar", 1)
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestIntSetScan(t *testing.T) {
+	ss := NewIntSetStore()
+
+	ss.store["MSFT:bid"] = mockIntSet(1)
+	ss.store["AAPL:bid"] = mockIntSet(1)
+	ss.store["AAPL:ask"] = mockIntSet(1)
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestIntSetIsMember(t *testing.T) {
+	ss := NewIntSetStore()
+
+	// no keys
+	ok := ss.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	ss.store["foo"] = mockIntSet(1)
+
+	ok = ss.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestIntSetClear(t *testing.T) {
+	ss := NewIntSetStore()
+
+	ss.store["foo"] = mockIntSet(1)
+	assert.Equal(t, 1, len(ss.store))
+
+	ss.Clear()
+	assert.Equal(t, 0, len(ss.store))
+}
+
+func TestIntSetConcurrentAddAndSInter(t *testing.T) {
+	ss := NewIntSetStore()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				ss.Add("a", 1)
+				ss.Add("b", 1)
+				ss.SInter("a", "b")
+				ss.Remove("a", 1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestIntSetConcurrentGetAndSet(t *testing.T) {
+	ss := NewIntSetStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Add("foo", 1)
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Contains("foo", 1)
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -1,6 +1,8 @@
 package seriesstore
 
 import (
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -18,6 +20,19 @@ func TestIntSet(t *testing.T) {
 	assert.Equal(t, ss.store["foo"], mockIntSeries())
 }
 
+func TestIntAppend(t *testing.T) {
+	ss := NewIntSStore()
+
+	// key not exist
+	ss.Append("foo", mockIntSeries()...)
+	assert.Equal(t, mockIntSeries(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", 10)
+	assert.Equal(t, len(mockIntSeries())+1, len(ss.store["foo"]))
+	assert.Equal(t, 10, ss.store["foo"][len(mockIntSeries())])
+}
+
 func TestIntSetIdx(t *testing.T) {
 	// key not exist
 	ss := NewIntSStore()
@@ -127,6 +142,108 @@ func TestIntGetRange(t *testing.T) {
 	assert.Equal(t, ErrIdxOutOfBounds, err)
 }
 
+func TestIntClone(t *testing.T) {
+	s := NewIntSStore()
+	s.store["a"] = mockIntSeries()
+	s.store["b"] = []int{7}
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockIntSeries())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = 7
+	assert.Equal(t, mockIntSeries(), s.store["a"])
+}
+
+func TestIntMergeFrom(t *testing.T) {
+	o := NewIntSStore()
+	o.store["b"] = []int{7}
+	o.store["c"] = []int{7}
+
+	// incoming values win without a conflict func
+	s := NewIntSStore()
+	s.store["a"] = mockIntSeries()
+	s.store["b"] = mockIntSeries()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]int{"a": mockIntSeries(), "b": []int{7}, "c": []int{7}}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewIntSStore()
+	s.store["a"] = mockIntSeries()
+	s.store["b"] = mockIntSeries()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []int) []int {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockIntSeries(), current)
+		assert.Equal(t, []int{7}, incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]int{"a": mockIntSeries(), "b": mockIntSeries(), "c": []int{7}}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]int{"b": []int{7}, "c": []int{7}}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestIntDiff(t *testing.T) {
+	s := NewIntSStore()
+	s.store["a"] = mockIntSeries()
+	s.store["b"] = mockIntSeries()
+	s.store["c"] = mockIntSeries()
+
+	o := NewIntSStore()
+	o.store["b"] = mockIntSeries()
+	o.store["c"] = []int{7}
+	o.store["d"] = []int{7}
+	o.store["e"] = []int{7}
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewIntSStore().Diff(NewIntSStore()).Empty())
+}
+
+func TestIntReplaceAll(t *testing.T) {
+	s := NewIntSStore()
+	s.store["a"] = mockIntSeries()
+	s.EnableKeyIndex()
+
+	values := map[string][]int{"b": []int{7}, "c": mockIntSeries()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockIntSeries()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestIntSize(t *testing.T) {
 	ss := NewIntSStore()
 
@@ -157,6 +274,103 @@ func TestIntMembers(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestIntSortedMembers(t *testing.T) {
+	ss := NewIntSStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockIntSeries()
+	ss.store["AAPL:bid"] = mockIntSeries()
+	ss.store["AAPL:ask"] = mockIntSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestIntMembersWithPrefix(t *testing.T) {
+	ss := NewIntSStore()
+
+	ss.store["MSFT:bid"] = mockIntSeries()
+	ss.store["AAPL:bid"] = mockIntSeries()
+	ss.store["AAPL:ask"] = mockIntSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestIntMembersMatching(t *testing.T) {
+	ss := NewIntSStore()
+
+	ss.store["MSFT:bid"] = mockIntSeries()
+	ss.store["AAPL:bid"] = mockIntSeries()
+	ss.store["AAPL:ask"] = mockIntSeries()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestIntKeyIndex(t *testing.T) {
+	ss := NewIntSStore()
+	ss.store["foo"] = mockIntSeries()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockIntSeries())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestIntScan(t *testing.T) {
+	ss := NewIntSStore()
+
+	ss.store["MSFT:bid"] = mockIntSeries()
+	ss.store["AAPL:bid"] = mockIntSeries()
+	ss.store["AAPL:ask"] = mockIntSeries()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestIntIsMember(t *testing.T) {
 	ss := NewIntSStore()
 
@@ -197,6 +411,93 @@ func TestIntClear(t *testing.T) {
 	assert.Equal(t, 0, len(ss.store))
 }
 
+func TestIntDigest(t *testing.T) {
+	s := NewIntSStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockIntSeries())
+	s.Set("b", []int{7})
+
+	// write order does not matter
+	o := NewIntSStore()
+	o.Set("b", []int{7})
+	o.Set("a", mockIntSeries())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", 7)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", []int{7})
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestIntEnableDigest(t *testing.T) {
+	s := NewIntSStore()
+	s.Set("a", mockIntSeries())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", 7, 7)
+	s.Append("b", 7)
+	o := NewIntSStore()
+	o.Set("a", append(mockIntSeries(), 7, 7))
+	o.Set("b", []int{7})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, 7))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, 7))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]int{"a": mockIntSeries()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestIntSyncFrom(t *testing.T) {
+	s := NewIntSStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockIntSeries())
+	}
+	s.Set("b", []int{7})
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", 7)
+	r.ReplaceAll(map[string][]int{"1": mockIntSeries(), "b": []int{7}, "c": []int{7}})
+	r.Set("2", []int{7})
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestIntConcurrentGetAndSet(t *testing.T) {
 	ss := NewIntSStore()
 
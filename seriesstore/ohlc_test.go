@@ -1,6 +1,8 @@
 package seriesstore
 
 import (
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -18,6 +20,19 @@ func TestOHLCSet(t *testing.T) {
 	assert.Equal(t, ss.store["foo"], mockOHLCSeries())
 }
 
+func TestOHLCAppend(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	// key not exist
+	ss.Append("foo", mockOHLCSeries()...)
+	assert.Equal(t, mockOHLCSeries(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", OHLC{1.0, 2.0, 0.5, 1.5})
+	assert.Equal(t, len(mockOHLCSeries())+1, len(ss.store["foo"]))
+	assert.Equal(t, OHLC{1.0, 2.0, 0.5, 1.5}, ss.store["foo"][len(mockOHLCSeries())])
+}
+
 func TestOHLCSetIdx(t *testing.T) {
 	// key not exist
 	ss := NewOHLCSStore()
@@ -128,6 +143,161 @@ func TestOHLCGetRange(t *testing.T) {
 	assert.Equal(t, ErrIdxOutOfBounds, err)
 }
 
+func TestOHLCColumn(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	// no keys
+	assert.Equal(t, map[string]OHLC{}, ss.Column(0))
+
+	ss.store["a"] = mockOHLCSeries()
+	ss.store["b"] = []OHLC{{1, 2, 0.5, 1.5}}
+	ss.store["c"] = []OHLC{}
+
+	// short series are skipped
+	assert.Equal(t, map[string]OHLC{"a": mockOHLCSeries()[0], "b": OHLC{1, 2, 0.5, 1.5}}, ss.Column(0))
+	assert.Equal(t, map[string]OHLC{"a": mockOHLCSeries()[1]}, ss.Column(1))
+	assert.Equal(t, map[string]OHLC{}, ss.Column(-1))
+}
+
+func TestOHLCLatest(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	// no keys
+	assert.Equal(t, map[string]OHLC{}, ss.Latest())
+
+	ss.store["a"] = mockOHLCSeries()
+	ss.store["b"] = []OHLC{{1, 2, 0.5, 1.5}}
+	ss.store["c"] = []OHLC{}
+
+	// empty series are skipped
+	assert.Equal(t, map[string]OHLC{"a": mockOHLCSeries()[2], "b": OHLC{1, 2, 0.5, 1.5}}, ss.Latest())
+}
+
+func TestOHLCCrossSection(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	ss.store["a"] = mockOHLCSeries()
+	ss.store["b"] = []OHLC{{1, 2, 0.5, 1.5}}
+
+	// no key
+	_, err := ss.CrossSection([]string{"a", "foo"}, 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// out of bounds
+	_, err = ss.CrossSection([]string{"a", "b"}, 1)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	v, err := ss.CrossSection([]string{"a", "b"}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]OHLC{"a": mockOHLCSeries()[0], "b": OHLC{1, 2, 0.5, 1.5}}, v)
+
+	v, err = ss.CrossSection([]string{"a"}, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]OHLC{"a": mockOHLCSeries()[2]}, v)
+}
+
+func TestOHLCClone(t *testing.T) {
+	s := NewOHLCSStore()
+	s.store["a"] = mockOHLCSeries()
+	s.store["b"] = []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockOHLCSeries())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = OHLC{}
+	assert.Equal(t, mockOHLCSeries(), s.store["a"])
+}
+
+func TestOHLCMergeFrom(t *testing.T) {
+	o := NewOHLCSStore()
+	o.store["b"] = []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+	o.store["c"] = []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+
+	// incoming values win without a conflict func
+	s := NewOHLCSStore()
+	s.store["a"] = mockOHLCSeries()
+	s.store["b"] = mockOHLCSeries()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]OHLC{"a": mockOHLCSeries(), "b": []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}, "c": []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewOHLCSStore()
+	s.store["a"] = mockOHLCSeries()
+	s.store["b"] = mockOHLCSeries()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []OHLC) []OHLC {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockOHLCSeries(), current)
+		assert.Equal(t, []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}, incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]OHLC{"a": mockOHLCSeries(), "b": mockOHLCSeries(), "c": []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]OHLC{"b": []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}, "c": []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestOHLCDiff(t *testing.T) {
+	s := NewOHLCSStore()
+	s.store["a"] = mockOHLCSeries()
+	s.store["b"] = mockOHLCSeries()
+	s.store["c"] = mockOHLCSeries()
+
+	o := NewOHLCSStore()
+	o.store["b"] = mockOHLCSeries()
+	o.store["c"] = []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+	o.store["d"] = []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+	o.store["e"] = []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewOHLCSStore().Diff(NewOHLCSStore()).Empty())
+}
+
+func TestOHLCReplaceAll(t *testing.T) {
+	s := NewOHLCSStore()
+	s.store["a"] = mockOHLCSeries()
+	s.EnableKeyIndex()
+
+	values := map[string][]OHLC{"b": []OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}}, "c": mockOHLCSeries()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockOHLCSeries()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestOHLCSize(t *testing.T) {
 	ss := NewOHLCSStore()
 
@@ -158,6 +328,103 @@ func TestOHLCMembers(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestOHLCSortedMembers(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockOHLCSeries()
+	ss.store["AAPL:bid"] = mockOHLCSeries()
+	ss.store["AAPL:ask"] = mockOHLCSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestOHLCMembersWithPrefix(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	ss.store["MSFT:bid"] = mockOHLCSeries()
+	ss.store["AAPL:bid"] = mockOHLCSeries()
+	ss.store["AAPL:ask"] = mockOHLCSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestOHLCMembersMatching(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	ss.store["MSFT:bid"] = mockOHLCSeries()
+	ss.store["AAPL:bid"] = mockOHLCSeries()
+	ss.store["AAPL:ask"] = mockOHLCSeries()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestOHLCKeyIndex(t *testing.T) {
+	ss := NewOHLCSStore()
+	ss.store["foo"] = mockOHLCSeries()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockOHLCSeries())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestOHLCScan(t *testing.T) {
+	ss := NewOHLCSStore()
+
+	ss.store["MSFT:bid"] = mockOHLCSeries()
+	ss.store["AAPL:bid"] = mockOHLCSeries()
+	ss.store["AAPL:ask"] = mockOHLCSeries()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestOHLCIsMember(t *testing.T) {
 	ss := NewOHLCSStore()
 
@@ -198,6 +465,93 @@ func TestOHLCClear(t *testing.T) {
 	assert.Equal(t, 0, len(ss.store))
 }
 
+func TestOHLCDigest(t *testing.T) {
+	s := NewOHLCSStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockOHLCSeries())
+	s.Set("b", mockOHLCSeries()[:1])
+
+	// write order does not matter
+	o := NewOHLCSStore()
+	o.Set("b", mockOHLCSeries()[:1])
+	o.Set("a", mockOHLCSeries())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", OHLC{1.0, 2.0, 0.5, 1.5})
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", mockOHLCSeries()[:1])
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestOHLCEnableDigest(t *testing.T) {
+	s := NewOHLCSStore()
+	s.Set("a", mockOHLCSeries())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", OHLC{1.0, 2.0, 0.5, 1.5}, OHLC{1.0, 2.0, 0.5, 1.5})
+	s.Append("b", OHLC{1.0, 2.0, 0.5, 1.5})
+	o := NewOHLCSStore()
+	o.Set("a", append(mockOHLCSeries(), OHLC{1.0, 2.0, 0.5, 1.5}, OHLC{1.0, 2.0, 0.5, 1.5}))
+	o.Set("b", []OHLC{OHLC{1.0, 2.0, 0.5, 1.5}})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, &OHLC{1.0, 2.0, 0.5, 1.5}))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, &OHLC{1.0, 2.0, 0.5, 1.5}))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]OHLC{"a": mockOHLCSeries()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestOHLCSyncFrom(t *testing.T) {
+	s := NewOHLCSStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockOHLCSeries())
+	}
+	s.Set("b", mockOHLCSeries()[:1])
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", OHLC{1.0, 2.0, 0.5, 1.5})
+	r.ReplaceAll(map[string][]OHLC{"1": mockOHLCSeries(), "b": mockOHLCSeries()[:1], "c": mockOHLCSeries()[:1]})
+	r.Set("2", mockOHLCSeries()[:1])
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestOHLCConcurrentGetAndSet(t *testing.T) {
 	ss := NewOHLCSStore()
 
@@ -0,0 +1,699 @@
+package seriesstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/merkle"
+)
+
+// OHLCV is a bar of Open High Low Close prices and the Volume traded over the interval starting at Time
+// Prices are float64, unlike OHLC, so high priced instruments keep full precision
+type OHLCV struct {
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+	Trades int64
+	VWAP   float64
+}
+
+// OHLCVFromOHLC converts a legacy OHLC bar starting at the given time to an OHLCV
+// The conversion is lossless, volume fields are zero
+func OHLCVFromOHLC(bar OHLC, start time.Time) OHLCV {
+	return OHLCV{
+		Time:  start,
+		Open:  float64(bar.Open),
+		High:  float64(bar.High),
+		Low:   float64(bar.Low),
+		Close: float64(bar.Close),
+	}
+}
+
+// ToOHLC converts the bar to a legacy OHLC, dropping time and volume fields
+// Prices are rounded to float32, so the conversion is only lossless for bars
+// converted from an OHLC or holding float32 representable prices
+func (b OHLCV) ToOHLC() OHLC {
+	return OHLC{float32(b.Open), float32(b.High), float32(b.Low), float32(b.Close)}
+}
+
+// OHLCVSeriesFromOHLC converts a series of consecutive legacy OHLC bars of the given interval, the first starting at start
+func OHLCVSeriesFromOHLC(bars []OHLC, start time.Time, interval time.Duration) []OHLCV {
+	out := make([]OHLCV, len(bars))
+	for i, b := range bars {
+		out[i] = OHLCVFromOHLC(b, start.Add(time.Duration(i)*interval))
+	}
+
+	return out
+}
+
+// OHLCSeriesFromOHLCV converts a series of bars to legacy OHLC bars, see OHLCV.ToOHLC
+func OHLCSeriesFromOHLCV(bars []OHLCV) []OHLC {
+	out := make([]OHLC, len(bars))
+	for i, b := range bars {
+		out[i] = b.ToOHLC()
+	}
+
+	return out
+}
+
+// OHLCVSStore is a store of OHLCV (Open High Low Close Volume stock ticker bars) slices
+// Implements the SeriesStore interface
+// All getter and setter functions provide bound checks where applicable
+// Embedded sync.Mutex to provide atomic operation ability
+type OHLCVSStore struct {
+	sync.Mutex
+	store  map[string][]OHLCV
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+	digest *merkle.Tree    // key/value hashes, nil unless EnableDigest is called
+}
+
+// NewOHLCVSStore constructs and initializes a new OHLCVSStore
+// Always use this function to init new OHLCVSStore
+func NewOHLCVSStore() *OHLCVSStore {
+	return &OHLCVSStore{store: make(map[string][]OHLCV)}
+}
+
+func (s *OHLCVSStore) set(key string, value []OHLCV) {
+	s.store[key] = value
+	s.index.Insert(key)
+	if s.digest != nil {
+		s.digest.Set(key, hashOHLCVs(merkle.Init(key), value))
+	}
+}
+
+// Set stores the given value mapped to the given key in the store
+func (s *OHLCVSStore) Set(key string, value []OHLCV) {
+	s.Lock()
+	s.set(key, value)
+	s.Unlock()
+}
+
+func (s *OHLCVSStore) append(key string, values ...OHLCV) {
+	s.store[key] = append(s.store[key], values...)
+	s.index.Insert(key)
+
+	// extend the hash of the series instead of hashing it again
+	if s.digest != nil {
+		h, ok := s.digest.State(key)
+		if !ok {
+			h = merkle.Init(key)
+		}
+		s.digest.Set(key, hashOHLCVs(h, values))
+	}
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store
+// The key is created if it does not exist
+func (s *OHLCVSStore) Append(key string, values ...OHLCV) {
+	s.Lock()
+	s.append(key, values...)
+	s.Unlock()
+}
+
+func (s *OHLCVSStore) setIdx(key string, idx int, value *OHLCV) error {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	if idx < 0 || idx >= len(v) {
+		return ErrIdxOutOfBounds
+	}
+
+	s.store[key][idx] = *value
+	if s.digest != nil {
+		s.digest.Set(key, hashOHLCVs(merkle.Init(key), s.store[key]))
+	}
+
+	return nil
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *OHLCVSStore) SetIdx(key string, idx int, value *OHLCV) error {
+	s.Lock()
+	v := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return v
+}
+
+func (s *OHLCVSStore) get(key string) ([]OHLCV, bool) {
+	// explicitly return second return value
+	v, ok := s.store[key]
+
+	return v, ok
+}
+
+// Get returns the value for the given key
+func (s *OHLCVSStore) Get(key string) ([]OHLCV, bool) {
+	s.Lock()
+	v, ok := s.get(key)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *OHLCVSStore) getIdx(key string, idx int) (OHLCV, error) {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return OHLCV{}, ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	if idx < 0 || idx >= len(v) {
+		return OHLCV{}, ErrIdxOutOfBounds
+	}
+
+	return s.store[key][idx], nil
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *OHLCVSStore) GetIdx(key string, idx int) (OHLCV, error) {
+	s.Lock()
+	v, err := s.getIdx(key, idx)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *OHLCVSStore) getRange(key string, lower, upper int) ([]OHLCV, error) {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	if lower < 0 || lower > len(v) || upper < 0 || upper > len(v) {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.store[key][lower:upper], nil
+}
+
+// GetRange returns all values for the given key within the specified range (inclusive:exclusive)
+func (s *OHLCVSStore) GetRange(key string, lower, upper int) ([]OHLCV, error) {
+	s.Lock()
+	v, err := s.getRange(key, lower, upper)
+	s.Unlock()
+
+	return v, err
+}
+
+func copyOHLCVs(xs []OHLCV) []OHLCV {
+	return append(make([]OHLCV, 0, len(xs)), xs...)
+}
+
+func equalOHLCVs(a, b []OHLCV) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *OHLCVSStore) snapshot() map[string][]OHLCV {
+	m := make(map[string][]OHLCV, len(s.store))
+	for k, v := range s.store {
+		m[k] = copyOHLCVs(v)
+	}
+
+	return m
+}
+
+func (s *OHLCVSStore) clone() *OHLCVSStore {
+	c := &OHLCVSStore{store: s.snapshot()}
+	if s.index != nil {
+		c.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		c.enableDigest()
+	}
+
+	return c
+}
+
+// Clone returns a deep copy of the store, including any enabled indexes
+func (s *OHLCVSStore) Clone() *OHLCVSStore {
+	s.Lock()
+	c := s.clone()
+	s.Unlock()
+
+	return c
+}
+
+func (s *OHLCVSStore) mergeFrom(values map[string][]OHLCV, conflictFn func(key string, current, incoming []OHLCV) []OHLCV) {
+	for k, v := range values {
+		if cur, ok := s.store[k]; ok && conflictFn != nil {
+			v = conflictFn(k, cur, v)
+		}
+
+		s.set(k, v)
+	}
+}
+
+// MergeFrom sets every key of other in the store
+// Keys held by both stores are set to conflictFn(key, current, incoming), or to the incoming value if conflictFn is nil
+// other is copied before the store is locked, so the two locks are never held together
+func (s *OHLCVSStore) MergeFrom(other *OHLCVSStore, conflictFn func(key string, current, incoming []OHLCV) []OHLCV) {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	s.mergeFrom(values, conflictFn)
+	s.Unlock()
+}
+
+func (s *OHLCVSStore) diff(values map[string][]OHLCV) KeyDiff {
+	d := newKeyDiff()
+	for k, v := range s.store {
+		ov, ok := values[k]
+		if !ok {
+			d.Removed = append(d.Removed, k)
+		} else if !equalOHLCVs(v, ov) {
+			d.Changed = append(d.Changed, k)
+		}
+	}
+
+	for k := range values {
+		if _, ok := s.store[k]; !ok {
+			d.Added = append(d.Added, k)
+		}
+	}
+
+	d.sort()
+
+	return d
+}
+
+// Diff returns the keys added, removed and changed going from the store to other
+// other is copied before the store is locked, so the two locks are never held together
+func (s *OHLCVSStore) Diff(other *OHLCVSStore) KeyDiff {
+	other.Lock()
+	values := other.snapshot()
+	other.Unlock()
+
+	s.Lock()
+	d := s.diff(values)
+	s.Unlock()
+
+	return d
+}
+
+func (s *OHLCVSStore) replaceAll(store map[string][]OHLCV) {
+	s.store = store
+	if s.index != nil {
+		s.index = nil
+		s.enableKeyIndex()
+	}
+
+	if s.digest != nil {
+		s.digest = nil
+		s.enableDigest()
+	}
+}
+
+// ReplaceAll replaces the whole contents of the store with a copy of the given values
+// The copy is made before the store is locked, readers see either the old or the new contents
+func (s *OHLCVSStore) ReplaceAll(values map[string][]OHLCV) {
+	store := make(map[string][]OHLCV, len(values))
+	for k, v := range values {
+		store[k] = copyOHLCVs(v)
+	}
+
+	s.Lock()
+	s.replaceAll(store)
+	s.Unlock()
+}
+
+// hashOHLCVs adds the values of a series to the hash state of its key
+func hashOHLCVs(h uint64, xs []OHLCV) uint64 {
+	for _, x := range xs {
+		h = merkle.WriteUint64(merkle.WriteUint64(h, uint64(x.Time.Unix())), uint64(x.Time.Nanosecond()))
+		h = merkle.WriteUint64(h, math.Float64bits(x.Open))
+		h = merkle.WriteUint64(h, math.Float64bits(x.High))
+		h = merkle.WriteUint64(h, math.Float64bits(x.Low))
+		h = merkle.WriteUint64(h, math.Float64bits(x.Close))
+		h = merkle.WriteUint64(h, math.Float64bits(x.Volume))
+		h = merkle.WriteUint64(h, uint64(x.Trades))
+		h = merkle.WriteUint64(h, math.Float64bits(x.VWAP))
+	}
+
+	return h
+}
+
+func (s *OHLCVSStore) enableDigest() {
+	if s.digest != nil {
+		return
+	}
+
+	s.digest = merkle.New()
+	for k, v := range s.store {
+		s.digest.Set(k, hashOHLCVs(merkle.Init(k), v))
+	}
+}
+
+// EnableDigest maintains a Merkle digest of the keys and values alongside the store,
+// so Digest and syncs no longer hash every value
+// Writes cost an extra hash of the written value once enabled, Append only hashes the appended values
+// Values changed through a slice given to Set or returned by Get are not seen by the digest
+func (s *OHLCVSStore) EnableDigest() {
+	s.Lock()
+	s.enableDigest()
+	s.Unlock()
+}
+
+func (s *OHLCVSStore) digestRoot() uint64 {
+	if s.digest != nil {
+		return s.digest.Root()
+	}
+
+	var d uint64
+	for k, v := range s.store {
+		d += merkle.Mix(hashOHLCVs(merkle.Init(k), v))
+	}
+
+	return d
+}
+
+// Digest returns a hash of every key and value in the store
+// Stores holding the same keys and values have the same digest, whatever order they were written in
+func (s *OHLCVSStore) Digest() uint64 {
+	s.Lock()
+	d := s.digestRoot()
+	s.Unlock()
+
+	return d
+}
+
+func (s *OHLCVSStore) digestTree() *merkle.Tree {
+	if s.digest != nil {
+		return s.digest.Copy()
+	}
+
+	t := merkle.New()
+	for k, v := range s.store {
+		t.Set(k, hashOHLCVs(merkle.Init(k), v))
+	}
+
+	return t
+}
+
+func (s *OHLCVSStore) bucketEntries(buckets merkle.BucketSet) ([]byte, error) {
+	entries := make(map[string][]OHLCV)
+	for k, v := range s.store {
+		if buckets.Contains(k) {
+			entries[k] = v
+		}
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(entries)
+
+	return buf.Bytes(), err
+}
+
+// applyEntries makes the given buckets hold exactly the given entries
+func (s *OHLCVSStore) applyEntries(buckets merkle.BucketSet, entries map[string][]OHLCV) int {
+	n := 0
+	for k := range s.store {
+		if _, ok := entries[k]; !ok && buckets.Contains(k) {
+			delete(s.store, k)
+			s.index.Delete(k)
+			s.digest.Delete(k)
+			n++
+		}
+	}
+
+	for k, v := range entries {
+		if cur, ok := s.store[k]; !ok || hashOHLCVs(merkle.Init(k), cur) != hashOHLCVs(merkle.Init(k), v) {
+			s.set(k, v)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (s *OHLCVSStore) syncReplica() merkle.Replica {
+	return merkle.Replica{
+		Tree: func() *merkle.Tree {
+			s.Lock()
+			t := s.digestTree()
+			s.Unlock()
+
+			return t
+		},
+		Entries: func(buckets merkle.BucketSet) ([]byte, error) {
+			s.Lock()
+			b, err := s.bucketEntries(buckets)
+			s.Unlock()
+
+			return b, err
+		},
+		Apply: func(buckets merkle.BucketSet, data []byte) (int, error) {
+			var entries map[string][]OHLCV
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				return 0, err
+			}
+
+			s.Lock()
+			n := s.applyEntries(buckets, entries)
+			s.Unlock()
+
+			return n, nil
+		},
+	}
+}
+
+// ServeSync answers the SyncFrom call of a store on the other end of rw, returning once it is done
+func (s *OHLCVSStore) ServeSync(rw io.ReadWriter) error {
+	return merkle.Serve(rw, s.syncReplica())
+}
+
+// SyncFrom updates the store to hold the keys and values of the store serving ServeSync on the other end of rw
+// Only the subtrees whose digests differ are walked, and only the keys in differing buckets are transferred
+// The store is not locked while waiting on rw, writes made to either store during the sync may be missed
+// returns the number of keys set or deleted
+func (s *OHLCVSStore) SyncFrom(rw io.ReadWriter) (int, error) {
+	return merkle.Sync(rw, s.syncReplica())
+}
+
+func (s *OHLCVSStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *OHLCVSStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *OHLCVSStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *OHLCVSStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *OHLCVSStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *OHLCVSStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *OHLCVSStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *OHLCVSStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *OHLCVSStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *OHLCVSStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *OHLCVSStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *OHLCVSStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *OHLCVSStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *OHLCVSStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *OHLCVSStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *OHLCVSStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *OHLCVSStore) memberLen(key string) (int, error) {
+	v, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return len(v), nil
+}
+
+// MemberLen returns the length of the series value stored at the given key
+func (s *OHLCVSStore) MemberLen(key string) (int, error) {
+	s.Lock()
+	l, err := s.memberLen(key)
+	s.Unlock()
+
+	return l, err
+}
+
+func (s *OHLCVSStore) clear() {
+	s.store = make(map[string][]OHLCV)
+	s.index.Clear()
+	s.digest.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *OHLCVSStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
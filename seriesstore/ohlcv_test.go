@@ -0,0 +1,554 @@
+package seriesstore
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mockOHLCVStart = time.Date(2018, 6, 1, 9, 30, 0, 0, time.UTC)
+
+func mockOHLCVSeries() []OHLCV {
+	return []OHLCV{
+		{mockOHLCVStart, 100.0, 200.0, 50.0, 101.0, 1000, 10, 120.5},
+		{mockOHLCVStart.Add(time.Minute), 101.0, 201.0, 49.0, 102.0, 2000, 20, 130.5},
+		{mockOHLCVStart.Add(2 * time.Minute), 102.0, 202.0, 48.0, 103.0, 3000, 30, 140.5},
+	}
+}
+
+func TestOHLCVFromOHLC(t *testing.T) {
+	bar := OHLC{100.25, 101.5, 99.75, 100.125}
+
+	v := OHLCVFromOHLC(bar, mockOHLCVStart)
+	assert.Equal(t, OHLCV{Time: mockOHLCVStart, Open: 100.25, High: 101.5, Low: 99.75, Close: 100.125}, v)
+
+	// round trip is lossless
+	assert.Equal(t, bar, v.ToOHLC())
+}
+
+func TestOHLCVToOHLC(t *testing.T) {
+	// float32 rounds prices above ~16M
+	v := OHLCV{Open: 123456789.01, High: 123456789.01, Low: 123456789.01, Close: 123456789.01}
+	assert.NotEqual(t, v.Open, float64(v.ToOHLC().Open))
+
+	v = mockOHLCVSeries()[0]
+	assert.Equal(t, OHLC{100.0, 200.0, 50.0, 101.0}, v.ToOHLC())
+}
+
+func TestOHLCVSeriesConversion(t *testing.T) {
+	bars := mockOHLCSeries()
+
+	series := OHLCVSeriesFromOHLC(bars, mockOHLCVStart, time.Minute)
+	assert.Equal(t, 3, len(series))
+	assert.Equal(t, mockOHLCVStart.Add(2*time.Minute), series[2].Time)
+	assert.Equal(t, 103.0, series[2].Close)
+
+	assert.Equal(t, bars, OHLCSeriesFromOHLCV(series))
+}
+
+func TestOHLCVSet(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	ss.Set("foo", mockOHLCVSeries())
+	assert.Equal(t, ss.store["foo"], mockOHLCVSeries())
+}
+
+func TestOHLCVAppend(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// key not exist
+	ss.Append("foo", mockOHLCVSeries()...)
+	assert.Equal(t, mockOHLCVSeries(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", OHLCV{Open: 1.0, High: 2.0, Low: 0.5, Close: 1.5})
+	assert.Equal(t, len(mockOHLCVSeries())+1, len(ss.store["foo"]))
+	assert.Equal(t, OHLCV{Open: 1.0, High: 2.0, Low: 0.5, Close: 1.5}, ss.store["foo"][len(mockOHLCVSeries())])
+}
+
+func TestOHLCVSetIdx(t *testing.T) {
+	// key not exist
+	ss := NewOHLCVSStore()
+	candle := OHLCV{Open: 109.0, High: 155.0, Low: 46.0, Close: 103.0}
+	candle2 := OHLCV{Open: 103.0, High: 159.0, Low: 44.0, Close: 108.0}
+	err := ss.SetIdx("foo", 1, &candle)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockOHLCVSeries()
+	err = ss.SetIdx("foo", 1, &candle)
+	assert.Nil(t, err)
+	assert.Equal(t, candle, ss.store["foo"][1])
+
+	// last idx
+	err = ss.SetIdx("foo", 2, &candle2)
+	assert.Nil(t, err)
+	assert.Equal(t, candle2, ss.store["foo"][2])
+
+	// out of bounds
+	// lower
+	err = ss.SetIdx("foo", -1, &candle)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	// upper
+	err = ss.SetIdx("foo", 5, &candle)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestOHLCVGet(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no key yet
+	series, ok := ss.Get("foo")
+	assert.False(t, ok)
+
+	// set key
+	ss.store["foo"] = mockOHLCVSeries()
+	series, ok = ss.Get("foo")
+	assert.True(t, ok)
+	assert.Equal(t, series, mockOHLCVSeries())
+}
+
+func TestOHLCVGetIdx(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no key
+	v, err := ss.GetIdx("foo", 1)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockOHLCVSeries()
+	v, err = ss.GetIdx("foo", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, mockOHLCVSeries()[0], v)
+
+	// last idx
+	v, err = ss.GetIdx("foo", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, mockOHLCVSeries()[2], v)
+
+	// out of bounds
+	// lower
+	v, err = ss.GetIdx("foo", -1)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	// upper
+	v, err = ss.GetIdx("foo", 10)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestOHLCVGetRange(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no key
+	rng, err := ss.GetRange("foo", 0, 2)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockOHLCVSeries()
+
+	// full range
+	rng, err = ss.GetRange("foo", 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, mockOHLCVSeries()[0:2], rng)
+
+	// partial range
+	rng, err = ss.GetRange("foo", 0, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, mockOHLCVSeries()[0:1], rng)
+
+	// out of bounds
+	// lower
+	rng, err = ss.GetRange("foo", -1, 3)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	// upper
+	rng, err = ss.GetRange("foo", 0, 10)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestOHLCVClone(t *testing.T) {
+	s := NewOHLCVSStore()
+	s.store["a"] = mockOHLCVSeries()
+	s.store["b"] = mockOHLCVSeries()[:1]
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockOHLCVSeries())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = OHLCV{}
+	assert.Equal(t, mockOHLCVSeries(), s.store["a"])
+}
+
+func TestOHLCVMergeFrom(t *testing.T) {
+	o := NewOHLCVSStore()
+	o.store["b"] = mockOHLCVSeries()[:1]
+	o.store["c"] = mockOHLCVSeries()[:1]
+
+	// incoming values win without a conflict func
+	s := NewOHLCVSStore()
+	s.store["a"] = mockOHLCVSeries()
+	s.store["b"] = mockOHLCVSeries()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]OHLCV{"a": mockOHLCVSeries(), "b": mockOHLCVSeries()[:1], "c": mockOHLCVSeries()[:1]}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewOHLCVSStore()
+	s.store["a"] = mockOHLCVSeries()
+	s.store["b"] = mockOHLCVSeries()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []OHLCV) []OHLCV {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockOHLCVSeries(), current)
+		assert.Equal(t, mockOHLCVSeries()[:1], incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]OHLCV{"a": mockOHLCVSeries(), "b": mockOHLCVSeries(), "c": mockOHLCVSeries()[:1]}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]OHLCV{"b": mockOHLCVSeries()[:1], "c": mockOHLCVSeries()[:1]}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestOHLCVDiff(t *testing.T) {
+	s := NewOHLCVSStore()
+	s.store["a"] = mockOHLCVSeries()
+	s.store["b"] = mockOHLCVSeries()
+	s.store["c"] = mockOHLCVSeries()
+
+	o := NewOHLCVSStore()
+	o.store["b"] = mockOHLCVSeries()
+	o.store["c"] = mockOHLCVSeries()[:1]
+	o.store["d"] = mockOHLCVSeries()[:1]
+	o.store["e"] = mockOHLCVSeries()[:1]
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewOHLCVSStore().Diff(NewOHLCVSStore()).Empty())
+}
+
+func TestOHLCVReplaceAll(t *testing.T) {
+	s := NewOHLCVSStore()
+	s.store["a"] = mockOHLCVSeries()
+	s.EnableKeyIndex()
+
+	values := map[string][]OHLCV{"b": mockOHLCVSeries()[:1], "c": mockOHLCVSeries()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockOHLCVSeries()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
+func TestOHLCVSize(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no keys
+	size := ss.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	ss.store["a"] = mockOHLCVSeries()
+	ss.store["b"] = mockOHLCVSeries()
+
+	size = ss.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestOHLCVMembers(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no keys
+	mems := ss.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	ss.store["a"] = mockOHLCVSeries()
+	ss.store["b"] = mockOHLCVSeries()
+
+	mems = ss.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestOHLCVSortedMembers(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:ask"] = mockOHLCVSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestOHLCVMembersWithPrefix(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	ss.store["MSFT:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:ask"] = mockOHLCVSeries()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestOHLCVMembersMatching(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	ss.store["MSFT:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:ask"] = mockOHLCVSeries()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestOHLCVKeyIndex(t *testing.T) {
+	ss := NewOHLCVSStore()
+	ss.store["foo"] = mockOHLCVSeries()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockOHLCVSeries())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestOHLCVScan(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	ss.store["MSFT:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:bid"] = mockOHLCVSeries()
+	ss.store["AAPL:ask"] = mockOHLCVSeries()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestOHLCVIsMember(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no keys
+	ok := ss.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	ss.store["foo"] = mockOHLCVSeries()
+
+	ok = ss.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestOHLCVMemberLen(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	// no keys
+	length, err := ss.MemberLen("foo")
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// add key
+	ss.store["foo"] = mockOHLCVSeries()
+
+	length, err = ss.MemberLen("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, length)
+}
+
+func TestOHLCVClear(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	ss.store["foo"] = mockOHLCVSeries()
+	assert.Equal(t, 1, len(ss.store))
+
+	ss.Clear()
+	assert.Equal(t, 0, len(ss.store))
+}
+
+func TestOHLCVDigest(t *testing.T) {
+	s := NewOHLCVSStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockOHLCVSeries())
+	s.Set("b", mockOHLCVSeries()[:1])
+
+	// write order does not matter
+	o := NewOHLCVSStore()
+	o.Set("b", mockOHLCVSeries()[:1])
+	o.Set("a", mockOHLCVSeries())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", mockOHLCVSeries()[2])
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", mockOHLCVSeries()[:1])
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestOHLCVEnableDigest(t *testing.T) {
+	s := NewOHLCVSStore()
+	s.Set("a", mockOHLCVSeries())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", mockOHLCVSeries()[2], mockOHLCVSeries()[2])
+	s.Append("b", mockOHLCVSeries()[2])
+	o := NewOHLCVSStore()
+	o.Set("a", append(mockOHLCVSeries(), mockOHLCVSeries()[2], mockOHLCVSeries()[2]))
+	o.Set("b", []OHLCV{mockOHLCVSeries()[2]})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, &mockOHLCVSeries()[2]))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, &mockOHLCVSeries()[2]))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]OHLCV{"a": mockOHLCVSeries()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestOHLCVSyncFrom(t *testing.T) {
+	s := NewOHLCVSStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockOHLCVSeries())
+	}
+	s.Set("b", mockOHLCVSeries()[:1])
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", mockOHLCVSeries()[2])
+	r.ReplaceAll(map[string][]OHLCV{"1": mockOHLCVSeries(), "b": mockOHLCVSeries()[:1], "c": mockOHLCVSeries()[:1]})
+	r.Set("2", mockOHLCVSeries()[:1])
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
+func TestOHLCVConcurrentGetAndSet(t *testing.T) {
+	ss := NewOHLCVSStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Set("foo", mockOHLCVSeries())
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Get("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,589 @@
+package seriesstore
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/skiplist"
+)
+
+// Side is a side of an order book
+type Side int
+
+const (
+	// Bid is the buy side of an order book, best price highest
+	Bid Side = iota
+	// Ask is the sell side of an order book, best price lowest
+	Ask
+)
+
+// PriceLevel is the aggregate size resting at a price of an order book side
+type PriceLevel struct {
+	Price float64
+	Size  float64
+}
+
+// BookSnapshot is a point in time copy of an order book
+// Both sides are ordered best price first
+type BookSnapshot struct {
+	Bids []PriceLevel
+	Asks []PriceLevel
+}
+
+// bookSide keeps the price levels of one side ordered by a skip list
+// Bid levels are scored by negated price so that both sides iterate best price first
+type bookSide struct {
+	levels *skiplist.List
+	sizes  map[float64]float64
+	bid    bool
+}
+
+func newBookSide(bid bool) *bookSide {
+	return &bookSide{levels: skiplist.New(), sizes: make(map[float64]float64), bid: bid}
+}
+
+func (b *bookSide) score(price float64) float64 {
+	if b.bid {
+		return -price
+	}
+
+	return price
+}
+
+func (b *bookSide) price(n *skiplist.Node) float64 {
+	if b.bid {
+		return -n.Score()
+	}
+
+	return n.Score()
+}
+
+func (b *bookSide) set(price, size float64) {
+	if size == 0 {
+		b.delete(price)
+		return
+	}
+
+	if _, ok := b.sizes[price]; !ok {
+		b.levels.Insert(b.score(price), "")
+	}
+	b.sizes[price] = size
+}
+
+func (b *bookSide) delete(price float64) bool {
+	if _, ok := b.sizes[price]; !ok {
+		return false
+	}
+
+	b.levels.Delete(b.score(price), "")
+	delete(b.sizes, price)
+
+	return true
+}
+
+func (b *bookSide) best() (PriceLevel, bool) {
+	n := b.levels.First()
+	if n == nil {
+		return PriceLevel{}, false
+	}
+
+	p := b.price(n)
+
+	return PriceLevel{p, b.sizes[p]}, true
+}
+
+// depth returns up to n levels best price first, all levels if n < 1
+func (b *bookSide) depth(n int) []PriceLevel {
+	if n < 1 || n > b.levels.Len() {
+		n = b.levels.Len()
+	}
+
+	out := make([]PriceLevel, 0, n)
+	for node := b.levels.First(); node != nil && len(out) < n; node = node.Next() {
+		p := b.price(node)
+		out = append(out, PriceLevel{p, b.sizes[p]})
+	}
+
+	return out
+}
+
+// cumulative sums the size of every level priced at or better than price
+func (b *bookSide) cumulative(price float64) float64 {
+	total := 0.0
+	limit := b.score(price)
+	for node := b.levels.First(); node != nil && node.Score() <= limit; node = node.Next() {
+		total += b.sizes[b.price(node)]
+	}
+
+	return total
+}
+
+type orderBook struct {
+	bids *bookSide
+	asks *bookSide
+}
+
+func newOrderBook() *orderBook {
+	return &orderBook{bids: newBookSide(true), asks: newBookSide(false)}
+}
+
+func (o *orderBook) side(side Side) *bookSide {
+	if side == Bid {
+		return o.bids
+	}
+
+	return o.asks
+}
+
+func (o *orderBook) empty() bool {
+	return len(o.bids.sizes) == 0 && len(o.asks.sizes) == 0
+}
+
+// OrderBookStore is a store of L2 limit order books keyed by symbol
+// Each side keeps its price levels sorted, so level updates are O(log n) and best prices O(1)
+// A symbol is deleted when its last price level is removed
+// Embedded sync.Mutex to provide atomic operation ability
+type OrderBookStore struct {
+	sync.Mutex
+	store map[string]*orderBook
+	index *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewOrderBookStore constructs and initializes a new OrderBookStore
+// Always use this function to init new OrderBookStores
+func NewOrderBookStore() *OrderBookStore {
+	return &OrderBookStore{store: make(map[string]*orderBook)}
+}
+
+func validPrice(price float64) bool {
+	return price > 0 && !math.IsInf(price, 1)
+}
+
+func validSize(size float64) bool {
+	return size >= 0 && !math.IsInf(size, 1)
+}
+
+func (s *OrderBookStore) setLevel(symbol string, side Side, price, size float64) {
+	o, ok := s.store[symbol]
+	if !ok {
+		if size == 0 {
+			return
+		}
+		o = newOrderBook()
+		s.store[symbol] = o
+		s.index.Insert(symbol)
+	}
+
+	o.side(side).set(price, size)
+
+	if o.empty() {
+		delete(s.store, symbol)
+		s.index.Delete(symbol)
+	}
+}
+
+// SetLevel adds or replaces the size resting at price on the given side of the book of symbol,
+// creating the book if it does not exist
+// A size of 0 deletes the level, as sent by most L2 feeds
+// returns ErrInvalidPrice or ErrInvalidSize for non positive prices and negative sizes
+func (s *OrderBookStore) SetLevel(symbol string, side Side, price, size float64) error {
+	if !validPrice(price) {
+		return ErrInvalidPrice
+	}
+	if !validSize(size) {
+		return ErrInvalidSize
+	}
+
+	s.Lock()
+	s.setLevel(symbol, side, price, size)
+	s.Unlock()
+
+	return nil
+}
+
+func (s *OrderBookStore) modifyLevel(symbol string, side Side, price, delta float64) (float64, error) {
+	o, ok := s.store[symbol]
+
+	// check exists
+	if !ok {
+		return 0.0, ErrKeyDoesNotExist
+	}
+
+	b := o.side(side)
+	size, ok := b.sizes[price]
+	if !ok {
+		return 0.0, ErrLevelDoesNotExist
+	}
+
+	size += delta
+	if math.IsNaN(size) || math.IsInf(size, 1) {
+		return 0.0, ErrInvalidSize
+	}
+	if size < 0 {
+		size = 0
+	}
+
+	b.set(price, size)
+
+	if o.empty() {
+		delete(s.store, symbol)
+		s.index.Delete(symbol)
+	}
+
+	return size, nil
+}
+
+// ModifyLevel atomically adds delta to the size resting at price on the given side of the book of symbol,
+// e.g. a negative delta for a partial fill
+// A level whose size drops to 0 or below is deleted
+// returns the new size, or ErrLevelDoesNotExist if there is no level at price
+func (s *OrderBookStore) ModifyLevel(symbol string, side Side, price, delta float64) (float64, error) {
+	s.Lock()
+	size, err := s.modifyLevel(symbol, side, price, delta)
+	s.Unlock()
+
+	return size, err
+}
+
+func (s *OrderBookStore) deleteLevel(symbol string, side Side, price float64) bool {
+	o, ok := s.store[symbol]
+	if !ok {
+		return false
+	}
+
+	ok = o.side(side).delete(price)
+
+	if o.empty() {
+		delete(s.store, symbol)
+		s.index.Delete(symbol)
+	}
+
+	return ok
+}
+
+// DeleteLevel removes the level at price on the given side of the book of symbol
+// returns true if the level existed
+func (s *OrderBookStore) DeleteLevel(symbol string, side Side, price float64) bool {
+	s.Lock()
+	ok := s.deleteLevel(symbol, side, price)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *OrderBookStore) best(symbol string, side Side) (PriceLevel, bool) {
+	o, ok := s.store[symbol]
+	if !ok {
+		return PriceLevel{}, false
+	}
+
+	return o.side(side).best()
+}
+
+// BestBid returns the highest bid level of the book of symbol
+// returns the level and boolean if the bid side is not empty
+func (s *OrderBookStore) BestBid(symbol string) (PriceLevel, bool) {
+	s.Lock()
+	v, ok := s.best(symbol, Bid)
+	s.Unlock()
+
+	return v, ok
+}
+
+// BestAsk returns the lowest ask level of the book of symbol
+// returns the level and boolean if the ask side is not empty
+func (s *OrderBookStore) BestAsk(symbol string) (PriceLevel, bool) {
+	s.Lock()
+	v, ok := s.best(symbol, Ask)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *OrderBookStore) touch(symbol string) (float64, float64, error) {
+	o, ok := s.store[symbol]
+
+	// check exists
+	if !ok {
+		return 0.0, 0.0, ErrKeyDoesNotExist
+	}
+
+	bid, ok := o.bids.best()
+	if !ok {
+		return 0.0, 0.0, ErrEmptySide
+	}
+	ask, ok := o.asks.best()
+	if !ok {
+		return 0.0, 0.0, ErrEmptySide
+	}
+
+	return bid.Price, ask.Price, nil
+}
+
+// Spread returns the best ask price minus the best bid price of the book of symbol
+// returns ErrEmptySide if either side has no levels
+func (s *OrderBookStore) Spread(symbol string) (float64, error) {
+	s.Lock()
+	bid, ask, err := s.touch(symbol)
+	s.Unlock()
+
+	return ask - bid, err
+}
+
+// Mid returns the midpoint of the best bid and ask prices of the book of symbol
+// returns ErrEmptySide if either side has no levels
+func (s *OrderBookStore) Mid(symbol string) (float64, error) {
+	s.Lock()
+	bid, ask, err := s.touch(symbol)
+	s.Unlock()
+
+	return (bid + ask) / 2, err
+}
+
+func (s *OrderBookStore) depthN(symbol string, n int) (BookSnapshot, error) {
+	o, ok := s.store[symbol]
+
+	// check exists
+	if !ok {
+		return BookSnapshot{}, ErrKeyDoesNotExist
+	}
+
+	return BookSnapshot{Bids: o.bids.depth(n), Asks: o.asks.depth(n)}, nil
+}
+
+// DepthN returns a consistent copy of the best n levels of each side of the book of symbol
+func (s *OrderBookStore) DepthN(symbol string, n int) (BookSnapshot, error) {
+	s.Lock()
+	v, err := s.depthN(symbol, n)
+	s.Unlock()
+
+	return v, err
+}
+
+// Snapshot returns a consistent copy of every level of the book of symbol
+func (s *OrderBookStore) Snapshot(symbol string) (BookSnapshot, error) {
+	s.Lock()
+	v, err := s.depthN(symbol, 0)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *OrderBookStore) cumulativeSize(symbol string, side Side, price float64) (float64, error) {
+	o, ok := s.store[symbol]
+
+	// check exists
+	if !ok {
+		return 0.0, ErrKeyDoesNotExist
+	}
+
+	return o.side(side).cumulative(price), nil
+}
+
+// CumulativeSize returns the total size on the given side of the book of symbol priced at or better than price,
+// i.e. bids at or above price and asks at or below price
+// This is the size a marketable order limited at price could fill against
+func (s *OrderBookStore) CumulativeSize(symbol string, side Side, price float64) (float64, error) {
+	s.Lock()
+	v, err := s.cumulativeSize(symbol, side, price)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *OrderBookStore) levels(symbol string, side Side) (int, error) {
+	o, ok := s.store[symbol]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return len(o.side(side).sizes), nil
+}
+
+// Levels returns the number of price levels on the given side of the book of symbol
+func (s *OrderBookStore) Levels(symbol string, side Side) (int, error) {
+	s.Lock()
+	n, err := s.levels(symbol, side)
+	s.Unlock()
+
+	return n, err
+}
+
+func (s *OrderBookStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *OrderBookStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *OrderBookStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *OrderBookStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *OrderBookStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *OrderBookStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *OrderBookStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *OrderBookStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *OrderBookStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *OrderBookStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *OrderBookStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *OrderBookStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *OrderBookStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *OrderBookStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *OrderBookStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *OrderBookStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *OrderBookStore) clear() {
+	s.store = make(map[string]*orderBook)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *OrderBookStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,414 @@
+package seriesstore
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockOrderBook() *orderBook {
+	o := newOrderBook()
+	o.bids.set(99.5, 300)
+	o.bids.set(100, 100)
+	o.bids.set(99.75, 200)
+	o.asks.set(100.5, 150)
+	o.asks.set(100.25, 50)
+	o.asks.set(101, 400)
+
+	return o
+}
+
+func TestOrderBookSetLevel(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// invalid
+	assert.Equal(t, ErrInvalidPrice, obs.SetLevel("AAPL", Bid, 0, 100))
+	assert.Equal(t, ErrInvalidPrice, obs.SetLevel("AAPL", Bid, math.NaN(), 100))
+	assert.Equal(t, ErrInvalidSize, obs.SetLevel("AAPL", Bid, 100, -1))
+	assert.Equal(t, ErrInvalidSize, obs.SetLevel("AAPL", Bid, 100, math.NaN()))
+	assert.False(t, obs.isMember("AAPL"))
+
+	// zero size on missing book does not create it
+	assert.Nil(t, obs.SetLevel("AAPL", Bid, 100, 0))
+	assert.False(t, obs.isMember("AAPL"))
+
+	// add
+	assert.Nil(t, obs.SetLevel("AAPL", Bid, 100, 100))
+	assert.Nil(t, obs.SetLevel("AAPL", Ask, 101, 200))
+	assert.Equal(t, 100.0, obs.store["AAPL"].bids.sizes[100])
+	assert.Equal(t, 200.0, obs.store["AAPL"].asks.sizes[101])
+
+	// replace
+	assert.Nil(t, obs.SetLevel("AAPL", Bid, 100, 50))
+	assert.Equal(t, 50.0, obs.store["AAPL"].bids.sizes[100])
+	assert.Equal(t, 1, obs.store["AAPL"].bids.levels.Len())
+
+	// zero size deletes, last level deletes key
+	assert.Nil(t, obs.SetLevel("AAPL", Bid, 100, 0))
+	assert.Equal(t, 0, obs.store["AAPL"].bids.levels.Len())
+	assert.Nil(t, obs.SetLevel("AAPL", Ask, 101, 0))
+	assert.False(t, obs.isMember("AAPL"))
+}
+
+func TestOrderBookModifyLevel(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, err := obs.ModifyLevel("AAPL", Bid, 100, 10)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	obs.store["AAPL"] = mockOrderBook()
+
+	// no level
+	_, err = obs.ModifyLevel("AAPL", Bid, 98, 10)
+	assert.Equal(t, ErrLevelDoesNotExist, err)
+
+	size, err := obs.ModifyLevel("AAPL", Bid, 100, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, 125.0, size)
+
+	size, err = obs.ModifyLevel("AAPL", Ask, 100.25, -20)
+	assert.Nil(t, err)
+	assert.Equal(t, 30.0, size)
+
+	// invalid
+	_, err = obs.ModifyLevel("AAPL", Ask, 100.25, math.NaN())
+	assert.Equal(t, ErrInvalidSize, err)
+
+	// filled through deletes level
+	size, err = obs.ModifyLevel("AAPL", Ask, 100.25, -50)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, size)
+	_, ok := obs.store["AAPL"].asks.sizes[100.25]
+	assert.False(t, ok)
+}
+
+func TestOrderBookDeleteLevel(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	assert.False(t, obs.DeleteLevel("AAPL", Bid, 100))
+
+	obs.store["AAPL"] = newOrderBook()
+	obs.store["AAPL"].bids.set(100, 100)
+
+	// no level
+	assert.False(t, obs.DeleteLevel("AAPL", Bid, 99))
+	assert.False(t, obs.DeleteLevel("AAPL", Ask, 100))
+
+	// last level deletes key
+	assert.True(t, obs.DeleteLevel("AAPL", Bid, 100))
+	assert.False(t, obs.isMember("AAPL"))
+}
+
+func TestOrderBookBest(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, ok := obs.BestBid("AAPL")
+	assert.False(t, ok)
+	_, ok = obs.BestAsk("AAPL")
+	assert.False(t, ok)
+
+	obs.store["AAPL"] = mockOrderBook()
+
+	bid, ok := obs.BestBid("AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, PriceLevel{100, 100}, bid)
+
+	ask, ok := obs.BestAsk("AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, PriceLevel{100.25, 50}, ask)
+
+	// empty side
+	obs.store["MSFT"] = newOrderBook()
+	obs.store["MSFT"].bids.set(50, 10)
+	_, ok = obs.BestAsk("MSFT")
+	assert.False(t, ok)
+}
+
+func TestOrderBookSpreadAndMid(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, err := obs.Spread("AAPL")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = obs.Mid("AAPL")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	obs.store["AAPL"] = mockOrderBook()
+
+	spread, err := obs.Spread("AAPL")
+	assert.Nil(t, err)
+	assert.Equal(t, 0.25, spread)
+
+	mid, err := obs.Mid("AAPL")
+	assert.Nil(t, err)
+	assert.Equal(t, 100.125, mid)
+
+	// empty side
+	obs.store["MSFT"] = newOrderBook()
+	obs.store["MSFT"].asks.set(50, 10)
+	_, err = obs.Spread("MSFT")
+	assert.Equal(t, ErrEmptySide, err)
+	_, err = obs.Mid("MSFT")
+	assert.Equal(t, ErrEmptySide, err)
+}
+
+func TestOrderBookDepthN(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, err := obs.DepthN("AAPL", 2)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	obs.store["AAPL"] = mockOrderBook()
+
+	book, err := obs.DepthN("AAPL", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []PriceLevel{{100, 100}, {99.75, 200}}, book.Bids)
+	assert.Equal(t, []PriceLevel{{100.25, 50}, {100.5, 150}}, book.Asks)
+
+	// more than available
+	book, err = obs.DepthN("AAPL", 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(book.Bids))
+	assert.Equal(t, 3, len(book.Asks))
+}
+
+func TestOrderBookSnapshot(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, err := obs.Snapshot("AAPL")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	obs.store["AAPL"] = mockOrderBook()
+
+	book, err := obs.Snapshot("AAPL")
+	assert.Nil(t, err)
+	assert.Equal(t, []PriceLevel{{100, 100}, {99.75, 200}, {99.5, 300}}, book.Bids)
+	assert.Equal(t, []PriceLevel{{100.25, 50}, {100.5, 150}, {101, 400}}, book.Asks)
+
+	// snapshot is a copy
+	book.Bids[0].Size = 1
+	assert.Equal(t, 100.0, obs.store["AAPL"].bids.sizes[100])
+}
+
+func TestOrderBookCumulativeSize(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, err := obs.CumulativeSize("AAPL", Bid, 100)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	obs.store["AAPL"] = mockOrderBook()
+
+	// bids at or above price
+	v, err := obs.CumulativeSize("AAPL", Bid, 99.75)
+	assert.Nil(t, err)
+	assert.Equal(t, 300.0, v)
+	v, _ = obs.CumulativeSize("AAPL", Bid, 101)
+	assert.Equal(t, 0.0, v)
+	v, _ = obs.CumulativeSize("AAPL", Bid, 1)
+	assert.Equal(t, 600.0, v)
+
+	// asks at or below price
+	v, err = obs.CumulativeSize("AAPL", Ask, 100.5)
+	assert.Nil(t, err)
+	assert.Equal(t, 200.0, v)
+	v, _ = obs.CumulativeSize("AAPL", Ask, 100)
+	assert.Equal(t, 0.0, v)
+}
+
+func TestOrderBookLevels(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no key
+	_, err := obs.Levels("AAPL", Bid)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	obs.store["AAPL"] = mockOrderBook()
+	obs.store["AAPL"].asks.set(102, 1)
+
+	n, err := obs.Levels("AAPL", Bid)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	n, _ = obs.Levels("AAPL", Ask)
+	assert.Equal(t, 4, n)
+}
+
+func TestOrderBookSize(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no keys
+	size := obs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	obs.store["a"] = mockOrderBook()
+	obs.store["b"] = mockOrderBook()
+
+	size = obs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestOrderBookMembers(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no keys
+	mems := obs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	obs.store["a"] = mockOrderBook()
+	obs.store["b"] = mockOrderBook()
+
+	mems = obs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestOrderBookSortedMembers(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no keys
+	assert.Equal(t, []string{}, obs.SortedMembers())
+
+	obs.store["MSFT:bid"] = mockOrderBook()
+	obs.store["AAPL:bid"] = mockOrderBook()
+	obs.store["AAPL:ask"] = mockOrderBook()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, obs.SortedMembers())
+
+	// indexed
+	obs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, obs.SortedMembers())
+}
+
+func TestOrderBookMembersWithPrefix(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	obs.store["MSFT:bid"] = mockOrderBook()
+	obs.store["AAPL:bid"] = mockOrderBook()
+	obs.store["AAPL:ask"] = mockOrderBook()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, obs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, obs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	obs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, obs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, obs.MembersWithPrefix("GOOG"))
+}
+
+func TestOrderBookMembersMatching(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	obs.store["MSFT:bid"] = mockOrderBook()
+	obs.store["AAPL:bid"] = mockOrderBook()
+	obs.store["AAPL:ask"] = mockOrderBook()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, obs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, obs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, obs.MembersMatching("GOOG:*"))
+
+	// indexed
+	obs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, obs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, obs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, obs.MembersMatching("GOOG:*"))
+}
+
+func TestOrderBookKeyIndex(t *testing.T) {
+	obs := NewOrderBookStore()
+	obs.store["foo"] = mockOrderBook()
+
+	// existing keys are indexed
+	obs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, obs.index.Keys())
+
+	// created keys are indexed
+	obs.SetLevel("bar", Bid, 100, 1)
+	assert.Equal(t, []string{"bar", "foo"}, obs.index.Keys())
+
+	// deleted keys are unindexed
+	obs.DeleteLevel("bar", Bid, 100)
+	assert.Equal(t, []string{"foo"}, obs.index.Keys())
+
+	// clear
+	obs.Clear()
+	assert.Equal(t, 0, obs.index.Len())
+}
+
+func TestOrderBookScan(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	obs.store["MSFT:bid"] = mockOrderBook()
+	obs.store["AAPL:bid"] = mockOrderBook()
+	obs.store["AAPL:ask"] = mockOrderBook()
+
+	keys, next := obs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = obs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = obs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, obs.index)
+
+	// no keys
+	obs.Clear()
+	keys, next = obs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestOrderBookIsMember(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	// no keys
+	ok := obs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	obs.store["foo"] = mockOrderBook()
+
+	ok = obs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestOrderBookClear(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	obs.store["foo"] = mockOrderBook()
+	assert.Equal(t, 1, len(obs.store))
+
+	obs.Clear()
+	assert.Equal(t, 0, len(obs.store))
+}
+
+func TestOrderBookConcurrentSetAndSnapshot(t *testing.T) {
+	obs := NewOrderBookStore()
+
+	go func() {
+		for i := 1; i <= 100; i++ {
+			obs.SetLevel("foo", Bid, float64(i), float64(i))
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			obs.Snapshot("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
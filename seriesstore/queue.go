@@ -0,0 +1,506 @@
+package seriesstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// deque is a growable ring buffer supporting O(1) amortized pushes and pops at both ends
+type deque struct {
+	buf   []interface{}
+	head  int
+	count int
+}
+
+func (q *deque) grow() {
+	size := len(q.buf) * 2
+	if size == 0 {
+		size = 8
+	}
+
+	buf := make([]interface{}, size)
+	for i := 0; i < q.count; i++ {
+		buf[i] = q.buf[(q.head+i)%len(q.buf)]
+	}
+	q.buf = buf
+	q.head = 0
+}
+
+func (q *deque) pushBack(v interface{}) {
+	if q.count == len(q.buf) {
+		q.grow()
+	}
+	q.buf[(q.head+q.count)%len(q.buf)] = v
+	q.count++
+}
+
+func (q *deque) pushFront(v interface{}) {
+	if q.count == len(q.buf) {
+		q.grow()
+	}
+	q.head = (q.head - 1 + len(q.buf)) % len(q.buf)
+	q.buf[q.head] = v
+	q.count++
+}
+
+func (q *deque) front() interface{} {
+	return q.buf[q.head]
+}
+
+func (q *deque) back() interface{} {
+	return q.buf[(q.head+q.count-1)%len(q.buf)]
+}
+
+func (q *deque) popFront() interface{} {
+	v := q.buf[q.head]
+	// release the reference for the gc
+	q.buf[q.head] = nil
+	q.head = (q.head + 1) % len(q.buf)
+	q.count--
+
+	return v
+}
+
+func (q *deque) popBack() interface{} {
+	i := (q.head + q.count - 1) % len(q.buf)
+	v := q.buf[i]
+	// release the reference for the gc
+	q.buf[i] = nil
+	q.count--
+
+	return v
+}
+
+// QueueStore is a store of double ended queues
+// Each key holds a FIFO queue, e.g. of order events, that may also be used as a deque or stack
+// Blocking pops wait for a value to be pushed, until cancelled by their context or timed out
+// A key is deleted when its last value is popped
+// Embedded sync.Mutex to provide atomic operation ability
+type QueueStore struct {
+	sync.Mutex
+	store   map[string]*deque
+	index   *keyindex.Index        // ordered keys, nil until EnableKeyIndex or Scan is called
+	waiters map[string]*keyWaiters // blocked pops of each key
+}
+
+// keyWaiters are the blocked pops of a key, woken together by closing ch on the next push
+type keyWaiters struct {
+	ch chan struct{}
+	n  int // number of pops waiting on ch, the entry is deleted when the last gives up
+}
+
+// NewQueueStore constructs and initializes a new QueueStore
+// Always use this function to init new QueueStores
+func NewQueueStore() *QueueStore {
+	return &QueueStore{store: make(map[string]*deque), waiters: make(map[string]*keyWaiters)}
+}
+
+func (s *QueueStore) queue(key string) *deque {
+	q, ok := s.store[key]
+	if !ok {
+		q = &deque{}
+		s.store[key] = q
+		s.index.Insert(key)
+	}
+
+	return q
+}
+
+// notify wakes every blocked pop waiting on the given key
+func (s *QueueStore) notify(key string) {
+	if w, ok := s.waiters[key]; ok {
+		close(w.ch)
+		delete(s.waiters, key)
+	}
+}
+
+func (s *QueueStore) pushBack(key string, values ...interface{}) {
+	if len(values) == 0 {
+		return
+	}
+
+	q := s.queue(key)
+	for _, v := range values {
+		q.pushBack(v)
+	}
+
+	s.notify(key)
+}
+
+// PushBack appends the given values to the back of the queue of the given key, in order
+// The key is created if it does not exist
+func (s *QueueStore) PushBack(key string, values ...interface{}) {
+	s.Lock()
+	s.pushBack(key, values...)
+	s.Unlock()
+}
+
+func (s *QueueStore) pushFront(key string, values ...interface{}) {
+	if len(values) == 0 {
+		return
+	}
+
+	q := s.queue(key)
+	for _, v := range values {
+		q.pushFront(v)
+	}
+
+	s.notify(key)
+}
+
+// PushFront prepends the given values to the front of the queue of the given key, one at a time,
+// so the last value given ends up at the front
+// The key is created if it does not exist
+func (s *QueueStore) PushFront(key string, values ...interface{}) {
+	s.Lock()
+	s.pushFront(key, values...)
+	s.Unlock()
+}
+
+func (s *QueueStore) pop(key string, front bool) (interface{}, bool) {
+	q, ok := s.store[key]
+	if !ok {
+		return nil, false
+	}
+
+	var v interface{}
+	if front {
+		v = q.popFront()
+	} else {
+		v = q.popBack()
+	}
+
+	if q.count == 0 {
+		delete(s.store, key)
+		s.index.Delete(key)
+	}
+
+	return v, true
+}
+
+// PopFront removes and returns the value at the front of the queue of the given key
+// returns the value and boolean if the queue was not empty
+func (s *QueueStore) PopFront(key string) (interface{}, bool) {
+	s.Lock()
+	v, ok := s.pop(key, true)
+	s.Unlock()
+
+	return v, ok
+}
+
+// PopBack removes and returns the value at the back of the queue of the given key
+// returns the value and boolean if the queue was not empty
+func (s *QueueStore) PopBack(key string) (interface{}, bool) {
+	s.Lock()
+	v, ok := s.pop(key, false)
+	s.Unlock()
+
+	return v, ok
+}
+
+// cancelWait removes a pop that gave up waiting on w, so waiters of idle keys are not kept forever
+func (s *QueueStore) cancelWait(key string, w *keyWaiters) {
+	// w was already woken and removed if a push raced the cancel
+	if s.waiters[key] != w {
+		return
+	}
+
+	w.n--
+	if w.n == 0 {
+		delete(s.waiters, key)
+	}
+}
+
+func (s *QueueStore) blockingPop(ctx context.Context, key string, front bool) (interface{}, error) {
+	for {
+		s.Lock()
+		v, ok := s.pop(key, front)
+		if ok {
+			s.Unlock()
+			return v, nil
+		}
+
+		w, ok := s.waiters[key]
+		if !ok {
+			w = &keyWaiters{ch: make(chan struct{})}
+			s.waiters[key] = w
+		}
+		w.n++
+		s.Unlock()
+
+		// another waiter may win the value, so retry after every push
+		select {
+		case <-w.ch:
+		case <-ctx.Done():
+			s.Lock()
+			s.cancelWait(key, w)
+			s.Unlock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// BlockingPopFront removes and returns the value at the front of the queue of the given key,
+// waiting for a value to be pushed if the queue is empty
+// returns the context error if ctx is done first
+func (s *QueueStore) BlockingPopFront(ctx context.Context, key string) (interface{}, error) {
+	return s.blockingPop(ctx, key, true)
+}
+
+// BlockingPopBack removes and returns the value at the back of the queue of the given key,
+// waiting for a value to be pushed if the queue is empty
+// returns the context error if ctx is done first
+func (s *QueueStore) BlockingPopBack(ctx context.Context, key string) (interface{}, error) {
+	return s.blockingPop(ctx, key, false)
+}
+
+// PopFrontTimeout is BlockingPopFront waiting at most timeout
+// returns context.DeadlineExceeded if no value was pushed in time
+func (s *QueueStore) PopFrontTimeout(key string, timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	v, err := s.blockingPop(ctx, key, true)
+	cancel()
+
+	return v, err
+}
+
+// PopBackTimeout is BlockingPopBack waiting at most timeout
+// returns context.DeadlineExceeded if no value was pushed in time
+func (s *QueueStore) PopBackTimeout(key string, timeout time.Duration) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	v, err := s.blockingPop(ctx, key, false)
+	cancel()
+
+	return v, err
+}
+
+func (s *QueueStore) peek(key string, front bool) (interface{}, bool) {
+	q, ok := s.store[key]
+	if !ok {
+		return nil, false
+	}
+
+	if front {
+		return q.front(), true
+	}
+
+	return q.back(), true
+}
+
+// PeekFront returns the value at the front of the queue of the given key without removing it
+// returns the value and boolean if the queue is not empty
+func (s *QueueStore) PeekFront(key string) (interface{}, bool) {
+	s.Lock()
+	v, ok := s.peek(key, true)
+	s.Unlock()
+
+	return v, ok
+}
+
+// PeekBack returns the value at the back of the queue of the given key without removing it
+// returns the value and boolean if the queue is not empty
+func (s *QueueStore) PeekBack(key string) (interface{}, bool) {
+	s.Lock()
+	v, ok := s.peek(key, false)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *QueueStore) length(key string) int {
+	q, ok := s.store[key]
+	if !ok {
+		return 0
+	}
+
+	return q.count
+}
+
+// Len returns the number of values in the queue of the given key, 0 if the key does not exist
+func (s *QueueStore) Len(key string) int {
+	s.Lock()
+	l := s.length(key)
+	s.Unlock()
+
+	return l
+}
+
+func (s *QueueStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *QueueStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *QueueStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *QueueStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *QueueStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *QueueStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *QueueStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *QueueStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *QueueStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *QueueStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *QueueStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *QueueStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *QueueStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *QueueStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *QueueStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *QueueStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *QueueStore) clear() {
+	s.store = make(map[string]*deque)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+// Blocked pops keep waiting for the next push
+func (s *QueueStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,429 @@
+package seriesstore
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockQueue(values ...interface{}) *deque {
+	q := &deque{}
+	for _, v := range values {
+		q.pushBack(v)
+	}
+
+	return q
+}
+
+// drain pops every value from the front of q
+func drain(q *deque) []interface{} {
+	out := make([]interface{}, 0)
+	for q.count > 0 {
+		out = append(out, q.popFront())
+	}
+
+	return out
+}
+
+func TestDequeGrowWrapped(t *testing.T) {
+	q := &deque{}
+
+	// wrap the head around before growing
+	for i := 0; i < 6; i++ {
+		q.pushBack(i)
+	}
+	q.popFront()
+	q.popFront()
+	for i := 6; i < 12; i++ {
+		q.pushBack(i)
+	}
+	q.pushFront(1)
+
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, drain(q))
+}
+
+func TestQueuePushBack(t *testing.T) {
+	qs := NewQueueStore()
+
+	qs.PushBack("orders", 1, 2)
+	qs.PushBack("orders", 3)
+	assert.Equal(t, []interface{}{1, 2, 3}, drain(qs.store["orders"]))
+
+	// no values
+	qs.PushBack("fills")
+	assert.False(t, qs.isMember("fills"))
+}
+
+func TestQueuePushFront(t *testing.T) {
+	qs := NewQueueStore()
+
+	qs.PushFront("orders", 1, 2)
+	qs.PushFront("orders", 3)
+	assert.Equal(t, []interface{}{3, 2, 1}, drain(qs.store["orders"]))
+
+	// no values
+	qs.PushFront("fills")
+	assert.False(t, qs.isMember("fills"))
+}
+
+func TestQueuePopFront(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no key
+	_, ok := qs.PopFront("orders")
+	assert.False(t, ok)
+
+	qs.store["orders"] = mockQueue("a", "b")
+	v, ok := qs.PopFront("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "a", v)
+
+	// last value deletes key
+	v, ok = qs.PopFront("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "b", v)
+	assert.False(t, qs.isMember("orders"))
+}
+
+func TestQueuePopBack(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no key
+	_, ok := qs.PopBack("orders")
+	assert.False(t, ok)
+
+	qs.store["orders"] = mockQueue("a", "b")
+	v, ok := qs.PopBack("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "b", v)
+
+	v, ok = qs.PopBack("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "a", v)
+	assert.False(t, qs.isMember("orders"))
+}
+
+func TestQueuePeek(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no key
+	_, ok := qs.PeekFront("orders")
+	assert.False(t, ok)
+	_, ok = qs.PeekBack("orders")
+	assert.False(t, ok)
+
+	qs.store["orders"] = mockQueue("a", "b", "c")
+	v, ok := qs.PeekFront("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "a", v)
+
+	v, ok = qs.PeekBack("orders")
+	assert.True(t, ok)
+	assert.Equal(t, "c", v)
+
+	// peek does not remove
+	assert.Equal(t, 3, qs.store["orders"].count)
+}
+
+func TestQueueLen(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no key
+	assert.Equal(t, 0, qs.Len("orders"))
+
+	qs.store["orders"] = mockQueue("a", "b", "c")
+	assert.Equal(t, 3, qs.Len("orders"))
+}
+
+func TestQueueBlockingPopFront(t *testing.T) {
+	qs := NewQueueStore()
+
+	// available immediately
+	qs.store["orders"] = mockQueue("a")
+	v, err := qs.BlockingPopFront(context.Background(), "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", v)
+
+	// waits for push
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		qs.PushBack("orders", "b", "c")
+	}()
+
+	v, err = qs.BlockingPopFront(context.Background(), "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", v)
+}
+
+func TestQueueBlockingPopBack(t *testing.T) {
+	qs := NewQueueStore()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		qs.PushFront("orders", "a", "b")
+	}()
+
+	v, err := qs.BlockingPopBack(context.Background(), "orders")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", v)
+}
+
+func TestQueueBlockingPopCancel(t *testing.T) {
+	qs := NewQueueStore()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := qs.BlockingPopFront(ctx, "orders")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestQueueBlockingPopCancelRemovesWaiter(t *testing.T) {
+	qs := NewQueueStore()
+
+	// timed out pops on an idle key leave nothing behind
+	for i := 0; i < 10; i++ {
+		_, err := qs.PopFrontTimeout("orders", time.Millisecond)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	}
+	assert.Equal(t, 0, len(qs.waiters))
+
+	// a cancelled pop does not drop another waiting on the same key
+	res := make(chan interface{})
+	go func() {
+		v, _ := qs.BlockingPopFront(context.Background(), "orders")
+		res <- v
+	}()
+
+	_, err := qs.PopFrontTimeout("orders", 50*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	qs.PushBack("orders", "a")
+	assert.Equal(t, "a", <-res)
+	assert.Equal(t, 0, len(qs.waiters))
+}
+
+func TestQueuePopTimeout(t *testing.T) {
+	qs := NewQueueStore()
+
+	_, err := qs.PopFrontTimeout("orders", 20*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	_, err = qs.PopBackTimeout("orders", 20*time.Millisecond)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	qs.store["orders"] = mockQueue("a", "b")
+	v, err := qs.PopFrontTimeout("orders", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", v)
+	v, err = qs.PopBackTimeout("orders", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", v)
+}
+
+func TestQueueBlockingPopManyWaiters(t *testing.T) {
+	qs := NewQueueStore()
+
+	var wg sync.WaitGroup
+	got := make(chan interface{}, 10)
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func() {
+			v, err := qs.PopFrontTimeout("orders", 5*time.Second)
+			assert.Nil(t, err)
+			got <- v
+			wg.Done()
+		}()
+	}
+
+	// every waiter receives exactly one value
+	for i := 0; i < 10; i++ {
+		qs.PushBack("orders", i)
+	}
+	wg.Wait()
+	close(got)
+
+	seen := make([]interface{}, 0)
+	for v := range got {
+		seen = append(seen, v)
+	}
+	assert.ElementsMatch(t, []interface{}{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, seen)
+}
+
+func TestQueueSize(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no keys
+	size := qs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	qs.store["a"] = mockQueue(1)
+	qs.store["b"] = mockQueue(1)
+
+	size = qs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestQueueMembers(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no keys
+	mems := qs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	qs.store["a"] = mockQueue(1)
+	qs.store["b"] = mockQueue(1)
+
+	mems = qs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestQueueSortedMembers(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no keys
+	assert.Equal(t, []string{}, qs.SortedMembers())
+
+	qs.store["MSFT:bid"] = mockQueue(1)
+	qs.store["AAPL:bid"] = mockQueue(1)
+	qs.store["AAPL:ask"] = mockQueue(1)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, qs.SortedMembers())
+
+	// indexed
+	qs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, qs.SortedMembers())
+}
+
+func TestQueueMembersWithPrefix(t *testing.T) {
+	qs := NewQueueStore()
+
+	qs.store["MSFT:bid"] = mockQueue(1)
+	qs.store["AAPL:bid"] = mockQueue(1)
+	qs.store["AAPL:ask"] = mockQueue(1)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, qs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, qs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	qs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, qs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, qs.MembersWithPrefix("GOOG"))
+}
+
+func TestQueueMembersMatching(t *testing.T) {
+	qs := NewQueueStore()
+
+	qs.store["MSFT:bid"] = mockQueue(1)
+	qs.store["AAPL:bid"] = mockQueue(1)
+	qs.store["AAPL:ask"] = mockQueue(1)
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, qs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, qs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, qs.MembersMatching("GOOG:*"))
+
+	// indexed
+	qs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, qs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, qs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, qs.MembersMatching("GOOG:*"))
+}
+
+func TestQueueKeyIndex(t *testing.T) {
+	qs := NewQueueStore()
+	qs.store["foo"] = mockQueue(1)
+
+	// existing keys are indexed
+	qs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, qs.index.Keys())
+
+	// created keys are indexed
+	qs.PushBack("bar", 1)
+	assert.Equal(t, []string{"bar", "foo"}, qs.index.Keys())
+
+	// deleted keys are unindexed
+	qs.PopFront("bar")
+	assert.Equal(t, []string{"foo"}, qs.index.Keys())
+
+	// clear
+	qs.Clear()
+	assert.Equal(t, 0, qs.index.Len())
+}
+
+func TestQueueScan(t *testing.T) {
+	qs := NewQueueStore()
+
+	qs.store["MSFT:bid"] = mockQueue(1)
+	qs.store["AAPL:bid"] = mockQueue(1)
+	qs.store["AAPL:ask"] = mockQueue(1)
+
+	keys, next := qs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = qs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = qs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, qs.index)
+
+	// no keys
+	qs.Clear()
+	keys, next = qs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestQueueIsMember(t *testing.T) {
+	qs := NewQueueStore()
+
+	// no keys
+	ok := qs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	qs.store["foo"] = mockQueue(1)
+
+	ok = qs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestQueueClear(t *testing.T) {
+	qs := NewQueueStore()
+
+	qs.store["foo"] = mockQueue(1)
+	assert.Equal(t, 1, len(qs.store))
+
+	qs.Clear()
+	assert.Equal(t, 0, len(qs.store))
+}
+
+func TestQueueConcurrentPushAndPop(t *testing.T) {
+	qs := NewQueueStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			qs.PushBack("foo", i)
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			qs.PopFront("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
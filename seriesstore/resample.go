@@ -0,0 +1,248 @@
+package seriesstore
+
+import (
+	"time"
+)
+
+// ResampleOptions describes the input bars of a resample and the boundaries of the output bars
+type ResampleOptions struct {
+	// Start is the open time of the first input bar
+	Start time.Time
+	// Source is the interval of the input bars, which are consecutive from Start
+	Source time.Duration
+	// Target is the interval of the output bars
+	Target time.Duration
+	// Location aligns output boundaries to its wall clock, UTC if nil
+	Location *time.Location
+	// Offset shifts output boundaries from wall clock multiples of Target,
+	// e.g. 9h30m with a 24h Target for sessions opening at 09:30
+	Offset time.Duration
+}
+
+func (o *ResampleOptions) validate() error {
+	if o.Source <= 0 || o.Target <= 0 {
+		return ErrInvalidInterval
+	}
+
+	return nil
+}
+
+// wallClock returns the wall clock of t in loc as the same reading in UTC
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	lt := t.In(loc)
+
+	return time.Date(lt.Year(), lt.Month(), lt.Day(), lt.Hour(), lt.Minute(), lt.Second(), lt.Nanosecond(), time.UTC)
+}
+
+// boundary returns the start of the output bar containing t
+func (o *ResampleOptions) boundary(t time.Time) time.Time {
+	loc := o.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// truncate the wall clock so daily bars start at local midnight on both sides of a DST change
+	wall := wallClock(t, loc)
+
+	rem := (wall.UnixNano() - int64(o.Offset)) % int64(o.Target)
+	if rem < 0 {
+		rem += int64(o.Target)
+	}
+	wall = wall.Add(-time.Duration(rem))
+
+	// keep elapsed time when no zone change lies in between, so the repeated hour
+	// when clocks fall back still starts its own bar
+	b := t.Add(-time.Duration(rem))
+	if wallClock(b, loc).Equal(wall) {
+		return b
+	}
+
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), loc).In(t.Location())
+}
+
+// resampleGroups splits n input bars into runs sharing an output bar
+// returns the index each run starts at and the start time of its output bar
+func resampleGroups(n int, opts *ResampleOptions) ([]int, []time.Time) {
+	idxs := make([]int, 0)
+	starts := make([]time.Time, 0)
+
+	for i := 0; i < n; i++ {
+		b := opts.boundary(opts.Start.Add(time.Duration(i) * opts.Source))
+		if len(starts) == 0 || !b.Equal(starts[len(starts)-1]) {
+			idxs = append(idxs, i)
+			starts = append(starts, b)
+		}
+	}
+
+	return idxs, starts
+}
+
+// mergeOHLC merges consecutive bars into one
+// first open, max high, min low, last close
+func mergeOHLC(bars []OHLC) OHLC {
+	m := bars[0]
+	for _, b := range bars[1:] {
+		if b.High > m.High {
+			m.High = b.High
+		}
+		if b.Low < m.Low {
+			m.Low = b.Low
+		}
+	}
+	m.Close = bars[len(bars)-1].Close
+
+	return m
+}
+
+// Resample merges consecutive bars into bars of the target interval of the given options
+// Input bars are assigned to an output bar by their open time, intervals without input produce no output
+// returns the output bars and their open times
+func Resample(bars []OHLC, opts ResampleOptions) ([]OHLC, []time.Time, error) {
+	if err := opts.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	idxs, starts := resampleGroups(len(bars), &opts)
+
+	out := make([]OHLC, len(idxs))
+	for g, lower := range idxs {
+		upper := len(bars)
+		if g+1 < len(idxs) {
+			upper = idxs[g+1]
+		}
+		out[g] = mergeOHLC(bars[lower:upper])
+	}
+
+	return out, starts, nil
+}
+
+// ResampleVolume sums per bar volumes with the same grouping as Resample
+func ResampleVolume(volumes []float64, opts ResampleOptions) ([]float64, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	idxs, _ := resampleGroups(len(volumes), &opts)
+
+	out := make([]float64, len(idxs))
+	g := -1
+	for i, v := range volumes {
+		if g+1 < len(idxs) && idxs[g+1] == i {
+			g++
+		}
+		out[g] += v
+	}
+
+	return out, nil
+}
+
+// ResampleInto resamples the bars stored at key and stores the result at dstKey in dst
+// dst may be the receiving store
+func (s *OHLCSStore) ResampleInto(key string, dst *OHLCSStore, dstKey string, opts ResampleOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	// copy under lock, the source may change while resampling
+	s.Lock()
+	v, ok := s.get(key)
+	if !ok {
+		s.Unlock()
+		return ErrKeyDoesNotExist
+	}
+	bars := make([]OHLC, len(v))
+	copy(bars, v)
+	s.Unlock()
+
+	out, _, err := Resample(bars, opts)
+	if err != nil {
+		return err
+	}
+
+	dst.Set(dstKey, out)
+
+	return nil
+}
+
+// mergeOHLCV merges consecutive bars into one starting at start
+// first open, max high, min low, last close, summed volume and trades, volume weighted VWAP
+func mergeOHLCV(bars []OHLCV, start time.Time) OHLCV {
+	m := bars[0]
+	m.Time = start
+	m.Volume = 0
+	m.Trades = 0
+
+	notional := 0.0
+	for _, b := range bars {
+		if b.High > m.High {
+			m.High = b.High
+		}
+		if b.Low < m.Low {
+			m.Low = b.Low
+		}
+		m.Volume += b.Volume
+		m.Trades += b.Trades
+		notional += b.VWAP * b.Volume
+	}
+	m.Close = bars[len(bars)-1].Close
+
+	m.VWAP = 0
+	if m.Volume != 0 {
+		m.VWAP = notional / m.Volume
+	}
+
+	return m
+}
+
+// ResampleOHLCV merges consecutive bars into bars of the target interval of the given options
+// Bars are assigned to an output bar by their own Time, the Start and Source options are ignored
+// Output bars are stamped with the start of their interval
+func ResampleOHLCV(bars []OHLCV, opts ResampleOptions) ([]OHLCV, error) {
+	if opts.Target <= 0 {
+		return nil, ErrInvalidInterval
+	}
+
+	out := make([]OHLCV, 0)
+	lower := 0
+	for lower < len(bars) {
+		start := opts.boundary(bars[lower].Time)
+
+		upper := lower + 1
+		for upper < len(bars) && opts.boundary(bars[upper].Time).Equal(start) {
+			upper++
+		}
+
+		out = append(out, mergeOHLCV(bars[lower:upper], start))
+		lower = upper
+	}
+
+	return out, nil
+}
+
+// ResampleInto resamples the bars stored at key and stores the result at dstKey in dst
+// dst may be the receiving store
+func (s *OHLCVSStore) ResampleInto(key string, dst *OHLCVSStore, dstKey string, opts ResampleOptions) error {
+	if opts.Target <= 0 {
+		return ErrInvalidInterval
+	}
+
+	// copy under lock, the source may change while resampling
+	s.Lock()
+	v, ok := s.get(key)
+	if !ok {
+		s.Unlock()
+		return ErrKeyDoesNotExist
+	}
+	bars := make([]OHLCV, len(v))
+	copy(bars, v)
+	s.Unlock()
+
+	out, err := ResampleOHLCV(bars, opts)
+	if err != nil {
+		return err
+	}
+
+	dst.Set(dstKey, out)
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package seriesstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var mockResampleStart = time.Date(2018, 6, 1, 9, 30, 0, 0, time.UTC)
+
+func mockMinuteBars(n int) []OHLC {
+	bars := make([]OHLC, n)
+	for i := range bars {
+		p := float32(100 + i)
+		bars[i] = OHLC{p, p + 2, p - 1, p + 1}
+	}
+
+	return bars
+}
+
+func TestResampleInvalidInterval(t *testing.T) {
+	_, _, err := Resample(mockMinuteBars(3), ResampleOptions{Start: mockResampleStart, Source: time.Minute})
+	assert.Equal(t, ErrInvalidInterval, err)
+
+	_, err = ResampleVolume([]float64{1.0}, ResampleOptions{Start: mockResampleStart, Target: time.Minute})
+	assert.Equal(t, ErrInvalidInterval, err)
+}
+
+func TestResample(t *testing.T) {
+	opts := ResampleOptions{Start: mockResampleStart, Source: time.Minute, Target: 5 * time.Minute}
+
+	out, starts, err := Resample(mockMinuteBars(12), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []OHLC{
+		{100, 106, 99, 105},
+		{105, 111, 104, 110},
+		{110, 113, 109, 112},
+	}, out)
+	assert.Equal(t, []time.Time{
+		mockResampleStart,
+		mockResampleStart.Add(5 * time.Minute),
+		mockResampleStart.Add(10 * time.Minute),
+	}, starts)
+
+	// empty input
+	out, starts, err = Resample([]OHLC{}, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(out))
+	assert.Equal(t, 0, len(starts))
+}
+
+func TestResampleUnalignedStart(t *testing.T) {
+	opts := ResampleOptions{Start: mockResampleStart.Add(3 * time.Minute), Source: time.Minute, Target: 5 * time.Minute}
+
+	// first output bar is partial
+	out, starts, err := Resample(mockMinuteBars(4), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []OHLC{{100, 103, 99, 102}, {102, 105, 101, 104}}, out)
+	assert.Equal(t, mockResampleStart, starts[0])
+}
+
+func TestResampleOffset(t *testing.T) {
+	// hourly bars aligned to the half hour
+	opts := ResampleOptions{Start: mockResampleStart, Source: time.Minute, Target: time.Hour, Offset: 30 * time.Minute}
+
+	out, starts, err := Resample(mockMinuteBars(90), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out))
+	assert.Equal(t, mockResampleStart.Add(time.Hour), starts[1])
+	assert.Equal(t, float32(160), out[1].Open)
+}
+
+func TestResampleLocation(t *testing.T) {
+	est := time.FixedZone("EST", -5*3600)
+
+	// 4 hourly bars spanning midnight in EST
+	opts := ResampleOptions{
+		Start:    time.Date(2018, 6, 2, 3, 0, 0, 0, time.UTC),
+		Source:   time.Hour,
+		Target:   24 * time.Hour,
+		Location: est,
+	}
+
+	out, starts, err := Resample(mockMinuteBars(4), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out))
+	assert.True(t, time.Date(2018, 6, 1, 0, 0, 0, 0, est).Equal(starts[0]))
+	assert.True(t, time.Date(2018, 6, 2, 0, 0, 0, 0, est).Equal(starts[1]))
+	assert.Equal(t, float32(102), out[1].Open)
+}
+
+func TestResampleDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	// the spring day has 23 hours
+	opts := ResampleOptions{
+		Start:    time.Date(2024, 3, 10, 0, 0, 0, 0, ny),
+		Source:   time.Hour,
+		Target:   24 * time.Hour,
+		Location: ny,
+	}
+
+	out, starts, err := Resample(mockMinuteBars(24), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out))
+	assert.True(t, time.Date(2024, 3, 10, 0, 0, 0, 0, ny).Equal(starts[0]))
+	assert.True(t, time.Date(2024, 3, 11, 0, 0, 0, 0, ny).Equal(starts[1]))
+	assert.Equal(t, float32(123), out[1].Open)
+
+	// the fall day has 25 hours
+	opts.Start = time.Date(2024, 11, 3, 0, 0, 0, 0, ny)
+
+	out, starts, err = Resample(mockMinuteBars(26), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out))
+	assert.True(t, time.Date(2024, 11, 3, 0, 0, 0, 0, ny).Equal(starts[0]))
+	assert.True(t, time.Date(2024, 11, 4, 0, 0, 0, 0, ny).Equal(starts[1]))
+	assert.Equal(t, float32(125), out[1].Open)
+
+	// the repeated hour is its own hourly bar
+	opts.Target = time.Hour
+
+	out, starts, err = Resample(mockMinuteBars(4), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(out))
+	assert.Equal(t, time.Hour, starts[2].Sub(starts[1]))
+}
+
+func TestResampleVolume(t *testing.T) {
+	opts := ResampleOptions{Start: mockResampleStart, Source: time.Minute, Target: 2 * time.Minute}
+
+	out, err := ResampleVolume([]float64{1, 2, 3, 4, 5}, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{3, 7, 5}, out)
+}
+
+func TestOHLCResampleInto(t *testing.T) {
+	ss := NewOHLCSStore()
+	dst := NewOHLCSStore()
+	opts := ResampleOptions{Start: mockResampleStart, Source: time.Minute, Target: 5 * time.Minute}
+
+	// no key
+	err := ss.ResampleInto("foo", dst, "foo:5m", opts)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	ss.store["foo"] = mockMinuteBars(10)
+
+	// invalid interval
+	err = ss.ResampleInto("foo", dst, "foo:5m", ResampleOptions{})
+	assert.Equal(t, ErrInvalidInterval, err)
+
+	err = ss.ResampleInto("foo", dst, "foo:5m", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, []OHLC{{100, 106, 99, 105}, {105, 111, 104, 110}}, dst.store["foo:5m"])
+
+	// into the same store
+	err = ss.ResampleInto("foo", ss, "foo:5m", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ss.store["foo:5m"]))
+}
+
+func TestResampleOHLCV(t *testing.T) {
+	bars := OHLCVSeriesFromOHLC(mockMinuteBars(7), mockResampleStart, time.Minute)
+	for i := range bars {
+		bars[i].Volume = float64(i + 1)
+		bars[i].Trades = 1
+		bars[i].VWAP = bars[i].Close
+	}
+
+	// invalid interval
+	_, err := ResampleOHLCV(bars, ResampleOptions{})
+	assert.Equal(t, ErrInvalidInterval, err)
+
+	out, err := ResampleOHLCV(bars, ResampleOptions{Target: 5 * time.Minute})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out))
+
+	assert.Equal(t, mockResampleStart, out[0].Time)
+	assert.Equal(t, 100.0, out[0].Open)
+	assert.Equal(t, 106.0, out[0].High)
+	assert.Equal(t, 99.0, out[0].Low)
+	assert.Equal(t, 105.0, out[0].Close)
+	assert.Equal(t, 15.0, out[0].Volume)
+	assert.Equal(t, int64(5), out[0].Trades)
+	assert.InDelta(t, (101.0*1+102.0*2+103.0*3+104.0*4+105.0*5)/15.0, out[0].VWAP, 1e-9)
+
+	assert.Equal(t, mockResampleStart.Add(5*time.Minute), out[1].Time)
+	assert.Equal(t, 13.0, out[1].Volume)
+}
+
+func TestResampleOHLCVGaps(t *testing.T) {
+	bars := []OHLCV{
+		{Time: mockResampleStart, Open: 1, High: 1, Low: 1, Close: 1},
+		{Time: mockResampleStart.Add(time.Hour), Open: 2, High: 2, Low: 2, Close: 2},
+	}
+
+	// no volume leaves vwap zero
+	out, err := ResampleOHLCV(bars, ResampleOptions{Target: 15 * time.Minute})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(out))
+	assert.Equal(t, 0.0, out[1].VWAP)
+	assert.Equal(t, mockResampleStart.Add(time.Hour), out[1].Time)
+}
+
+func TestOHLCVResampleInto(t *testing.T) {
+	ss := NewOHLCVSStore()
+	opts := ResampleOptions{Target: 5 * time.Minute}
+
+	// no key
+	err := ss.ResampleInto("foo", ss, "foo:5m", opts)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	ss.store["foo"] = OHLCVSeriesFromOHLC(mockMinuteBars(10), mockResampleStart, time.Minute)
+
+	// invalid interval
+	err = ss.ResampleInto("foo", ss, "foo:5m", ResampleOptions{})
+	assert.Equal(t, ErrInvalidInterval, err)
+
+	err = ss.ResampleInto("foo", ss, "foo:5m", opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ss.store["foo:5m"]))
+	assert.Equal(t, 110.0, ss.store["foo:5m"][1].Close)
+}
@@ -0,0 +1,231 @@
+package seriesstore
+
+import (
+	"math"
+)
+
+// RollingStats is a point in time snapshot of the aggregations of a RollingWindow
+type RollingStats struct {
+	Count    int
+	Sum      float64
+	Mean     float64
+	Variance float64
+	StdDev   float64
+	Min      float64
+	Max      float64
+}
+
+// RollingWindow maintains count, sum, mean, variance, min and max aggregations
+// over the most recent values pushed to it, updated incrementally in O(1) amortized time
+// Mean and variance use Welford's online algorithm, min and max use monotonic deques
+// A window size < 1 aggregates over every value ever pushed
+// Note: RollingWindow is NOT safe for concurrent use, see RollingFloat64SStore
+type RollingWindow struct {
+	size   int
+	values []float64 // ring buffer of the values currently in the window
+	head   int       // ring index of the oldest value in the window
+	seq    int       // total number of values ever pushed
+	count  int
+	sum    float64
+	mean   float64
+	m2     float64
+	minQ   monotonicDeque
+	maxQ   monotonicDeque
+}
+
+// NewRollingWindow constructs and initializes a new RollingWindow of the given size
+// Always use this function when creating a new RollingWindow
+func NewRollingWindow(size int) *RollingWindow {
+	w := &RollingWindow{maxQ: monotonicDeque{max: true}}
+	if size > 0 {
+		w.size = size
+		w.values = make([]float64, 0, size)
+	}
+
+	return w
+}
+
+// Push adds the value to the window, evicting the oldest value if the window is full
+func (w *RollingWindow) Push(value float64) {
+	if w.size > 0 && w.count == w.size {
+		w.evict(w.values[w.head])
+		w.values[w.head] = value
+		w.head = (w.head + 1) % w.size
+	} else if w.size > 0 {
+		w.values = append(w.values, value)
+	}
+
+	// welford add
+	w.count++
+	w.sum += value
+	d := value - w.mean
+	w.mean += d / float64(w.count)
+	w.m2 += d * (value - w.mean)
+
+	w.minQ.push(w.seq, value)
+	w.maxQ.push(w.seq, value)
+	w.seq++
+
+	// drop extrema that fell out of the window
+	if w.size > 0 {
+		w.minQ.evictBefore(w.seq - w.size)
+		w.maxQ.evictBefore(w.seq - w.size)
+	} else {
+		// nothing is ever evicted, only the extremum itself is needed
+		w.minQ.keepFront()
+		w.maxQ.keepFront()
+	}
+}
+
+func (w *RollingWindow) evict(value float64) {
+	// welford remove
+	w.count--
+	w.sum -= value
+	if w.count == 0 {
+		w.mean = 0.0
+		w.m2 = 0.0
+		return
+	}
+
+	d := value - w.mean
+	w.mean -= d / float64(w.count)
+	w.m2 -= d * (value - w.mean)
+	if w.m2 < 0 {
+		// guard against accumulated rounding error
+		w.m2 = 0.0
+	}
+}
+
+// Size returns the configured size of the window, 0 if unbounded
+func (w *RollingWindow) Size() int {
+	return w.size
+}
+
+// Full checks if the window holds size values
+// An unbounded window is never full
+func (w *RollingWindow) Full() bool {
+	return w.size > 0 && w.count == w.size
+}
+
+// Count returns the number of values currently in the window
+func (w *RollingWindow) Count() int {
+	return w.count
+}
+
+// Sum returns the sum of the values in the window
+func (w *RollingWindow) Sum() float64 {
+	return w.sum
+}
+
+// Mean returns the arithmetic mean of the values in the window
+func (w *RollingWindow) Mean() float64 {
+	return w.mean
+}
+
+// Variance returns the population variance of the values in the window
+func (w *RollingWindow) Variance() float64 {
+	if w.count == 0 {
+		return 0.0
+	}
+
+	return w.m2 / float64(w.count)
+}
+
+// SampleVariance returns the sample (n-1) variance of the values in the window
+func (w *RollingWindow) SampleVariance() float64 {
+	if w.count < 2 {
+		return 0.0
+	}
+
+	return w.m2 / float64(w.count-1)
+}
+
+// StdDev returns the population standard deviation of the values in the window
+func (w *RollingWindow) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
+
+// Min returns the minimum value in the window, 0 if the window is empty
+func (w *RollingWindow) Min() float64 {
+	return w.minQ.front()
+}
+
+// Max returns the maximum value in the window, 0 if the window is empty
+func (w *RollingWindow) Max() float64 {
+	return w.maxQ.front()
+}
+
+// Stats returns a snapshot of all aggregations of the window
+func (w *RollingWindow) Stats() RollingStats {
+	return RollingStats{
+		Count:    w.count,
+		Sum:      w.sum,
+		Mean:     w.mean,
+		Variance: w.Variance(),
+		StdDev:   w.StdDev(),
+		Min:      w.Min(),
+		Max:      w.Max(),
+	}
+}
+
+// Reset empties the window, keeping its size
+func (w *RollingWindow) Reset() {
+	*w = *NewRollingWindow(w.size)
+}
+
+type dequeEntry struct {
+	seq   int
+	value float64
+}
+
+// monotonicDeque keeps window extrema candidates ordered by arrival
+// the front of the deque is always the current extremum
+type monotonicDeque struct {
+	entries []dequeEntry
+	head    int
+	max     bool // tracks the maximum if true, else the minimum
+}
+
+func (q *monotonicDeque) dominates(value, back float64) bool {
+	if q.max {
+		return value >= back
+	}
+
+	return value <= back
+}
+
+func (q *monotonicDeque) push(seq int, value float64) {
+	// older candidates that can never be the extremum again are dropped
+	for len(q.entries) > q.head && q.dominates(value, q.entries[len(q.entries)-1].value) {
+		q.entries = q.entries[:len(q.entries)-1]
+	}
+
+	q.entries = append(q.entries, dequeEntry{seq, value})
+}
+
+func (q *monotonicDeque) evictBefore(seq int) {
+	for q.head < len(q.entries) && q.entries[q.head].seq < seq {
+		q.head++
+	}
+
+	// reclaim evicted space once it dominates the backing array
+	if q.head > 0 && q.head >= len(q.entries)/2 {
+		n := copy(q.entries, q.entries[q.head:])
+		q.entries = q.entries[:n]
+		q.head = 0
+	}
+}
+
+func (q *monotonicDeque) keepFront() {
+	if len(q.entries)-q.head > 1 {
+		q.entries = q.entries[:q.head+1]
+	}
+}
+
+func (q *monotonicDeque) front() float64 {
+	if q.head >= len(q.entries) {
+		return 0.0
+	}
+
+	return q.entries[q.head].value
+}
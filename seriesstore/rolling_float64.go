@@ -0,0 +1,334 @@
+package seriesstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// RollingFloat64SStore is a store of float64 rolling windows
+// Values appended to a key are aggregated incrementally over the store window,
+// so count, sum, mean, variance, standard deviation, min and max of a key are all O(1) reads
+// Embedded sync.Mutex to provide atomic operation ability
+type RollingFloat64SStore struct {
+	sync.Mutex
+	window int
+	store  map[string]*RollingWindow
+	index  *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewRollingFloat64SStore constructs and initializes a new RollingFloat64SStore
+// aggregating over the last window values of each key, or all values if window < 1
+// Always use this function to init new RollingFloat64SStores
+func NewRollingFloat64SStore(window int) *RollingFloat64SStore {
+	if window < 1 {
+		window = 0
+	}
+
+	return &RollingFloat64SStore{window: window, store: make(map[string]*RollingWindow)}
+}
+
+// Window returns the window size of the store, 0 if unbounded
+func (s *RollingFloat64SStore) Window() int {
+	return s.window
+}
+
+func (s *RollingFloat64SStore) append(key string, value float64) {
+	w, ok := s.store[key]
+	if !ok {
+		w = NewRollingWindow(s.window)
+		s.store[key] = w
+		s.index.Insert(key)
+	}
+
+	w.Push(value)
+}
+
+// Append adds the given value to the rolling window of the given key,
+// creating the key if it does not exist
+func (s *RollingFloat64SStore) Append(key string, value float64) {
+	s.Lock()
+	s.append(key, value)
+	s.Unlock()
+}
+
+func (s *RollingFloat64SStore) stats(key string) (RollingStats, error) {
+	w, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return RollingStats{}, ErrKeyDoesNotExist
+	}
+
+	return w.Stats(), nil
+}
+
+// Stats returns a snapshot of all rolling aggregations for the given key
+func (s *RollingFloat64SStore) Stats(key string) (RollingStats, error) {
+	s.Lock()
+	v, err := s.stats(key)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *RollingFloat64SStore) count(key string) (int, error) {
+	w, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return w.Count(), nil
+}
+
+// Count returns the number of values in the window of the given key
+func (s *RollingFloat64SStore) Count(key string) (int, error) {
+	s.Lock()
+	c, err := s.count(key)
+	s.Unlock()
+
+	return c, err
+}
+
+func (s *RollingFloat64SStore) aggregate(key string, agg func(w *RollingWindow) float64) (float64, error) {
+	w, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0.0, ErrKeyDoesNotExist
+	}
+
+	return agg(w), nil
+}
+
+// Sum returns the sum of the values in the window of the given key
+func (s *RollingFloat64SStore) Sum(key string) (float64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*RollingWindow).Sum)
+	s.Unlock()
+
+	return v, err
+}
+
+// Mean returns the mean of the values in the window of the given key
+func (s *RollingFloat64SStore) Mean(key string) (float64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*RollingWindow).Mean)
+	s.Unlock()
+
+	return v, err
+}
+
+// Variance returns the population variance of the values in the window of the given key
+func (s *RollingFloat64SStore) Variance(key string) (float64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*RollingWindow).Variance)
+	s.Unlock()
+
+	return v, err
+}
+
+// StdDev returns the population standard deviation of the values in the window of the given key
+func (s *RollingFloat64SStore) StdDev(key string) (float64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*RollingWindow).StdDev)
+	s.Unlock()
+
+	return v, err
+}
+
+// Min returns the minimum value in the window of the given key
+func (s *RollingFloat64SStore) Min(key string) (float64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*RollingWindow).Min)
+	s.Unlock()
+
+	return v, err
+}
+
+// Max returns the maximum value in the window of the given key
+func (s *RollingFloat64SStore) Max(key string) (float64, error) {
+	s.Lock()
+	v, err := s.aggregate(key, (*RollingWindow).Max)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *RollingFloat64SStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *RollingFloat64SStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *RollingFloat64SStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *RollingFloat64SStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *RollingFloat64SStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *RollingFloat64SStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *RollingFloat64SStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *RollingFloat64SStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *RollingFloat64SStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *RollingFloat64SStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *RollingFloat64SStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *RollingFloat64SStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *RollingFloat64SStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *RollingFloat64SStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *RollingFloat64SStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *RollingFloat64SStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *RollingFloat64SStore) clear() {
+	s.store = make(map[string]*RollingWindow)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *RollingFloat64SStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,272 @@
+package seriesstore
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingFloat64Append(t *testing.T) {
+	ss := NewRollingFloat64SStore(2)
+
+	ss.Append("foo", 1.0)
+	assert.True(t, ss.isMember("foo"))
+	assert.Equal(t, 1, ss.store["foo"].Count())
+
+	ss.Append("foo", 2.0)
+	ss.Append("foo", 3.0)
+	assert.Equal(t, 2, ss.store["foo"].Count())
+	assert.Equal(t, 5.0, ss.store["foo"].Sum())
+}
+
+func TestRollingFloat64Window(t *testing.T) {
+	ss := NewRollingFloat64SStore(5)
+	assert.Equal(t, 5, ss.Window())
+
+	// unbounded
+	ss = NewRollingFloat64SStore(-1)
+	assert.Equal(t, 0, ss.Window())
+}
+
+func TestRollingFloat64Stats(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	// no key
+	_, err := ss.Stats("foo")
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	for _, v := range []float64{4.0, 8.0, 6.0, 2.0} {
+		ss.Append("foo", v)
+	}
+
+	st, err := ss.Stats("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, st.Count)
+	assert.Equal(t, 16.0, st.Sum)
+	assert.Equal(t, 2.0, st.Min)
+	assert.Equal(t, 8.0, st.Max)
+}
+
+func TestRollingFloat64Aggregations(t *testing.T) {
+	ss := NewRollingFloat64SStore(4)
+
+	// no key
+	_, err := ss.Count("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = ss.Sum("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = ss.Mean("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = ss.Variance("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = ss.StdDev("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = ss.Min("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = ss.Max("foo")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	for _, v := range []float64{2.0, 4.0, 4.0, 4.0, 5.0, 5.0, 7.0, 9.0} {
+		ss.Append("foo", v)
+	}
+
+	c, err := ss.Count("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, c)
+
+	v, err := ss.Sum("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 26.0, v)
+
+	v, err = ss.Mean("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 6.5, v)
+
+	v, err = ss.Variance("foo")
+	assert.Nil(t, err)
+	assert.InDelta(t, 2.75, v, 1e-12)
+
+	v, err = ss.StdDev("foo")
+	assert.Nil(t, err)
+	assert.InDelta(t, math.Sqrt(2.75), v, 1e-12)
+
+	v, err = ss.Min("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 5.0, v)
+
+	v, err = ss.Max("foo")
+	assert.Nil(t, err)
+	assert.Equal(t, 9.0, v)
+}
+
+func TestRollingFloat64Size(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	// no keys
+	size := ss.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	ss.Append("a", 1.0)
+	ss.Append("b", 1.0)
+
+	size = ss.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestRollingFloat64Members(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	// no keys
+	mems := ss.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	ss.Append("a", 1.0)
+	ss.Append("b", 1.0)
+
+	mems = ss.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestRollingFloat64SortedMembers(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:ask"] = NewRollingWindow(3)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestRollingFloat64MembersWithPrefix(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	ss.store["MSFT:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:ask"] = NewRollingWindow(3)
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestRollingFloat64MembersMatching(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	ss.store["MSFT:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:ask"] = NewRollingWindow(3)
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestRollingFloat64KeyIndex(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+	ss.store["foo"] = NewRollingWindow(3)
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Append("bar", 1.0)
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestRollingFloat64Scan(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	ss.store["MSFT:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:bid"] = NewRollingWindow(3)
+	ss.store["AAPL:ask"] = NewRollingWindow(3)
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestRollingFloat64IsMember(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	// no keys
+	ok := ss.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	ss.Append("foo", 1.0)
+
+	ok = ss.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestRollingFloat64Clear(t *testing.T) {
+	ss := NewRollingFloat64SStore(3)
+
+	ss.Append("foo", 1.0)
+	assert.Equal(t, 1, len(ss.store))
+
+	ss.Clear()
+	assert.Equal(t, 0, len(ss.store))
+}
+
+func TestRollingFloat64ConcurrentAppendAndGet(t *testing.T) {
+	ss := NewRollingFloat64SStore(10)
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Append("foo", float64(i))
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Stats("foo")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
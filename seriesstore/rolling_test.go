@@ -0,0 +1,126 @@
+package seriesstore
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bruteStats computes the expected aggregations of values from scratch
+func bruteStats(values []float64) RollingStats {
+	st := RollingStats{Count: len(values), Min: values[0], Max: values[0]}
+	for _, v := range values {
+		st.Sum += v
+		st.Min = math.Min(st.Min, v)
+		st.Max = math.Max(st.Max, v)
+	}
+	st.Mean = st.Sum / float64(len(values))
+
+	for _, v := range values {
+		st.Variance += (v - st.Mean) * (v - st.Mean)
+	}
+	st.Variance /= float64(len(values))
+	st.StdDev = math.Sqrt(st.Variance)
+
+	return st
+}
+
+func TestRollingWindowEmpty(t *testing.T) {
+	w := NewRollingWindow(3)
+
+	assert.Equal(t, 3, w.Size())
+	assert.Equal(t, 0, w.Count())
+	assert.False(t, w.Full())
+	assert.Equal(t, RollingStats{}, w.Stats())
+}
+
+func TestRollingWindowPush(t *testing.T) {
+	w := NewRollingWindow(3)
+
+	w.Push(1.0)
+	w.Push(5.0)
+	assert.False(t, w.Full())
+	assert.Equal(t, 2, w.Count())
+	assert.Equal(t, 6.0, w.Sum())
+	assert.Equal(t, 1.0, w.Min())
+	assert.Equal(t, 5.0, w.Max())
+
+	w.Push(3.0)
+	assert.True(t, w.Full())
+	assert.Equal(t, 3.0, w.Mean())
+
+	// evicts 1.0
+	w.Push(4.0)
+	assert.Equal(t, 3, w.Count())
+	assert.Equal(t, 12.0, w.Sum())
+	assert.Equal(t, 3.0, w.Min())
+	assert.Equal(t, 5.0, w.Max())
+
+	// evicts 5.0
+	w.Push(2.0)
+	assert.Equal(t, 2.0, w.Min())
+	assert.Equal(t, 4.0, w.Max())
+	assert.InDelta(t, 2.0/3.0, w.Variance(), 1e-12)
+	assert.InDelta(t, 1.0, w.SampleVariance(), 1e-12)
+}
+
+func TestRollingWindowMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	size := 16
+	w := NewRollingWindow(size)
+
+	values := make([]float64, 0)
+	for i := 0; i < 1000; i++ {
+		v := r.NormFloat64()*10 + 100
+		values = append(values, v)
+		w.Push(v)
+
+		lower := len(values) - size
+		if lower < 0 {
+			lower = 0
+		}
+		exp := bruteStats(values[lower:])
+		act := w.Stats()
+
+		assert.Equal(t, exp.Count, act.Count)
+		assert.InDelta(t, exp.Sum, act.Sum, 1e-9)
+		assert.InDelta(t, exp.Mean, act.Mean, 1e-9)
+		assert.InDelta(t, exp.Variance, act.Variance, 1e-9)
+		assert.InDelta(t, exp.StdDev, act.StdDev, 1e-9)
+		assert.Equal(t, exp.Min, act.Min)
+		assert.Equal(t, exp.Max, act.Max)
+	}
+}
+
+func TestRollingWindowUnbounded(t *testing.T) {
+	w := NewRollingWindow(0)
+
+	for i := 1; i <= 100; i++ {
+		w.Push(float64(i))
+	}
+
+	assert.Equal(t, 0, w.Size())
+	assert.False(t, w.Full())
+	assert.Equal(t, 100, w.Count())
+	assert.Equal(t, 5050.0, w.Sum())
+	assert.Equal(t, 1.0, w.Min())
+	assert.Equal(t, 100.0, w.Max())
+	// only the extremum is retained
+	assert.Equal(t, 1, len(w.minQ.entries)-w.minQ.head)
+}
+
+func TestRollingWindowReset(t *testing.T) {
+	w := NewRollingWindow(2)
+	w.Push(1.0)
+	w.Push(2.0)
+
+	w.Reset()
+	assert.Equal(t, 2, w.Size())
+	assert.Equal(t, RollingStats{}, w.Stats())
+
+	w.Push(7.0)
+	assert.Equal(t, 7.0, w.Min())
+	assert.Equal(t, 7.0, w.Max())
+}
@@ -9,6 +9,46 @@ var (
 	ErrKeyDoesNotExist = errors.New("key does not exist")
 	// ErrIdxOutOfBounds is thrown when given indices for a range are out of bounds
 	ErrIdxOutOfBounds = errors.New("index out of bounds")
+	// ErrInvalidInterval is thrown when a resampling or bar interval is not positive
+	ErrInvalidInterval = errors.New("invalid interval")
+	// ErrInvalidThreshold is thrown when a bar tick, volume or dollar threshold is not a positive number
+	ErrInvalidThreshold = errors.New("invalid bar threshold")
+	// ErrNotInteger is thrown when incrementing a value that is not an integer
+	ErrNotInteger = errors.New("value is not an integer")
+	// ErrIntegerOverflow is thrown when an increment would overflow
+	ErrIntegerOverflow = errors.New("increment would overflow")
+	// ErrInvalidScore is thrown when a sorted set score is NaN
+	ErrInvalidScore = errors.New("score is not a number")
+	// ErrInvalidPrice is thrown when an order book price is not a positive number
+	ErrInvalidPrice = errors.New("price is not a positive number")
+	// ErrInvalidSize is thrown when an order book size is negative or not a number
+	ErrInvalidSize = errors.New("size is negative or not a number")
+	// ErrLevelDoesNotExist is thrown when an order book price level is not found
+	ErrLevelDoesNotExist = errors.New("price level does not exist")
+	// ErrEmptySide is thrown when an order book side has no price levels
+	ErrEmptySide = errors.New("order book side is empty")
+	// ErrInvalidPrecision is thrown when histogram significant figures are not within 1 and 5
+	ErrInvalidPrecision = errors.New("invalid histogram precision")
+	// ErrValueOutOfRange is thrown when a histogram value is negative or above its highest trackable value
+	ErrValueOutOfRange = errors.New("value out of histogram range")
+	// ErrInvalidQuantile is thrown when a quantile is not within 0 and 1
+	ErrInvalidQuantile = errors.New("invalid quantile")
+	// ErrIncompatibleHistogram is thrown when merging histograms of different ranges or precisions
+	ErrIncompatibleHistogram = errors.New("incompatible histogram")
+	// ErrDuplicateColumn is thrown when a frame schema names a column twice
+	ErrDuplicateColumn = errors.New("duplicate column")
+	// ErrColumnDoesNotExist is thrown when a frame column name is not found
+	ErrColumnDoesNotExist = errors.New("column does not exist")
+	// ErrColumnType is thrown when a value or accessor does not match the type of a frame column
+	ErrColumnType = errors.New("value does not match column type")
+	// ErrRowLength is thrown when a row does not have one value per frame column
+	ErrRowLength = errors.New("row length does not match columns")
+	// ErrSchemaMismatch is thrown when a frame does not have the columns of the store
+	ErrSchemaMismatch = errors.New("frame schema does not match store")
+	// ErrEmptyCrossSection is thrown when a cross section has no values
+	ErrEmptyCrossSection = errors.New("cross section is empty")
+	// ErrOutOfOrder is thrown when appending a point older than the last point of a series
+	ErrOutOfOrder = errors.New("point out of order")
 )
 
 // A SeriesStore is a key/value storage that stores a data series
@@ -19,6 +59,9 @@ type SeriesStore interface {
 	// Set sets the key in the store to the given series value
 	Set(key string, value []interface{})
 
+	// Append adds the given values to the end of the series value of the given key in the store
+	Append(key string, values ...interface{})
+
 	// SetIdx sets the index value of series of the given key in the store
 	SetIdx(key string, idx int, value interface{}) error
 
@@ -39,6 +82,19 @@ type SeriesStore interface {
 	// Members returns a list of string keys in the store
 	Members() []string
 
+	// SortedMembers returns a list of string keys in the store in ascending order
+	SortedMembers() []string
+
+	// MembersWithPrefix returns a sorted list of string keys in the store starting with the given prefix
+	MembersWithPrefix(prefix string) []string
+
+	// MembersMatching returns a sorted list of string keys in the store matching the given glob pattern
+	MembersMatching(pattern string) []string
+
+	// Scan returns a batch of at most count string keys in the store matching the given glob pattern,
+	// and the cursor of the next batch, "" once every key has been returned
+	Scan(cursor string, count int, match string) ([]string, string)
+
 	// IsMember checks if the given key is a member of the store
 	isMember(key string) bool
 
@@ -0,0 +1,475 @@
+package seriesstore
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+	"github.com/blacklabcapital/safestore/internal/skiplist"
+)
+
+// ScoredMember is a member of a sorted set and its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// sortedSet pairs a skip list ordered by score with a map for O(1) score lookups
+type sortedSet struct {
+	list   *skiplist.List
+	scores map[string]float64
+}
+
+func newSortedSet() *sortedSet {
+	return &sortedSet{list: skiplist.New(), scores: make(map[string]float64)}
+}
+
+// scoreOf returns the score of member, zero if the set or member does not exist
+func (z *sortedSet) scoreOf(member string) float64 {
+	if z == nil {
+		return 0.0
+	}
+
+	return z.scores[member]
+}
+
+// SortedSetStore is a store of sorted sets, like Redis sorted sets
+// Each key holds unique members ordered by score, then member for equal scores
+// Adds, removes, score updates, rank lookups and range starts are all O(log n)
+// A key is deleted when its last member is removed
+// Embedded sync.Mutex to provide atomic operation ability
+type SortedSetStore struct {
+	sync.Mutex
+	store map[string]*sortedSet
+	index *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewSortedSetStore constructs and initializes a new SortedSetStore
+// Always use this function to init new SortedSetStores
+func NewSortedSetStore() *SortedSetStore {
+	return &SortedSetStore{store: make(map[string]*sortedSet)}
+}
+
+func (s *SortedSetStore) add(key, member string, score float64) bool {
+	z, ok := s.store[key]
+	if !ok {
+		z = newSortedSet()
+		s.store[key] = z
+		s.index.Insert(key)
+	}
+
+	old, ok := z.scores[member]
+	if ok {
+		if old == score {
+			return false
+		}
+		z.list.Delete(old, member)
+	}
+
+	z.list.Insert(score, member)
+	z.scores[member] = score
+
+	return !ok
+}
+
+// Add sets the score of member in the sorted set of the given key,
+// creating the key if it does not exist
+// returns true if the member is new, or ErrInvalidScore if score is NaN
+func (s *SortedSetStore) Add(key, member string, score float64) (bool, error) {
+	if math.IsNaN(score) {
+		return false, ErrInvalidScore
+	}
+
+	s.Lock()
+	added := s.add(key, member, score)
+	s.Unlock()
+
+	return added, nil
+}
+
+func (s *SortedSetStore) incrScore(key, member string, delta float64) (float64, error) {
+	score := s.store[key].scoreOf(member) + delta
+	if math.IsNaN(score) {
+		return 0.0, ErrInvalidScore
+	}
+
+	s.add(key, member, score)
+
+	return score, nil
+}
+
+// IncrScore atomically adds delta to the score of member in the sorted set of the given key
+// A missing member starts from zero
+// returns the new score, or ErrInvalidScore if it would be NaN
+func (s *SortedSetStore) IncrScore(key, member string, delta float64) (float64, error) {
+	s.Lock()
+	score, err := s.incrScore(key, member, delta)
+	s.Unlock()
+
+	return score, err
+}
+
+func (s *SortedSetStore) score(key, member string) (float64, bool) {
+	z, ok := s.store[key]
+	if !ok {
+		return 0.0, false
+	}
+
+	// explicitly return second return value
+	v, ok := z.scores[member]
+
+	return v, ok
+}
+
+// Score returns the score of member in the sorted set of the given key
+// returns the score and boolean if the member exists
+func (s *SortedSetStore) Score(key, member string) (float64, bool) {
+	s.Lock()
+	v, ok := s.score(key, member)
+	s.Unlock()
+
+	return v, ok
+}
+
+func (s *SortedSetStore) remove(key string, members ...string) int {
+	z, ok := s.store[key]
+	if !ok {
+		return 0
+	}
+
+	n := 0
+	for _, m := range members {
+		if score, ok := z.scores[m]; ok {
+			z.list.Delete(score, m)
+			delete(z.scores, m)
+			n++
+		}
+	}
+
+	if len(z.scores) == 0 {
+		delete(s.store, key)
+		s.index.Delete(key)
+	}
+
+	return n
+}
+
+// Remove deletes the given members from the sorted set of the given key
+// returns the number of members removed
+func (s *SortedSetStore) Remove(key string, members ...string) int {
+	s.Lock()
+	n := s.remove(key, members...)
+	s.Unlock()
+
+	return n
+}
+
+func (s *SortedSetStore) rank(key, member string, reverse bool) (int, bool) {
+	z, ok := s.store[key]
+	if !ok {
+		return 0, false
+	}
+
+	score, ok := z.scores[member]
+	if !ok {
+		return 0, false
+	}
+
+	r := z.list.Rank(score, member)
+	if reverse {
+		r = z.list.Len() - 1 - r
+	}
+
+	return r, true
+}
+
+// Rank returns the zero based position of member in ascending score order
+// returns the rank and boolean if the member exists
+func (s *SortedSetStore) Rank(key, member string) (int, bool) {
+	s.Lock()
+	r, ok := s.rank(key, member, false)
+	s.Unlock()
+
+	return r, ok
+}
+
+// RevRank returns the zero based position of member in descending score order,
+// e.g. 0 for the leader of a leaderboard
+// returns the rank and boolean if the member exists
+func (s *SortedSetStore) RevRank(key, member string) (int, bool) {
+	s.Lock()
+	r, ok := s.rank(key, member, true)
+	s.Unlock()
+
+	return r, ok
+}
+
+func (s *SortedSetStore) rangeByRank(key string, lower, upper int, reverse bool) ([]ScoredMember, error) {
+	z, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	// bounds check
+	l := z.list.Len()
+	if lower < 0 || lower > l || upper < 0 || upper > l || lower > upper {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	out := make([]ScoredMember, 0, upper-lower)
+	if reverse {
+		for n := z.list.ByRank(l - 1 - lower); n != nil && len(out) < upper-lower; n = n.Prev() {
+			out = append(out, ScoredMember{n.Member(), n.Score()})
+		}
+	} else {
+		for n := z.list.ByRank(lower); n != nil && len(out) < upper-lower; n = n.Next() {
+			out = append(out, ScoredMember{n.Member(), n.Score()})
+		}
+	}
+
+	return out, nil
+}
+
+// RangeByRank returns the members of the given key ranked within the specified range (inclusive:exclusive)
+// in ascending score order
+func (s *SortedSetStore) RangeByRank(key string, lower, upper int) ([]ScoredMember, error) {
+	s.Lock()
+	v, err := s.rangeByRank(key, lower, upper, false)
+	s.Unlock()
+
+	return v, err
+}
+
+// RevRangeByRank returns the members of the given key ranked within the specified range (inclusive:exclusive)
+// in descending score order, e.g. RevRangeByRank(key, 0, 10) is the top 10
+func (s *SortedSetStore) RevRangeByRank(key string, lower, upper int) ([]ScoredMember, error) {
+	s.Lock()
+	v, err := s.rangeByRank(key, lower, upper, true)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *SortedSetStore) rangeByScore(key string, min, max float64) ([]ScoredMember, error) {
+	z, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	out := make([]ScoredMember, 0)
+	for n := z.list.SeekScore(min); n != nil && n.Score() <= max; n = n.Next() {
+		out = append(out, ScoredMember{n.Member(), n.Score()})
+	}
+
+	return out, nil
+}
+
+// RangeByScore returns the members of the given key scored within min and max (inclusive:inclusive)
+// in ascending score order
+func (s *SortedSetStore) RangeByScore(key string, min, max float64) ([]ScoredMember, error) {
+	s.Lock()
+	v, err := s.rangeByScore(key, min, max)
+	s.Unlock()
+
+	return v, err
+}
+
+func (s *SortedSetStore) card(key string) (int, error) {
+	z, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return z.list.Len(), nil
+}
+
+// Card returns the number of members in the sorted set of the given key
+func (s *SortedSetStore) Card(key string) (int, error) {
+	s.Lock()
+	c, err := s.card(key)
+	s.Unlock()
+
+	return c, err
+}
+
+func (s *SortedSetStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *SortedSetStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *SortedSetStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *SortedSetStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *SortedSetStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *SortedSetStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *SortedSetStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *SortedSetStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *SortedSetStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *SortedSetStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *SortedSetStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *SortedSetStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *SortedSetStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *SortedSetStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *SortedSetStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *SortedSetStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *SortedSetStore) clear() {
+	s.store = make(map[string]*sortedSet)
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *SortedSetStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,401 @@
+package seriesstore
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockSortedSet() *sortedSet {
+	z := newSortedSet()
+	for m, s := range map[string]float64{"AAPL": 300.0, "MSFT": 200.0, "IBM": 100.0, "GOOG": 200.0} {
+		z.list.Insert(s, m)
+		z.scores[m] = s
+	}
+
+	return z
+}
+
+func TestSortedSetAdd(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	added, err := zs.Add("vol", "AAPL", 10.0)
+	assert.Nil(t, err)
+	assert.True(t, added)
+	assert.Equal(t, 10.0, zs.store["vol"].scores["AAPL"])
+
+	// update score
+	added, err = zs.Add("vol", "AAPL", 5.0)
+	assert.Nil(t, err)
+	assert.False(t, added)
+	assert.Equal(t, 5.0, zs.store["vol"].scores["AAPL"])
+	assert.Equal(t, 1, zs.store["vol"].list.Len())
+	assert.Equal(t, 5.0, zs.store["vol"].list.First().Score())
+
+	// same score
+	added, err = zs.Add("vol", "AAPL", 5.0)
+	assert.Nil(t, err)
+	assert.False(t, added)
+
+	// nan
+	_, err = zs.Add("vol", "MSFT", math.NaN())
+	assert.Equal(t, ErrInvalidScore, err)
+	assert.Equal(t, 1, len(zs.store["vol"].scores))
+}
+
+func TestSortedSetIncrScore(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// missing member starts from zero
+	v, err := zs.IncrScore("vol", "AAPL", 2.5)
+	assert.Nil(t, err)
+	assert.Equal(t, 2.5, v)
+
+	v, err = zs.IncrScore("vol", "AAPL", -1.0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, v)
+	assert.Equal(t, 1.5, zs.store["vol"].list.First().Score())
+
+	// nan
+	zs.store["vol"].list.Delete(1.5, "AAPL")
+	zs.store["vol"].list.Insert(math.Inf(1), "AAPL")
+	zs.store["vol"].scores["AAPL"] = math.Inf(1)
+	_, err = zs.IncrScore("vol", "AAPL", math.Inf(-1))
+	assert.Equal(t, ErrInvalidScore, err)
+}
+
+func TestSortedSetScore(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no key
+	_, ok := zs.Score("vol", "AAPL")
+	assert.False(t, ok)
+
+	zs.store["vol"] = mockSortedSet()
+	v, ok := zs.Score("vol", "AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, 300.0, v)
+
+	// no member
+	_, ok = zs.Score("vol", "FOO")
+	assert.False(t, ok)
+}
+
+func TestSortedSetRemove(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no key
+	assert.Equal(t, 0, zs.Remove("vol", "AAPL"))
+
+	zs.store["vol"] = mockSortedSet()
+	assert.Equal(t, 2, zs.Remove("vol", "AAPL", "IBM", "FOO"))
+	assert.Equal(t, 2, zs.store["vol"].list.Len())
+	_, ok := zs.store["vol"].scores["AAPL"]
+	assert.False(t, ok)
+
+	// last member deletes key
+	assert.Equal(t, 2, zs.Remove("vol", "MSFT", "GOOG"))
+	assert.False(t, zs.isMember("vol"))
+}
+
+func TestSortedSetRank(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no key
+	_, ok := zs.Rank("vol", "AAPL")
+	assert.False(t, ok)
+
+	zs.store["vol"] = mockSortedSet()
+
+	r, ok := zs.Rank("vol", "IBM")
+	assert.True(t, ok)
+	assert.Equal(t, 0, r)
+
+	// equal scores ordered by member
+	r, _ = zs.Rank("vol", "GOOG")
+	assert.Equal(t, 1, r)
+	r, _ = zs.Rank("vol", "MSFT")
+	assert.Equal(t, 2, r)
+
+	r, ok = zs.RevRank("vol", "AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, 0, r)
+	r, _ = zs.RevRank("vol", "IBM")
+	assert.Equal(t, 3, r)
+
+	// no member
+	_, ok = zs.RevRank("vol", "FOO")
+	assert.False(t, ok)
+}
+
+func TestSortedSetRangeByRank(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no key
+	_, err := zs.RangeByRank("vol", 0, 2)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	zs.store["vol"] = mockSortedSet()
+
+	rng, err := zs.RangeByRank("vol", 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{{"IBM", 100.0}, {"GOOG", 200.0}}, rng)
+
+	// full range
+	rng, err = zs.RangeByRank("vol", 0, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(rng))
+
+	// empty range
+	rng, err = zs.RangeByRank("vol", 4, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(rng))
+
+	// top 2
+	rng, err = zs.RevRangeByRank("vol", 0, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{{"AAPL", 300.0}, {"MSFT", 200.0}}, rng)
+
+	rng, err = zs.RevRangeByRank("vol", 3, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{{"IBM", 100.0}}, rng)
+
+	// out of bounds
+	_, err = zs.RangeByRank("vol", -1, 2)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = zs.RevRangeByRank("vol", 0, 5)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = zs.RangeByRank("vol", 3, 2)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+}
+
+func TestSortedSetRangeByScore(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no key
+	_, err := zs.RangeByScore("vol", 0, 1)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	zs.store["vol"] = mockSortedSet()
+
+	rng, err := zs.RangeByScore("vol", 150.0, 300.0)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{{"GOOG", 200.0}, {"MSFT", 200.0}, {"AAPL", 300.0}}, rng)
+
+	rng, err = zs.RangeByScore("vol", math.Inf(-1), 100.0)
+	assert.Nil(t, err)
+	assert.Equal(t, []ScoredMember{{"IBM", 100.0}}, rng)
+
+	// none in range
+	rng, err = zs.RangeByScore("vol", 301.0, 400.0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(rng))
+}
+
+func TestSortedSetCard(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no key
+	_, err := zs.Card("vol")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	zs.store["vol"] = mockSortedSet()
+	c, err := zs.Card("vol")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, c)
+}
+
+func TestSortedSetSize(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no keys
+	size := zs.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	zs.store["a"] = mockSortedSet()
+	zs.store["b"] = mockSortedSet()
+
+	size = zs.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestSortedSetMembers(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no keys
+	mems := zs.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	zs.store["a"] = mockSortedSet()
+	zs.store["b"] = mockSortedSet()
+
+	mems = zs.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestSortedSetSortedMembers(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no keys
+	assert.Equal(t, []string{}, zs.SortedMembers())
+
+	zs.store["MSFT:bid"] = mockSortedSet()
+	zs.store["AAPL:bid"] = mockSortedSet()
+	zs.store["AAPL:ask"] = mockSortedSet()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, zs.SortedMembers())
+
+	// indexed
+	zs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, zs.SortedMembers())
+}
+
+func TestSortedSetMembersWithPrefix(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	zs.store["MSFT:bid"] = mockSortedSet()
+	zs.store["AAPL:bid"] = mockSortedSet()
+	zs.store["AAPL:ask"] = mockSortedSet()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, zs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, zs.MembersWithPrefix("GOOG"))
+
+	// indexed
+	zs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, zs.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, zs.MembersWithPrefix("GOOG"))
+}
+
+func TestSortedSetMembersMatching(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	zs.store["MSFT:bid"] = mockSortedSet()
+	zs.store["AAPL:bid"] = mockSortedSet()
+	zs.store["AAPL:ask"] = mockSortedSet()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, zs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, zs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, zs.MembersMatching("GOOG:*"))
+
+	// indexed
+	zs.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, zs.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, zs.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, zs.MembersMatching("GOOG:*"))
+}
+
+func TestSortedSetKeyIndex(t *testing.T) {
+	zs := NewSortedSetStore()
+	zs.store["foo"] = mockSortedSet()
+
+	// existing keys are indexed
+	zs.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, zs.index.Keys())
+
+	// created keys are indexed
+	zs.Add("bar", "a", 1)
+	assert.Equal(t, []string{"bar", "foo"}, zs.index.Keys())
+
+	// deleted keys are unindexed
+	zs.Remove("bar", "a")
+	assert.Equal(t, []string{"foo"}, zs.index.Keys())
+
+	// clear
+	zs.Clear()
+	assert.Equal(t, 0, zs.index.Len())
+}
+
+func TestSortedSetScan(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	zs.store["MSFT:bid"] = mockSortedSet()
+	zs.store["AAPL:bid"] = mockSortedSet()
+	zs.store["AAPL:ask"] = mockSortedSet()
+
+	keys, next := zs.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = zs.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = zs.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, zs.index)
+
+	// no keys
+	zs.Clear()
+	keys, next = zs.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestSortedSetIsMember(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	// no keys
+	ok := zs.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	zs.store["foo"] = mockSortedSet()
+
+	ok = zs.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestSortedSetClear(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	zs.store["foo"] = mockSortedSet()
+	assert.Equal(t, 1, len(zs.store))
+
+	zs.Clear()
+	assert.Equal(t, 0, len(zs.store))
+}
+
+func TestSortedSetConcurrentIncrScore(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				zs.IncrScore("vol", "AAPL", 1.0)
+				zs.RevRangeByRank("vol", 0, 1)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 400.0, zs.store["vol"].scores["AAPL"])
+}
+
+func TestSortedSetConcurrentGetAndSet(t *testing.T) {
+	zs := NewSortedSetStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			zs.Add("vol", "AAPL", float64(i))
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			zs.Rank("vol", "AAPL")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
@@ -0,0 +1,426 @@
+package seriesstore
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/blacklabcapital/safestore/internal/keyindex"
+)
+
+// StringSetStore is a store of string sets
+// Each key holds a set of unique members, e.g. a watchlist or symbol universe
+// Multi key operations are computed under one lock so they see a consistent store
+// A key is deleted when its last member is removed
+// Embedded sync.Mutex to provide atomic operation ability
+type StringSetStore struct {
+	sync.Mutex
+	store map[string]map[string]struct{}
+	index *keyindex.Index // ordered keys, nil until EnableKeyIndex or Scan is called
+}
+
+// NewStringSetStore constructs and initializes a new StringSetStore
+// Always use this function to init new StringSetStores
+func NewStringSetStore() *StringSetStore {
+	return &StringSetStore{store: make(map[string]map[string]struct{})}
+}
+
+func (s *StringSetStore) add(key string, members ...string) int {
+	// never create an empty set
+	if len(members) == 0 {
+		return 0
+	}
+
+	set, ok := s.store[key]
+	if !ok {
+		set = make(map[string]struct{}, len(members))
+		s.store[key] = set
+		s.index.Insert(key)
+	}
+
+	n := 0
+	for _, m := range members {
+		if _, ok := set[m]; !ok {
+			set[m] = struct{}{}
+			n++
+		}
+	}
+
+	return n
+}
+
+// Add adds the given members to the set of the given key, creating the key if it does not exist
+// returns the number of members that were not already in the set
+func (s *StringSetStore) Add(key string, members ...string) int {
+	s.Lock()
+	n := s.add(key, members...)
+	s.Unlock()
+
+	return n
+}
+
+func (s *StringSetStore) remove(key string, members ...string) int {
+	set, ok := s.store[key]
+	if !ok {
+		return 0
+	}
+
+	n := 0
+	for _, m := range members {
+		if _, ok := set[m]; ok {
+			delete(set, m)
+			n++
+		}
+	}
+
+	if len(set) == 0 {
+		delete(s.store, key)
+		s.index.Delete(key)
+	}
+
+	return n
+}
+
+// Remove removes the given members from the set of the given key
+// returns the number of members removed
+func (s *StringSetStore) Remove(key string, members ...string) int {
+	s.Lock()
+	n := s.remove(key, members...)
+	s.Unlock()
+
+	return n
+}
+
+func (s *StringSetStore) contains(key, member string) bool {
+	_, ok := s.store[key][member]
+
+	return ok
+}
+
+// Contains checks if member is in the set of the given key
+func (s *StringSetStore) Contains(key, member string) bool {
+	s.Lock()
+	ok := s.contains(key, member)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *StringSetStore) card(key string) (int, error) {
+	set, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return 0, ErrKeyDoesNotExist
+	}
+
+	return len(set), nil
+}
+
+// Card returns the number of members in the set of the given key
+func (s *StringSetStore) Card(key string) (int, error) {
+	s.Lock()
+	c, err := s.card(key)
+	s.Unlock()
+
+	return c, err
+}
+
+func (s *StringSetStore) sMembers(key string) ([]string, error) {
+	set, ok := s.store[key]
+
+	// check exists
+	if !ok {
+		return nil, ErrKeyDoesNotExist
+	}
+
+	mems := make([]string, len(set))
+
+	i := 0
+	for m := range set {
+		mems[i] = m
+		i++
+	}
+
+	return mems, nil
+}
+
+// SMembers returns all members of the set of the given key
+// Note: use Members for the keys of the store
+func (s *StringSetStore) SMembers(key string) ([]string, error) {
+	s.Lock()
+	mems, err := s.sMembers(key)
+	s.Unlock()
+
+	return mems, err
+}
+
+func (s *StringSetStore) sUnion(keys ...string) []string {
+	union := make(map[string]struct{})
+	for _, k := range keys {
+		for m := range s.store[k] {
+			union[m] = struct{}{}
+		}
+	}
+
+	mems := make([]string, 0, len(union))
+	for m := range union {
+		mems = append(mems, m)
+	}
+
+	return mems
+}
+
+// SUnion returns the members in any of the sets of the given keys
+// Missing keys are treated as empty sets
+func (s *StringSetStore) SUnion(keys ...string) []string {
+	s.Lock()
+	mems := s.sUnion(keys...)
+	s.Unlock()
+
+	return mems
+}
+
+func (s *StringSetStore) sInter(keys ...string) []string {
+	mems := make([]string, 0)
+	if len(keys) == 0 {
+		return mems
+	}
+
+	// iterate the smallest set, probe the others
+	smallest := s.store[keys[0]]
+	for _, k := range keys[1:] {
+		if len(s.store[k]) < len(smallest) {
+			smallest = s.store[k]
+		}
+	}
+
+	for m := range smallest {
+		in := true
+		for _, k := range keys {
+			if _, ok := s.store[k][m]; !ok {
+				in = false
+				break
+			}
+		}
+
+		if in {
+			mems = append(mems, m)
+		}
+	}
+
+	return mems
+}
+
+// SInter returns the members in all of the sets of the given keys
+// Missing keys are treated as empty sets
+func (s *StringSetStore) SInter(keys ...string) []string {
+	s.Lock()
+	mems := s.sInter(keys...)
+	s.Unlock()
+
+	return mems
+}
+
+func (s *StringSetStore) sDiff(key string, others ...string) []string {
+	mems := make([]string, 0)
+	for m := range s.store[key] {
+		in := false
+		for _, k := range others {
+			if _, ok := s.store[k][m]; ok {
+				in = true
+				break
+			}
+		}
+
+		if !in {
+			mems = append(mems, m)
+		}
+	}
+
+	return mems
+}
+
+// SDiff returns the members of the set of the given key that are in none of the sets of the others
+// Missing keys are treated as empty sets
+func (s *StringSetStore) SDiff(key string, others ...string) []string {
+	s.Lock()
+	mems := s.sDiff(key, others...)
+	s.Unlock()
+
+	return mems
+}
+
+func (s *StringSetStore) size() int {
+	return len(s.store)
+}
+
+// Size returns the current size of the store
+// Note: this is NOT capacity
+func (s *StringSetStore) Size() int {
+	s.Lock()
+	size := s.size()
+	s.Unlock()
+
+	return size
+}
+
+func (s *StringSetStore) members() []string {
+	mems := make([]string, len(s.store))
+
+	i := 0
+	for k := range s.store {
+		mems[i] = k
+		i++
+	}
+
+	return mems
+}
+
+// Members returns all keys of the store
+func (s *StringSetStore) Members() []string {
+	s.Lock()
+	v := s.members()
+	s.Unlock()
+
+	return v
+}
+
+func (s *StringSetStore) enableKeyIndex() {
+	if s.index != nil {
+		return
+	}
+
+	s.index = keyindex.New()
+	for k := range s.store {
+		s.index.Insert(k)
+	}
+}
+
+// EnableKeyIndex maintains an ordered index of the keys alongside the store,
+// so prefix, glob and sorted key queries no longer scan every key
+// Creating and deleting keys costs an extra O(log n) once enabled
+func (s *StringSetStore) EnableKeyIndex() {
+	s.Lock()
+	s.enableKeyIndex()
+	s.Unlock()
+}
+
+func (s *StringSetStore) sortedMembers() []string {
+	if s.index != nil {
+		return s.index.Keys()
+	}
+
+	mems := s.members()
+	sort.Strings(mems)
+
+	return mems
+}
+
+// SortedMembers returns all keys of the store in ascending order
+func (s *StringSetStore) SortedMembers() []string {
+	s.Lock()
+	v := s.sortedMembers()
+	s.Unlock()
+
+	return v
+}
+
+func (s *StringSetStore) membersWithPrefix(prefix string) []string {
+	if s.index != nil {
+		return s.index.WithPrefix(prefix)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if strings.HasPrefix(k, prefix) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersWithPrefix returns the keys of the store starting with prefix in ascending order
+func (s *StringSetStore) MembersWithPrefix(prefix string) []string {
+	s.Lock()
+	v := s.membersWithPrefix(prefix)
+	s.Unlock()
+
+	return v
+}
+
+func (s *StringSetStore) membersMatching(pattern string) []string {
+	if s.index != nil {
+		return s.index.Matching(pattern)
+	}
+
+	mems := make([]string, 0)
+	for k := range s.store {
+		if keyindex.Match(pattern, k) {
+			mems = append(mems, k)
+		}
+	}
+	sort.Strings(mems)
+
+	return mems
+}
+
+// MembersMatching returns the keys of the store matching the glob pattern in ascending order, e.g. "AAPL:*"
+// See keyindex.Match for the pattern syntax
+func (s *StringSetStore) MembersMatching(pattern string) []string {
+	s.Lock()
+	v := s.membersMatching(pattern)
+	s.Unlock()
+
+	return v
+}
+
+func (s *StringSetStore) scan(cursor string, count int, match string) ([]string, string) {
+	// batches page through the ordered key index, so each costs O(log n + count) rather than a walk of every key
+	s.enableKeyIndex()
+
+	return s.index.Scan(cursor, count, match)
+}
+
+// Scan returns a batch of at most count keys of the store matching the glob pattern match, "" for all keys,
+// and the cursor of the next batch
+// Start a scan with cursor "" and pass each returned cursor to the next call until it is "" again
+// Every key present for the whole scan is returned exactly once, and the store is only locked per batch
+// The first scan enables the key index, see EnableKeyIndex
+// A batch may hold fewer than count keys, or none, before the scan is done
+func (s *StringSetStore) Scan(cursor string, count int, match string) ([]string, string) {
+	s.Lock()
+	keys, next := s.scan(cursor, count, match)
+	s.Unlock()
+
+	return keys, next
+}
+
+func (s *StringSetStore) isMember(key string) bool {
+	_, ok := s.store[key]
+
+	return ok
+}
+
+// IsMember checks if the given key exists in the store
+func (s *StringSetStore) IsMember(key string) bool {
+	s.Lock()
+	ok := s.isMember(key)
+	s.Unlock()
+
+	return ok
+}
+
+func (s *StringSetStore) clear() {
+	s.store = make(map[string]map[string]struct{})
+	s.index.Clear()
+}
+
+// Clear deletes all keys in the store
+func (s *StringSetStore) Clear() {
+	s.Lock()
+	s.clear()
+	s.Unlock()
+}
@@ -0,0 +1,311 @@
+package seriesstore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockStringSet(members ...string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, m := range members {
+		set[m] = struct{}{}
+	}
+
+	return set
+}
+
+func TestStringSetAdd(t *testing.T) {
+	ss := NewStringSetStore()
+
+	assert.Equal(t, 2, ss.Add("tech", "AAPL", "MSFT"))
+	assert.Equal(t, mockStringSet("AAPL", "MSFT"), ss.store["tech"])
+
+	// existing members
+	assert.Equal(t, 1, ss.Add("tech", "AAPL", "IBM"))
+	assert.Equal(t, 3, len(ss.store["tech"]))
+
+	// no members
+	assert.Equal(t, 0, ss.Add("energy"))
+	assert.False(t, ss.isMember("energy"))
+}
+
+func TestStringSetRemove(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no key
+	assert.Equal(t, 0, ss.Remove("tech", "AAPL"))
+
+	ss.store["tech"] = mockStringSet("AAPL", "MSFT", "IBM")
+	assert.Equal(t, 2, ss.Remove("tech", "AAPL", "IBM", "FOO"))
+	assert.Equal(t, mockStringSet("MSFT"), ss.store["tech"])
+
+	// last member deletes key
+	assert.Equal(t, 1, ss.Remove("tech", "MSFT"))
+	assert.False(t, ss.isMember("tech"))
+}
+
+func TestStringSetContains(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no key
+	assert.False(t, ss.Contains("tech", "AAPL"))
+
+	ss.store["tech"] = mockStringSet("AAPL")
+	assert.True(t, ss.Contains("tech", "AAPL"))
+	assert.False(t, ss.Contains("tech", "IBM"))
+}
+
+func TestStringSetCard(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no key
+	_, err := ss.Card("tech")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	ss.store["tech"] = mockStringSet("AAPL", "MSFT")
+	c, err := ss.Card("tech")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, c)
+}
+
+func TestStringSetSMembers(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no key
+	_, err := ss.SMembers("tech")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	ss.store["tech"] = mockStringSet("AAPL", "MSFT")
+	mems, err := ss.SMembers("tech")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"AAPL", "MSFT"}, mems)
+}
+
+func TestStringSetSUnion(t *testing.T) {
+	ss := NewStringSetStore()
+	ss.store["a"] = mockStringSet("AAPL", "MSFT")
+	ss.store["b"] = mockStringSet("MSFT", "IBM")
+
+	assert.ElementsMatch(t, []string{"AAPL", "MSFT", "IBM"}, ss.SUnion("a", "b", "missing"))
+	assert.Equal(t, 0, len(ss.SUnion()))
+}
+
+func TestStringSetSInter(t *testing.T) {
+	ss := NewStringSetStore()
+	ss.store["a"] = mockStringSet("AAPL", "MSFT", "IBM")
+	ss.store["b"] = mockStringSet("MSFT", "IBM", "GOOG")
+	ss.store["c"] = mockStringSet("IBM", "MSFT")
+
+	assert.ElementsMatch(t, []string{"MSFT", "IBM"}, ss.SInter("a", "b", "c"))
+	assert.ElementsMatch(t, []string{"AAPL", "MSFT", "IBM"}, ss.SInter("a"))
+
+	// missing key is empty
+	assert.Equal(t, 0, len(ss.SInter("a", "missing")))
+	assert.Equal(t, 0, len(ss.SInter()))
+}
+
+func TestStringSetSDiff(t *testing.T) {
+	ss := NewStringSetStore()
+	ss.store["a"] = mockStringSet("AAPL", "MSFT", "IBM")
+	ss.store["b"] = mockStringSet("MSFT")
+	ss.store["c"] = mockStringSet("IBM", "GOOG")
+
+	assert.ElementsMatch(t, []string{"AAPL"}, ss.SDiff("a", "b", "c"))
+	assert.ElementsMatch(t, []string{"AAPL", "MSFT", "IBM"}, ss.SDiff("a", "missing"))
+	assert.Equal(t, 0, len(ss.SDiff("missing", "a")))
+}
+
+func TestStringSetSize(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no keys
+	size := ss.Size()
+	assert.Equal(t, 0, size)
+
+	// add two keys
+	ss.store["a"] = mockStringSet("AAPL")
+	ss.store["b"] = mockStringSet("AAPL")
+
+	size = ss.Size()
+	assert.Equal(t, 2, size)
+}
+
+func TestStringSetMembers(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no keys
+	mems := ss.Members()
+	assert.Equal(t, 0, len(mems))
+
+	// add two keys
+	ss.store["a"] = mockStringSet("AAPL")
+	ss.store["b"] = mockStringSet("AAPL")
+
+	mems = ss.Members()
+	assert.Equal(t, 2, len(mems))
+}
+
+func TestStringSetSortedMembers(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:ask"] = mockStringSet("AAPL")
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestStringSetMembersWithPrefix(t *testing.T) {
+	ss := NewStringSetStore()
+
+	ss.store["MSFT:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:ask"] = mockStringSet("AAPL")
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestStringSetMembersMatching(t *testing.T) {
+	ss := NewStringSetStore()
+
+	ss.store["MSFT:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:ask"] = mockStringSet("AAPL")
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestStringSetKeyIndex(t *testing.T) {
+	ss := NewStringSetStore()
+	ss.store["foo"] = mockStringSet("AAPL")
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Add("bar", "a")
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// deleted keys are unindexed
+	ss.Remove("bar", "a")
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestStringSetScan(t *testing.T) {
+	ss := NewStringSetStore()
+
+	ss.store["MSFT:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:bid"] = mockStringSet("AAPL")
+	ss.store["AAPL:ask"] = mockStringSet("AAPL")
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
+func TestStringSetIsMember(t *testing.T) {
+	ss := NewStringSetStore()
+
+	// no keys
+	ok := ss.IsMember("foo")
+	assert.False(t, ok)
+
+	// add key
+	ss.store["foo"] = mockStringSet("AAPL")
+
+	ok = ss.IsMember("foo")
+	assert.True(t, ok)
+}
+
+func TestStringSetClear(t *testing.T) {
+	ss := NewStringSetStore()
+
+	ss.store["foo"] = mockStringSet("AAPL")
+	assert.Equal(t, 1, len(ss.store))
+
+	ss.Clear()
+	assert.Equal(t, 0, len(ss.store))
+}
+
+func TestStringSetConcurrentAddAndSInter(t *testing.T) {
+	ss := NewStringSetStore()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			for i := 0; i < 100; i++ {
+				ss.Add("a", "AAPL")
+				ss.Add("b", "AAPL")
+				ss.SInter("a", "b")
+				ss.Remove("a", "AAPL")
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStringSetConcurrentGetAndSet(t *testing.T) {
+	ss := NewStringSetStore()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Add("foo", "AAPL")
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			ss.Contains("foo", "AAPL")
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
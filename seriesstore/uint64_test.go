@@ -1,6 +1,8 @@
 package seriesstore
 
 import (
+	"net"
+	"strconv"
 	"testing"
 	"time"
 
@@ -18,6 +20,19 @@ func TestUint64Set(t *testing.T) {
 	assert.Equal(t, ss.store["foo"], mockUint64Series())
 }
 
+func TestUint64Append(t *testing.T) {
+	ss := NewUint64SStore()
+
+	// key not exist
+	ss.Append("foo", mockUint64Series()...)
+	assert.Equal(t, mockUint64Series(), ss.store["foo"])
+
+	// existing key
+	ss.Append("foo", 10)
+	assert.Equal(t, len(mockUint64Series())+1, len(ss.store["foo"]))
+	assert.Equal(t, uint64(10), ss.store["foo"][len(mockUint64Series())])
+}
+
 func TestUint64SetIdx(t *testing.T) {
 	// key not exist
 	ss := NewUint64SStore()
@@ -127,6 +142,108 @@ func TestUint64GetRange(t *testing.T) {
 	assert.Equal(t, ErrIdxOutOfBounds, err)
 }
 
+func TestUint64Clone(t *testing.T) {
+	s := NewUint64SStore()
+	s.store["a"] = mockUint64Series()
+	s.store["b"] = []uint64{7}
+	s.EnableKeyIndex()
+
+	c := s.Clone()
+	assert.Equal(t, s.store, c.store)
+	assert.Equal(t, []string{"a", "b"}, c.index.Keys())
+
+	// clone is independent of the store
+	c.Set("c", mockUint64Series())
+	assert.False(t, s.IsMember("c"))
+	assert.Equal(t, []string{"a", "b"}, s.index.Keys())
+
+	c.store["a"][0] = 7
+	assert.Equal(t, mockUint64Series(), s.store["a"])
+}
+
+func TestUint64MergeFrom(t *testing.T) {
+	o := NewUint64SStore()
+	o.store["b"] = []uint64{7}
+	o.store["c"] = []uint64{7}
+
+	// incoming values win without a conflict func
+	s := NewUint64SStore()
+	s.store["a"] = mockUint64Series()
+	s.store["b"] = mockUint64Series()
+	s.MergeFrom(o, nil)
+	assert.Equal(t, map[string][]uint64{"a": mockUint64Series(), "b": []uint64{7}, "c": []uint64{7}}, s.store)
+
+	// conflict func decides keys held by both stores
+	s = NewUint64SStore()
+	s.store["a"] = mockUint64Series()
+	s.store["b"] = mockUint64Series()
+	s.EnableKeyIndex()
+	s.MergeFrom(o, func(key string, current, incoming []uint64) []uint64 {
+		assert.Equal(t, "b", key)
+		assert.Equal(t, mockUint64Series(), current)
+		assert.Equal(t, []uint64{7}, incoming)
+		return current
+	})
+	assert.Equal(t, map[string][]uint64{"a": mockUint64Series(), "b": mockUint64Series(), "c": []uint64{7}}, s.store)
+	assert.Equal(t, []string{"a", "b", "c"}, s.index.Keys())
+
+	// other is untouched
+	assert.Equal(t, map[string][]uint64{"b": []uint64{7}, "c": []uint64{7}}, o.store)
+
+	// merging a store into itself does not deadlock
+	s.MergeFrom(s, nil)
+	assert.Equal(t, 3, s.Size())
+}
+
+func TestUint64Diff(t *testing.T) {
+	s := NewUint64SStore()
+	s.store["a"] = mockUint64Series()
+	s.store["b"] = mockUint64Series()
+	s.store["c"] = mockUint64Series()
+
+	o := NewUint64SStore()
+	o.store["b"] = mockUint64Series()
+	o.store["c"] = []uint64{7}
+	o.store["d"] = []uint64{7}
+	o.store["e"] = []uint64{7}
+
+	d := s.Diff(o)
+	assert.Equal(t, []string{"d", "e"}, d.Added)
+	assert.Equal(t, []string{"a"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+	assert.False(t, d.Empty())
+
+	// reversed
+	d = o.Diff(s)
+	assert.Equal(t, []string{"a"}, d.Added)
+	assert.Equal(t, []string{"d", "e"}, d.Removed)
+	assert.Equal(t, []string{"c"}, d.Changed)
+
+	// same contents
+	assert.True(t, s.Diff(s).Empty())
+	assert.True(t, s.Diff(s.Clone()).Empty())
+	assert.True(t, NewUint64SStore().Diff(NewUint64SStore()).Empty())
+}
+
+func TestUint64ReplaceAll(t *testing.T) {
+	s := NewUint64SStore()
+	s.store["a"] = mockUint64Series()
+	s.EnableKeyIndex()
+
+	values := map[string][]uint64{"b": []uint64{7}, "c": mockUint64Series()}
+	s.ReplaceAll(values)
+	assert.Equal(t, values, s.store)
+	assert.Equal(t, []string{"b", "c"}, s.index.Keys())
+
+	// store holds a copy of the values
+	values["d"] = mockUint64Series()
+	assert.False(t, s.IsMember("d"))
+
+	s.ReplaceAll(nil)
+	assert.Equal(t, 0, s.Size())
+	assert.Equal(t, 0, s.index.Len())
+}
+
 func TestUint64Size(t *testing.T) {
 	ss := NewUint64SStore()
 
@@ -157,6 +274,103 @@ func TestUint64Members(t *testing.T) {
 	assert.Equal(t, 2, len(mems))
 }
 
+func TestUint64SortedMembers(t *testing.T) {
+	ss := NewUint64SStore()
+
+	// no keys
+	assert.Equal(t, []string{}, ss.SortedMembers())
+
+	ss.store["MSFT:bid"] = mockUint64Series()
+	ss.store["AAPL:bid"] = mockUint64Series()
+	ss.store["AAPL:ask"] = mockUint64Series()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid", "MSFT:bid"}, ss.SortedMembers())
+}
+
+func TestUint64MembersWithPrefix(t *testing.T) {
+	ss := NewUint64SStore()
+
+	ss.store["MSFT:bid"] = mockUint64Series()
+	ss.store["AAPL:bid"] = mockUint64Series()
+	ss.store["AAPL:ask"] = mockUint64Series()
+
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, ss.MembersWithPrefix("AAPL:"))
+	assert.Equal(t, []string{}, ss.MembersWithPrefix("GOOG"))
+}
+
+func TestUint64MembersMatching(t *testing.T) {
+	ss := NewUint64SStore()
+
+	ss.store["MSFT:bid"] = mockUint64Series()
+	ss.store["AAPL:bid"] = mockUint64Series()
+	ss.store["AAPL:ask"] = mockUint64Series()
+
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+
+	// indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, ss.MembersMatching("*:bid"))
+	assert.Equal(t, []string{"AAPL:ask"}, ss.MembersMatching("AAPL:a*"))
+	assert.Equal(t, []string{}, ss.MembersMatching("GOOG:*"))
+}
+
+func TestUint64KeyIndex(t *testing.T) {
+	ss := NewUint64SStore()
+	ss.store["foo"] = mockUint64Series()
+
+	// existing keys are indexed
+	ss.EnableKeyIndex()
+	assert.Equal(t, []string{"foo"}, ss.index.Keys())
+
+	// created keys are indexed
+	ss.Set("bar", mockUint64Series())
+	assert.Equal(t, []string{"bar", "foo"}, ss.index.Keys())
+
+	// clear
+	ss.Clear()
+	assert.Equal(t, 0, ss.index.Len())
+}
+
+func TestUint64Scan(t *testing.T) {
+	ss := NewUint64SStore()
+
+	ss.store["MSFT:bid"] = mockUint64Series()
+	ss.store["AAPL:bid"] = mockUint64Series()
+	ss.store["AAPL:ask"] = mockUint64Series()
+
+	keys, next := ss.Scan("", 2, "")
+	assert.Equal(t, []string{"AAPL:ask", "AAPL:bid"}, keys)
+	assert.NotEqual(t, "", next)
+
+	keys, next = ss.Scan(next, 2, "")
+	assert.Equal(t, []string{"MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	keys, next = ss.Scan("", 10, "*:bid")
+	assert.Equal(t, []string{"AAPL:bid", "MSFT:bid"}, keys)
+	assert.Equal(t, "", next)
+
+	// the first scan enabled the key index
+	assert.NotNil(t, ss.index)
+
+	// no keys
+	ss.Clear()
+	keys, next = ss.Scan("", 10, "")
+	assert.Equal(t, []string{}, keys)
+	assert.Equal(t, "", next)
+}
+
 func TestUint64IsMember(t *testing.T) {
 	ss := NewUint64SStore()
 
@@ -197,6 +411,93 @@ func TestUint64Clear(t *testing.T) {
 	assert.Equal(t, 0, len(ss.store))
 }
 
+func TestUint64Digest(t *testing.T) {
+	s := NewUint64SStore()
+	assert.Equal(t, uint64(0), s.Digest())
+
+	s.Set("a", mockUint64Series())
+	s.Set("b", []uint64{7})
+
+	// write order does not matter
+	o := NewUint64SStore()
+	o.Set("b", []uint64{7})
+	o.Set("a", mockUint64Series())
+	assert.Equal(t, s.Digest(), o.Digest())
+
+	// values and keys do
+	o.Append("b", uint64(7))
+	assert.NotEqual(t, s.Digest(), o.Digest())
+	o.Set("b", []uint64{7})
+	assert.Equal(t, s.Digest(), o.Digest())
+	o.Set("c", nil)
+	assert.NotEqual(t, s.Digest(), o.Digest())
+}
+
+func TestUint64EnableDigest(t *testing.T) {
+	s := NewUint64SStore()
+	s.Set("a", mockUint64Series())
+	d := s.Digest()
+
+	s.EnableDigest()
+	assert.NotNil(t, s.digest)
+	assert.Equal(t, d, s.Digest())
+
+	// appends extend the hash of the series
+	s.Append("a", uint64(7), uint64(7))
+	s.Append("b", uint64(7))
+	o := NewUint64SStore()
+	o.Set("a", append(mockUint64Series(), uint64(7), uint64(7)))
+	o.Set("b", []uint64{uint64(7)})
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	// setIdx hashes the series again
+	assert.Nil(t, s.SetIdx("a", 0, uint64(7)))
+	assert.NotEqual(t, o.Digest(), s.Digest())
+	assert.Nil(t, o.SetIdx("a", 0, uint64(7)))
+	assert.Equal(t, o.Digest(), s.Digest())
+
+	c := s.Clone()
+	assert.NotNil(t, c.digest)
+	assert.Equal(t, s.Digest(), c.Digest())
+
+	s.ReplaceAll(map[string][]uint64{"a": mockUint64Series()})
+	assert.Equal(t, 1, s.digest.Len())
+	assert.Equal(t, d, s.Digest())
+
+	s.Clear()
+	assert.Equal(t, uint64(0), s.Digest())
+}
+
+func TestUint64SyncFrom(t *testing.T) {
+	s := NewUint64SStore()
+	for i := 0; i < 100; i++ {
+		s.Set(strconv.Itoa(i), mockUint64Series())
+	}
+	s.Set("b", []uint64{7})
+
+	r := s.Clone()
+	r.EnableDigest()
+	r.Append("b", uint64(7))
+	r.ReplaceAll(map[string][]uint64{"1": mockUint64Series(), "b": []uint64{7}, "c": []uint64{7}})
+	r.Set("2", []uint64{7})
+
+	// most keys only differ on one side
+	for _, want := range []int{100, 0} {
+		a, b := net.Pipe()
+		served := make(chan error, 1)
+		go func() { served <- s.ServeSync(a) }()
+
+		n, err := r.SyncFrom(b)
+		assert.Nil(t, err)
+		assert.Nil(t, <-served)
+		assert.Equal(t, want, n)
+		assert.True(t, s.Diff(r).Empty())
+		assert.Equal(t, s.Digest(), r.Digest())
+		a.Close()
+		b.Close()
+	}
+}
+
 func TestUint64ConcurrentGetAndSet(t *testing.T) {
 	ss := NewUint64SStore()
 
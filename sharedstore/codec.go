@@ -0,0 +1,80 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"math"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// value sizes in bytes
+const (
+	float32Size = 4
+	float64Size = 8
+	int32Size   = 4
+	int64Size   = 8
+	uint32Size  = 4
+	uint64Size  = 8
+	ohlcSize    = 16
+)
+
+func putFloat32(b []byte, v float32) {
+	le.PutUint32(b, math.Float32bits(v))
+}
+
+func getFloat32(b []byte) float32 {
+	return math.Float32frombits(le.Uint32(b))
+}
+
+func putFloat64(b []byte, v float64) {
+	le.PutUint64(b, math.Float64bits(v))
+}
+
+func getFloat64(b []byte) float64 {
+	return math.Float64frombits(le.Uint64(b))
+}
+
+func putInt32(b []byte, v int32) {
+	le.PutUint32(b, uint32(v))
+}
+
+func getInt32(b []byte) int32 {
+	return int32(le.Uint32(b))
+}
+
+func putInt64(b []byte, v int64) {
+	le.PutUint64(b, uint64(v))
+}
+
+func getInt64(b []byte) int64 {
+	return int64(le.Uint64(b))
+}
+
+func putUint32(b []byte, v uint32) {
+	le.PutUint32(b, v)
+}
+
+func getUint32(b []byte) uint32 {
+	return le.Uint32(b)
+}
+
+func putUint64(b []byte, v uint64) {
+	le.PutUint64(b, v)
+}
+
+func getUint64(b []byte) uint64 {
+	return le.Uint64(b)
+}
+
+func putOHLC(b []byte, v seriesstore.OHLC) {
+	putFloat32(b[0:], v.Open)
+	putFloat32(b[4:], v.High)
+	putFloat32(b[8:], v.Low)
+	putFloat32(b[12:], v.Close)
+}
+
+func getOHLC(b []byte) seriesstore.OHLC {
+	return seriesstore.OHLC{Open: getFloat32(b[0:]), High: getFloat32(b[4:]), Low: getFloat32(b[8:]), Close: getFloat32(b[12:])}
+}
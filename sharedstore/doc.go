@@ -0,0 +1,61 @@
+// Package sharedstore provides stores of fixed size values held in a memory-mapped file,
+// so several processes on the same machine read the same data without each holding a copy
+//
+// A file has a single writer process, which creates it with one of the Create functions or reopens it
+// with one of the Open functions, and any number of reader processes, which map it read-only.
+// Writers hold an exclusive flock on the file, a second writer fails with ErrWriterExists.
+// Every key has a slot guarded by a seqlock: the writer makes the slot sequence odd, updates the slot
+// and makes it even again, and readers retry any copy of a slot whose sequence was odd or changed,
+// so reads never see a half written value and never block the writer.
+// A slot left odd by a writer that died is deleted when the file is next opened for writing.
+// Closing a reader or store waits for reads in progress on other goroutines.
+//
+// Scalar stores such as Float64Store hold one value per key. Series stores such as Float64SStore hold
+// the last depth values appended to each key, older values are dropped as new ones are appended.
+// The number of keys and the series depth are fixed when the file is created.
+//
+// The package is only built on linux.
+//
+// # File layout
+//
+// All fields are little endian, offsets and sizes are in bytes.
+// The header is 64 bytes:
+//
+//	offset  size  field
+//	0       8     magic, the ASCII bytes "SAFESTOR"
+//	8       4     layout version, currently 1
+//	12      4     value type: 1 float32, 2 float64, 3 int32, 4 int64, 5 uint32, 6 uint64, 7 OHLC
+//	16      4     value size
+//	20      4     depth, the number of values held per key, 0 for a scalar store
+//	24      4     capacity, the number of key slots
+//	28      4     key size, the longest key in bytes
+//	32      4     slot size
+//	36      4     reserved
+//	40      8     count, the number of keys in use, updated atomically
+//	48      16    reserved
+//
+// Slot i starts at offset 64 + i * slot size. Slot size is 24 + key size + max(depth, 1) * value size,
+// rounded up to a multiple of 8, so every slot starts 8 byte aligned:
+//
+//	offset  size  field
+//	0       8     sequence, odd while the writer updates the slot, updated atomically
+//	8       4     state: 0 empty, 1 used, 2 deleted
+//	12      4     key length
+//	16      4     n, the number of values held
+//	20      4     head, the position of the oldest value
+//	24      k     key bytes, k is the key size
+//	24 + k        values, max(depth, 1) values of value size each
+//
+// Series values form a ring: value i of a key, oldest first, is at position (head + i) % depth.
+// Integers are two's complement, floats are IEEE 754 bits, and an OHLC value is its Open, High, Low and Close
+// float32 fields in that order.
+//
+// A key is found by open addressing: probing starts at slot FNV-1a(key) % capacity, the 32 bit FNV-1a hash of the key bytes,
+// and moves to the next slot, wrapping around, until the key or an empty slot is found.
+// Deleted slots keep probing going and may be reused by the writer.
+//
+// To read a slot, load the sequence, retry while it is odd, copy the slot, then load the sequence again
+// and retry the copy if it changed. Every 8 byte word of a slot, the sequence included, and the count are read
+// and written with sequentially consistent atomic 64 bit operations in native byte order, which is little endian
+// on amd64 and arm64, so the copy is ordered between the two loads of the sequence on either architecture
+package sharedstore
@@ -0,0 +1,535 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"encoding/binary"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+)
+
+var le = binary.LittleEndian
+
+// region is a mapped store file
+// Reads only take mu for reading, so they never wait on each other or on the writer,
+// writes must be made by a single goroutine at a time
+type region struct {
+	mu        sync.RWMutex // held for writing by close, so reads in progress never see the file unmapped
+	file      *os.File
+	data      []byte
+	valueType int
+	valueSize int
+	depth     int
+	capacity  int
+	slotSize  int
+	keys      map[string]int // slot of each key in use, nil unless writable
+	scratch   []byte         // the writer's copy of the slot being updated, nil unless writable
+}
+
+func slotSize(valueSize, depth int) int {
+	if depth < 1 {
+		depth = 1
+	}
+
+	// round up to keep every slot 8 byte aligned
+	return (slotHeader + KeySize + depth*valueSize + 7) &^ 7
+}
+
+// probeStart returns the first slot probed for key, by the 32 bit FNV-1a hash of the key
+func probeStart(key string, capacity int) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return int(h % uint32(capacity))
+}
+
+func mapFile(f *os.File, size int, writable bool) ([]byte, error) {
+	prot := syscall.PROT_READ
+	if writable {
+		prot |= syscall.PROT_WRITE
+	}
+
+	return syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED)
+}
+
+func lockFile(f *os.File) error {
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return ErrWriterExists
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// createRegion creates a new store file at path and maps it for writing
+// Fails if the file already exists
+func createRegion(path string, valueType, valueSize, capacity, depth int) (*region, error) {
+	if capacity < 1 || depth < 0 {
+		return nil, ErrInvalidSize
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &region{
+		file:      f,
+		valueType: valueType,
+		valueSize: valueSize,
+		depth:     depth,
+		capacity:  capacity,
+		slotSize:  slotSize(valueSize, depth),
+		keys:      make(map[string]int),
+	}
+	r.scratch = make([]byte, r.slotSize)
+
+	if err := r.init(); err != nil {
+		r.close()
+		os.Remove(path)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *region) init() error {
+	if err := lockFile(r.file); err != nil {
+		return err
+	}
+
+	size := headerSize + r.capacity*r.slotSize
+	if err := r.file.Truncate(int64(size)); err != nil {
+		return err
+	}
+
+	data, err := mapFile(r.file, size, true)
+	if err != nil {
+		return err
+	}
+	r.data = data
+
+	le.PutUint32(data[offVersion:], Version)
+	le.PutUint32(data[offType:], uint32(r.valueType))
+	le.PutUint32(data[offValueSize:], uint32(r.valueSize))
+	le.PutUint32(data[offDepth:], uint32(r.depth))
+	le.PutUint32(data[offCapacity:], uint32(r.capacity))
+	le.PutUint32(data[offKeySize:], KeySize)
+	le.PutUint32(data[offSlotSize:], uint32(r.slotSize))
+
+	// the magic goes last, readers reject the file until the header is complete
+	copy(data[offMagic:], magic)
+
+	return nil
+}
+
+// openRegion maps an existing store file holding values of the given type
+// series checks the file is a series store rather than a scalar store
+func openRegion(path string, valueType, valueSize int, series, writable bool) (*region, error) {
+	flag := os.O_RDONLY
+	if writable {
+		flag = os.O_RDWR
+	}
+
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &region{file: f}
+	if err := r.load(valueType, valueSize, series, writable); err != nil {
+		r.close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *region) load(valueType, valueSize int, series, writable bool) error {
+	if writable {
+		if err := lockFile(r.file); err != nil {
+			return err
+		}
+	}
+
+	fi, err := r.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size := int(fi.Size())
+	if size < headerSize {
+		return ErrInvalidFile
+	}
+
+	data, err := mapFile(r.file, size, writable)
+	if err != nil {
+		return err
+	}
+	r.data = data
+
+	r.valueType = int(le.Uint32(data[offType:]))
+	r.valueSize = int(le.Uint32(data[offValueSize:]))
+	r.depth = int(le.Uint32(data[offDepth:]))
+	r.capacity = int(le.Uint32(data[offCapacity:]))
+	r.slotSize = int(le.Uint32(data[offSlotSize:]))
+
+	if string(data[offMagic:offMagic+len(magic)]) != magic ||
+		le.Uint32(data[offVersion:]) != Version ||
+		le.Uint32(data[offKeySize:]) != KeySize ||
+		r.valueType != valueType || r.valueSize != valueSize ||
+		(r.depth > 0) != series || r.capacity < 1 ||
+		r.slotSize != slotSize(r.valueSize, r.depth) ||
+		size < headerSize+r.capacity*r.slotSize {
+		return ErrInvalidFile
+	}
+
+	if writable {
+		r.scratch = make([]byte, r.slotSize)
+		r.recover()
+	}
+
+	return nil
+}
+
+// recover rebuilds the key slots of a reopened writer
+// A slot left mid update by a writer that died may be torn, so it is deleted rather than made readable again
+func (r *region) recover() {
+	r.keys = make(map[string]int)
+	for i := 0; i < r.capacity; i++ {
+		s := r.slot(i)
+		if atomic.LoadUint64(seqOf(s))&1 == 1 {
+			r.setState(i, slotDeleted)
+		}
+
+		if le.Uint32(s[offState:]) == slotUsed {
+			r.keys[slotKey(s)] = i
+		}
+	}
+
+	atomic.StoreUint64(r.count(), uint64(len(r.keys)))
+}
+
+// close waits for reads in progress to return before unmapping the file
+func (r *region) close() error {
+	r.mu.Lock()
+	var err error
+	if r.data != nil {
+		err = syscall.Munmap(r.data)
+		r.data = nil
+	}
+
+	// closing the file releases the writer lock
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	r.mu.Unlock()
+
+	return err
+}
+
+// word returns the 8 byte word of b at off
+func word(b []byte, off int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&b[off : off+8][0]))
+}
+
+// loadWords copies src into dst with an atomic load of each word, len(dst) must be a multiple of 8
+func loadWords(dst, src []byte) {
+	for off := 0; off < len(dst); off += 8 {
+		*word(dst, off) = atomic.LoadUint64(word(src, off))
+	}
+}
+
+// storeWords copies the words of src after the slot sequence into dst with an atomic store of each word that changed
+func storeWords(dst, src []byte) {
+	for off := offState; off < len(src); off += 8 {
+		w, p := *word(src, off), word(dst, off)
+		if atomic.LoadUint64(p) != w {
+			atomic.StoreUint64(p, w)
+		}
+	}
+}
+
+func (r *region) count() *uint64 {
+	return word(r.data, offCount)
+}
+
+func (r *region) size() int {
+	r.mu.RLock()
+	n := 0
+	if r.data != nil {
+		n = int(atomic.LoadUint64(r.count()))
+	}
+	r.mu.RUnlock()
+
+	return n
+}
+
+func (r *region) slot(i int) []byte {
+	off := headerSize + i*r.slotSize
+
+	return r.data[off : off+r.slotSize]
+}
+
+func seqOf(s []byte) *uint64 {
+	return word(s, offSeq)
+}
+
+// slotKey returns the key of a slot, the slot must be a consistent copy or owned by the writer
+func slotKey(s []byte) string {
+	n := int(le.Uint32(s[offKeyLen:]))
+	if n > KeySize {
+		n = KeySize
+	}
+
+	return string(s[offKey : offKey+n])
+}
+
+func hasKey(s []byte, key string) bool {
+	return le.Uint32(s[offState:]) == slotUsed &&
+		int(le.Uint32(s[offKeyLen:])) == len(key) &&
+		string(s[offKey:offKey+len(key)]) == key
+}
+
+// copySlot copies the first len(buf) bytes of slot i into buf, retrying until the copy is not torn by a write
+// Every word is loaded atomically, so the copy is ordered between the two loads of the sequence
+func (r *region) copySlot(i int, buf []byte) {
+	s := r.slot(i)
+	seq := seqOf(s)
+	for {
+		before := atomic.LoadUint64(seq)
+		if before&1 == 0 {
+			loadWords(buf, s[:len(buf)])
+			if atomic.LoadUint64(seq) == before {
+				return
+			}
+		}
+
+		runtime.Gosched()
+	}
+}
+
+// lookup copies the slot of key into buf, which must hold a whole slot
+// returns ErrKeyDoesNotExist if key is not found, or ErrClosed
+func (r *region) lookup(key string, buf []byte) error {
+	r.mu.RLock()
+	err := r.find(key, buf)
+	r.mu.RUnlock()
+
+	return err
+}
+
+func (r *region) find(key string, buf []byte) error {
+	if r.data == nil {
+		return ErrClosed
+	}
+	if len(key) > KeySize {
+		return ErrKeyDoesNotExist
+	}
+
+	start := probeStart(key, r.capacity)
+	for p := 0; p < r.capacity; p++ {
+		i := (start + p) % r.capacity
+
+		// probe on the slot header and key, only copy the values of a match
+		r.copySlot(i, buf[:offKey+KeySize])
+		if le.Uint32(buf[offState:]) == slotEmpty {
+			return ErrKeyDoesNotExist
+		}
+
+		if hasKey(buf, key) {
+			r.copySlot(i, buf)
+			if hasKey(buf, key) {
+				return nil
+			}
+		}
+	}
+
+	return ErrKeyDoesNotExist
+}
+
+func (r *region) isMember(key string) bool {
+	return r.lookup(key, make([]byte, r.slotSize)) == nil
+}
+
+func (r *region) members() []string {
+	r.mu.RLock()
+	if r.data == nil {
+		r.mu.RUnlock()
+		return nil
+	}
+
+	buf := make([]byte, offKey+KeySize)
+	mems := make([]string, 0, atomic.LoadUint64(r.count()))
+	for i := 0; i < r.capacity; i++ {
+		r.copySlot(i, buf)
+		if le.Uint32(buf[offState:]) == slotUsed {
+			mems = append(mems, slotKey(buf))
+		}
+	}
+	r.mu.RUnlock()
+
+	return mems
+}
+
+// value returns the bytes of the value at ring position pos of a slot
+func (r *region) value(s []byte, pos int) []byte {
+	off := offKey + KeySize + pos*r.valueSize
+
+	return s[off : off+r.valueSize]
+}
+
+// at returns the bytes of value idx of a slot, oldest first
+func (r *region) at(s []byte, idx int) []byte {
+	if r.depth < 1 {
+		return r.value(s, 0)
+	}
+
+	head := int(le.Uint32(s[offHead:]))
+
+	return r.value(s, (head+idx)%r.depth)
+}
+
+func slotLen(s []byte) int {
+	return int(le.Uint32(s[offN:]))
+}
+
+// push makes room for one more value at the end of a series slot, dropping the oldest value when the slot is full
+// returns the bytes of the new value
+func (r *region) push(s []byte) []byte {
+	n := slotLen(s)
+	if n < r.depth {
+		le.PutUint32(s[offN:], uint32(n+1))
+		return r.at(s, n)
+	}
+
+	head := int(le.Uint32(s[offHead:]))
+	le.PutUint32(s[offHead:], uint32((head+1)%r.depth))
+
+	return r.value(s, head)
+}
+
+// update runs fn on the writer's copy of slot i, then stores the words it changed between the seqlock updates
+// A slot already odd was left mid update by a writer that died, the update completes it
+func (r *region) update(i int, fn func(s []byte) error) error {
+	s := r.slot(i)
+	buf := r.scratch
+	loadWords(buf, s)
+	err := fn(buf)
+
+	seq := seqOf(s)
+	if atomic.LoadUint64(seq)&1 == 0 {
+		atomic.AddUint64(seq, 1)
+	}
+	storeWords(s, buf)
+	atomic.AddUint64(seq, 1)
+
+	return err
+}
+
+// write runs fn on the slot of key, creating the key if create is set
+// fn may return an error after leaving the slot unchanged
+func (r *region) write(key string, create bool, fn func(s []byte) error) error {
+	if r.data == nil {
+		return ErrClosed
+	}
+
+	i, ok := r.keys[key]
+	if !ok {
+		if !create {
+			return ErrKeyDoesNotExist
+		}
+
+		if len(key) > KeySize {
+			return ErrKeyTooLong
+		}
+
+		if len(r.keys) >= r.capacity {
+			return ErrStoreFull
+		}
+
+		i = r.free(key)
+	}
+
+	err := r.update(i, func(s []byte) error {
+		if !ok {
+			le.PutUint32(s[offState:], slotUsed)
+			le.PutUint32(s[offKeyLen:], uint32(len(key)))
+			copy(s[offKey:offKey+KeySize], key)
+			le.PutUint32(s[offN:], 0)
+			le.PutUint32(s[offHead:], 0)
+		}
+
+		return fn(s)
+	})
+
+	if !ok {
+		r.keys[key] = i
+		atomic.StoreUint64(r.count(), uint64(len(r.keys)))
+	}
+
+	return err
+}
+
+// free returns the first slot not in use on the probe sequence of key
+func (r *region) free(key string) int {
+	start := probeStart(key, r.capacity)
+	for p := 0; p < r.capacity; p++ {
+		i := (start + p) % r.capacity
+		if le.Uint32(r.slot(i)[offState:]) != slotUsed {
+			return i
+		}
+	}
+
+	// callers check a slot is free first
+	panic("sharedstore: no free slot")
+}
+
+// setState updates the state of slot i, dropping its values
+func (r *region) setState(i int, state uint32) {
+	r.update(i, func(s []byte) error {
+		le.PutUint32(s[offState:], state)
+		le.PutUint32(s[offN:], 0)
+		le.PutUint32(s[offHead:], 0)
+
+		return nil
+	})
+}
+
+func (r *region) delete(key string) {
+	i, ok := r.keys[key]
+	if !ok || r.data == nil {
+		return
+	}
+
+	// deleted slots keep the probe sequences of other keys going
+	r.setState(i, slotDeleted)
+	delete(r.keys, key)
+	atomic.StoreUint64(r.count(), uint64(len(r.keys)))
+}
+
+func (r *region) clear() {
+	if r.data == nil {
+		return
+	}
+
+	for i := 0; i < r.capacity; i++ {
+		if le.Uint32(r.slot(i)[offState:]) != slotEmpty {
+			r.setState(i, slotEmpty)
+		}
+	}
+
+	r.keys = make(map[string]int)
+	atomic.StoreUint64(r.count(), 0)
+}
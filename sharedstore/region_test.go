@@ -0,0 +1,309 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tempPath returns a path in a new temp dir and a func removing the dir
+func tempPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sharedstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "store"), func() { os.RemoveAll(dir) }
+}
+
+func TestSlotSize(t *testing.T) {
+	// header and key take 64 bytes
+	assert.Equal(t, 72, slotSize(float64Size, 0))
+	assert.Equal(t, 72, slotSize(float64Size, 1))
+	assert.Equal(t, 72, slotSize(float32Size, 1))
+	assert.Equal(t, 64+3*16, slotSize(ohlcSize, 3))
+
+	// rounded up to 8 bytes
+	assert.Equal(t, 64+16, slotSize(float32Size, 3))
+}
+
+func TestProbeStart(t *testing.T) {
+	// FNV-1a of "a" is 0xe40c292c
+	assert.Equal(t, int(uint32(0xe40c292c)%7), probeStart("a", 7))
+	assert.Equal(t, int(uint32(2166136261)%7), probeStart("", 7))
+	assert.Equal(t, 0, probeStart("a", 1))
+}
+
+func TestCreateRegionLayout(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	r, err := createRegion(path, typeFloat64, float64Size, 4, 3)
+	assert.Nil(t, err)
+	defer r.close()
+
+	fi, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(64+4*88), fi.Size())
+
+	d := r.data
+	assert.Equal(t, "SAFESTOR", string(d[0:8]))
+	assert.Equal(t, uint32(1), le.Uint32(d[8:]))
+	assert.Equal(t, uint32(2), le.Uint32(d[12:]))
+	assert.Equal(t, uint32(8), le.Uint32(d[16:]))
+	assert.Equal(t, uint32(3), le.Uint32(d[20:]))
+	assert.Equal(t, uint32(4), le.Uint32(d[24:]))
+	assert.Equal(t, uint32(KeySize), le.Uint32(d[28:]))
+	assert.Equal(t, uint32(88), le.Uint32(d[32:]))
+	assert.Equal(t, uint64(0), le.Uint64(d[40:]))
+
+	// a key lands in its probe start slot
+	assert.Nil(t, r.write("a", true, func(s []byte) error {
+		putFloat64(r.push(s), 1.5)
+		return nil
+	}))
+
+	i := probeStart("a", 4)
+	s := d[64+i*88 : 64+(i+1)*88]
+	assert.Equal(t, uint64(2), le.Uint64(s[0:]))
+	assert.Equal(t, uint32(slotUsed), le.Uint32(s[8:]))
+	assert.Equal(t, uint32(1), le.Uint32(s[12:]))
+	assert.Equal(t, uint32(1), le.Uint32(s[16:]))
+	assert.Equal(t, uint32(0), le.Uint32(s[20:]))
+	assert.Equal(t, "a", string(s[24:25]))
+	assert.Equal(t, 1.5, getFloat64(s[64:]))
+	assert.Equal(t, uint64(1), le.Uint64(d[40:]))
+}
+
+func TestCreateRegionErrors(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	_, err := createRegion(path, typeFloat64, float64Size, 0, 0)
+	assert.Equal(t, ErrInvalidSize, err)
+	_, err = createRegion(path, typeFloat64, float64Size, 1, -1)
+	assert.Equal(t, ErrInvalidSize, err)
+
+	r, err := createRegion(path, typeFloat64, float64Size, 1, 0)
+	assert.Nil(t, err)
+	defer r.close()
+
+	// never replaces a file
+	_, err = createRegion(path, typeFloat64, float64Size, 1, 0)
+	assert.True(t, os.IsExist(err))
+}
+
+func TestOpenRegion(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	_, err := openRegion(path, typeFloat64, float64Size, false, false)
+	assert.True(t, os.IsNotExist(err))
+
+	w, err := createRegion(path, typeFloat64, float64Size, 2, 0)
+	assert.Nil(t, err)
+
+	// a single writer at a time
+	_, err = openRegion(path, typeFloat64, float64Size, false, true)
+	assert.Equal(t, ErrWriterExists, err)
+
+	// wrong value type or kind
+	_, err = openRegion(path, typeInt64, int64Size, false, false)
+	assert.Equal(t, ErrInvalidFile, err)
+	_, err = openRegion(path, typeFloat64, float64Size, true, false)
+	assert.Equal(t, ErrInvalidFile, err)
+
+	r, err := openRegion(path, typeFloat64, float64Size, false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, r.capacity)
+	assert.Equal(t, 0, r.depth)
+	assert.Nil(t, r.keys)
+	assert.Nil(t, r.close())
+
+	// closing the writer releases the file
+	assert.Nil(t, w.close())
+	w, err = openRegion(path, typeFloat64, float64Size, false, true)
+	assert.Nil(t, err)
+	assert.Nil(t, w.close())
+}
+
+func TestOpenRegionInvalidFile(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte("SAFESTOR"), 0644))
+	_, err := openRegion(path, typeFloat64, float64Size, false, false)
+	assert.Equal(t, ErrInvalidFile, err)
+
+	r, err := createRegion(path+"2", typeFloat64, float64Size, 2, 0)
+	assert.Nil(t, err)
+	defer r.close()
+
+	// truncated slots
+	assert.Nil(t, os.Truncate(path+"2", 64+72))
+	_, err = openRegion(path+"2", typeFloat64, float64Size, false, false)
+	assert.Equal(t, ErrInvalidFile, err)
+}
+
+func TestRegionRecover(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	w, err := createRegion(path, typeInt64, int64Size, 4, 0)
+	assert.Nil(t, err)
+	for _, k := range []string{"a", "b", "c"} {
+		w.write(k, true, func(s []byte) error { return nil })
+	}
+	w.delete("b")
+
+	// a writer dying mid update leaves the slot sequence odd
+	i := w.keys["a"]
+	atomic.AddUint64(seqOf(w.slot(i)), 1)
+	w.close()
+
+	w, err = openRegion(path, typeInt64, int64Size, false, true)
+	assert.Nil(t, err)
+	defer w.close()
+	// the slot may be torn, so its key is dropped rather than made readable again
+	assert.Equal(t, map[string]int{"c": w.keys["c"]}, w.keys)
+	assert.Equal(t, 1, w.size())
+	assert.Equal(t, uint64(0), atomic.LoadUint64(seqOf(w.slot(i)))&1)
+	assert.Equal(t, uint32(slotDeleted), le.Uint32(w.slot(i)[offState:]))
+	assert.False(t, w.isMember("a"))
+}
+
+func TestRegionProbing(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	r, err := createRegion(path, typeInt64, int64Size, 4, 0)
+	assert.Nil(t, err)
+	defer r.close()
+
+	noop := func(s []byte) error { return nil }
+	keys := []string{"a", "b", "c", "d"}
+	for _, k := range keys {
+		assert.Nil(t, r.write(k, true, noop))
+	}
+	assert.Equal(t, ErrStoreFull, r.write("e", true, noop))
+	assert.ElementsMatch(t, keys, r.members())
+
+	// deleted slots keep later keys reachable and are reused
+	for _, k := range keys[:3] {
+		r.delete(k)
+	}
+	assert.True(t, r.isMember("d"))
+	assert.False(t, r.isMember("a"))
+	assert.Nil(t, r.write("e", true, noop))
+	assert.ElementsMatch(t, []string{"d", "e"}, r.members())
+	assert.Equal(t, 2, r.size())
+
+	r.clear()
+	assert.Equal(t, 0, r.size())
+	assert.Len(t, r.members(), 0)
+	for i := 0; i < r.capacity; i++ {
+		assert.Equal(t, uint32(slotEmpty), le.Uint32(r.slot(i)[offState:]))
+	}
+
+	long := string(make([]byte, KeySize+1))
+	assert.Equal(t, ErrKeyTooLong, r.write(long, true, noop))
+	assert.False(t, r.isMember(long))
+	assert.Equal(t, ErrKeyDoesNotExist, r.write("a", false, noop))
+}
+
+func TestRegionRing(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	r, err := createRegion(path, typeInt64, int64Size, 1, 3)
+	assert.Nil(t, err)
+	defer r.close()
+
+	s := r.slot(0)
+	for i := int64(1); i <= 5; i++ {
+		putInt64(r.push(s), i)
+	}
+
+	// the oldest values are dropped
+	assert.Equal(t, 3, slotLen(s))
+	assert.Equal(t, uint32(2), le.Uint32(s[offHead:]))
+	got := make([]int64, 0)
+	for i := 0; i < slotLen(s); i++ {
+		got = append(got, getInt64(r.at(s, i)))
+	}
+	assert.Equal(t, []int64{3, 4, 5}, got)
+}
+
+func TestRegionClosed(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	r, err := createRegion(path, typeInt64, int64Size, 1, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, r.close())
+
+	assert.Equal(t, ErrClosed, r.write("a", true, func(s []byte) error { return nil }))
+	assert.Equal(t, ErrClosed, r.lookup("a", make([]byte, r.slotSize)))
+	assert.False(t, r.isMember("a"))
+	assert.Nil(t, r.members())
+	assert.Equal(t, 0, r.size())
+	r.delete("a")
+	r.clear()
+}
+
+// TestHelperReaderProcess is run in a child process by TestCrossProcess
+func TestHelperReaderProcess(t *testing.T) {
+	path := os.Getenv("SHAREDSTORE_READER_PATH")
+	if path == "" {
+		t.Skip("only run as a child process")
+	}
+
+	s, err := OpenFloat64SReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// wait for the parent to append the last value
+	for {
+		v, err := s.GetIdx("px", 0)
+		if err == nil && v == 99 {
+			break
+		}
+	}
+
+	v, _ := s.Get("px")
+	os.Stdout.WriteString(strconv.FormatFloat(v[0], 'f', -1, 64) + "\n")
+}
+
+func TestCrossProcess(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	w, err := CreateFloat64SStore(path, 8, 1)
+	assert.Nil(t, err)
+	defer w.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=TestHelperReaderProcess")
+	cmd.Env = append(os.Environ(), "SHAREDSTORE_READER_PATH="+path)
+	out := make(chan []byte)
+	go func() {
+		b, _ := cmd.Output()
+		out <- b
+	}()
+
+	for i := 0; i < 100; i++ {
+		w.Append("px", float64(i))
+	}
+
+	assert.Contains(t, string(<-out), "99\n")
+}
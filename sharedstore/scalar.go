@@ -0,0 +1,740 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"sync"
+)
+
+// Float32Reader is a read-only view of a Float32Store file, mapped by any number of processes
+// Reads never block the writer or each other
+type Float32Reader struct {
+	r *region
+}
+
+// OpenFloat32Reader maps the Float32Store file at path read-only
+func OpenFloat32Reader(path string) (*Float32Reader, error) {
+	r, err := openRegion(path, typeFloat32, float32Size, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float32Reader{r: r}, nil
+}
+
+// Get returns the value for the given key
+// returns false if the key is not in the store or the store is closed
+func (s *Float32Reader) Get(key string) (float32, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return 0, false
+	}
+
+	return getFloat32(s.r.at(buf, 0)), true
+}
+
+// Size returns the number of keys in the store
+func (s *Float32Reader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Float32Reader) Capacity() int {
+	return s.r.capacity
+}
+
+// Members returns a list of keys in the store
+func (s *Float32Reader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Float32Reader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Float32Reader) Close() error {
+	return s.r.close()
+}
+
+// Float32Store is a store of float32 values in a memory-mapped file shared with Float32Readers in other processes
+// A file has a single Float32Store open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Float32Store struct {
+	sync.Mutex
+	Float32Reader
+}
+
+// CreateFloat32Store creates a new file at path holding up to capacity keys and opens it for writing
+// Fails if the file already exists
+func CreateFloat32Store(path string, capacity int) (*Float32Store, error) {
+	r, err := createRegion(path, typeFloat32, float32Size, capacity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float32Store{Float32Reader: Float32Reader{r: r}}, nil
+}
+
+// OpenFloat32Store opens the existing Float32Store file at path for writing
+func OpenFloat32Store(path string) (*Float32Store, error) {
+	r, err := openRegion(path, typeFloat32, float32Size, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float32Store{Float32Reader: Float32Reader{r: r}}, nil
+}
+
+func (s *Float32Store) set(key string, value float32) error {
+	return s.r.write(key, true, func(b []byte) error {
+		putFloat32(s.r.at(b, 0), value)
+		le.PutUint32(b[offN:], 1)
+
+		return nil
+	})
+}
+
+// Set stores the given value mapped to the given key
+func (s *Float32Store) Set(key string, value float32) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its value from the store
+func (s *Float32Store) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Float32Store) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Float32Store) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Float64Reader is a read-only view of a Float64Store file, mapped by any number of processes
+// Reads never block the writer or each other
+type Float64Reader struct {
+	r *region
+}
+
+// OpenFloat64Reader maps the Float64Store file at path read-only
+func OpenFloat64Reader(path string) (*Float64Reader, error) {
+	r, err := openRegion(path, typeFloat64, float64Size, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float64Reader{r: r}, nil
+}
+
+// Get returns the value for the given key
+// returns false if the key is not in the store or the store is closed
+func (s *Float64Reader) Get(key string) (float64, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return 0, false
+	}
+
+	return getFloat64(s.r.at(buf, 0)), true
+}
+
+// Size returns the number of keys in the store
+func (s *Float64Reader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Float64Reader) Capacity() int {
+	return s.r.capacity
+}
+
+// Members returns a list of keys in the store
+func (s *Float64Reader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Float64Reader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Float64Reader) Close() error {
+	return s.r.close()
+}
+
+// Float64Store is a store of float64 values in a memory-mapped file shared with Float64Readers in other processes
+// A file has a single Float64Store open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Float64Store struct {
+	sync.Mutex
+	Float64Reader
+}
+
+// CreateFloat64Store creates a new file at path holding up to capacity keys and opens it for writing
+// Fails if the file already exists
+func CreateFloat64Store(path string, capacity int) (*Float64Store, error) {
+	r, err := createRegion(path, typeFloat64, float64Size, capacity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float64Store{Float64Reader: Float64Reader{r: r}}, nil
+}
+
+// OpenFloat64Store opens the existing Float64Store file at path for writing
+func OpenFloat64Store(path string) (*Float64Store, error) {
+	r, err := openRegion(path, typeFloat64, float64Size, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float64Store{Float64Reader: Float64Reader{r: r}}, nil
+}
+
+func (s *Float64Store) set(key string, value float64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		putFloat64(s.r.at(b, 0), value)
+		le.PutUint32(b[offN:], 1)
+
+		return nil
+	})
+}
+
+// Set stores the given value mapped to the given key
+func (s *Float64Store) Set(key string, value float64) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its value from the store
+func (s *Float64Store) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Float64Store) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Float64Store) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Int32Reader is a read-only view of a Int32Store file, mapped by any number of processes
+// Reads never block the writer or each other
+type Int32Reader struct {
+	r *region
+}
+
+// OpenInt32Reader maps the Int32Store file at path read-only
+func OpenInt32Reader(path string) (*Int32Reader, error) {
+	r, err := openRegion(path, typeInt32, int32Size, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int32Reader{r: r}, nil
+}
+
+// Get returns the value for the given key
+// returns false if the key is not in the store or the store is closed
+func (s *Int32Reader) Get(key string) (int32, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return 0, false
+	}
+
+	return getInt32(s.r.at(buf, 0)), true
+}
+
+// Size returns the number of keys in the store
+func (s *Int32Reader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Int32Reader) Capacity() int {
+	return s.r.capacity
+}
+
+// Members returns a list of keys in the store
+func (s *Int32Reader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Int32Reader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Int32Reader) Close() error {
+	return s.r.close()
+}
+
+// Int32Store is a store of int32 values in a memory-mapped file shared with Int32Readers in other processes
+// A file has a single Int32Store open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Int32Store struct {
+	sync.Mutex
+	Int32Reader
+}
+
+// CreateInt32Store creates a new file at path holding up to capacity keys and opens it for writing
+// Fails if the file already exists
+func CreateInt32Store(path string, capacity int) (*Int32Store, error) {
+	r, err := createRegion(path, typeInt32, int32Size, capacity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int32Store{Int32Reader: Int32Reader{r: r}}, nil
+}
+
+// OpenInt32Store opens the existing Int32Store file at path for writing
+func OpenInt32Store(path string) (*Int32Store, error) {
+	r, err := openRegion(path, typeInt32, int32Size, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int32Store{Int32Reader: Int32Reader{r: r}}, nil
+}
+
+func (s *Int32Store) set(key string, value int32) error {
+	return s.r.write(key, true, func(b []byte) error {
+		putInt32(s.r.at(b, 0), value)
+		le.PutUint32(b[offN:], 1)
+
+		return nil
+	})
+}
+
+// Set stores the given value mapped to the given key
+func (s *Int32Store) Set(key string, value int32) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its value from the store
+func (s *Int32Store) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Int32Store) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Int32Store) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Int64Reader is a read-only view of a Int64Store file, mapped by any number of processes
+// Reads never block the writer or each other
+type Int64Reader struct {
+	r *region
+}
+
+// OpenInt64Reader maps the Int64Store file at path read-only
+func OpenInt64Reader(path string) (*Int64Reader, error) {
+	r, err := openRegion(path, typeInt64, int64Size, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int64Reader{r: r}, nil
+}
+
+// Get returns the value for the given key
+// returns false if the key is not in the store or the store is closed
+func (s *Int64Reader) Get(key string) (int64, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return 0, false
+	}
+
+	return getInt64(s.r.at(buf, 0)), true
+}
+
+// Size returns the number of keys in the store
+func (s *Int64Reader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Int64Reader) Capacity() int {
+	return s.r.capacity
+}
+
+// Members returns a list of keys in the store
+func (s *Int64Reader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Int64Reader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Int64Reader) Close() error {
+	return s.r.close()
+}
+
+// Int64Store is a store of int64 values in a memory-mapped file shared with Int64Readers in other processes
+// A file has a single Int64Store open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Int64Store struct {
+	sync.Mutex
+	Int64Reader
+}
+
+// CreateInt64Store creates a new file at path holding up to capacity keys and opens it for writing
+// Fails if the file already exists
+func CreateInt64Store(path string, capacity int) (*Int64Store, error) {
+	r, err := createRegion(path, typeInt64, int64Size, capacity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int64Store{Int64Reader: Int64Reader{r: r}}, nil
+}
+
+// OpenInt64Store opens the existing Int64Store file at path for writing
+func OpenInt64Store(path string) (*Int64Store, error) {
+	r, err := openRegion(path, typeInt64, int64Size, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int64Store{Int64Reader: Int64Reader{r: r}}, nil
+}
+
+func (s *Int64Store) set(key string, value int64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		putInt64(s.r.at(b, 0), value)
+		le.PutUint32(b[offN:], 1)
+
+		return nil
+	})
+}
+
+// Set stores the given value mapped to the given key
+func (s *Int64Store) Set(key string, value int64) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its value from the store
+func (s *Int64Store) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Int64Store) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Int64Store) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Uint32Reader is a read-only view of a Uint32Store file, mapped by any number of processes
+// Reads never block the writer or each other
+type Uint32Reader struct {
+	r *region
+}
+
+// OpenUint32Reader maps the Uint32Store file at path read-only
+func OpenUint32Reader(path string) (*Uint32Reader, error) {
+	r, err := openRegion(path, typeUint32, uint32Size, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint32Reader{r: r}, nil
+}
+
+// Get returns the value for the given key
+// returns false if the key is not in the store or the store is closed
+func (s *Uint32Reader) Get(key string) (uint32, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return 0, false
+	}
+
+	return getUint32(s.r.at(buf, 0)), true
+}
+
+// Size returns the number of keys in the store
+func (s *Uint32Reader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Uint32Reader) Capacity() int {
+	return s.r.capacity
+}
+
+// Members returns a list of keys in the store
+func (s *Uint32Reader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Uint32Reader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Uint32Reader) Close() error {
+	return s.r.close()
+}
+
+// Uint32Store is a store of uint32 values in a memory-mapped file shared with Uint32Readers in other processes
+// A file has a single Uint32Store open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Uint32Store struct {
+	sync.Mutex
+	Uint32Reader
+}
+
+// CreateUint32Store creates a new file at path holding up to capacity keys and opens it for writing
+// Fails if the file already exists
+func CreateUint32Store(path string, capacity int) (*Uint32Store, error) {
+	r, err := createRegion(path, typeUint32, uint32Size, capacity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint32Store{Uint32Reader: Uint32Reader{r: r}}, nil
+}
+
+// OpenUint32Store opens the existing Uint32Store file at path for writing
+func OpenUint32Store(path string) (*Uint32Store, error) {
+	r, err := openRegion(path, typeUint32, uint32Size, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint32Store{Uint32Reader: Uint32Reader{r: r}}, nil
+}
+
+func (s *Uint32Store) set(key string, value uint32) error {
+	return s.r.write(key, true, func(b []byte) error {
+		putUint32(s.r.at(b, 0), value)
+		le.PutUint32(b[offN:], 1)
+
+		return nil
+	})
+}
+
+// Set stores the given value mapped to the given key
+func (s *Uint32Store) Set(key string, value uint32) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its value from the store
+func (s *Uint32Store) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Uint32Store) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Uint32Store) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Uint64Reader is a read-only view of a Uint64Store file, mapped by any number of processes
+// Reads never block the writer or each other
+type Uint64Reader struct {
+	r *region
+}
+
+// OpenUint64Reader maps the Uint64Store file at path read-only
+func OpenUint64Reader(path string) (*Uint64Reader, error) {
+	r, err := openRegion(path, typeUint64, uint64Size, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint64Reader{r: r}, nil
+}
+
+// Get returns the value for the given key
+// returns false if the key is not in the store or the store is closed
+func (s *Uint64Reader) Get(key string) (uint64, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return 0, false
+	}
+
+	return getUint64(s.r.at(buf, 0)), true
+}
+
+// Size returns the number of keys in the store
+func (s *Uint64Reader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Uint64Reader) Capacity() int {
+	return s.r.capacity
+}
+
+// Members returns a list of keys in the store
+func (s *Uint64Reader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Uint64Reader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Uint64Reader) Close() error {
+	return s.r.close()
+}
+
+// Uint64Store is a store of uint64 values in a memory-mapped file shared with Uint64Readers in other processes
+// A file has a single Uint64Store open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Uint64Store struct {
+	sync.Mutex
+	Uint64Reader
+}
+
+// CreateUint64Store creates a new file at path holding up to capacity keys and opens it for writing
+// Fails if the file already exists
+func CreateUint64Store(path string, capacity int) (*Uint64Store, error) {
+	r, err := createRegion(path, typeUint64, uint64Size, capacity, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint64Store{Uint64Reader: Uint64Reader{r: r}}, nil
+}
+
+// OpenUint64Store opens the existing Uint64Store file at path for writing
+func OpenUint64Store(path string) (*Uint64Store, error) {
+	r, err := openRegion(path, typeUint64, uint64Size, false, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint64Store{Uint64Reader: Uint64Reader{r: r}}, nil
+}
+
+func (s *Uint64Store) set(key string, value uint64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		putUint64(s.r.at(b, 0), value)
+		le.PutUint32(b[offN:], 1)
+
+		return nil
+	})
+}
+
+// Set stores the given value mapped to the given key
+func (s *Uint64Store) Set(key string, value uint64) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its value from the store
+func (s *Uint64Store) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Uint64Store) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Uint64Store) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
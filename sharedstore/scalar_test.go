@@ -0,0 +1,221 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat64Store(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s, err := CreateFloat64Store(path, 4)
+	assert.Nil(t, err)
+	defer s.Close()
+	assert.Equal(t, 4, s.Capacity())
+
+	r, err := OpenFloat64Reader(path)
+	assert.Nil(t, err)
+	defer r.Close()
+
+	assert.Nil(t, s.Set("a", 1.5))
+	assert.Nil(t, s.Set("b", math.Inf(-1)))
+	assert.Nil(t, s.Set("a", 2.5))
+
+	// the writer and readers see the same values
+	for _, g := range []interface {
+		Get(key string) (float64, bool)
+	}{s, r} {
+		v, ok := g.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 2.5, v)
+		v, ok = g.Get("b")
+		assert.True(t, ok)
+		assert.True(t, math.IsInf(v, -1))
+		_, ok = g.Get("c")
+		assert.False(t, ok)
+	}
+
+	assert.Equal(t, 2, r.Size())
+	assert.ElementsMatch(t, []string{"a", "b"}, r.Members())
+	assert.True(t, r.IsMember("a"))
+
+	s.Delete("a")
+	assert.False(t, r.IsMember("a"))
+	assert.Equal(t, 1, r.Size())
+
+	s.Clear()
+	assert.Equal(t, 0, r.Size())
+	_, ok := r.Get("b")
+	assert.False(t, ok)
+}
+
+func TestFloat64StoreErrors(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	_, err := CreateFloat64Store(path, 0)
+	assert.Equal(t, ErrInvalidSize, err)
+
+	s, err := CreateFloat64Store(path, 1)
+	assert.Nil(t, err)
+
+	assert.Nil(t, s.Set("a", 1))
+	assert.Equal(t, ErrStoreFull, s.Set("b", 1))
+	assert.Equal(t, ErrKeyTooLong, s.Set(string(make([]byte, KeySize+1)), 1))
+
+	// one writer at a time
+	_, err = OpenFloat64Store(path)
+	assert.Equal(t, ErrWriterExists, err)
+
+	// a scalar file is not a series file or another value type
+	_, err = OpenFloat64SReader(path)
+	assert.Equal(t, ErrInvalidFile, err)
+	_, err = OpenInt64Reader(path)
+	assert.Equal(t, ErrInvalidFile, err)
+
+	assert.Nil(t, s.Close())
+	assert.Equal(t, ErrClosed, s.Set("a", 1))
+	_, ok := s.Get("a")
+	assert.False(t, ok)
+
+	// reopened with its contents
+	s, err = OpenFloat64Store(path)
+	assert.Nil(t, err)
+	defer s.Close()
+	v, ok := s.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1.0, v)
+	assert.Nil(t, s.Set("a", 2))
+}
+
+func TestScalarStoresRoundTrip(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	f32, err := CreateFloat32Store(path+"f32", 2)
+	assert.Nil(t, err)
+	defer f32.Close()
+	f32.Set("a", -1.25)
+	f32r, err := OpenFloat32Reader(path + "f32")
+	assert.Nil(t, err)
+	defer f32r.Close()
+	v32, _ := f32r.Get("a")
+	assert.Equal(t, float32(-1.25), v32)
+
+	i32, err := CreateInt32Store(path+"i32", 2)
+	assert.Nil(t, err)
+	defer i32.Close()
+	i32.Set("a", math.MinInt32)
+	i32r, err := OpenInt32Reader(path + "i32")
+	assert.Nil(t, err)
+	defer i32r.Close()
+	vi32, _ := i32r.Get("a")
+	assert.Equal(t, int32(math.MinInt32), vi32)
+
+	i64, err := CreateInt64Store(path+"i64", 2)
+	assert.Nil(t, err)
+	defer i64.Close()
+	i64.Set("a", math.MinInt64)
+	i64r, err := OpenInt64Reader(path + "i64")
+	assert.Nil(t, err)
+	defer i64r.Close()
+	vi64, _ := i64r.Get("a")
+	assert.Equal(t, int64(math.MinInt64), vi64)
+
+	u32, err := CreateUint32Store(path+"u32", 2)
+	assert.Nil(t, err)
+	defer u32.Close()
+	u32.Set("a", math.MaxUint32)
+	u32r, err := OpenUint32Reader(path + "u32")
+	assert.Nil(t, err)
+	defer u32r.Close()
+	vu32, _ := u32r.Get("a")
+	assert.Equal(t, uint32(math.MaxUint32), vu32)
+
+	u64, err := CreateUint64Store(path+"u64", 2)
+	assert.Nil(t, err)
+	defer u64.Close()
+	u64.Set("a", math.MaxUint64)
+	u64r, err := OpenUint64Reader(path + "u64")
+	assert.Nil(t, err)
+	defer u64r.Close()
+	vu64, _ := u64r.Get("a")
+	assert.Equal(t, uint64(math.MaxUint64), vu64)
+}
+
+func TestFloat64StoreConcurrentGetAndSet(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s, _ := CreateFloat64Store(path, 16)
+	defer s.Close()
+	r, _ := OpenFloat64Reader(path)
+	defer r.Close()
+
+	// both halves of a value are always written together
+	go func() {
+		for i := 0; i < 100000; i++ {
+			if i%2 == 0 {
+				s.Set("foo", 1)
+			} else {
+				s.Set("foo", math.Inf(1))
+			}
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 100000; i++ {
+			if v, ok := r.Get("foo"); ok && v != 1 && !math.IsInf(v, 1) {
+				t.Error("torn read ", v)
+				return
+			}
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
+
+func TestFloat64ReaderConcurrentGetAndClose(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s, _ := CreateFloat64Store(path, 16)
+	defer s.Close()
+	s.Set("foo", 1)
+
+	for round := 0; round < 50; round++ {
+		r, err := OpenFloat64Reader(path)
+		assert.Nil(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Close waits for reads in progress, later reads find the reader closed
+				for {
+					v, ok := r.Get("foo")
+					if !ok {
+						return
+					}
+					if v != 1 {
+						t.Error("bad read ", v)
+						return
+					}
+				}
+			}()
+		}
+
+		time.Sleep(time.Millisecond)
+		assert.Nil(t, r.Close())
+		wg.Wait()
+	}
+}
@@ -0,0 +1,1170 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"sync"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+)
+
+// Float32SReader is a read-only view of a Float32SStore file, mapped by any number of processes
+// Reads never block the writer or each other
+type Float32SReader struct {
+	r *region
+}
+
+// OpenFloat32SReader maps the Float32SStore file at path read-only
+func OpenFloat32SReader(path string) (*Float32SReader, error) {
+	r, err := openRegion(path, typeFloat32, float32Size, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float32SReader{r: r}, nil
+}
+
+func (s *Float32SReader) values(buf []byte, lower, upper int) []float32 {
+	v := make([]float32, upper-lower)
+	for i := range v {
+		v[i] = getFloat32(s.r.at(buf, lower+i))
+	}
+
+	return v
+}
+
+// Get returns a copy of the series for the given key, oldest value first
+// returns false if the key is not in the store or the store is closed
+func (s *Float32SReader) Get(key string) ([]float32, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return nil, false
+	}
+
+	return s.values(buf, 0, slotLen(buf)), true
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *Float32SReader) GetIdx(key string, idx int) (float32, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	// bounds check
+	if idx < 0 || idx >= slotLen(buf) {
+		return 0, ErrIdxOutOfBounds
+	}
+
+	return getFloat32(s.r.at(buf, idx)), nil
+}
+
+// GetRange returns a copy of all values for the given key within the specified range (inclusive:exclusive)
+func (s *Float32SReader) GetRange(key string, lower, upper int) ([]float32, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return nil, err
+	}
+
+	// bounds check
+	n := slotLen(buf)
+	if lower < 0 || lower > n || upper < lower || upper > n {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.values(buf, lower, upper), nil
+}
+
+// MemberLen returns the length of the series for the given key
+func (s *Float32SReader) MemberLen(key string) (int, error) {
+	buf := make([]byte, s.r.slotSize)
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	return slotLen(buf), nil
+}
+
+// Size returns the number of keys in the store
+func (s *Float32SReader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Float32SReader) Capacity() int {
+	return s.r.capacity
+}
+
+// Depth returns the number of values held for each key
+func (s *Float32SReader) Depth() int {
+	return s.r.depth
+}
+
+// Members returns a list of keys in the store
+func (s *Float32SReader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Float32SReader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Float32SReader) Close() error {
+	return s.r.close()
+}
+
+// Float32SStore is a store of float32 series in a memory-mapped file shared with Float32SReaders in other processes
+// Each key holds its last depth values, appending to a full series drops its oldest value
+// A file has a single Float32SStore open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Float32SStore struct {
+	sync.Mutex
+	Float32SReader
+}
+
+// CreateFloat32SStore creates a new file at path holding up to capacity keys of depth values each and opens it for writing
+// Fails if the file already exists
+func CreateFloat32SStore(path string, capacity, depth int) (*Float32SStore, error) {
+	if depth < 1 {
+		return nil, ErrInvalidSize
+	}
+
+	r, err := createRegion(path, typeFloat32, float32Size, capacity, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float32SStore{Float32SReader: Float32SReader{r: r}}, nil
+}
+
+// OpenFloat32SStore opens the existing Float32SStore file at path for writing
+func OpenFloat32SStore(path string) (*Float32SStore, error) {
+	r, err := openRegion(path, typeFloat32, float32Size, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float32SStore{Float32SReader: Float32SReader{r: r}}, nil
+}
+
+func (s *Float32SStore) set(key string, value []float32) error {
+	return s.r.write(key, true, func(b []byte) error {
+		le.PutUint32(b[offN:], 0)
+		le.PutUint32(b[offHead:], 0)
+		for _, v := range value {
+			putFloat32(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Set stores a copy of the given series mapped to the given key in the store, keeping only its last depth values
+func (s *Float32SStore) Set(key string, value []float32) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Float32SStore) append(key string, values ...float32) error {
+	return s.r.write(key, true, func(b []byte) error {
+		for _, v := range values {
+			putFloat32(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store,
+// dropping the oldest values beyond the store depth
+// The key is created if it does not exist
+func (s *Float32SStore) Append(key string, values ...float32) error {
+	s.Lock()
+	err := s.append(key, values...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Float32SStore) setIdx(key string, idx int, value float32) error {
+	return s.r.write(key, false, func(b []byte) error {
+		// bounds check
+		if idx < 0 || idx >= slotLen(b) {
+			return ErrIdxOutOfBounds
+		}
+
+		putFloat32(s.r.at(b, idx), value)
+
+		return nil
+	})
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *Float32SStore) SetIdx(key string, idx int, value float32) error {
+	s.Lock()
+	err := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its series from the store
+func (s *Float32SStore) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Float32SStore) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Float32SStore) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Float64SReader is a read-only view of a Float64SStore file, mapped by any number of processes
+// Reads never block the writer or each other
+type Float64SReader struct {
+	r *region
+}
+
+// OpenFloat64SReader maps the Float64SStore file at path read-only
+func OpenFloat64SReader(path string) (*Float64SReader, error) {
+	r, err := openRegion(path, typeFloat64, float64Size, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float64SReader{r: r}, nil
+}
+
+func (s *Float64SReader) values(buf []byte, lower, upper int) []float64 {
+	v := make([]float64, upper-lower)
+	for i := range v {
+		v[i] = getFloat64(s.r.at(buf, lower+i))
+	}
+
+	return v
+}
+
+// Get returns a copy of the series for the given key, oldest value first
+// returns false if the key is not in the store or the store is closed
+func (s *Float64SReader) Get(key string) ([]float64, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return nil, false
+	}
+
+	return s.values(buf, 0, slotLen(buf)), true
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *Float64SReader) GetIdx(key string, idx int) (float64, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	// bounds check
+	if idx < 0 || idx >= slotLen(buf) {
+		return 0, ErrIdxOutOfBounds
+	}
+
+	return getFloat64(s.r.at(buf, idx)), nil
+}
+
+// GetRange returns a copy of all values for the given key within the specified range (inclusive:exclusive)
+func (s *Float64SReader) GetRange(key string, lower, upper int) ([]float64, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return nil, err
+	}
+
+	// bounds check
+	n := slotLen(buf)
+	if lower < 0 || lower > n || upper < lower || upper > n {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.values(buf, lower, upper), nil
+}
+
+// MemberLen returns the length of the series for the given key
+func (s *Float64SReader) MemberLen(key string) (int, error) {
+	buf := make([]byte, s.r.slotSize)
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	return slotLen(buf), nil
+}
+
+// Size returns the number of keys in the store
+func (s *Float64SReader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Float64SReader) Capacity() int {
+	return s.r.capacity
+}
+
+// Depth returns the number of values held for each key
+func (s *Float64SReader) Depth() int {
+	return s.r.depth
+}
+
+// Members returns a list of keys in the store
+func (s *Float64SReader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Float64SReader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Float64SReader) Close() error {
+	return s.r.close()
+}
+
+// Float64SStore is a store of float64 series in a memory-mapped file shared with Float64SReaders in other processes
+// Each key holds its last depth values, appending to a full series drops its oldest value
+// A file has a single Float64SStore open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Float64SStore struct {
+	sync.Mutex
+	Float64SReader
+}
+
+// CreateFloat64SStore creates a new file at path holding up to capacity keys of depth values each and opens it for writing
+// Fails if the file already exists
+func CreateFloat64SStore(path string, capacity, depth int) (*Float64SStore, error) {
+	if depth < 1 {
+		return nil, ErrInvalidSize
+	}
+
+	r, err := createRegion(path, typeFloat64, float64Size, capacity, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float64SStore{Float64SReader: Float64SReader{r: r}}, nil
+}
+
+// OpenFloat64SStore opens the existing Float64SStore file at path for writing
+func OpenFloat64SStore(path string) (*Float64SStore, error) {
+	r, err := openRegion(path, typeFloat64, float64Size, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Float64SStore{Float64SReader: Float64SReader{r: r}}, nil
+}
+
+func (s *Float64SStore) set(key string, value []float64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		le.PutUint32(b[offN:], 0)
+		le.PutUint32(b[offHead:], 0)
+		for _, v := range value {
+			putFloat64(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Set stores a copy of the given series mapped to the given key in the store, keeping only its last depth values
+func (s *Float64SStore) Set(key string, value []float64) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Float64SStore) append(key string, values ...float64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		for _, v := range values {
+			putFloat64(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store,
+// dropping the oldest values beyond the store depth
+// The key is created if it does not exist
+func (s *Float64SStore) Append(key string, values ...float64) error {
+	s.Lock()
+	err := s.append(key, values...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Float64SStore) setIdx(key string, idx int, value float64) error {
+	return s.r.write(key, false, func(b []byte) error {
+		// bounds check
+		if idx < 0 || idx >= slotLen(b) {
+			return ErrIdxOutOfBounds
+		}
+
+		putFloat64(s.r.at(b, idx), value)
+
+		return nil
+	})
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *Float64SStore) SetIdx(key string, idx int, value float64) error {
+	s.Lock()
+	err := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its series from the store
+func (s *Float64SStore) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Float64SStore) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Float64SStore) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Int64SReader is a read-only view of a Int64SStore file, mapped by any number of processes
+// Reads never block the writer or each other
+type Int64SReader struct {
+	r *region
+}
+
+// OpenInt64SReader maps the Int64SStore file at path read-only
+func OpenInt64SReader(path string) (*Int64SReader, error) {
+	r, err := openRegion(path, typeInt64, int64Size, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int64SReader{r: r}, nil
+}
+
+func (s *Int64SReader) values(buf []byte, lower, upper int) []int64 {
+	v := make([]int64, upper-lower)
+	for i := range v {
+		v[i] = getInt64(s.r.at(buf, lower+i))
+	}
+
+	return v
+}
+
+// Get returns a copy of the series for the given key, oldest value first
+// returns false if the key is not in the store or the store is closed
+func (s *Int64SReader) Get(key string) ([]int64, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return nil, false
+	}
+
+	return s.values(buf, 0, slotLen(buf)), true
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *Int64SReader) GetIdx(key string, idx int) (int64, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	// bounds check
+	if idx < 0 || idx >= slotLen(buf) {
+		return 0, ErrIdxOutOfBounds
+	}
+
+	return getInt64(s.r.at(buf, idx)), nil
+}
+
+// GetRange returns a copy of all values for the given key within the specified range (inclusive:exclusive)
+func (s *Int64SReader) GetRange(key string, lower, upper int) ([]int64, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return nil, err
+	}
+
+	// bounds check
+	n := slotLen(buf)
+	if lower < 0 || lower > n || upper < lower || upper > n {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.values(buf, lower, upper), nil
+}
+
+// MemberLen returns the length of the series for the given key
+func (s *Int64SReader) MemberLen(key string) (int, error) {
+	buf := make([]byte, s.r.slotSize)
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	return slotLen(buf), nil
+}
+
+// Size returns the number of keys in the store
+func (s *Int64SReader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Int64SReader) Capacity() int {
+	return s.r.capacity
+}
+
+// Depth returns the number of values held for each key
+func (s *Int64SReader) Depth() int {
+	return s.r.depth
+}
+
+// Members returns a list of keys in the store
+func (s *Int64SReader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Int64SReader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Int64SReader) Close() error {
+	return s.r.close()
+}
+
+// Int64SStore is a store of int64 series in a memory-mapped file shared with Int64SReaders in other processes
+// Each key holds its last depth values, appending to a full series drops its oldest value
+// A file has a single Int64SStore open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Int64SStore struct {
+	sync.Mutex
+	Int64SReader
+}
+
+// CreateInt64SStore creates a new file at path holding up to capacity keys of depth values each and opens it for writing
+// Fails if the file already exists
+func CreateInt64SStore(path string, capacity, depth int) (*Int64SStore, error) {
+	if depth < 1 {
+		return nil, ErrInvalidSize
+	}
+
+	r, err := createRegion(path, typeInt64, int64Size, capacity, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int64SStore{Int64SReader: Int64SReader{r: r}}, nil
+}
+
+// OpenInt64SStore opens the existing Int64SStore file at path for writing
+func OpenInt64SStore(path string) (*Int64SStore, error) {
+	r, err := openRegion(path, typeInt64, int64Size, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Int64SStore{Int64SReader: Int64SReader{r: r}}, nil
+}
+
+func (s *Int64SStore) set(key string, value []int64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		le.PutUint32(b[offN:], 0)
+		le.PutUint32(b[offHead:], 0)
+		for _, v := range value {
+			putInt64(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Set stores a copy of the given series mapped to the given key in the store, keeping only its last depth values
+func (s *Int64SStore) Set(key string, value []int64) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Int64SStore) append(key string, values ...int64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		for _, v := range values {
+			putInt64(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store,
+// dropping the oldest values beyond the store depth
+// The key is created if it does not exist
+func (s *Int64SStore) Append(key string, values ...int64) error {
+	s.Lock()
+	err := s.append(key, values...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Int64SStore) setIdx(key string, idx int, value int64) error {
+	return s.r.write(key, false, func(b []byte) error {
+		// bounds check
+		if idx < 0 || idx >= slotLen(b) {
+			return ErrIdxOutOfBounds
+		}
+
+		putInt64(s.r.at(b, idx), value)
+
+		return nil
+	})
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *Int64SStore) SetIdx(key string, idx int, value int64) error {
+	s.Lock()
+	err := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its series from the store
+func (s *Int64SStore) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Int64SStore) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Int64SStore) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// Uint64SReader is a read-only view of a Uint64SStore file, mapped by any number of processes
+// Reads never block the writer or each other
+type Uint64SReader struct {
+	r *region
+}
+
+// OpenUint64SReader maps the Uint64SStore file at path read-only
+func OpenUint64SReader(path string) (*Uint64SReader, error) {
+	r, err := openRegion(path, typeUint64, uint64Size, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint64SReader{r: r}, nil
+}
+
+func (s *Uint64SReader) values(buf []byte, lower, upper int) []uint64 {
+	v := make([]uint64, upper-lower)
+	for i := range v {
+		v[i] = getUint64(s.r.at(buf, lower+i))
+	}
+
+	return v
+}
+
+// Get returns a copy of the series for the given key, oldest value first
+// returns false if the key is not in the store or the store is closed
+func (s *Uint64SReader) Get(key string) ([]uint64, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return nil, false
+	}
+
+	return s.values(buf, 0, slotLen(buf)), true
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *Uint64SReader) GetIdx(key string, idx int) (uint64, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	// bounds check
+	if idx < 0 || idx >= slotLen(buf) {
+		return 0, ErrIdxOutOfBounds
+	}
+
+	return getUint64(s.r.at(buf, idx)), nil
+}
+
+// GetRange returns a copy of all values for the given key within the specified range (inclusive:exclusive)
+func (s *Uint64SReader) GetRange(key string, lower, upper int) ([]uint64, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return nil, err
+	}
+
+	// bounds check
+	n := slotLen(buf)
+	if lower < 0 || lower > n || upper < lower || upper > n {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.values(buf, lower, upper), nil
+}
+
+// MemberLen returns the length of the series for the given key
+func (s *Uint64SReader) MemberLen(key string) (int, error) {
+	buf := make([]byte, s.r.slotSize)
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	return slotLen(buf), nil
+}
+
+// Size returns the number of keys in the store
+func (s *Uint64SReader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *Uint64SReader) Capacity() int {
+	return s.r.capacity
+}
+
+// Depth returns the number of values held for each key
+func (s *Uint64SReader) Depth() int {
+	return s.r.depth
+}
+
+// Members returns a list of keys in the store
+func (s *Uint64SReader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *Uint64SReader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *Uint64SReader) Close() error {
+	return s.r.close()
+}
+
+// Uint64SStore is a store of uint64 series in a memory-mapped file shared with Uint64SReaders in other processes
+// Each key holds its last depth values, appending to a full series drops its oldest value
+// A file has a single Uint64SStore open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type Uint64SStore struct {
+	sync.Mutex
+	Uint64SReader
+}
+
+// CreateUint64SStore creates a new file at path holding up to capacity keys of depth values each and opens it for writing
+// Fails if the file already exists
+func CreateUint64SStore(path string, capacity, depth int) (*Uint64SStore, error) {
+	if depth < 1 {
+		return nil, ErrInvalidSize
+	}
+
+	r, err := createRegion(path, typeUint64, uint64Size, capacity, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint64SStore{Uint64SReader: Uint64SReader{r: r}}, nil
+}
+
+// OpenUint64SStore opens the existing Uint64SStore file at path for writing
+func OpenUint64SStore(path string) (*Uint64SStore, error) {
+	r, err := openRegion(path, typeUint64, uint64Size, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Uint64SStore{Uint64SReader: Uint64SReader{r: r}}, nil
+}
+
+func (s *Uint64SStore) set(key string, value []uint64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		le.PutUint32(b[offN:], 0)
+		le.PutUint32(b[offHead:], 0)
+		for _, v := range value {
+			putUint64(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Set stores a copy of the given series mapped to the given key in the store, keeping only its last depth values
+func (s *Uint64SStore) Set(key string, value []uint64) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Uint64SStore) append(key string, values ...uint64) error {
+	return s.r.write(key, true, func(b []byte) error {
+		for _, v := range values {
+			putUint64(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store,
+// dropping the oldest values beyond the store depth
+// The key is created if it does not exist
+func (s *Uint64SStore) Append(key string, values ...uint64) error {
+	s.Lock()
+	err := s.append(key, values...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *Uint64SStore) setIdx(key string, idx int, value uint64) error {
+	return s.r.write(key, false, func(b []byte) error {
+		// bounds check
+		if idx < 0 || idx >= slotLen(b) {
+			return ErrIdxOutOfBounds
+		}
+
+		putUint64(s.r.at(b, idx), value)
+
+		return nil
+	})
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *Uint64SStore) SetIdx(key string, idx int, value uint64) error {
+	s.Lock()
+	err := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its series from the store
+func (s *Uint64SStore) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *Uint64SStore) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *Uint64SStore) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
+
+// OHLCSReader is a read-only view of a OHLCSStore file, mapped by any number of processes
+// Reads never block the writer or each other
+type OHLCSReader struct {
+	r *region
+}
+
+// OpenOHLCSReader maps the OHLCSStore file at path read-only
+func OpenOHLCSReader(path string) (*OHLCSReader, error) {
+	r, err := openRegion(path, typeOHLC, ohlcSize, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OHLCSReader{r: r}, nil
+}
+
+func (s *OHLCSReader) values(buf []byte, lower, upper int) []seriesstore.OHLC {
+	v := make([]seriesstore.OHLC, upper-lower)
+	for i := range v {
+		v[i] = getOHLC(s.r.at(buf, lower+i))
+	}
+
+	return v
+}
+
+// Get returns a copy of the series for the given key, oldest value first
+// returns false if the key is not in the store or the store is closed
+func (s *OHLCSReader) Get(key string) ([]seriesstore.OHLC, bool) {
+	buf := make([]byte, s.r.slotSize)
+	if s.r.lookup(key, buf) != nil {
+		return nil, false
+	}
+
+	return s.values(buf, 0, slotLen(buf)), true
+}
+
+// GetIdx returns the value for the given key at the specified index
+func (s *OHLCSReader) GetIdx(key string, idx int) (seriesstore.OHLC, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return seriesstore.OHLC{}, err
+	}
+
+	// bounds check
+	if idx < 0 || idx >= slotLen(buf) {
+		return seriesstore.OHLC{}, ErrIdxOutOfBounds
+	}
+
+	return getOHLC(s.r.at(buf, idx)), nil
+}
+
+// GetRange returns a copy of all values for the given key within the specified range (inclusive:exclusive)
+func (s *OHLCSReader) GetRange(key string, lower, upper int) ([]seriesstore.OHLC, error) {
+	buf := make([]byte, s.r.slotSize)
+
+	// check exists
+	if err := s.r.lookup(key, buf); err != nil {
+		return nil, err
+	}
+
+	// bounds check
+	n := slotLen(buf)
+	if lower < 0 || lower > n || upper < lower || upper > n {
+		return nil, ErrIdxOutOfBounds
+	}
+
+	return s.values(buf, lower, upper), nil
+}
+
+// MemberLen returns the length of the series for the given key
+func (s *OHLCSReader) MemberLen(key string) (int, error) {
+	buf := make([]byte, s.r.slotSize)
+	if err := s.r.lookup(key, buf); err != nil {
+		return 0, err
+	}
+
+	return slotLen(buf), nil
+}
+
+// Size returns the number of keys in the store
+func (s *OHLCSReader) Size() int {
+	return s.r.size()
+}
+
+// Capacity returns the number of keys the store can hold
+func (s *OHLCSReader) Capacity() int {
+	return s.r.capacity
+}
+
+// Depth returns the number of values held for each key
+func (s *OHLCSReader) Depth() int {
+	return s.r.depth
+}
+
+// Members returns a list of keys in the store
+func (s *OHLCSReader) Members() []string {
+	return s.r.members()
+}
+
+// IsMember checks if the given key is in the store
+func (s *OHLCSReader) IsMember(key string) bool {
+	return s.r.isMember(key)
+}
+
+// Close unmaps the file once reads in progress return, the reader must not be used after
+func (s *OHLCSReader) Close() error {
+	return s.r.close()
+}
+
+// OHLCSStore is a store of seriesstore.OHLC series in a memory-mapped file shared with OHLCSReaders in other processes
+// Each key holds its last depth values, appending to a full series drops its oldest value
+// A file has a single OHLCSStore open at a time, in one process
+// Embedded sync.Mutex to provide atomic operation ability
+type OHLCSStore struct {
+	sync.Mutex
+	OHLCSReader
+}
+
+// CreateOHLCSStore creates a new file at path holding up to capacity keys of depth values each and opens it for writing
+// Fails if the file already exists
+func CreateOHLCSStore(path string, capacity, depth int) (*OHLCSStore, error) {
+	if depth < 1 {
+		return nil, ErrInvalidSize
+	}
+
+	r, err := createRegion(path, typeOHLC, ohlcSize, capacity, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OHLCSStore{OHLCSReader: OHLCSReader{r: r}}, nil
+}
+
+// OpenOHLCSStore opens the existing OHLCSStore file at path for writing
+func OpenOHLCSStore(path string) (*OHLCSStore, error) {
+	r, err := openRegion(path, typeOHLC, ohlcSize, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OHLCSStore{OHLCSReader: OHLCSReader{r: r}}, nil
+}
+
+func (s *OHLCSStore) set(key string, value []seriesstore.OHLC) error {
+	return s.r.write(key, true, func(b []byte) error {
+		le.PutUint32(b[offN:], 0)
+		le.PutUint32(b[offHead:], 0)
+		for _, v := range value {
+			putOHLC(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Set stores a copy of the given series mapped to the given key in the store, keeping only its last depth values
+func (s *OHLCSStore) Set(key string, value []seriesstore.OHLC) error {
+	s.Lock()
+	err := s.set(key, value)
+	s.Unlock()
+
+	return err
+}
+
+func (s *OHLCSStore) append(key string, values ...seriesstore.OHLC) error {
+	return s.r.write(key, true, func(b []byte) error {
+		for _, v := range values {
+			putOHLC(s.r.push(b), v)
+		}
+
+		return nil
+	})
+}
+
+// Append adds the given values to the end of the series mapped to the given key in the store,
+// dropping the oldest values beyond the store depth
+// The key is created if it does not exist
+func (s *OHLCSStore) Append(key string, values ...seriesstore.OHLC) error {
+	s.Lock()
+	err := s.append(key, values...)
+	s.Unlock()
+
+	return err
+}
+
+func (s *OHLCSStore) setIdx(key string, idx int, value *seriesstore.OHLC) error {
+	return s.r.write(key, false, func(b []byte) error {
+		// bounds check
+		if idx < 0 || idx >= slotLen(b) {
+			return ErrIdxOutOfBounds
+		}
+
+		putOHLC(s.r.at(b, idx), *value)
+
+		return nil
+	})
+}
+
+// SetIdx stores the given value mapped to the given key at the specified index in the store
+func (s *OHLCSStore) SetIdx(key string, idx int, value *seriesstore.OHLC) error {
+	s.Lock()
+	err := s.setIdx(key, idx, value)
+	s.Unlock()
+
+	return err
+}
+
+// Delete removes the given key and its series from the store
+func (s *OHLCSStore) Delete(key string) {
+	s.Lock()
+	s.r.delete(key)
+	s.Unlock()
+}
+
+// Clear removes all keys from the store
+func (s *OHLCSStore) Clear() {
+	s.Lock()
+	s.r.clear()
+	s.Unlock()
+}
+
+// Close unmaps the file and releases it to another writer, the store must not be used after
+func (s *OHLCSStore) Close() error {
+	s.Lock()
+	err := s.r.close()
+	s.Unlock()
+
+	return err
+}
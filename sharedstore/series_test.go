@@ -0,0 +1,213 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/blacklabcapital/safestore/seriesstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloat64SStore(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	_, err := CreateFloat64SStore(path, 4, 0)
+	assert.Equal(t, ErrInvalidSize, err)
+
+	s, err := CreateFloat64SStore(path, 4, 3)
+	assert.Nil(t, err)
+	defer s.Close()
+	assert.Equal(t, 3, s.Depth())
+
+	r, err := OpenFloat64SReader(path)
+	assert.Nil(t, err)
+	defer r.Close()
+	assert.Equal(t, 3, r.Depth())
+	assert.Equal(t, 4, r.Capacity())
+
+	assert.Nil(t, s.Append("a", 1, 2))
+	v, ok := r.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []float64{1, 2}, v)
+
+	// only the last depth values are kept
+	assert.Nil(t, s.Append("a", 3, 4))
+	v, _ = r.Get("a")
+	assert.Equal(t, []float64{2, 3, 4}, v)
+	n, err := r.MemberLen("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	assert.Nil(t, s.Set("b", []float64{5, 6, 7, 8}))
+	v, _ = r.Get("b")
+	assert.Equal(t, []float64{6, 7, 8}, v)
+	assert.Nil(t, s.Set("b", nil))
+	v, ok = r.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, []float64{}, v)
+
+	assert.ElementsMatch(t, []string{"a", "b"}, r.Members())
+	assert.Equal(t, 2, r.Size())
+	s.Delete("b")
+	assert.False(t, r.IsMember("b"))
+
+	s.Clear()
+	assert.Equal(t, 0, r.Size())
+	_, ok = r.Get("a")
+	assert.False(t, ok)
+}
+
+func TestFloat64SStoreIdx(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s, err := CreateFloat64SStore(path, 4, 4)
+	assert.Nil(t, err)
+	defer s.Close()
+	r, err := OpenFloat64SReader(path)
+	assert.Nil(t, err)
+	defer r.Close()
+
+	_, err = r.GetIdx("a", 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = r.GetRange("a", 0, 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	_, err = r.MemberLen("a")
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+	assert.Equal(t, ErrKeyDoesNotExist, s.SetIdx("a", 0, 1))
+	assert.False(t, s.IsMember("a"))
+
+	s.Append("a", 1, 2, 3, 4, 5, 6)
+
+	// indices count from the oldest kept value
+	v, err := r.GetIdx("a", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, v)
+	v, err = r.GetIdx("a", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 6.0, v)
+	_, err = r.GetIdx("a", 4)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+	_, err = r.GetIdx("a", -1)
+	assert.Equal(t, ErrIdxOutOfBounds, err)
+
+	vs, err := r.GetRange("a", 1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{4, 5}, vs)
+	vs, err = r.GetRange("a", 4, 4)
+	assert.Nil(t, err)
+	assert.Len(t, vs, 0)
+	for _, b := range [][2]int{{-1, 2}, {0, 5}, {3, 2}} {
+		_, err = r.GetRange("a", b[0], b[1])
+		assert.Equal(t, ErrIdxOutOfBounds, err)
+	}
+
+	assert.Nil(t, s.SetIdx("a", 1, 9))
+	assert.Equal(t, ErrIdxOutOfBounds, s.SetIdx("a", 4, 9))
+	vs, _ = r.Get("a")
+	assert.Equal(t, []float64{3, 9, 5, 6}, vs)
+}
+
+func TestOHLCSStore(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s, err := CreateOHLCSStore(path, 2, 2)
+	assert.Nil(t, err)
+
+	bars := []seriesstore.OHLC{
+		{Open: 100, High: 200, Low: 50, Close: 101},
+		{Open: 101, High: 201, Low: 49, Close: 102},
+		{Open: 102, High: 202, Low: 48, Close: 103},
+	}
+	assert.Nil(t, s.Append("a", bars...))
+	assert.Nil(t, s.SetIdx("a", 0, &seriesstore.OHLC{Open: 1, High: 2, Low: 0.5, Close: 1.5}))
+	assert.Equal(t, ErrIdxOutOfBounds, s.SetIdx("a", 2, &seriesstore.OHLC{}))
+	assert.Nil(t, s.Close())
+	_, ok := s.Get("a")
+	assert.False(t, ok)
+	_, err = s.GetIdx("a", 0)
+	assert.Equal(t, ErrClosed, err)
+
+	// reopened by a new writer with its contents
+	s, err = OpenOHLCSStore(path)
+	assert.Nil(t, err)
+	defer s.Close()
+	assert.Equal(t, 2, s.Depth())
+
+	r, err := OpenOHLCSReader(path)
+	assert.Nil(t, err)
+	defer r.Close()
+
+	v, ok := r.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []seriesstore.OHLC{{Open: 1, High: 2, Low: 0.5, Close: 1.5}, bars[2]}, v)
+	_, err = r.GetIdx("b", 0)
+	assert.Equal(t, ErrKeyDoesNotExist, err)
+
+	// a series file is not a scalar file
+	_, err = OpenFloat32Reader(path)
+	assert.Equal(t, ErrInvalidFile, err)
+}
+
+func TestSeriesStoresRoundTrip(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	f32, err := CreateFloat32SStore(path+"f32", 2, 2)
+	assert.Nil(t, err)
+	defer f32.Close()
+	f32.Append("a", 1.5, -2.5)
+	v32, _ := f32.Get("a")
+	assert.Equal(t, []float32{1.5, -2.5}, v32)
+
+	i64, err := CreateInt64SStore(path+"i64", 2, 2)
+	assert.Nil(t, err)
+	defer i64.Close()
+	i64.Append("a", -1, 1)
+	vi64, _ := i64.Get("a")
+	assert.Equal(t, []int64{-1, 1}, vi64)
+
+	u64, err := CreateUint64SStore(path+"u64", 2, 2)
+	assert.Nil(t, err)
+	defer u64.Close()
+	u64.Append("a", 1, 1<<63)
+	vu64, _ := u64.Get("a")
+	assert.Equal(t, []uint64{1, 1 << 63}, vu64)
+}
+
+func TestOHLCSStoreConcurrentGetAndAppend(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+
+	s, _ := CreateOHLCSStore(path, 16, 64)
+	defer s.Close()
+	r, _ := OpenOHLCSReader(path)
+	defer r.Close()
+
+	// every bar has all four fields equal
+	go func() {
+		for i := 0; i < 100000; i++ {
+			f := float32(i)
+			s.Append("foo", seriesstore.OHLC{Open: f, High: f, Low: f, Close: f})
+		}
+	}()
+
+	go func() {
+		for i := 0; i < 10000; i++ {
+			v, _ := r.Get("foo")
+			for j, b := range v {
+				if b.Open != b.High || b.Open != b.Low || b.Open != b.Close || (j > 0 && b.Open != v[j-1].Open+1) {
+					t.Error("torn read ", b)
+					return
+				}
+			}
+		}
+	}()
+
+	time.Sleep(time.Second * 2)
+}
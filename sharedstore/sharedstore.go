@@ -0,0 +1,79 @@
+//go:build linux
+// +build linux
+
+package sharedstore
+
+import (
+	"errors"
+)
+
+var (
+	// ErrKeyDoesNotExist is thrown when a search key is not found
+	ErrKeyDoesNotExist = errors.New("key does not exist")
+	// ErrIdxOutOfBounds is thrown when given indices for a range are out of bounds
+	ErrIdxOutOfBounds = errors.New("index out of bounds")
+	// ErrKeyTooLong is thrown when writing a key longer than KeySize bytes
+	ErrKeyTooLong = errors.New("key too long")
+	// ErrStoreFull is thrown when writing a new key to a store with every slot in use
+	ErrStoreFull = errors.New("store full")
+	// ErrWriterExists is thrown when opening a file for writing while another writer holds it
+	ErrWriterExists = errors.New("file already has a writer")
+	// ErrInvalidFile is thrown when opening a file that is not a store of the expected layout and value type
+	ErrInvalidFile = errors.New("invalid store file")
+	// ErrInvalidSize is thrown when creating a store with a capacity or depth below 1
+	ErrInvalidSize = errors.New("invalid store size")
+	// ErrClosed is thrown when using a closed store
+	ErrClosed = errors.New("store closed")
+)
+
+const (
+	// KeySize is the longest key in bytes
+	KeySize = 40
+	// Version is the layout version written to new files
+	Version = 1
+
+	magic      = "SAFESTOR"
+	headerSize = 64
+	slotHeader = 24
+)
+
+// value types of the file header
+const (
+	typeFloat32 = iota + 1
+	typeFloat64
+	typeInt32
+	typeInt64
+	typeUint32
+	typeUint64
+	typeOHLC
+)
+
+// header field offsets
+const (
+	offMagic     = 0
+	offVersion   = 8
+	offType      = 12
+	offValueSize = 16
+	offDepth     = 20
+	offCapacity  = 24
+	offKeySize   = 28
+	offSlotSize  = 32
+	offCount     = 40
+)
+
+// slot field offsets
+const (
+	offSeq    = 0
+	offState  = 8
+	offKeyLen = 12
+	offN      = 16
+	offHead   = 20
+	offKey    = 24
+)
+
+// slot states
+const (
+	slotEmpty = iota
+	slotUsed
+	slotDeleted
+)